@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"server/sdk"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// approvalsCommand habla con el endpoint local de aprobaciones del SDK que esta corriendo
+func approvalsCommand(cfg *sdk.Config, args []string) error {
+	if cfg.Approvals == nil {
+		return errors.New("approvals are not configured")
+	}
+	approval, err := cfg.Approvals.ApprovalConfig()
+	if err != nil {
+		return err
+	}
+	base := "http://" + approval.Listen + "/approvals"
+
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+
+	switch args[0] {
+	case "list":
+		var body struct {
+			Pending []sdk.PendingApproval `json:"pending"`
+		}
+		if err := callApprovals(http.MethodGet, base, approval.Token, nil, &body); err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ACTION ID\tACTION\tTARGET\tEXPIRES IN\tREASONING")
+		for _, p := range body.Pending {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.ActionID, p.Action, p.Target,
+				time.Until(p.ExpiresAt).Round(time.Second), p.Reasoning)
+		}
+		return w.Flush()
+
+	case "approve", "deny":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: approvals %s <action_id>", args[0])
+		}
+		var payload interface{}
+		if args[0] == "deny" && len(args) > 2 {
+			payload = map[string]string{"reason": strings.Join(args[2:], " ")}
+		}
+		if err := callApprovals(http.MethodPost, base+"/"+args[1]+"/"+args[0], approval.Token, payload, nil); err != nil {
+			return err
+		}
+		fmt.Printf("%s: %s\n", args[1], map[string]string{"approve": "approved", "deny": "denied"}[args[0]])
+		return nil
+	}
+
+	return fmt.Errorf("unknown approvals command %q (list, approve, deny)", args[0])
+}
+
+func callApprovals(method, url, token string, payload, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		raw, _ := json.Marshal(payload)
+		body = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var answer struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&answer)
+		return fmt.Errorf("sdk answered %d: %s", resp.StatusCode, answer.Error)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
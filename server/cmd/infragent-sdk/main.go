@@ -0,0 +1,133 @@
+package main
+
+// infragent-sdk corre el SDK sin escribir Go: lee las acciones, checks y la politica
+// de un YAML (ver sdk.Config)
+//
+//	infragent-sdk -config /etc/infragent/sdk.yaml
+//
+// Con approvals configurado, las acciones retenidas se manejan con:
+//
+//	infragent-sdk -config ... approvals list
+//	infragent-sdk -config ... approvals approve <action_id>
+//	infragent-sdk -config ... approvals deny <action_id> [motivo]
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"server/sdk"
+	"syscall"
+	"time"
+)
+
+func main() {
+	configPath := flag.String("config", "infragent.yaml", "path to the YAML config")
+	flag.Parse()
+
+	cfg, err := sdk.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if flag.Arg(0) == "approvals" {
+		if err := approvalsCommand(cfg, flag.Args()[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	agent, err := sdk.NewSDKFromConfig(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for _, spec := range cfg.Monitors {
+		monitor, err := spec.MonitorConfig()
+		if err != nil {
+			log.Fatal(err)
+		}
+		go func() {
+			if err := agent.Monitor(ctx, monitor); err != nil && ctx.Err() == nil {
+				log.Printf("[SDK] el monitor de %s termino: %v", monitor.Service, err)
+			}
+		}()
+	}
+
+	if cfg.Metrics != nil {
+		metrics, err := cfg.Metrics.MetricsConfig()
+		if err != nil {
+			log.Fatal(err)
+		}
+		go func() {
+			if err := agent.CollectMetrics(ctx, metrics); err != nil && ctx.Err() == nil {
+				log.Printf("[SDK] las metricas del host se apagaron: %v", err)
+			}
+		}()
+	}
+
+	for _, spec := range cfg.Logs {
+		watch, err := spec.LogWatch()
+		if err != nil {
+			log.Fatal(err)
+		}
+		go func() {
+			if err := agent.TailLogs(ctx, watch); err != nil && ctx.Err() == nil {
+				log.Printf("[SDK] se dejo de seguir %s: %v", watch.Path, err)
+			}
+		}()
+	}
+
+	if cfg.Kubernetes != nil {
+		watch, err := cfg.Kubernetes.KubeWatchConfig()
+		if err != nil {
+			log.Fatal(err)
+		}
+		api, err := sdk.InClusterKubeAPI()
+		if err != nil {
+			log.Fatal(err)
+		}
+		go func() {
+			if err := agent.WatchKubernetes(ctx, api, watch); err != nil && ctx.Err() == nil {
+				log.Printf("[SDK] se dejo de vigilar Kubernetes: %v", err)
+			}
+		}()
+	}
+
+	if cfg.Approvals != nil {
+		go func() {
+			if err := agent.ServeApprovals(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("[SDK] el endpoint de aprobaciones termino: %v", err)
+			}
+		}()
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		if cfg.Mode == "pull" {
+			errc <- agent.RunPull(ctx)
+		} else {
+			errc <- agent.Start(ctx)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+	case err := <-errc:
+		if err != nil && ctx.Err() == nil {
+			log.Printf("[SDK] %v", err)
+		}
+	}
+
+	// las acciones en curso terminan y los eventos que quedaron en la cola salen antes
+	// de terminar (o quedan en el buffer en disco)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := agent.Shutdown(shutdownCtx); err != nil {
+		log.Printf("[SDK] no se pudo terminar limpio: %v", err)
+	}
+}
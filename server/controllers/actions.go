@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	models "server/model"
+	"server/repositories"
+	"server/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ActionController struct {
+	results *service.ActionResults
+	undo    *service.Undo
+}
+
+func NewActionController(results *service.ActionResults, undo *service.Undo) *ActionController {
+	return &ActionController{results: results, undo: undo}
+}
+
+// ReportResult recibe el progreso o el resultado final de una accion asincrona desde el SDK
+func (ac *ActionController) ReportResult(c *gin.Context) {
+	client, ok := c.MustGet("client").(*models.Client)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var report models.ActionResultReport
+	if err := c.ShouldBindJSON(&report); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+
+	action, err := ac.results.ReportResult(c.Request.Context(), client.ID.String(), c.Param("id"), &report)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrActionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrActionFinished):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvalidActionStatus):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": action.ID, "status": action.Status})
+}
+
+// Undo ejecuta la accion compensatoria de una accion (desde el dashboard)
+func (ac *ActionController) Undo(ctx *gin.Context) {
+	clientID, ok := currentClientID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ticket, err := ac.undo.Undo(ctx.Request.Context(), clientID, ctx.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrActionNotFound):
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrAlreadyUndone), errors.Is(err, service.ErrUndoNotFinished):
+			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrNotReversible), errors.Is(err, service.ErrUndoRejected):
+			ctx.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if ticket.Status == models.QueueQueued {
+		status = http.StatusAccepted
+	}
+	ctx.JSON(status, ticket)
+}
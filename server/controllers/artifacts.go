@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	models "server/model"
+	"server/repositories"
+	"server/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ArtifactController struct {
+	artifacts *service.Artifacts
+}
+
+func NewArtifactController(artifacts *service.Artifacts) *ArtifactController {
+	return &ArtifactController{artifacts: artifacts}
+}
+
+// Upload: POST /v1/actions/:id/artifacts?name=goroutines.txt, el body es el archivo
+func (ac *ArtifactController) Upload(c *gin.Context) {
+	client, ok := c.MustGet("client").(*models.Client)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, models.MaxArtifactSize)
+	artifact, err := ac.artifacts.Upload(c.Request.Context(), client.ID.String(), c.Param("id"), c.Query("name"), c.ContentType(), body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.Is(err, repositories.ErrActionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrArtifactTooLarge), errors.As(err, &tooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": service.ErrArtifactTooLarge.Error()})
+		case errors.Is(err, service.ErrInvalidArtifact):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			log.Printf("[Artifacts] no se pudo guardar el artifact de %s: %v", c.Param("id"), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, artifact)
+}
+
+// List: GET /api/actions/:id/artifacts
+func (ac *ArtifactController) List(ctx *gin.Context) {
+	clientID, ok := currentClientID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	artifacts, err := ac.artifacts.List(ctx.Request.Context(), clientID, ctx.Param("id"))
+	if err != nil {
+		if errors.Is(err, repositories.ErrActionNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, artifacts)
+}
+
+// Download: GET /api/artifacts/:id, siempre como adjunto (nunca se renderiza en el dashboard)
+func (ac *ArtifactController) Download(ctx *gin.Context) {
+	clientID, ok := currentClientID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	artifact, content, err := ac.artifacts.Open(ctx.Request.Context(), clientID, ctx.Param("id"))
+	if err != nil {
+		if errors.Is(err, repositories.ErrArtifactNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("[Artifacts] no se pudo abrir el artifact %s: %v", ctx.Param("id"), err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	defer content.Close()
+
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", artifact.Name))
+	ctx.Header("Content-Length", strconv.FormatInt(artifact.Size, 10))
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Header("X-Artifact-SHA256", artifact.SHA256)
+	ctx.Status(http.StatusOK)
+	ctx.Header("Content-Type", artifact.ContentType)
+	io.Copy(ctx.Writer, content)
+}
@@ -68,7 +68,7 @@ func (lc *LoginController) CompleteRegistration(ctx *gin.Context) {
 	var req models.CompleteRegistrationRequest
 
 	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "webhook_url is required"})
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "company_name is required"})
 		return
 	}
 
@@ -85,7 +85,7 @@ func (lc *LoginController) CompleteRegistration(ctx *gin.Context) {
 		return
 	}
 
-	response, err := lc.service.CompleteRegistration(ctx, userIDStr, req.CompanyName, req.WebhookURL)
+	response, err := lc.service.CompleteRegistration(ctx, userIDStr, req.CompanyName, req.WebhookURL, req.DeliveryMode)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -115,13 +115,14 @@ func (lc *LoginController) GetCurrentUser(ctx *gin.Context) {
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"id":           user.ID,
-		"email":        user.Email,
-		"name":         user.Nombre,
-		"company_name": user.CompanyName,
-		"webhook_url":  user.WebhookURL,
-		"metodo":       user.Metodo,
-		"created_at":   user.CreatedAt,
-		"updated_at":   user.UpdatedAt,
+		"id":            user.ID,
+		"email":         user.Email,
+		"name":          user.Nombre,
+		"company_name":  user.CompanyName,
+		"webhook_url":   user.WebhookURL,
+		"delivery_mode": user.DeliveryMode,
+		"metodo":        user.Metodo,
+		"created_at":    user.CreatedAt,
+		"updated_at":    user.UpdatedAt,
 	})
 }
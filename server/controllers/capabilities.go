@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	models "server/model"
+	"server/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CapabilityController struct {
+	registry *service.CapabilityRegistry
+}
+
+func NewCapabilityController(registry *service.CapabilityRegistry) *CapabilityController {
+	return &CapabilityController{registry: registry}
+}
+
+// Register: POST /v1/capabilities, el SDK manda sus handlers al arrancar
+func (cc *CapabilityController) Register(c *gin.Context) {
+	client, ok := c.MustGet("client").(*models.Client)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var caps models.Capabilities
+	if err := c.ShouldBindJSON(&caps); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+
+	if err := cc.registry.Register(c.Request.Context(), client, &caps); err != nil {
+		if errors.Is(err, service.ErrInvalidCapabilities) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "registered", "actions": len(caps.Actions)})
+}
+
+// Get: GET /api/capabilities, lo que registro el SDK del cliente (dashboard)
+func (cc *CapabilityController) Get(ctx *gin.Context) {
+	clientID, ok := currentClientID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	caps, err := cc.registry.Get(ctx.Request.Context(), clientID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	if caps == nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "the SDK has not registered its capabilities yet"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, caps)
+}
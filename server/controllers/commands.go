@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	models "server/model"
+	"server/repositories"
+	"server/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CommandController struct {
+	commands *service.Commands
+}
+
+func NewCommandController(commands *service.Commands) *CommandController {
+	return &CommandController{commands: commands}
+}
+
+// Poll: GET /v1/commands?wait=30 (segundos). Long-polling para SDKs en modo pull
+func (cc *CommandController) Poll(c *gin.Context) {
+	client, ok := c.MustGet("client").(*models.Client)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	wait := 30 * time.Second
+	if raw := c.Query("wait"); raw != "" {
+		seconds, err := strconv.Atoi(raw)
+		if err != nil || seconds < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "wait must be a number of seconds"})
+			return
+		}
+		wait = time.Duration(seconds) * time.Second
+	}
+
+	commands, err := cc.commands.Poll(c.Request.Context(), client.ID.String(), client.ProtocolVersion, wait)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"commands": commands})
+}
+
+// Ack: POST /v1/commands/:id/ack
+func (cc *CommandController) Ack(c *gin.Context) {
+	client, ok := c.MustGet("client").(*models.Client)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := cc.commands.Ack(c.Request.Context(), client.ID.String(), c.Param("id")); err != nil {
+		if errors.Is(err, repositories.ErrActionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "command not found or already acked"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": models.ActionStatusRunning})
+}
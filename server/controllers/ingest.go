@@ -1,50 +1,45 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	models "server/model"
 	"server/service"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-type IngestHandlerController struct {
-	ingestHandler service.IngestHandler
+type IngestController struct {
+	ingest *service.IngestHandler
 }
 
-func NewNewEventInRequest(s service.IngestHandler) *IngestHandlerController {
-	return &IngestHandlerController{ingestHandler: s}
+func NewIngestController(ingest *service.IngestHandler) *IngestController {
+	return &IngestController{ingest: ingest}
 }
 
-func (IHC *IngestHandlerController) NewEventInRequest(c *gin.Context) {
-
-	authHeader := c.GetHeader("Authorization")
-
-	if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer") {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Se requiere API KEY"})
+// ReportEvents: POST /v1/events, el SDK manda los eventos en lotes
+func (ic *IngestController) ReportEvents(c *gin.Context) {
+	client, ok := c.MustGet("client").(*models.Client)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 		return
 	}
-	apiKey := strings.TrimPrefix(authHeader, "Bearer")
-
-	var event models.Event
 
-	if err := c.ShouldBindJSON(&event); err != nil {
+	var batch models.EventBatch
+	if err := c.ShouldBindJSON(&batch); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "formato invalido para evento"})
 		return
 	}
 
-	err := IHC.ingestHandler.NewEventInRequestService(c.Request.Context(), apiKey, &event)
-
+	ids, err := ic.ingest.Ingest(c.Request.Context(), client, batch.Events)
 	if err != nil {
-		if err.Error() == "unauthorized" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "faltan credenciales para poder validar la peticion"})
+		if errors.Is(err, service.ErrInvalidEvent) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error interno, intentelo mas tarde"})
 		return
 	}
 
-	c.JSON(http.StatusAccepted, gin.H{"status": "event_queued"})
-
+	c.JSON(http.StatusAccepted, gin.H{"status": "event_queued", "event_ids": ids})
 }
@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"net/http"
+	executor "server/service/exec"
+
+	"github.com/gin-gonic/gin"
+)
+
+type QueueController struct {
+	queue *executor.ExecutionQueue
+}
+
+func NewQueueController(queue *executor.ExecutionQueue) *QueueController {
+	return &QueueController{queue: queue}
+}
+
+// GetQueue devuelve, por target, la accion en vuelo y las que esperan su turno
+func (qc *QueueController) GetQueue(ctx *gin.Context) {
+	clientID, ok := currentClientID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"targets": qc.queue.State(clientID)})
+}
@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	models "server/model"
+	"server/repositories"
+	"server/service"
+	"server/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TargetController struct {
+	targets *service.Targets
+}
+
+func NewTargetController(targets *service.Targets) *TargetController {
+	return &TargetController{targets: targets}
+}
+
+// userID del JWT (el usuario logueado es el cliente)
+func currentClientID(ctx *gin.Context) (string, bool) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		return "", false
+	}
+	userIDStr, ok := userID.(string)
+	return userIDStr, ok
+}
+
+func (tc *TargetController) ListTargets(ctx *gin.Context) {
+	clientID, ok := currentClientID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	bindings, err := tc.targets.List(ctx, clientID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"targets": bindings})
+}
+
+func (tc *TargetController) SaveTarget(ctx *gin.Context) {
+	clientID, ok := currentClientID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.SaveTargetRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "backend is required"})
+		return
+	}
+
+	binding, err := tc.targets.Save(ctx, clientID, ctx.Param("target"), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownBackend) || errors.Is(err, service.ErrInvalidTargetConfig) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, binding)
+}
+
+func (tc *TargetController) DeleteTarget(ctx *gin.Context) {
+	clientID, ok := currentClientID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := tc.targets.Delete(ctx, clientID, ctx.Param("target")); err != nil {
+		if errors.Is(err, repositories.ErrTargetNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (tc *TargetController) SaveCredential(ctx *gin.Context) {
+	clientID, ok := currentClientID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.SaveCredentialRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "secret is required"})
+		return
+	}
+
+	credential, err := tc.targets.SaveCredential(ctx, clientID, ctx.Param("kind"), ctx.Param("name"), req.Secret)
+	if err != nil {
+		if errors.Is(err, utils.ErrNoCredentialsKey) {
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "credential storage is not configured"})
+			return
+		}
+		// credencial invalida (kubeconfig mal formado, tipo desconocido...)
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, credential)
+}
+
+func (tc *TargetController) ListCommandTemplates(ctx *gin.Context) {
+	clientID, ok := currentClientID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	templates, err := tc.targets.ListCommandTemplates(ctx, clientID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"command_templates": templates})
+}
+
+func (tc *TargetController) SaveCommandTemplate(ctx *gin.Context) {
+	clientID, ok := currentClientID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req models.SaveCommandTemplateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "template is required"})
+		return
+	}
+
+	tpl, err := tc.targets.SaveCommandTemplate(ctx, clientID, ctx.Param("action"), ctx.Param("target"), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCommandTemplate) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tpl)
+}
+
+func (tc *TargetController) DeleteCommandTemplate(ctx *gin.Context) {
+	clientID, ok := currentClientID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if err := tc.targets.DeleteCommandTemplate(ctx, clientID, ctx.Param("action"), ctx.Param("target")); err != nil {
+		if errors.Is(err, repositories.ErrCommandTemplateNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	models "server/model"
+	"server/service"
+	executor "server/service/exec"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookController struct {
+	monitor  *executor.WebhookMonitor
+	verifier *service.WebhookVerifier
+}
+
+func NewWebhookController(monitor *executor.WebhookMonitor, verifier *service.WebhookVerifier) *WebhookController {
+	return &WebhookController{monitor: monitor, verifier: verifier}
+}
+
+// GetHealth devuelve la salud del webhook del cliente y si las acciones estan suspendidas
+func (wc *WebhookController) GetHealth(ctx *gin.Context) {
+	clientID, ok := currentClientID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	health, err := wc.monitor.Health(ctx.Request.Context(), clientID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, health)
+}
+
+// Verify le manda el challenge al webhook actual; con el SDK corriendo lo deja verificado
+func (wc *WebhookController) Verify(ctx *gin.Context) {
+	clientID, ok := currentClientID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	client, err := wc.verifier.Verify(ctx.Request.Context(), clientID)
+	wc.verification(ctx, client, err)
+}
+
+type changeWebhookRequest struct {
+	WebhookURL string `json:"webhook_url" binding:"required"`
+}
+
+// ChangeURL cambia el webhook_url: queda sin verificar hasta que el SDK conteste el challenge
+func (wc *WebhookController) ChangeURL(ctx *gin.Context) {
+	clientID, ok := currentClientID(ctx)
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req changeWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client, err := wc.verifier.ChangeURL(ctx.Request.Context(), clientID, req.WebhookURL)
+	if client == nil && err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	wc.verification(ctx, client, err)
+}
+
+func (wc *WebhookController) verification(ctx *gin.Context, client *models.Client, err error) {
+	switch {
+	case err == nil:
+		ctx.JSON(http.StatusOK, gin.H{"webhook_url": client.WebhookURL, "verified": true, "verified_at": client.WebhookVerifiedAt})
+	case errors.Is(err, service.ErrWebhookVerification):
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"webhook_url": client.WebhookURL, "verified": false, "error": err.Error()})
+	case errors.Is(err, service.ErrNoWebhook):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+}
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
 	"server/controllers"
 	"server/internal/database"
+	"server/middleware"
 	"server/repositories"
 	"server/routes"
 	"server/service"
+	"server/service/agent/llm"
+	executor "server/service/exec"
 	"server/utils"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -25,12 +31,58 @@ func main() {
 	sqlDB := database.GetSQLDB()
 	utils.NewAuth()
 
-	loginService := service.NewLogin(repositories.NewPostgresStorage(sqlDB))
+	storage := repositories.NewPostgresStorage(sqlDB)
+
+	loginService := service.NewLogin(storage)
 
 	loginController := controllers.NewLoginController(loginService)
 
 	wsController := controllers.NewWebSocketController()
 
+	// los SDK en modo pull esperan sus comandos en /v1/commands
+	commandBroker := executor.NewCommandBroker()
+
+	// una accion en vuelo por target, el resto espera en la cola
+	actionExecutor := executor.NewExecutor(storage, commandBroker, 0)
+	executionQueue := executor.NewExecutionQueue(actionExecutor, storage)
+	queueController := controllers.NewQueueController(executionQueue)
+	webhookController := controllers.NewWebhookController(actionExecutor.WebhookMonitor(), service.NewWebhookVerifier(storage))
+
+	actionResults := service.NewActionResults(storage, executionQueue)
+	actionController := controllers.NewActionController(actionResults, service.NewUndo(storage, executionQueue))
+
+	commandController := controllers.NewCommandController(service.NewCommands(storage, commandBroker))
+
+	// eventos que reporta el SDK, los procesa el proximo tick del agente
+	ingestController := controllers.NewIngestController(service.NewIngestHandler(storage, storage, storage))
+
+	// lo que sabe hacer el SDK de cada cliente, para no proponerle acciones que no tiene
+	capabilityController := controllers.NewCapabilityController(service.NewCapabilityRegistry(storage))
+
+	// lo que junta collect_diagnostics (dumps, logs...), en disco o en un bucket S3
+	artifactStore, err := service.NewArtifactStoreFromEnv()
+	if err != nil {
+		log.Fatal("Error configurando el almacenamiento de artifacts:", err)
+	}
+	artifactController := controllers.NewArtifactController(service.NewArtifacts(storage, artifactStore))
+
+	targetController := controllers.NewTargetController(service.NewTargets(storage, storage, storage))
+
+	// marca como fallidas las acciones asincronas que nunca reportaron resultado
+	go actionResults.StartReaper(context.Background(), time.Minute)
+
+	// el agente: cada tick le pasa los eventos pendientes al LLM y manda su decision a la cola
+	if apiKey := os.Getenv("GEMINI_API_KEY"); apiKey != "" {
+		agentEngine := service.NewAgentEngine(llm.ConnectionToGeminiLLM(apiKey, os.Getenv("GEMINI_MODEL")),
+			storage, storage, storage, storage, storage, storage, storage, executionQueue)
+		go agentEngine.Start(context.Background(), 30*time.Second)
+	} else {
+		log.Println("GEMINI_API_KEY no configurada: el agente no va a proponer acciones")
+	}
+
+	// prueba los webhooks suspendidos y reactiva las acciones cuando vuelven
+	go actionExecutor.WebhookMonitor().StartProber(context.Background(), time.Minute)
+
 	router := gin.Default()
 
 	router.Use(cors.New(cors.Config{
@@ -40,7 +92,8 @@ func main() {
 		AllowCredentials: true,
 	}))
 
-	setupRoutes := routes.NewSetUpRoutes(loginController, wsController)
+	setupRoutes := routes.NewSetUpRoutes(loginController, wsController, actionController, commandController, targetController, queueController, webhookController,
+		ingestController, capabilityController, artifactController, middleware.NewMiddleware(storage))
 
 	setupRoutes.SetUpRoutes(router)
 
@@ -3,6 +3,7 @@ package middleware
 import (
 	"fmt"
 	"net/http"
+	models "server/model"
 	"server/repositories"
 	"server/utils"
 	"strings"
@@ -14,10 +15,15 @@ type Middleware struct {
 	client repositories.ClientStorage
 }
 
-func (m *Middleware) authMiddlewareApi() gin.HandlerFunc {
+func NewMiddleware(client repositories.ClientStorage) *Middleware {
+	return &Middleware{client: client}
+}
+
+// APIKeyMiddleware autentica al SDK del cliente con "Authorization: Bearer <api_key>"
+func (m *Middleware) APIKeyMiddleware() gin.HandlerFunc {
 
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("auth_token")
+		authHeader := c.GetHeader("Authorization")
 
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authorization header"})
@@ -41,6 +47,15 @@ func (m *Middleware) authMiddlewareApi() gin.HandlerFunc {
 			return
 		}
 
+		// el SDK anuncia en cada request la version del envelope que entiende
+		if header := c.GetHeader(models.ProtocolHeader); header != "" {
+			if version := models.NegotiateProtocol(header); version != client.ProtocolVersion {
+				if err := m.client.UpdateProtocolVersion(c.Request.Context(), client.ID.String(), version); err == nil {
+					client.ProtocolVersion = version
+				}
+			}
+		}
+
 		c.Set("client", client)
 		c.Next()
 	}
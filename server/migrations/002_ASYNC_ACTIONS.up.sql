@@ -0,0 +1,9 @@
+-- Long-running actions: the SDK answers 202 and reports progress/result later
+ALTER TABLE actions
+    ADD COLUMN IF NOT EXISTS deadline_at TIMESTAMP, -- after this a running action is marked failed
+    ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+-- status ahora puede ser: pending, running, success, failed
+
+CREATE INDEX IF NOT EXISTS idx_actions_open_deadline ON actions(deadline_at)
+    WHERE status IN ('pending', 'running');
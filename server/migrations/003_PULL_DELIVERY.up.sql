@@ -0,0 +1,9 @@
+-- Pull mode: clients that can't accept inbound traffic fetch their commands
+ALTER TABLE clients
+    ADD COLUMN IF NOT EXISTS delivery_mode VARCHAR(10) NOT NULL DEFAULT 'push'; -- push, pull
+
+ALTER TABLE actions
+    ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMP; -- cuando el SDK (pull) confirmo el comando
+
+CREATE INDEX IF NOT EXISTS idx_actions_client_undelivered ON actions(client_id, created_at)
+    WHERE status = 'pending' AND delivered_at IS NULL;
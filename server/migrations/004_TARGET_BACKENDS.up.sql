@@ -0,0 +1,23 @@
+-- Which executor backend handles each target of a client (webhook, pull, kubernetes...)
+CREATE TABLE IF NOT EXISTS target_bindings (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    target VARCHAR(255) NOT NULL, -- payments-api, db, etc (decision.Target)
+    backend VARCHAR(50) NOT NULL,
+    config JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(client_id, target)
+);
+
+-- Credentials the client gives us (kubeconfig, etc). secret va cifrado con CREDENTIALS_KEY
+CREATE TABLE IF NOT EXISTS client_credentials (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    kind VARCHAR(50) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    secret TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(client_id, kind, name)
+);
@@ -0,0 +1,13 @@
+-- Allowlisted commands for the ssh backend (the LLM never sends free-form commands)
+CREATE TABLE IF NOT EXISTS command_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    action_type VARCHAR(100) NOT NULL, -- restart, scale, etc
+    target VARCHAR(255) NOT NULL,
+    template TEXT NOT NULL,
+    params_schema JSONB NOT NULL DEFAULT '{}'::jsonb,
+    timeout_seconds INT NOT NULL DEFAULT 60,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(client_id, action_type, target)
+);
@@ -0,0 +1,3 @@
+-- What to do when an action arrives for a target that already has one in flight
+ALTER TABLE client_configs
+    ADD COLUMN IF NOT EXISTS conflict_rule VARCHAR(20) NOT NULL DEFAULT 'queue'; -- reject, queue, supersede
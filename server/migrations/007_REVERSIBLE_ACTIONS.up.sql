@@ -0,0 +1,6 @@
+-- Snapshot of the target before the action and the link from an undo to the action it compensates
+ALTER TABLE actions
+    ADD COLUMN IF NOT EXISTS pre_state JSONB,
+    ADD COLUMN IF NOT EXISTS undo_of UUID REFERENCES actions(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_actions_undo_of ON actions(undo_of) WHERE undo_of IS NOT NULL;
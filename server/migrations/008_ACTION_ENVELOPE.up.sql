@@ -0,0 +1,7 @@
+-- Envelope version the client's SDK negotiated (0 = bare decision, SDKs before the envelope)
+ALTER TABLE clients
+    ADD COLUMN IF NOT EXISTS protocol_version INT NOT NULL DEFAULT 0;
+
+-- Events that triggered each action, sent to the SDK in the envelope
+ALTER TABLE actions
+    ADD COLUMN IF NOT EXISTS event_ids JSONB NOT NULL DEFAULT '[]'::jsonb;
@@ -0,0 +1,14 @@
+-- Health of each client's webhook; suspended_at set = agent in notify-only mode
+CREATE TABLE IF NOT EXISTS webhook_health (
+    client_id UUID PRIMARY KEY REFERENCES clients(id) ON DELETE CASCADE,
+    consecutive_failures INT NOT NULL DEFAULT 0,
+    last_success_at TIMESTAMP,
+    last_failure_at TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    recent JSONB NOT NULL DEFAULT '[]'::jsonb, -- last deliveries (true = ok), oldest first
+    suspended_at TIMESTAMP,
+    last_probe_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_health_suspended ON webhook_health(suspended_at) WHERE suspended_at IS NOT NULL;
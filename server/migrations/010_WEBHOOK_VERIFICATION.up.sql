@@ -0,0 +1,4 @@
+-- When the client's SDK proved it owns webhook_url (challenge signed with the webhook secret).
+-- NULL = unverified: no actions are sent to the webhook until it is verified again.
+ALTER TABLE clients
+    ADD COLUMN IF NOT EXISTS webhook_verified_at TIMESTAMP;
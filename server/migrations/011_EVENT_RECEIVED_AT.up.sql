@@ -0,0 +1,4 @@
+-- When the event reached the backend. created_at is when the SDK saw it; the two
+-- differ when the SDK buffered the event on disk while the backend was unreachable.
+ALTER TABLE events
+    ADD COLUMN IF NOT EXISTS received_at TIMESTAMP NOT NULL DEFAULT NOW();
@@ -0,0 +1,8 @@
+-- Actions the client's SDK registered (handler names, parameter schemas), sent on startup.
+-- No row = SDK from before the handshake: only client_configs.allowed_actions applies.
+CREATE TABLE IF NOT EXISTS client_capabilities (
+    client_id UUID PRIMARY KEY REFERENCES clients(id) ON DELETE CASCADE,
+    sdk_version TEXT NOT NULL DEFAULT '',
+    actions JSONB NOT NULL DEFAULT '[]'::jsonb,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,15 @@
+-- Files the SDK uploads as evidence of an action (diagnostics). The content is in the
+-- artifact store (local disk or S3-compatible) under storage_key.
+CREATE TABLE IF NOT EXISTS artifacts (
+    id UUID PRIMARY KEY,
+    client_id UUID NOT NULL REFERENCES clients(id) ON DELETE CASCADE,
+    action_id UUID NOT NULL REFERENCES actions(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    storage_key TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_artifacts_action ON artifacts(action_id);
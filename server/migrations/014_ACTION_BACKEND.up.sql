@@ -0,0 +1,12 @@
+-- Backend que entrega cada accion. /v1/commands solo sirve las del backend pull: las demas
+-- tambien se guardan pending mientras el webhook, kubernetes, docker... las entregan.
+ALTER TABLE actions
+    ADD COLUMN IF NOT EXISTS backend VARCHAR(20) NOT NULL DEFAULT '';
+
+-- las que ya estaban esperando a un SDK en modo pull
+UPDATE actions SET backend = 'pull'
+    WHERE status = 'pending' AND delivered_at IS NULL AND result->>'delivery' = 'pull';
+
+DROP INDEX IF EXISTS idx_actions_client_undelivered;
+CREATE INDEX IF NOT EXISTS idx_actions_client_undelivered ON actions(client_id, created_at)
+    WHERE backend = 'pull' AND status = 'pending' AND delivered_at IS NULL;
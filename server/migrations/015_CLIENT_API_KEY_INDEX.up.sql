@@ -0,0 +1,4 @@
+-- Cada request del SDK busca al cliente por el sha256 de su api key: sin indice era un
+-- scan de clients. Los que todavia no completaron el registro tienen el hash vacio.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_clients_api_key_hash ON clients(api_key_hash)
+    WHERE api_key_hash <> '';
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// MaxArtifactSize is the largest file the SDK can upload for an action
+const MaxArtifactSize = 50 << 20
+
+// Artifact is a file the SDK uploaded as evidence of an action (goroutine dump,
+// log tail, process list...). The content lives in the artifact store under StorageKey.
+type Artifact struct {
+	ID          string    `json:"id"`
+	ClientID    string    `json:"client_id"`
+	ActionID    string    `json:"action_id"`
+	Name        string    `json:"name"` // e.g. "goroutines.txt"
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	StorageKey  string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
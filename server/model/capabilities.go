@@ -0,0 +1,63 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// AgentActions never reach the client's infrastructure (the agent only observes or
+// tells the owner), so they stay available whatever the SDK registered
+var AgentActions = []string{"wait", "notify"}
+
+// Capabilities is what the client's SDK can run; it posts them to /v1/capabilities
+// on startup and the agent only proposes (and accepts) those actions
+type Capabilities struct {
+	ClientID   string             `json:"client_id"`
+	SDKVersion string             `json:"sdk_version"`
+	Actions    []ActionCapability `json:"actions"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+}
+
+// ActionCapability is one handler registered with On/OnAsync. A nil Params
+// means the SDK didn't describe them, so any params are accepted.
+type ActionCapability struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Async       bool                   `json:"async"`
+	Targets     []string               `json:"targets,omitempty"` // empty = any target
+	Params      map[string]ParamSchema `json:"params,omitempty"`
+}
+
+// Action returns the capability with that name, nil if the SDK doesn't have it
+func (c *Capabilities) Action(name string) *ActionCapability {
+	if c == nil {
+		return nil
+	}
+	for i := range c.Actions {
+		if c.Actions[i].Name == name {
+			return &c.Actions[i]
+		}
+	}
+	return nil
+}
+
+// Supports reports whether the action can run on target
+func (a *ActionCapability) Supports(target string) bool {
+	return len(a.Targets) == 0 || slices.Contains(a.Targets, target)
+}
+
+// SupportedActions is allowed intersected with what the SDK registered, for the targets
+// the SDK runs. Without capabilities (an SDK from before the handshake) allowed is
+// returned as is.
+func SupportedActions(allowed []string, caps *Capabilities) []string {
+	if caps == nil {
+		return allowed
+	}
+	supported := make([]string, 0, len(allowed))
+	for _, action := range allowed {
+		if slices.Contains(AgentActions, action) || caps.Action(action) != nil {
+			supported = append(supported, action)
+		}
+	}
+	return supported
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// ProtocolHeader carries the protocol version on every backend <-> SDK request and response:
+// the backend sends the version of the body, the SDK answers with the newest one it speaks.
+const ProtocolHeader = "X-InfrAgent-Protocol"
+
+// Versions of the body the backend sends to the SDK
+const (
+	ProtocolLegacy  = 0          // the bare LLMDecision, for SDKs older than the envelope
+	ProtocolV1      = 1          // ActionEnvelope
+	ProtocolVersion = ProtocolV1 // newest version this build speaks
+)
+
+// ActionEnvelope wraps a decision with what the SDK needs to correlate it and
+// run it only once. Fields are only ever added, never renamed or removed,
+// within a protocol version.
+type ActionEnvelope struct {
+	ProtocolVersion int         `json:"protocol_version"`
+	ActionID        string      `json:"action_id"`
+	AgentID         string      `json:"agent_id"`
+	EventIDs        []string    `json:"event_ids"`          // events that triggered the decision
+	Deadline        *time.Time  `json:"deadline,omitempty"` // async results after this are rejected
+	IssuedAt        time.Time   `json:"issued_at"`
+	Decision        LLMDecision `json:"decision"`
+}
+
+// NegotiateProtocol turns the version announced by the other side into the
+// version both understand (legacy when missing or invalid)
+func NegotiateProtocol(header string) int {
+	version, err := strconv.Atoi(header)
+	if err != nil || version < ProtocolLegacy {
+		return ProtocolLegacy
+	}
+	if version > ProtocolVersion {
+		return ProtocolVersion
+	}
+	return version
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// Event severities, from least to most urgent
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Event types the SDK reports on its own (the app can report any other type)
+const (
+	EventAppDown      = "app_down"
+	EventAppRecovered = "app_recovered"
+	EventHighCPU      = "high_cpu"
+	EventHighMemory   = "high_memory"
+	EventDiskFull     = "disk_full"
+	EventErrorSpike   = "error_spike"
+	EventLatencyHigh  = "latency_high"
+
+	// Kubernetes watcher
+	EventCrashLoop           = "crash_loop"
+	EventOOMKilled           = "oom_killed"
+	EventReadinessFailed     = "readiness_failed"
+	EventReplicasUnavailable = "replicas_unavailable"
+)
+
+// LateEventThreshold is how long after created_at an event can reach the backend
+// and still count as fresh; later ones were buffered by the SDK while offline
+const LateEventThreshold = time.Minute
+
+// Delay is how long the event took to reach the backend (0 if not received yet)
+func (e Event) Delay() time.Duration {
+	if e.ReceivedAt == nil || e.ReceivedAt.Before(e.CreatedAt) {
+		return 0
+	}
+	return e.ReceivedAt.Sub(e.CreatedAt)
+}
+
+// Late reports whether the event was replayed from the SDK's buffer
+func (e Event) Late() bool {
+	return e.Delay() > LateEventThreshold
+}
+
+// MaxEventBatch is the most events the SDK can post in one request to /v1/events
+const MaxEventBatch = 100
+
+// EventBatch is what the SDK posts to /v1/events. created_at is when the SDK saw
+// the event (it may arrive later if the backend was unreachable); the backend
+// stamps received_at on arrival.
+type EventBatch struct {
+	Events []Event `json:"events" binding:"required"`
+}
+
+// SeverityRank orders severities; unknown ones rank as info
+func SeverityRank(severity string) int {
+	switch severity {
+	case SeverityCritical:
+		return 2
+	case SeverityWarning:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Conflict rules: what happens when an action targets a (client, target) that is busy
+const (
+	ConflictReject    = "reject"    // the new action is recorded as failed (target_busy)
+	ConflictQueue     = "queue"     // the new action waits its turn
+	ConflictSupersede = "supersede" // the new action replaces everything waiting
+)
+
+// Where an action submitted to the execution queue came from
+const (
+	SourceTick   = "tick"   // the agent loop
+	SourceManual = "manual" // a human through the API
+)
+
+// Queue entry states
+const (
+	QueueRunning    = "running"
+	QueueQueued     = "queued"
+	QueueRejected   = "rejected"
+	QueueExecuted   = "executed"
+	QueueSuperseded = "superseded"
+)
+
+// QueueEntry is an action waiting for (or holding) the lock of its target
+type QueueEntry struct {
+	ID         string     `json:"id"`
+	ClientID   string     `json:"client_id"`
+	Target     string     `json:"target"`
+	ActionType string     `json:"action_type"`
+	Source     string     `json:"source"`
+	Status     string     `json:"status"`
+	ActionID   string     `json:"action_id,omitempty"` // set once it was handed to the executor
+	UndoOf     string     `json:"undo_of,omitempty"`   // the action it compensates, for undos
+	EnqueuedAt time.Time  `json:"enqueued_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+}
+
+// QueueLane is the execution state of one (client, target)
+type QueueLane struct {
+	Target  string       `json:"target"`
+	Running *QueueEntry  `json:"running,omitempty"`
+	Waiting []QueueEntry `json:"waiting"`
+}
+
+// QueueTicket is what Submit returns: the action if it ran right away, or the queue entry
+type QueueTicket struct {
+	Status string     `json:"status"` // executed, queued, rejected
+	Entry  QueueEntry `json:"entry"`
+	Action *Action    `json:"action,omitempty"`
+}
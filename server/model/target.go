@@ -0,0 +1,97 @@
+package models
+
+import "time"
+
+// Executor backends a target can be bound to
+const (
+	BackendWebhook    = "webhook"    // POST to the client's SDK (push, the default)
+	BackendPull       = "pull"       // the SDK fetches it from /v1/commands
+	BackendKubernetes = "kubernetes" // we act on the cluster directly with the client's kubeconfig
+	BackendDocker     = "docker"     // Docker Engine API over a unix socket or TCP+TLS
+	BackendSSH        = "ssh"        // pre-registered command templates run over SSH
+	BackendGitOps     = "gitops"     // rollback = revert commit in the client's config repo
+)
+
+// ServerSideBackend reports whether we act on the client's infrastructure ourselves,
+// without going through their SDK
+func ServerSideBackend(backend string) bool {
+	return backend != BackendWebhook && backend != BackendPull
+}
+
+// TargetBinding says which executor backend handles a decision.Target for a client
+// and how (namespace/deployment, bounds, credential to use...). Targets without a
+// binding go to the SDK according to Client.DeliveryMode.
+type TargetBinding struct {
+	ID        string                 `json:"id"`
+	ClientID  string                 `json:"client_id"`
+	Target    string                 `json:"target"`
+	Backend   string                 `json:"backend"`
+	Config    map[string]interface{} `json:"config"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+// Credential kinds stored per client
+const (
+	CredentialKubeconfig = "kubeconfig"
+	CredentialDockerTLS  = "docker_tls" // JSON {"ca": PEM, "cert": PEM, "key": PEM}
+	CredentialSSH        = "ssh"        // JSON {"user", "private_key" or "password", "host_key"}
+	CredentialGit        = "git"        // JSON {"username", "token"} (https) or {"ssh_key", "known_hosts"}
+)
+
+// Credential is a secret the client gave us to act on their infrastructure.
+// Secret is encrypted at rest and never serialized back to the API.
+type Credential struct {
+	ID        string    `json:"id"`
+	ClientID  string    `json:"client_id"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Secret    string    `json:"-"` // encrypted (utils.EncryptSecret)
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SaveTargetRequest is the body of PUT /api/targets/:target
+type SaveTargetRequest struct {
+	Backend string                 `json:"backend" binding:"required"`
+	Config  map[string]interface{} `json:"config"`
+}
+
+// SaveCredentialRequest is the body of PUT /api/credentials/:kind/:name
+type SaveCredentialRequest struct {
+	Secret string `json:"secret" binding:"required"`
+}
+
+// CommandTemplate is a command the client allowed us to run over SSH for an
+// action type and target. The LLM only picks params, never the command itself.
+type CommandTemplate struct {
+	ID             string                 `json:"id"`
+	ClientID       string                 `json:"client_id"`
+	ActionType     string                 `json:"action_type"`
+	Target         string                 `json:"target"`
+	Template       string                 `json:"template"` // text/template, ej: "systemctl restart {{.unit}}"
+	ParamsSchema   map[string]ParamSchema `json:"params_schema"`
+	TimeoutSeconds int                    `json:"timeout_seconds"`
+	CreatedAt      time.Time              `json:"created_at"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+}
+
+// ParamSchema validates one decision param before it is rendered into a command
+// (or, for an SDK capability, before the action is sent)
+type ParamSchema struct {
+	Type        string      `json:"type"` // "string", "integer", "boolean"
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Pattern     string      `json:"pattern,omitempty"` // regexp for strings
+	Enum        []string    `json:"enum,omitempty"`
+	Min         *float64    `json:"min,omitempty"`
+	Max         *float64    `json:"max,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+}
+
+// SaveCommandTemplateRequest is the body of PUT /api/command-templates/:action/:target
+type SaveCommandTemplateRequest struct {
+	Template       string                 `json:"template" binding:"required"`
+	ParamsSchema   map[string]ParamSchema `json:"params_schema"`
+	TimeoutSeconds int                    `json:"timeout_seconds"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,20 +18,30 @@ type ClientRegister struct {
 
 // Client represents a registered user/company
 type Client struct {
-	ID            uuid.UUID `json:"id"`
-	Nombre        string    `json:"nombre"`
-	Email         string    `json:"email"`
-	Password      string    `json:"password"`
-	CompanyName   string    `json:"company_name"`
-	Metodo        string    `json:"metodo"` // este "metodo" nos dice si inicio con google id o LOCAL
-	GoogleID      string    `json:"google_id,omitempty"`
-	APIKeyHash    string    `json:"-"`
-	WebhookSecret string    `json:"-"`
-	WebhookURL    string    `json:"webhook_url"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID              uuid.UUID `json:"id"`
+	Nombre          string    `json:"nombre"`
+	Email           string    `json:"email"`
+	Password        string    `json:"password"`
+	CompanyName     string    `json:"company_name"`
+	Metodo          string    `json:"metodo"` // este "metodo" nos dice si inicio con google id o LOCAL
+	GoogleID        string    `json:"google_id,omitempty"`
+	APIKeyHash      string    `json:"-"`
+	WebhookSecret   string    `json:"-"`
+	WebhookURL      string    `json:"webhook_url"`
+	DeliveryMode    string    `json:"delivery_mode"`    // "push" (webhook) o "pull" (el SDK pide los comandos)
+	ProtocolVersion int       `json:"protocol_version"` // version del envelope que entiende su SDK
+	// cuando el SDK demostro que el webhook es suyo (nil = sin verificar, sin acciones por webhook)
+	WebhookVerifiedAt *time.Time `json:"webhook_verified_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
 }
 
+// How actions reach the client's SDK
+const (
+	DeliveryPush = "push" // we POST to Client.WebhookURL
+	DeliveryPull = "pull" // the SDK long-polls /v1/commands (clients behind NAT/firewalls)
+)
+
 type LoginResponse struct {
 	Success       bool   `json:"success"`
 	Message       string `json:"message"`
@@ -55,28 +66,61 @@ type Event struct {
 	ID          string                 `json:"id"`
 	ClientID    string                 `json:"client_id"`
 	AgentID     string                 `json:"agent_id"`
-	Type        string                 `json:"type"`         // "app_down", "high_cpu", "error_spike", etc
-	Service     string                 `json:"service"`      // "api", "db", "worker", etc
-	Severity    string                 `json:"severity"`     // "info", "warning", "critical"
-	Data        map[string]interface{} `json:"data"`         // Flexible metadata
-	ProcessedAt *time.Time             `json:"processed_at"` // nil = pending
-	CreatedAt   time.Time              `json:"created_at"`
+	Type        string                 `json:"type"`                  // "app_down", "high_cpu", "error_spike", etc
+	Service     string                 `json:"service"`               // "api", "db", "worker", etc
+	Severity    string                 `json:"severity"`              // "info", "warning", "critical"
+	Data        map[string]interface{} `json:"data"`                  // Flexible metadata
+	ProcessedAt *time.Time             `json:"processed_at"`          // nil = pending
+	CreatedAt   time.Time              `json:"created_at"`            // when the SDK saw it
+	ReceivedAt  *time.Time             `json:"received_at,omitempty"` // when it reached the backend, set on ingest
 }
 
 // Action represents a decision made and executed by the agent
 type Action struct {
-	ID         string                 `json:"id"`
-	AgentID    string                 `json:"agent_id"`
-	ClientID   string                 `json:"client_id"`
-	Type       string                 `json:"type"`   // "restart", "notify", "wait", "scale", etc
-	Target     string                 `json:"target"` // "api", "db", etc
-	Params     map[string]interface{} `json:"params"`
-	Reasoning  string                 `json:"reasoning"`  // Why the agent chose this
-	Confidence float64                `json:"confidence"` // LLM confidence score
-	Status     string                 `json:"status"`     // "pending", "success", "failed"
-	Result     map[string]interface{} `json:"result"`     // Execution result
-	ExecutedAt *time.Time             `json:"executed_at"`
-	CreatedAt  time.Time              `json:"created_at"`
+	ID          string                 `json:"id"`
+	AgentID     string                 `json:"agent_id"`
+	ClientID    string                 `json:"client_id"`
+	Type        string                 `json:"type"`   // "restart", "notify", "wait", "scale", etc
+	Target      string                 `json:"target"` // "api", "db", etc
+	Params      map[string]interface{} `json:"params"`
+	Reasoning   string                 `json:"reasoning"`         // Why the agent chose this
+	Confidence  float64                `json:"confidence"`        // LLM confidence score
+	Status      string                 `json:"status"`            // "pending", "running", "success", "failed"
+	Backend     string                 `json:"backend,omitempty"` // who delivers it: webhook, pull, kubernetes...
+	Result      map[string]interface{} `json:"result"`            // Execution result
+	ExecutedAt  *time.Time             `json:"executed_at"`
+	DeliveredAt *time.Time             `json:"delivered_at,omitempty"` // pull mode: when the SDK acked it
+	DeadlineAt  *time.Time             `json:"deadline_at,omitempty"`  // async actions are failed after this
+	PreState    map[string]interface{} `json:"pre_state,omitempty"`    // what the target looked like before the action
+	UndoOf      *string                `json:"undo_of,omitempty"`      // set on compensating actions
+	EventIDs    []string               `json:"event_ids"`              // events that triggered it
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// PreStateUndoKey is the pre_state entry with the compensating action,
+// {"action": "scale", "params": {"replicas": 3}}. Without it the action can't be undone.
+const PreStateUndoKey = "undo"
+
+// ActionIDHeader carries the action ID on every webhook call so the SDK can report back
+const ActionIDHeader = "X-InfrAgent-Action-ID"
+
+// Action lifecycle: pending -> running -> success/failed
+const (
+	ActionStatusPending = "pending"
+	ActionStatusRunning = "running"
+	ActionStatusSuccess = "success"
+	ActionStatusFailed  = "failed"
+)
+
+// ActionResultReport is what the SDK posts to /v1/actions/:id/result
+// while a long-running action progresses and when it finishes
+type ActionResultReport struct {
+	Status   string                 `json:"status" binding:"required"` // "running", "success", "failed"
+	Progress map[string]interface{} `json:"progress,omitempty"`
+	Result   map[string]interface{} `json:"result,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+	PreState map[string]interface{} `json:"pre_state,omitempty"` // snapshot taken before acting, see PreStateUndoKey
 }
 
 // Notification represents an alert sent to the client
@@ -109,6 +153,22 @@ type AgentRunContext struct {
 	RestartCountHour int
 	ServiceHealth    map[string]string
 	ClientConfig     ClientConfig
+	Capabilities     *Capabilities // what the client's SDK registered, nil = unknown
+	ServerTargets    []string      // targets bound to a server-side backend: the SDK capabilities don't apply
+}
+
+// SDKTarget reports whether the client's SDK runs the actions on target (webhook or pull)
+func (c AgentRunContext) SDKTarget(target string) bool {
+	return !slices.Contains(c.ServerTargets, target)
+}
+
+// AvailableActions is what the agent can propose: the SDK capabilities only limit the
+// targets the SDK runs, a target bound to a server-side backend takes any allowed action
+func (c AgentRunContext) AvailableActions() []string {
+	if len(c.ServerTargets) > 0 {
+		return c.ClientConfig.AllowedActions
+	}
+	return SupportedActions(c.ClientConfig.AllowedActions, c.Capabilities)
 }
 
 // ClientConfig represents the rules and limits for this client
@@ -117,6 +177,7 @@ type ClientConfig struct {
 	AllowedActions     []string `json:"allowed_actions"` // Whitelist
 	NotifyOnNthRestart int      `json:"notify_on_nth_restart"`
 	CooldownMinutes    int      `json:"cooldown_minutes"`
+	ConflictRule       string   `json:"conflict_rule"` // what to do when the target is busy: "reject", "queue", "supersede"
 }
 
 // LLMDecision represents the decision made by the LLM
@@ -129,12 +190,22 @@ type LLMDecision struct {
 	Confidence   float64                `json:"confidence"`
 	Alternative  string                 `json:"alternative,omitempty"` // Fallback plan
 	ShouldNotify bool                   `json:"should_notify"`
+	UndoOf       string                 `json:"undo_of,omitempty"` // the action this one compensates
+	EventIDs     []string               `json:"-"`                 // set by the engine, travels in the envelope
+}
+
+// PendingCommand is an action waiting for a pull-mode SDK to fetch it
+type PendingCommand struct {
+	ActionID string          `json:"action_id"`
+	Decision LLMDecision     `json:"decision"`
+	Envelope *ActionEnvelope `json:"envelope,omitempty"` // only for SDKs that speak ProtocolV1
 }
 
 // CompleteRegistrationRequest represents the request to complete registration after Google login
 type CompleteRegistrationRequest struct {
-	CompanyName string `json:"company_name" binding:"required"`
-	WebhookURL  string `json:"webhook_url" binding:"required"`
+	CompanyName  string `json:"company_name" binding:"required"`
+	WebhookURL   string `json:"webhook_url"`   // required unless delivery_mode is "pull"
+	DeliveryMode string `json:"delivery_mode"` // "push" (default) or "pull"
 }
 
 // CompleteRegistrationResponse represents the response after completing registration
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// ProbeHeader marks the backend's reachability probes to a suspended webhook;
+// the SDK answers them without dispatching anything
+const ProbeHeader = "X-InfrAgent-Probe"
+
+// WebhookHealth is how the client's webhook has been answering lately.
+// While Suspended the agent is notify-only: actions are not sent, the owner is told instead.
+type WebhookHealth struct {
+	ClientID            string     `json:"client_id"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	Recent              []bool     `json:"-"`            // last deliveries, oldest first
+	SuccessRate         float64    `json:"success_rate"` // over Recent (1 when there is no history)
+	Samples             int        `json:"samples"`
+	Suspended           bool       `json:"suspended"`
+	SuspendedAt         *time.Time `json:"suspended_at,omitempty"`
+	LastProbeAt         *time.Time `json:"last_probe_at,omitempty"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// Ownership challenge: the backend sends a random challenge signed with the webhook
+// secret, the SDK checks it and answers with its own signature over the same challenge.
+const (
+	ChallengeHeader = "X-InfrAgent-Challenge"
+	SignatureHeader = "X-InfrAgent-Signature"
+)
+
+// Who signs: the backend when it sends the challenge, the SDK when it answers.
+// They differ so an answer can't just replay the backend's signature.
+const (
+	ChallengeFromBackend = "challenge"
+	ChallengeFromSDK     = "response"
+)
+
+// WebhookChallengeResponse is what the SDK answers to a challenge
+type WebhookChallengeResponse struct {
+	Challenge string `json:"challenge"`
+	Signature string `json:"signature"`
+}
+
+// SignChallenge is the hex HMAC-SHA256 of "<purpose>:<challenge>" with the webhook secret
+func SignChallenge(secret, purpose, challenge string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose + ":" + challenge))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyChallenge checks a signature made with SignChallenge in constant time
+func VerifyChallenge(secret, purpose, challenge, signature string) bool {
+	expected := SignChallenge(secret, purpose, challenge)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
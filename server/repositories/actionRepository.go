@@ -2,16 +2,84 @@ package repositories
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	models "server/model"
 	"time"
 )
 
+var ErrActionNotFound = errors.New("action not found")
+
+// columnas que leen todas las queries de acciones (en el orden de scanAction)
+const actionColumns = `id, agent_id, client_id, type, target, params, reasoning, confidence, status, backend, result,
+		executed_at, delivered_at, deadline_at, pre_state, undo_of, event_ids, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAction(row rowScanner) (*models.Action, error) {
+	var a models.Action
+	var paramsJSON, resultJSON, preStateJSON, eventIDsJSON []byte
+
+	err := row.Scan(&a.ID, &a.AgentID, &a.ClientID, &a.Type, &a.Target, &paramsJSON,
+		&a.Reasoning, &a.Confidence, &a.Status, &a.Backend, &resultJSON,
+		&a.ExecutedAt, &a.DeliveredAt, &a.DeadlineAt, &preStateJSON, &a.UndoOf, &eventIDsJSON, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(paramsJSON, &a.Params)
+	json.Unmarshal(resultJSON, &a.Result)
+	json.Unmarshal(eventIDsJSON, &a.EventIDs)
+	if preStateJSON != nil {
+		json.Unmarshal(preStateJSON, &a.PreState)
+	}
+
+	return &a, nil
+}
+
+func scanActions(rows *sql.Rows) ([]models.Action, error) {
+	defer rows.Close()
+
+	var actions []models.Action
+
+	for rows.Next() {
+		a, err := scanAction(rows)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, *a)
+	}
+
+	return actions, rows.Err()
+}
+
 type ActionStorage interface {
 	SaveAction(ctx context.Context, action *models.Action) error
 	GetRecentActions(ctx context.Context, agentID string, limit int) ([]models.Action, error)
 	CountActionsSince(ctx context.Context, agentID, actionType string, since time.Time) (int, error)
+	GetAction(ctx context.Context, id string) (*models.Action, error)
+	GetUndeliveredActions(ctx context.Context, clientID string, limit int) ([]models.Action, error)
+	MarkActionDelivered(ctx context.Context, clientID, id string) error
+	UpdateActionStatus(ctx context.Context, action *models.Action) error
+	UpdateDeliveredAction(ctx context.Context, action *models.Action) (bool, error)
+	FailStalledActions(ctx context.Context, now time.Time) (int64, error)
+	GetUndoActions(ctx context.Context, actionID string) ([]models.Action, error)
+}
+
+// pre_state es NULL cuando el backend no saco snapshot
+func preStateJSON(action *models.Action) ([]byte, error) {
+	if action.PreState == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(action.PreState)
+	if err != nil {
+		return nil, fmt.Errorf("marshal pre_state: %w", err)
+	}
+	return data, nil
 }
 
 func (s *PostgresStorage) SaveAction(ctx context.Context, action *models.Action) error {
@@ -27,11 +95,25 @@ func (s *PostgresStorage) SaveAction(ctx context.Context, action *models.Action)
 		return fmt.Errorf("marshal result: %w", err)
 	}
 
+	preState, err := preStateJSON(action)
+	if err != nil {
+		return err
+	}
+
+	eventIDs := action.EventIDs
+	if eventIDs == nil {
+		eventIDs = []string{}
+	}
+	eventIDsJSON, err := json.Marshal(eventIDs)
+	if err != nil {
+		return fmt.Errorf("marshal event_ids: %w", err)
+	}
+
 	_, err = s.db.ExecContext(ctx, `
-		INSERT INTO actions (id, agent_id, client_id, type, target, params, reasoning, confidence, status, result, executed_at, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		INSERT INTO actions (id, agent_id, client_id, type, target, params, reasoning, confidence, status, backend, result, executed_at, deadline_at, pre_state, undo_of, event_ids, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $17)
 	`, action.ID, action.AgentID, action.ClientID, action.Type, action.Target, paramsJSON,
-		action.Reasoning, action.Confidence, action.Status, resultJSON, action.ExecutedAt, action.CreatedAt)
+		action.Reasoning, action.Confidence, action.Status, action.Backend, resultJSON, action.ExecutedAt, action.DeadlineAt, preState, action.UndoOf, eventIDsJSON, action.CreatedAt)
 
 	return err
 }
@@ -39,7 +121,7 @@ func (s *PostgresStorage) SaveAction(ctx context.Context, action *models.Action)
 // return the recent actions of the AGENT
 func (s *PostgresStorage) GetRecentActions(ctx context.Context, agentId string, limit int) ([]models.Action, error) {
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, agent_id, client_id, type, target, params, reasoning, confidence, status, result, executed_at, created_at
+		SELECT `+actionColumns+`
 		FROM actions
 		WHERE agent_id = $1
 		ORDER BY created_at DESC
@@ -49,26 +131,7 @@ func (s *PostgresStorage) GetRecentActions(ctx context.Context, agentId string,
 		return nil, err
 	}
 
-	defer rows.Close()
-
-	var actions []models.Action
-
-	for rows.Next() {
-		var a models.Action
-		var paramsJSON, resultJSON []byte
-
-		err := rows.Scan(&a.ID, &a.AgentID, &a.ClientID, &a.Type, &a.Target, &paramsJSON,
-			&a.Reasoning, &a.Confidence, &a.Status, &resultJSON, &a.ExecutedAt, &a.CreatedAt)
-		if err != nil {
-			return nil, err
-		}
-		json.Unmarshal(paramsJSON, &a.Params)
-		json.Unmarshal(resultJSON, &a.Result)
-
-		actions = append(actions, a)
-	}
-
-	return actions, nil
+	return scanActions(rows)
 }
 
 // RETORNA LA CANTIDAD DE ACCIONES QUE SE HICIERON EN UN AGENTE DESDE "X" MOMENTO
@@ -86,3 +149,164 @@ func (s *PostgresStorage) CountActionsSince(ctx context.Context, agentID, action
 	return count, err
 
 }
+
+func (s *PostgresStorage) GetAction(ctx context.Context, id string) (*models.Action, error) {
+	a, err := scanAction(s.db.QueryRowContext(ctx, `
+		SELECT `+actionColumns+`
+		FROM actions
+		WHERE id = $1
+	`, id))
+
+	if err == sql.ErrNoRows {
+		return nil, ErrActionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// actualiza estado, resultado, deadline y pre-state de una accion (el resto de la fila no cambia nunca)
+func (s *PostgresStorage) UpdateActionStatus(ctx context.Context, action *models.Action) error {
+	resultJSON, err := json.Marshal(action.Result)
+	if err != nil {
+		return fmt.Errorf("marshal result: %w", err)
+	}
+
+	preState, err := preStateJSON(action)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE actions
+		SET status = $1,
+		result = $2,
+		executed_at = $3,
+		deadline_at = $4,
+		pre_state = $5,
+		updated_at = NOW()
+		WHERE id = $6
+	`, action.Status, resultJSON, action.ExecutedAt, action.DeadlineAt, preState, action.ID)
+
+	return err
+}
+
+// guarda lo que dejo el backend al entregar la accion, solo si sigue pending: el SDK puede
+// haber reportado antes de que Deliver volviera y su estado no se pisa (solo se completa
+// el deadline si todavia no tenia). Devuelve false si la accion ya no estaba pending.
+func (s *PostgresStorage) UpdateDeliveredAction(ctx context.Context, action *models.Action) (bool, error) {
+	resultJSON, err := json.Marshal(action.Result)
+	if err != nil {
+		return false, fmt.Errorf("marshal result: %w", err)
+	}
+
+	preState, err := preStateJSON(action)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE actions
+		SET status = $1,
+		result = $2,
+		executed_at = $3,
+		deadline_at = $4,
+		pre_state = $5,
+		updated_at = NOW()
+		WHERE id = $6 AND status = 'pending'
+	`, action.Status, resultJSON, action.ExecutedAt, action.DeadlineAt, preState, action.ID)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil || n > 0 {
+		return n > 0, err
+	}
+
+	// sin deadline una accion que el SDK dejo en running no venceria nunca
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE actions
+		SET deadline_at = $1,
+		updated_at = NOW()
+		WHERE id = $2 AND status = 'running' AND deadline_at IS NULL
+	`, action.DeadlineAt, action.ID)
+
+	return false, err
+}
+
+// MARCA COMO FALLIDAS LAS ACCIONES ASINCRONAS QUE PASARON SU DEADLINE SIN REPORTAR RESULTADO
+func (s *PostgresStorage) FailStalledActions(ctx context.Context, now time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE actions
+		SET status = 'failed',
+		result = result || '{"error": "action_timed_out"}'::jsonb,
+		updated_at = NOW()
+		WHERE status IN ('pending', 'running')
+		AND deadline_at IS NOT NULL
+		AND deadline_at < $1
+	`, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// acciones que todavia no fueron tomadas por un SDK en modo pull (las mas viejas primero).
+// Solo las del backend pull: las de otros backends tambien estan pending mientras se entregan
+func (s *PostgresStorage) GetUndeliveredActions(ctx context.Context, clientID string, limit int) ([]models.Action, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+actionColumns+`
+		FROM actions
+		WHERE client_id = $1 AND backend = $2 AND status = 'pending' AND delivered_at IS NULL
+		ORDER BY created_at
+		LIMIT $3
+	`, clientID, models.BackendPull, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanActions(rows)
+}
+
+// el SDK confirmo que recibio el comando: pasa a running
+func (s *PostgresStorage) MarkActionDelivered(ctx context.Context, clientID, id string) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE actions
+		SET delivered_at = NOW(),
+		executed_at = NOW(),
+		status = 'running',
+		updated_at = NOW()
+		WHERE id = $1 AND client_id = $2 AND backend = $3 AND status = 'pending' AND delivered_at IS NULL
+	`, id, clientID, models.BackendPull)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrActionNotFound
+	}
+	return nil
+}
+
+// acciones compensatorias (undo) de una accion, las mas nuevas primero
+func (s *PostgresStorage) GetUndoActions(ctx context.Context, actionID string) ([]models.Action, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+actionColumns+`
+		FROM actions
+		WHERE undo_of = $1
+		ORDER BY created_at DESC
+	`, actionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanActions(rows)
+}
@@ -10,9 +10,10 @@ import (
 
 type AgentStorage interface {
 	GetAgent(ctx context.Context, id string) (*models.Agent, error)
-	GetAgentById(ctx context.Context, clientId string) (*models.Agent, error)
+	GetAgentByClientId(ctx context.Context, clientId string) (*models.Agent, error)
 	UpdateAgentState(ctx context.Context, id string, state string) error
 	GetAgentByApiKey(ctx context.Context, apiKey string) (*models.Agent, error)
+	GetAgentsToTick(ctx context.Context, now time.Time) ([]string, error)
 }
 
 // QUERY PARA OBTENER EL AGENTE EN ESPECIFICO PARA NUESTRO CLIENTE (LUEGO OPTIMIZAMOS)
@@ -63,6 +64,32 @@ func (s *PostgresStorage) UpdateAgentState(ctx context.Context, id string, state
 	return err
 }
 
+// agentes con eventos pendientes que no estan en cooldown (los que tiene que correr el proximo tick)
+func (s *PostgresStorage) GetAgentsToTick(ctx context.Context, now time.Time) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT a.id
+		FROM agents a
+		JOIN events e ON e.agent_id = a.id
+		WHERE e.processed_at IS NULL
+		AND a.cooldown_until <= $1
+	`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
 // seteamos el cooldown, luego del cooldown se vuelve a ejecutar el tick
 func (s *PostgresStorage) SetAgentCooldown(ctx context.Context, id string, duration time.Duration) error {
 	coolDownUntil := time.Now().Add(duration)
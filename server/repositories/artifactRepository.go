@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	models "server/model"
+)
+
+var ErrArtifactNotFound = errors.New("artifact not found")
+
+type ArtifactStorage interface {
+	SaveArtifact(ctx context.Context, artifact *models.Artifact) error
+	GetArtifact(ctx context.Context, id string) (*models.Artifact, error)
+	ListArtifacts(ctx context.Context, actionID string) ([]models.Artifact, error)
+}
+
+const artifactColumns = `id, client_id, action_id, name, content_type, size, sha256, storage_key, created_at`
+
+func scanArtifact(row rowScanner) (*models.Artifact, error) {
+	var a models.Artifact
+	err := row.Scan(&a.ID, &a.ClientID, &a.ActionID, &a.Name, &a.ContentType, &a.Size, &a.SHA256, &a.StorageKey, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (s *PostgresStorage) SaveArtifact(ctx context.Context, a *models.Artifact) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO artifacts (`+artifactColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, a.ID, a.ClientID, a.ActionID, a.Name, a.ContentType, a.Size, a.SHA256, a.StorageKey, a.CreatedAt)
+	return err
+}
+
+func (s *PostgresStorage) GetArtifact(ctx context.Context, id string) (*models.Artifact, error) {
+	a, err := scanArtifact(s.db.QueryRowContext(ctx, `
+		SELECT `+artifactColumns+`
+		FROM artifacts
+		WHERE id = $1
+	`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrArtifactNotFound
+	}
+	return a, err
+}
+
+func (s *PostgresStorage) ListArtifacts(ctx context.Context, actionID string) ([]models.Artifact, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+artifactColumns+`
+		FROM artifacts
+		WHERE action_id = $1
+		ORDER BY created_at
+	`, actionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	artifacts := []models.Artifact{}
+	for rows.Next() {
+		a, err := scanArtifact(rows)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, *a)
+	}
+	return artifacts, rows.Err()
+}
@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	models "server/model"
+)
+
+type CapabilityStorage interface {
+	// nil si el SDK del cliente nunca mando sus capacidades
+	GetCapabilities(ctx context.Context, clientID string) (*models.Capabilities, error)
+	SaveCapabilities(ctx context.Context, caps *models.Capabilities) error
+}
+
+func (s *PostgresStorage) GetCapabilities(ctx context.Context, clientID string) (*models.Capabilities, error) {
+	var caps models.Capabilities
+	var actionsJSON []byte
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT client_id, sdk_version, actions, updated_at
+		FROM client_capabilities
+		WHERE client_id = $1
+	`, clientID).Scan(&caps.ClientID, &caps.SDKVersion, &actionsJSON, &caps.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(actionsJSON, &caps.Actions); err != nil {
+		return nil, fmt.Errorf("unmarshal capabilities: %w", err)
+	}
+	return &caps, nil
+}
+
+// cada handshake reemplaza las capacidades anteriores (el SDK puede haber sacado handlers)
+func (s *PostgresStorage) SaveCapabilities(ctx context.Context, caps *models.Capabilities) error {
+	actions := caps.Actions
+	if actions == nil {
+		actions = []models.ActionCapability{}
+	}
+	actionsJSON, err := json.Marshal(actions)
+	if err != nil {
+		return fmt.Errorf("marshal capabilities: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO client_capabilities (client_id, sdk_version, actions, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (client_id) DO UPDATE
+		SET sdk_version = EXCLUDED.sdk_version,
+		    actions = EXCLUDED.actions,
+		    updated_at = EXCLUDED.updated_at
+	`, caps.ClientID, caps.SDKVersion, actionsJSON, caps.UpdatedAt)
+	return err
+}
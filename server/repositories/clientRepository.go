@@ -7,7 +7,7 @@ import (
 	models "server/model"
 )
 
-type clientStorage interface {
+type ClientConfigStorage interface {
 	GetClientConfig(ctx context.Context, agentID string) (models.ClientConfig, error)
 
 	// Notification operations
@@ -20,11 +20,11 @@ func (s *PostgresStorage) GetClientConfig(ctx context.Context, agentId string) (
 	var allowedActionsJSON []byte
 
 	err := s.db.QueryRowContext(ctx, `
-	SELECT c.max_restarts_per_hour, c.allowed_actions, c.notify_on_nth_restart, c.cooldown_minutes
-	FROM clients_config c 
+	SELECT c.max_restarts_per_hour, c.allowed_actions, c.notify_on_nth_restart, c.cooldown_minutes, c.conflict_rule
+	FROM client_configs c 
 	JOIN agents a on a.client_id = c.client_id
 	where a.id = $1
-`, agentId).Scan(&cfg.MaxRestartsPerHour, &cfg.AllowedActions, &cfg.NotifyOnNthRestart, &cfg.CooldownMinutes)
+`, agentId).Scan(&cfg.MaxRestartsPerHour, &allowedActionsJSON, &cfg.NotifyOnNthRestart, &cfg.CooldownMinutes, &cfg.ConflictRule)
 
 	if err == sql.ErrNoRows {
 		return models.ClientConfig{
@@ -32,6 +32,7 @@ func (s *PostgresStorage) GetClientConfig(ctx context.Context, agentId string) (
 			AllowedActions:     []string{"restart", "notify", "wait"},
 			NotifyOnNthRestart: 3,
 			CooldownMinutes:    5,
+			ConflictRule:       models.ConflictQueue,
 		}, nil
 	}
 
@@ -36,9 +36,9 @@ func (r *EventRepository) CreateEvent(ctx context.Context, event *models.Event)
 	}
 
 	_, err = r.DB.Exec(ctx, `
-		INSERT INTO events (id, client_id, agent_id, type, service, severity, data, processed_at, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-	`, event.ID, event.ClientID, event.AgentID, event.Type, event.Service, event.Severity, dataJSON, event.ProcessedAt, event.CreatedAt)
+		INSERT INTO events (id, client_id, agent_id, type, service, severity, data, processed_at, created_at, received_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE($10, NOW()))
+	`, event.ID, event.ClientID, event.AgentID, event.Type, event.Service, event.Severity, dataJSON, event.ProcessedAt, event.CreatedAt, event.ReceivedAt)
 	return err
 }
 
@@ -48,7 +48,7 @@ func ReturnGetPendingEvents(ctx context.Context, agentId string) ([]models.Event
 
 func (r *EventRepository) GetPendingEvents(ctx context.Context, agentId string) ([]models.Event, error) {
 	rows, err := r.DB.Query(ctx, `
-		SELECT id, client_id, agent_id, type, service, severity, data, processed_at, created_at
+		SELECT id, client_id, agent_id, type, service, severity, data, processed_at, created_at, received_at
 		FROM events
 		WHERE agent_id = $1 AND processed_at IS NULL
 		ORDER BY created_at
@@ -67,7 +67,7 @@ func (r *EventRepository) GetPendingEvents(ctx context.Context, agentId string)
 		var e models.Event
 		var dataJSON []byte
 
-		err := rows.Scan(&e.ID, &e.ClientID, &e.AgentID, &e.Type, &e.Service, &e.Severity, &dataJSON, &e.ProcessedAt, &e.CreatedAt)
+		err := rows.Scan(&e.ID, &e.ClientID, &e.AgentID, &e.Type, &e.Service, &e.Severity, &dataJSON, &e.ProcessedAt, &e.CreatedAt, &e.ReceivedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -94,3 +94,58 @@ func (r *EventRepository) MarkEventProcessed(ctx context.Context, eventId string
 	`, eventId)
 	return err
 }
+
+// las mismas queries sobre database/sql, para que PostgresStorage sea un EventStorage
+
+func (s *PostgresStorage) CreateEvent(ctx context.Context, event *models.Event) error {
+	dataJSON, err := json.Marshal(event.Data)
+	if err != nil {
+		return fmt.Errorf("marshal event data: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO events (id, client_id, agent_id, type, service, severity, data, processed_at, created_at, received_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, COALESCE($10, NOW()))
+	`, event.ID, event.ClientID, event.AgentID, event.Type, event.Service, event.Severity, dataJSON, event.ProcessedAt, event.CreatedAt, event.ReceivedAt)
+	return err
+}
+
+func (s *PostgresStorage) GetPendingEvents(ctx context.Context, agentId string) ([]models.Event, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, client_id, agent_id, type, service, severity, data, processed_at, created_at, received_at
+		FROM events
+		WHERE agent_id = $1 AND processed_at IS NULL
+		ORDER BY created_at
+		LIMIT 50
+	`, agentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.Event
+	for rows.Next() {
+		var e models.Event
+		var dataJSON []byte
+
+		if err := rows.Scan(&e.ID, &e.ClientID, &e.AgentID, &e.Type, &e.Service, &e.Severity, &dataJSON, &e.ProcessedAt, &e.CreatedAt, &e.ReceivedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(dataJSON, &e.Data); err != nil {
+			return nil, fmt.Errorf("unmarshal event data: %w", err)
+		}
+
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+func (s *PostgresStorage) MarkEventProcessed(ctx context.Context, eventId string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE events
+		SET processed_at = NOW()
+		WHERE id = $1
+	`, eventId)
+	return err
+}
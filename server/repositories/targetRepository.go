@@ -0,0 +1,232 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	models "server/model"
+)
+
+var (
+	ErrTargetNotFound     = errors.New("target binding not found")
+	ErrCredentialNotFound = errors.New("credential not found")
+)
+
+type TargetStorage interface {
+	GetTargetBinding(ctx context.Context, clientID, target string) (*models.TargetBinding, error)
+	ListTargetBindings(ctx context.Context, clientID string) ([]models.TargetBinding, error)
+	SaveTargetBinding(ctx context.Context, binding *models.TargetBinding) error
+	DeleteTargetBinding(ctx context.Context, clientID, target string) error
+}
+
+type CredentialStorage interface {
+	GetCredential(ctx context.Context, clientID, kind, name string) (*models.Credential, error)
+	SaveCredential(ctx context.Context, credential *models.Credential) error
+}
+
+func (s *PostgresStorage) GetTargetBinding(ctx context.Context, clientID, target string) (*models.TargetBinding, error) {
+	var b models.TargetBinding
+	var configJSON []byte
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, client_id, target, backend, config, created_at, updated_at
+		FROM target_bindings
+		WHERE client_id = $1 AND target = $2
+	`, clientID, target).Scan(&b.ID, &b.ClientID, &b.Target, &b.Backend, &configJSON, &b.CreatedAt, &b.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrTargetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(configJSON, &b.Config)
+	return &b, nil
+}
+
+func (s *PostgresStorage) ListTargetBindings(ctx context.Context, clientID string) ([]models.TargetBinding, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, client_id, target, backend, config, created_at, updated_at
+		FROM target_bindings
+		WHERE client_id = $1
+		ORDER BY target
+	`, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bindings := []models.TargetBinding{}
+	for rows.Next() {
+		var b models.TargetBinding
+		var configJSON []byte
+
+		if err := rows.Scan(&b.ID, &b.ClientID, &b.Target, &b.Backend, &configJSON, &b.CreatedAt, &b.UpdatedAt); err != nil {
+			return nil, err
+		}
+		json.Unmarshal(configJSON, &b.Config)
+		bindings = append(bindings, b)
+	}
+
+	return bindings, rows.Err()
+}
+
+// crea o reemplaza el binding del target
+func (s *PostgresStorage) SaveTargetBinding(ctx context.Context, binding *models.TargetBinding) error {
+	configJSON, err := json.Marshal(binding.Config)
+	if err != nil {
+		return fmt.Errorf("marshal config: %w", err)
+	}
+
+	return s.db.QueryRowContext(ctx, `
+		INSERT INTO target_bindings (client_id, target, backend, config)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (client_id, target) DO UPDATE
+		SET backend = EXCLUDED.backend,
+		config = EXCLUDED.config,
+		updated_at = NOW()
+		RETURNING id, created_at, updated_at
+	`, binding.ClientID, binding.Target, binding.Backend, configJSON).Scan(&binding.ID, &binding.CreatedAt, &binding.UpdatedAt)
+}
+
+func (s *PostgresStorage) DeleteTargetBinding(ctx context.Context, clientID, target string) error {
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM target_bindings
+		WHERE client_id = $1 AND target = $2
+	`, clientID, target)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrTargetNotFound
+	}
+	return nil
+}
+
+func (s *PostgresStorage) GetCredential(ctx context.Context, clientID, kind, name string) (*models.Credential, error) {
+	var c models.Credential
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, client_id, kind, name, secret, created_at, updated_at
+		FROM client_credentials
+		WHERE client_id = $1 AND kind = $2 AND name = $3
+	`, clientID, kind, name).Scan(&c.ID, &c.ClientID, &c.Kind, &c.Name, &c.Secret, &c.CreatedAt, &c.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrCredentialNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// el secret ya tiene que venir cifrado
+func (s *PostgresStorage) SaveCredential(ctx context.Context, credential *models.Credential) error {
+	return s.db.QueryRowContext(ctx, `
+		INSERT INTO client_credentials (client_id, kind, name, secret)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (client_id, kind, name) DO UPDATE
+		SET secret = EXCLUDED.secret,
+		updated_at = NOW()
+		RETURNING id, created_at, updated_at
+	`, credential.ClientID, credential.Kind, credential.Name, credential.Secret).Scan(&credential.ID, &credential.CreatedAt, &credential.UpdatedAt)
+}
+
+var ErrCommandTemplateNotFound = errors.New("command template not found")
+
+type CommandTemplateStorage interface {
+	GetCommandTemplate(ctx context.Context, clientID, actionType, target string) (*models.CommandTemplate, error)
+	ListCommandTemplates(ctx context.Context, clientID string) ([]models.CommandTemplate, error)
+	SaveCommandTemplate(ctx context.Context, tpl *models.CommandTemplate) error
+	DeleteCommandTemplate(ctx context.Context, clientID, actionType, target string) error
+}
+
+func (s *PostgresStorage) GetCommandTemplate(ctx context.Context, clientID, actionType, target string) (*models.CommandTemplate, error) {
+	var t models.CommandTemplate
+	var schemaJSON []byte
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, client_id, action_type, target, template, params_schema, timeout_seconds, created_at, updated_at
+		FROM command_templates
+		WHERE client_id = $1 AND action_type = $2 AND target = $3
+	`, clientID, actionType, target).Scan(&t.ID, &t.ClientID, &t.ActionType, &t.Target, &t.Template, &schemaJSON,
+		&t.TimeoutSeconds, &t.CreatedAt, &t.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrCommandTemplateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(schemaJSON, &t.ParamsSchema); err != nil {
+		return nil, fmt.Errorf("unmarshal params schema: %w", err)
+	}
+	return &t, nil
+}
+
+func (s *PostgresStorage) ListCommandTemplates(ctx context.Context, clientID string) ([]models.CommandTemplate, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, client_id, action_type, target, template, params_schema, timeout_seconds, created_at, updated_at
+		FROM command_templates
+		WHERE client_id = $1
+		ORDER BY target, action_type
+	`, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []models.CommandTemplate{}
+	for rows.Next() {
+		var t models.CommandTemplate
+		var schemaJSON []byte
+
+		if err := rows.Scan(&t.ID, &t.ClientID, &t.ActionType, &t.Target, &t.Template, &schemaJSON,
+			&t.TimeoutSeconds, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		json.Unmarshal(schemaJSON, &t.ParamsSchema)
+		templates = append(templates, t)
+	}
+
+	return templates, rows.Err()
+}
+
+func (s *PostgresStorage) SaveCommandTemplate(ctx context.Context, tpl *models.CommandTemplate) error {
+	schemaJSON, err := json.Marshal(tpl.ParamsSchema)
+	if err != nil {
+		return fmt.Errorf("marshal params schema: %w", err)
+	}
+
+	return s.db.QueryRowContext(ctx, `
+		INSERT INTO command_templates (client_id, action_type, target, template, params_schema, timeout_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (client_id, action_type, target) DO UPDATE
+		SET template = EXCLUDED.template,
+		params_schema = EXCLUDED.params_schema,
+		timeout_seconds = EXCLUDED.timeout_seconds,
+		updated_at = NOW()
+		RETURNING id, created_at, updated_at
+	`, tpl.ClientID, tpl.ActionType, tpl.Target, tpl.Template, schemaJSON, tpl.TimeoutSeconds).Scan(&tpl.ID, &tpl.CreatedAt, &tpl.UpdatedAt)
+}
+
+func (s *PostgresStorage) DeleteCommandTemplate(ctx context.Context, clientID, actionType, target string) error {
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM command_templates
+		WHERE client_id = $1 AND action_type = $2 AND target = $3
+	`, clientID, actionType, target)
+	if err != nil {
+		return err
+	}
+
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrCommandTemplateNotFound
+	}
+	return nil
+}
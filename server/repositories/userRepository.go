@@ -24,6 +24,9 @@ type ClientStorage interface {
 	UpdateClient(ctx context.Context, user *models.Client) error
 	UpdateClientComplete(ctx context.Context, user *models.Client) error
 	FixClientID(ctx context.Context, email string, newID string) error
+	UpdateProtocolVersion(ctx context.Context, id string, version int) error
+	UpdateWebhookURL(ctx context.Context, id string, webhookURL string) error
+	MarkWebhookVerified(ctx context.Context, id string, webhookURL string, at time.Time) error
 }
 
 type PostgresStorage struct {
@@ -61,10 +64,10 @@ func (s *PostgresStorage) GetClient(ctx context.Context, id string) (*models.Cli
 	var ErrUserNotFound = errors.New("user not found")
 
 	err := s.db.QueryRowContext(ctx, `
-	SELECT id, nombre, email, password, company_name, metodo, google_id, api_key_hash, web_hook_secret, web_hook_url, created_at, updated_at
+	SELECT id, nombre, email, password, company_name, metodo, google_id, api_key_hash, web_hook_secret, web_hook_url, delivery_mode, protocol_version, webhook_verified_at, created_at, updated_at
 	FROM clients 
 	WHERE id = $1 
-`, id).Scan(&c.ID, &c.Nombre, &c.Email, &c.Password, &c.CompanyName, &c.Metodo, &c.GoogleID, &c.APIKeyHash, &c.WebhookSecret, &c.WebhookURL, &c.CreatedAt, &c.UpdatedAt)
+`, id).Scan(&c.ID, &c.Nombre, &c.Email, &c.Password, &c.CompanyName, &c.Metodo, &c.GoogleID, &c.APIKeyHash, &c.WebhookSecret, &c.WebhookURL, &c.DeliveryMode, &c.ProtocolVersion, &c.WebhookVerifiedAt, &c.CreatedAt, &c.UpdatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, ErrUserNotFound
@@ -74,27 +77,23 @@ func (s *PostgresStorage) GetClient(ctx context.Context, id string) (*models.Cli
 
 func (s *PostgresStorage) GetClientByAPIKey(ctx context.Context, APIKey string) (*models.Client, error) {
 
-	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, nombre, email, password, company_name, metodo, google_id, api_key_hash, web_hook_secret, web_hook_url, created_at, updated_at
+	var c models.Client
+
+	// las api keys se guardan hasheadas con sha256 (ver CompleteRegistration): el hash es
+	// determinista, asi que se busca por el indice en vez de comparar cliente por cliente
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, nombre, email, password, company_name, metodo, google_id, api_key_hash, web_hook_secret, web_hook_url, delivery_mode, protocol_version, webhook_verified_at, created_at, updated_at
 		FROM clients
-	`)
+		WHERE api_key_hash = $1
+	`, utils.HashAPIKey(APIKey)).Scan(&c.ID, &c.Nombre, &c.Email, &c.Password, &c.CompanyName, &c.Metodo, &c.GoogleID, &c.APIKeyHash, &c.WebhookSecret, &c.WebhookURL, &c.DeliveryMode, &c.ProtocolVersion, &c.WebhookVerifiedAt, &c.CreatedAt, &c.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("invalid api key")
+	}
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var c models.Client
-
-		if err := rows.Scan(&c.ID, &c.Nombre, &c.Email, &c.Password, &c.CompanyName, &c.Metodo, &c.GoogleID, &c.APIKeyHash, &c.WebhookSecret, &c.WebhookURL, &c.CreatedAt, &c.UpdatedAt); err != nil {
-			continue
-		}
-
-		if utils.IsValidaAPIKey(APIKey, c.APIKeyHash) {
-			return &c, nil
-		}
-	}
-	return nil, fmt.Errorf("invalid api key")
+	return &c, nil
 }
 
 func (s *PostgresStorage) GetClientByEmail(ctx context.Context, email string) (*models.Client, error) {
@@ -166,9 +165,58 @@ func (s *PostgresStorage) UpdateClientComplete(ctx context.Context, user *models
 		    webhook_url = $2,
 		    api_key_hash = $3,
 		    web_hook_secret = $4,
-		    updated_at = $5
-		WHERE id = $6
-	`, user.CompanyName, user.WebhookURL, user.APIKeyHash, user.WebhookSecret, time.Now(), user.ID)
+		    delivery_mode = $5,
+		    webhook_verified_at = NULL,
+		    updated_at = $6
+		WHERE id = $7
+	`, user.CompanyName, user.WebhookURL, user.APIKeyHash, user.WebhookSecret, user.DeliveryMode, time.Now(), user.ID)
 
 	return err
 }
+
+// version del envelope que negocio el SDK del cliente
+func (s *PostgresStorage) UpdateProtocolVersion(ctx context.Context, id string, version int) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE clients
+		SET protocol_version = $1,
+		    updated_at = $2
+		WHERE id = $3
+	`, version, time.Now(), id)
+
+	return err
+}
+
+// cambia la url del webhook; la nueva queda sin verificar
+func (s *PostgresStorage) UpdateWebhookURL(ctx context.Context, id string, webhookURL string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE clients
+		SET webhook_url = $1,
+		    webhook_verified_at = NULL,
+		    updated_at = $2
+		WHERE id = $3
+	`, webhookURL, time.Now(), id)
+
+	return err
+}
+
+// marca el webhook como verificado, solo si la url no cambio mientras lo verificabamos
+func (s *PostgresStorage) MarkWebhookVerified(ctx context.Context, id string, webhookURL string, at time.Time) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE clients
+		SET webhook_verified_at = $1,
+		    updated_at = $1
+		WHERE id = $2 AND webhook_url = $3
+	`, at, id, webhookURL)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	models "server/model"
+)
+
+type WebhookHealthStorage interface {
+	GetWebhookHealth(ctx context.Context, clientID string) (*models.WebhookHealth, error)
+	SaveWebhookHealth(ctx context.Context, health *models.WebhookHealth) error
+	ListSuspendedWebhooks(ctx context.Context) ([]models.WebhookHealth, error)
+}
+
+const webhookHealthColumns = `client_id, consecutive_failures, last_success_at, last_failure_at, last_error,
+		recent, suspended_at, last_probe_at, updated_at`
+
+func scanWebhookHealth(row rowScanner) (*models.WebhookHealth, error) {
+	var h models.WebhookHealth
+	var recentJSON []byte
+
+	err := row.Scan(&h.ClientID, &h.ConsecutiveFailures, &h.LastSuccessAt, &h.LastFailureAt, &h.LastError,
+		&recentJSON, &h.SuspendedAt, &h.LastProbeAt, &h.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(recentJSON, &h.Recent)
+	h.Suspended = h.SuspendedAt != nil
+	fillSuccessRate(&h)
+
+	return &h, nil
+}
+
+// success_rate sale de las ultimas entregas guardadas en recent
+func fillSuccessRate(h *models.WebhookHealth) {
+	h.Samples = len(h.Recent)
+	h.SuccessRate = 1
+	if h.Samples == 0 {
+		return
+	}
+	ok := 0
+	for _, r := range h.Recent {
+		if r {
+			ok++
+		}
+	}
+	h.SuccessRate = float64(ok) / float64(h.Samples)
+}
+
+// un cliente sin entregas todavia esta sano (la fila se crea con la primera)
+func (s *PostgresStorage) GetWebhookHealth(ctx context.Context, clientID string) (*models.WebhookHealth, error) {
+	h, err := scanWebhookHealth(s.db.QueryRowContext(ctx, `
+		SELECT `+webhookHealthColumns+`
+		FROM webhook_health
+		WHERE client_id = $1
+	`, clientID))
+
+	if err == sql.ErrNoRows {
+		health := &models.WebhookHealth{ClientID: clientID, Recent: []bool{}}
+		fillSuccessRate(health)
+		return health, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+func (s *PostgresStorage) SaveWebhookHealth(ctx context.Context, health *models.WebhookHealth) error {
+	recent := health.Recent
+	if recent == nil {
+		recent = []bool{}
+	}
+	recentJSON, err := json.Marshal(recent)
+	if err != nil {
+		return fmt.Errorf("marshal recent: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO webhook_health (client_id, consecutive_failures, last_success_at, last_failure_at, last_error, recent, suspended_at, last_probe_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+		ON CONFLICT (client_id) DO UPDATE
+		SET consecutive_failures = EXCLUDED.consecutive_failures,
+		last_success_at = EXCLUDED.last_success_at,
+		last_failure_at = EXCLUDED.last_failure_at,
+		last_error = EXCLUDED.last_error,
+		recent = EXCLUDED.recent,
+		suspended_at = EXCLUDED.suspended_at,
+		last_probe_at = EXCLUDED.last_probe_at,
+		updated_at = NOW()
+	`, health.ClientID, health.ConsecutiveFailures, health.LastSuccessAt, health.LastFailureAt, health.LastError,
+		recentJSON, health.SuspendedAt, health.LastProbeAt)
+
+	return err
+}
+
+// webhooks suspendidos, para que el prober los vuelva a probar
+func (s *PostgresStorage) ListSuspendedWebhooks(ctx context.Context) ([]models.WebhookHealth, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+webhookHealthColumns+`
+		FROM webhook_health
+		WHERE suspended_at IS NOT NULL
+		ORDER BY suspended_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.WebhookHealth
+	for rows.Next() {
+		h, err := scanWebhookHealth(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *h)
+	}
+
+	return list, rows.Err()
+}
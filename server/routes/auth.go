@@ -8,8 +8,17 @@ import (
 )
 
 type SetUpRoutes struct {
-	controllers  *controllers.LoginController
-	wsController *controllers.WebSocketController
+	controllers          *controllers.LoginController
+	wsController         *controllers.WebSocketController
+	actionController     *controllers.ActionController
+	commandController    *controllers.CommandController
+	targetController     *controllers.TargetController
+	queueController      *controllers.QueueController
+	webhookController    *controllers.WebhookController
+	ingestController     *controllers.IngestController
+	capabilityController *controllers.CapabilityController
+	artifactController   *controllers.ArtifactController
+	middleware           *middleware.Middleware
 }
 
 func (sp *SetUpRoutes) SetUpRoutes(router *gin.Engine) {
@@ -24,11 +33,57 @@ func (sp *SetUpRoutes) SetUpRoutes(router *gin.Engine) {
 		authorized.GET("/me", sp.controllers.GetCurrentUser)
 		authorized.POST("/complete-registration", sp.controllers.CompleteRegistration)
 	}
+
+	// Configuracion del cliente (dashboard), protegida con JWT
+	api := router.Group("/api")
+	api.Use(middleware.JWTMiddleware())
+	{
+		api.GET("/targets", sp.targetController.ListTargets)
+		api.PUT("/targets/:target", sp.targetController.SaveTarget)
+		api.DELETE("/targets/:target", sp.targetController.DeleteTarget)
+		api.PUT("/credentials/:kind/:name", sp.targetController.SaveCredential)
+		api.GET("/command-templates", sp.targetController.ListCommandTemplates)
+		api.PUT("/command-templates/:action/:target", sp.targetController.SaveCommandTemplate)
+		api.DELETE("/command-templates/:action/:target", sp.targetController.DeleteCommandTemplate)
+		api.GET("/queue", sp.queueController.GetQueue)
+		api.POST("/actions/:id/undo", sp.actionController.Undo)
+		api.GET("/webhook/health", sp.webhookController.GetHealth)
+		api.POST("/webhook/verify", sp.webhookController.Verify)
+		api.PUT("/webhook", sp.webhookController.ChangeURL)
+		api.GET("/capabilities", sp.capabilityController.Get)
+		api.GET("/actions/:id/artifacts", sp.artifactController.List)
+		api.GET("/artifacts/:id", sp.artifactController.Download)
+	}
+
+	// Rutas que usa el SDK, protegidas con la API key del cliente
+	sdk := router.Group("/v1")
+	sdk.Use(sp.middleware.APIKeyMiddleware())
+	{
+		sdk.POST("/events", sp.ingestController.ReportEvents)
+		sdk.POST("/actions/:id/result", sp.actionController.ReportResult)
+		sdk.GET("/commands", sp.commandController.Poll)
+		sdk.POST("/commands/:id/ack", sp.commandController.Ack)
+		sdk.POST("/capabilities", sp.capabilityController.Register)
+		sdk.POST("/actions/:id/artifacts", sp.artifactController.Upload)
+	}
 }
 
-func NewSetUpRoutes(loginController *controllers.LoginController, wsController *controllers.WebSocketController) *SetUpRoutes {
+func NewSetUpRoutes(loginController *controllers.LoginController, wsController *controllers.WebSocketController,
+	actionController *controllers.ActionController, commandController *controllers.CommandController, targetController *controllers.TargetController,
+	queueController *controllers.QueueController, webhookController *controllers.WebhookController,
+	ingestController *controllers.IngestController, capabilityController *controllers.CapabilityController,
+	artifactController *controllers.ArtifactController, mw *middleware.Middleware) *SetUpRoutes {
 	return &SetUpRoutes{
-		controllers:  loginController,
-		wsController: wsController,
+		controllers:          loginController,
+		wsController:         wsController,
+		actionController:     actionController,
+		commandController:    commandController,
+		targetController:     targetController,
+		queueController:      queueController,
+		webhookController:    webhookController,
+		ingestController:     ingestController,
+		capabilityController: capabilityController,
+		artifactController:   artifactController,
+		middleware:           mw,
 	}
 }
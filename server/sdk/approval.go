@@ -0,0 +1,229 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	models "server/model"
+	"slices"
+	"sort"
+	"time"
+)
+
+const (
+	defaultApprovalTimeout = 10 * time.Minute
+	defaultApprovalListen  = "127.0.0.1:9001"
+)
+
+// ApprovalConfig holds the listed actions until a local human approves them
+type ApprovalConfig struct {
+	Actions []string      // actions that need approval, e.g. ["rollback"]
+	Timeout time.Duration // auto-deny after this (and never after the action deadline)
+	Listen  string        // local address of the approvals endpoint
+	Token   string        // if set, the endpoint wants "Authorization: Bearer <token>"
+}
+
+// PendingApproval is an action waiting for a human
+type PendingApproval struct {
+	ActionID   string                 `json:"action_id"`
+	Action     string                 `json:"action"`
+	Target     string                 `json:"target"`
+	Params     map[string]interface{} `json:"params,omitempty"`
+	Reasoning  string                 `json:"reasoning"`
+	Confidence float64                `json:"confidence"`
+	ReceivedAt time.Time              `json:"received_at"`
+	ExpiresAt  time.Time              `json:"expires_at"`
+
+	decision models.LLMDecision
+	timer    *time.Timer
+}
+
+var ErrApprovalNotFound = errors.New("no pending approval with that action id")
+
+// RequireApproval turns on the approval gate for cfg.Actions
+func (a *AgentSDK) RequireApproval(cfg ApprovalConfig) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultApprovalTimeout
+	}
+	if cfg.Listen == "" {
+		cfg.Listen = defaultApprovalListen
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.approval = &cfg
+}
+
+func (a *AgentSDK) needsApproval(action string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.approval != nil && slices.Contains(a.approval.Actions, action)
+}
+
+// hold parks the action until someone approves or denies it, and answers 202:
+// the backend waits for the result like with any async action
+func (a *AgentSDK) hold(actionID string, decision models.LLMDecision, deadline *time.Time) (int, map[string]interface{}) {
+	if actionID == "" {
+		return http.StatusBadRequest, map[string]interface{}{"status": "aborted", "reason": "missing action id"}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	expires := now.Add(a.approval.Timeout)
+	if deadline != nil && deadline.Before(expires) {
+		expires = *deadline
+	}
+
+	pending := &PendingApproval{
+		ActionID:   actionID,
+		Action:     decision.Action,
+		Target:     decision.Target,
+		Params:     decision.Params,
+		Reasoning:  decision.Reasoning,
+		Confidence: decision.Confidence,
+		ReceivedAt: now,
+		ExpiresAt:  expires,
+		decision:   decision,
+	}
+	pending.timer = time.AfterFunc(time.Until(expires), func() {
+		a.resolve(actionID, false, "approval timed out")
+	})
+	a.pending[actionID] = pending
+
+	fmt.Printf("[SDK] %s sobre %s espera aprobacion (action %s, vence %s)\n", decision.Action, decision.Target, actionID, expires.Format(time.RFC3339))
+	return http.StatusAccepted, map[string]interface{}{"status": "awaiting_approval", "handle": actionID, "expires_at": expires}
+}
+
+// Approvals lists the actions waiting for a human, oldest first
+func (a *AgentSDK) Approvals() []PendingApproval {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	list := make([]PendingApproval, 0, len(a.pending))
+	for _, p := range a.pending {
+		list = append(list, *p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ReceivedAt.Before(list[j].ReceivedAt) })
+	return list
+}
+
+// Approve runs a held action; its result is reported to the backend as usual
+func (a *AgentSDK) Approve(actionID string) error {
+	return a.resolve(actionID, true, "")
+}
+
+// Deny drops a held action and reports it to the backend as failed
+func (a *AgentSDK) Deny(actionID, reason string) error {
+	if reason == "" {
+		reason = "denied by operator"
+	}
+	return a.resolve(actionID, false, reason)
+}
+
+func (a *AgentSDK) resolve(actionID string, approved bool, reason string) error {
+	a.mu.Lock()
+	pending, ok := a.pending[actionID]
+	if ok {
+		delete(a.pending, actionID)
+		pending.timer.Stop()
+	}
+	a.mu.Unlock()
+
+	if !ok {
+		return ErrApprovalNotFound
+	}
+
+	if !approved {
+		fmt.Printf("[SDK] %s sobre %s no se ejecuta: %s\n", pending.Action, pending.Target, reason)
+		go a.reportOutcome(actionID, http.StatusForbidden, map[string]interface{}{"status": "denied", "approval": "denied", "reason": reason})
+		return nil
+	}
+
+	go func() {
+		status, body := a.run(actionID, pending.decision)
+		if status == http.StatusAccepted {
+			return // async handler, reports on its own
+		}
+		body["approval"] = "approved"
+		a.reportOutcome(actionID, status, body)
+	}()
+	return nil
+}
+
+// ApprovalHandler is the local approvals API:
+//
+//	GET  /approvals                 pending actions
+//	POST /approvals/{id}/approve
+//	POST /approvals/{id}/deny       optional body {"reason": "..."}
+func (a *AgentSDK) ApprovalHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /approvals", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"pending": a.Approvals()})
+	})
+	mux.HandleFunc("POST /approvals/{id}/approve", func(w http.ResponseWriter, r *http.Request) {
+		a.writeResolution(w, r.PathValue("id"), a.Approve(r.PathValue("id")), "approved")
+	})
+	mux.HandleFunc("POST /approvals/{id}/deny", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Reason string `json:"reason"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		a.writeResolution(w, r.PathValue("id"), a.Deny(r.PathValue("id"), body.Reason), "denied")
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.mu.Lock()
+		token := ""
+		if a.approval != nil {
+			token = a.approval.Token
+		}
+		a.mu.Unlock()
+
+		if token != "" && r.Header.Get("Authorization") != "Bearer "+token {
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"error": "unauthorized"})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (a *AgentSDK) writeResolution(w http.ResponseWriter, actionID string, err error, status string) {
+	if errors.Is(err, ErrApprovalNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"action_id": actionID, "status": status})
+}
+
+// ServeApprovals serves ApprovalHandler on the configured local address until ctx is cancelled
+func (a *AgentSDK) ServeApprovals(ctx context.Context) error {
+	a.mu.Lock()
+	cfg := a.approval
+	a.mu.Unlock()
+	if cfg == nil {
+		return errors.New("approvals are not enabled, call RequireApproval first")
+	}
+
+	server := &http.Server{Addr: cfg.Listen, Handler: a.ApprovalHandler(), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	fmt.Printf("[SDK] aprobaciones en http://%s/approvals\n", cfg.Listen)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return ctx.Err()
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,193 @@
+package sdk
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	models "server/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+type reportedResult struct {
+	actionID string
+	report   models.ActionResultReport
+}
+
+// newResultBackend es un /v1/actions/{id}/result que pasa cada reporte por el canal
+func newResultBackend(t *testing.T) (*httptest.Server, chan reportedResult) {
+	t.Helper()
+
+	results := make(chan reportedResult, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/actions/"), "/result")
+		var report models.ActionResultReport
+		json.NewDecoder(r.Body).Decode(&report)
+		results <- reportedResult{actionID: id, report: report}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, results
+}
+
+func waitResult(t *testing.T, results chan reportedResult) reportedResult {
+	t.Helper()
+	select {
+	case r := <-results:
+		return r
+	case <-time.After(2 * time.Second):
+		t.Fatal("no result reported")
+		return reportedResult{}
+	}
+}
+
+func TestApprovalGate(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		deadline time.Duration // 0 = sin deadline
+		resolve  func(a *AgentSDK) error
+		wantRun  bool
+		wantErr  string
+	}{
+		{name: "approved", timeout: time.Hour, resolve: func(a *AgentSDK) error { return a.Approve("act-1") }, wantRun: true},
+		{name: "denied", timeout: time.Hour, resolve: func(a *AgentSDK) error { return a.Deny("act-1", "not during the sale") }, wantErr: "not during the sale"},
+		{name: "denied without a reason", timeout: time.Hour, resolve: func(a *AgentSDK) error { return a.Deny("act-1", "") }, wantErr: "denied by operator"},
+		{name: "times out", timeout: 50 * time.Millisecond, wantErr: "approval timed out"},
+		{name: "the deadline comes first", timeout: time.Hour, deadline: 50 * time.Millisecond, wantErr: "approval timed out"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend, results := newResultBackend(t)
+			a := NewSDK("key", backend.URL, "secret")
+			ran := make(chan string, 1)
+			a.On("rollback", func(target string, params map[string]interface{}) error {
+				ran <- target
+				return nil
+			})
+			a.RequireApproval(ApprovalConfig{Actions: []string{"rollback"}, Timeout: tt.timeout})
+
+			envelope := models.ActionEnvelope{ActionID: "act-1", Decision: models.LLMDecision{Action: "rollback", Target: "api", Confidence: 0.95, Reasoning: "bad deploy"}}
+			if tt.deadline > 0 {
+				deadline := time.Now().Add(tt.deadline)
+				envelope.Deadline = &deadline
+			}
+			status, body := a.dispatch(envelope)
+			if status != http.StatusAccepted || body["status"] != "awaiting_approval" {
+				t.Fatalf("status = %d, body = %v", status, body)
+			}
+
+			pending := a.Approvals()
+			if len(pending) != 1 || pending[0].ActionID != "act-1" || pending[0].Reasoning != "bad deploy" {
+				t.Fatalf("pending = %+v", pending)
+			}
+			if tt.deadline > 0 && pending[0].ExpiresAt.After(time.Now().Add(time.Minute)) {
+				t.Errorf("expires at %s, after the action deadline", pending[0].ExpiresAt)
+			}
+			select {
+			case <-ran:
+				t.Fatal("ran before the approval")
+			default:
+			}
+
+			if tt.resolve != nil {
+				if err := tt.resolve(a); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result := waitResult(t, results)
+			if result.actionID != "act-1" {
+				t.Errorf("reported %q", result.actionID)
+			}
+			if tt.wantRun {
+				if <-ran != "api" || result.report.Status != models.ActionStatusSuccess || result.report.Result["approval"] != "approved" {
+					t.Errorf("report = %+v", result.report)
+				}
+			} else if result.report.Status != models.ActionStatusFailed || result.report.Error != tt.wantErr {
+				t.Errorf("report = %+v, want failed with %q", result.report, tt.wantErr)
+			}
+
+			if len(a.Approvals()) != 0 {
+				t.Error("still pending")
+			}
+			if err := a.Approve("act-1"); !errors.Is(err, ErrApprovalNotFound) {
+				t.Errorf("second resolution: err = %v", err)
+			}
+		})
+	}
+}
+
+func TestApprovalOnlyForListedActions(t *testing.T) {
+	a := NewSDK("key", "http://127.0.0.1:1", "secret")
+	a.On("scale", func(target string, params map[string]interface{}) error { return nil })
+	a.RequireApproval(ApprovalConfig{Actions: []string{"rollback"}})
+
+	status, body := a.dispatch(models.ActionEnvelope{ActionID: "act-2", Decision: models.LLMDecision{Action: "scale", Target: "api", Confidence: 0.95}})
+	if status != http.StatusOK || len(a.Approvals()) != 0 {
+		t.Errorf("status = %d, body = %v", status, body)
+	}
+}
+
+func TestApprovalHandler(t *testing.T) {
+	backend, results := newResultBackend(t)
+	a := NewSDK("key", backend.URL, "secret")
+	a.On("rollback", func(target string, params map[string]interface{}) error { return nil })
+	a.RequireApproval(ApprovalConfig{Actions: []string{"rollback"}, Token: "local-token"})
+
+	for _, id := range []string{"act-1", "act-2"} {
+		a.dispatch(models.ActionEnvelope{ActionID: id, Decision: models.LLMDecision{Action: "rollback", Target: "api", Confidence: 0.95}})
+	}
+
+	srv := httptest.NewServer(a.ApprovalHandler())
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "without the token", method: http.MethodGet, path: "/approvals", wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", method: http.MethodGet, path: "/approvals", token: "nope", wantStatus: http.StatusUnauthorized},
+		{name: "lists the pending ones", method: http.MethodGet, path: "/approvals", token: "local-token", wantStatus: http.StatusOK, wantBody: `"action_id":"act-2"`},
+		{name: "approve", method: http.MethodPost, path: "/approvals/act-1/approve", token: "local-token", wantStatus: http.StatusOK, wantBody: `"approved"`},
+		{name: "approve twice", method: http.MethodPost, path: "/approvals/act-1/approve", token: "local-token", wantStatus: http.StatusNotFound},
+		{name: "deny with a reason", method: http.MethodPost, path: "/approvals/act-2/deny", token: "local-token", body: `{"reason":"freeze"}`, wantStatus: http.StatusOK, wantBody: `"denied"`},
+		{name: "unknown action", method: http.MethodPost, path: "/approvals/act-9/deny", token: "local-token", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			var body json.RawMessage
+			json.NewDecoder(resp.Body).Decode(&body)
+			if resp.StatusCode != tt.wantStatus || !strings.Contains(string(body), tt.wantBody) {
+				t.Errorf("status = %d, body = %s", resp.StatusCode, body)
+			}
+		})
+	}
+
+	got := map[string]string{}
+	for i := 0; i < 2; i++ {
+		r := waitResult(t, results)
+		got[r.actionID] = r.report.Status + " " + r.report.Error
+	}
+	if got["act-1"] != "success " || got["act-2"] != "failed freeze" {
+		t.Errorf("reports = %v", got)
+	}
+}
@@ -0,0 +1,317 @@
+package sdk
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	models "server/model"
+	"time"
+)
+
+// What to drop when the event buffer is full
+const (
+	DropOldest         = "oldest"          // the oldest event
+	DropLowestSeverity = "lowest_severity" // the oldest of the least severe events
+)
+
+// When the disk buffer is fsynced
+const (
+	FsyncAlways   = "always"   // after every event, survives a power loss
+	FsyncInterval = "interval" // every ReporterConfig.FsyncInterval while it changes
+	FsyncNever    = "never"    // left to the OS, survives a crash of the process only
+)
+
+// queuedEvent is an event waiting to be sent; seq orders them and identifies
+// them across a send (the queue can evict while a batch is in flight)
+type queuedEvent struct {
+	Seq   uint64       `json:"seq"`
+	Event models.Event `json:"event"`
+	size  int64
+}
+
+// segmentRecord is a line of the buffer file: an event, or the seqs of events
+// that left the queue (sent, rejected or dropped)
+type segmentRecord struct {
+	Seq   uint64        `json:"seq,omitempty"`
+	Event *models.Event `json:"event,omitempty"`
+	Ack   []uint64      `json:"ack,omitempty"`
+}
+
+// compactMinBytes is how much acked data the file carries before it is compacted
+const compactMinBytes = 64 << 10
+
+// eventQueue holds the events in order, bounded by count and bytes. With a path
+// every change is appended to a segment file first, so the events survive a
+// restart of the SDK and are replayed in the same order. The file is truncated
+// once every event was acked, and compacted when the acked lines outweigh the
+// pending ones: a long outage costs one appended line per event.
+type eventQueue struct {
+	maxEvents int
+	maxBytes  int64
+	drop      string
+
+	events []queuedEvent
+	bytes  int64
+	seq    uint64
+
+	path          string
+	file          *os.File
+	fileBytes     int64 // tamano del segmento, eventos pendientes + lo ya confirmado
+	fsync         string
+	fsyncInterval time.Duration
+	lastSync      time.Time
+	dirty         bool // escrito desde el ultimo fsync
+}
+
+func newEventQueue(cfg ReporterConfig) (*eventQueue, error) {
+	q := &eventQueue{
+		maxEvents:     cfg.QueueSize,
+		maxBytes:      cfg.MaxBufferBytes,
+		drop:          cfg.DropPolicy,
+		path:          cfg.BufferPath,
+		fsync:         cfg.Fsync,
+		fsyncInterval: cfg.FsyncInterval,
+	}
+	if q.path == "" {
+		return q, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(q.path), 0o700); err != nil {
+		return nil, err
+	}
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	// compactamos al abrir: limpia una ultima linea cortada y aplica los limites nuevos
+	q.evict()
+	if err := q.rewrite(); err != nil {
+		return nil, err
+	}
+	if len(q.events) > 0 {
+		fmt.Printf("[SDK] %d eventos pendientes en %s, se reenvian en orden\n", len(q.events), q.path)
+	}
+	return q, nil
+}
+
+// load replays the segment left by a previous run: events minus the acked ones.
+// A line cut by a crash is skipped.
+func (q *eventQueue) load() error {
+	f, err := os.Open(q.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	acked := map[uint64]bool{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 4<<20)
+	for scanner.Scan() {
+		var record segmentRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		for _, seq := range record.Ack {
+			acked[seq] = true
+		}
+		if record.Event == nil {
+			continue
+		}
+		q.events = append(q.events, queuedEvent{Seq: record.Seq, Event: *record.Event, size: int64(len(scanner.Bytes()) + 1)})
+		q.seq = max(q.seq, record.Seq)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	kept := q.events[:0]
+	for _, e := range q.events {
+		if !acked[e.Seq] {
+			kept = append(kept, e)
+			q.bytes += e.size
+		}
+	}
+	q.events = kept
+	return nil
+}
+
+// push adds an event and returns how many had to be dropped to make room
+func (q *eventQueue) push(event models.Event) (int, error) {
+	q.seq++
+	e := queuedEvent{Seq: q.seq, Event: event}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return 0, err
+	}
+	line = append(line, '\n')
+	e.size = int64(len(line))
+
+	q.events = append(q.events, e)
+	q.bytes += e.size
+
+	dropped := q.evict()
+	if q.file == nil {
+		return len(dropped), nil
+	}
+	if err := q.append(line); err != nil {
+		return len(dropped), err
+	}
+	if len(dropped) > 0 {
+		return len(dropped), q.ack(dropped)
+	}
+	return 0, q.sync(false)
+}
+
+// peek returns up to n events from the front without removing them
+func (q *eventQueue) peek(n int) []queuedEvent {
+	return append([]queuedEvent(nil), q.events[:min(n, len(q.events))]...)
+}
+
+// remove takes out the events of a batch that was delivered (or rejected)
+func (q *eventQueue) remove(batch []queuedEvent) error {
+	done := make(map[uint64]bool, len(batch))
+	for _, e := range batch {
+		done[e.Seq] = true
+	}
+
+	var removed []uint64
+	kept := q.events[:0]
+	for _, e := range q.events {
+		if done[e.Seq] {
+			q.bytes -= e.size
+			removed = append(removed, e.Seq)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	q.events = kept
+
+	if q.file == nil || len(removed) == 0 {
+		return nil
+	}
+	return q.ack(removed)
+}
+
+func (q *eventQueue) len() int {
+	return len(q.events)
+}
+
+// evict drops events until the queue fits its limits (always keeps the newest
+// one) and returns their seqs
+func (q *eventQueue) evict() []uint64 {
+	var dropped []uint64
+	for len(q.events) > 1 && (q.maxEvents > 0 && len(q.events) > q.maxEvents || q.maxBytes > 0 && q.bytes > q.maxBytes) {
+		i := 0
+		if q.drop == DropLowestSeverity {
+			for j := range q.events {
+				if models.SeverityRank(q.events[j].Event.Severity) < models.SeverityRank(q.events[i].Event.Severity) {
+					i = j
+				}
+			}
+		}
+		q.bytes -= q.events[i].size
+		dropped = append(dropped, q.events[i].Seq)
+		q.events = append(q.events[:i], q.events[i+1:]...)
+	}
+	return dropped
+}
+
+// ack records that the seqs left the queue. With nothing pending the segment is
+// truncated; when the acked lines outweigh the pending events it is compacted.
+func (q *eventQueue) ack(seqs []uint64) error {
+	switch {
+	case len(q.events) == 0:
+		if err := q.file.Truncate(0); err != nil {
+			return err
+		}
+		q.fileBytes = 0
+		q.dirty = true
+		return q.sync(false)
+	case q.fileBytes-q.bytes > max(q.bytes, compactMinBytes):
+		return q.rewrite()
+	}
+
+	line, err := json.Marshal(segmentRecord{Ack: seqs})
+	if err != nil {
+		return err
+	}
+	if err := q.append(append(line, '\n')); err != nil {
+		return err
+	}
+	return q.sync(false)
+}
+
+func (q *eventQueue) append(line []byte) error {
+	n, err := q.file.Write(line)
+	q.fileBytes += int64(n)
+	q.dirty = true
+	return err
+}
+
+// rewrite compacts the segment to the pending events (temp file + rename, so a
+// crash leaves the old or the new one)
+func (q *eventQueue) rewrite() error {
+	if q.path == "" {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(q.path), filepath.Base(q.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for _, e := range q.events {
+		line, err := json.Marshal(segmentRecord{Seq: e.Seq, Event: &e.Event})
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if q.fsync != FsyncNever {
+		if err := tmp.Sync(); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if q.file != nil {
+		q.file.Close()
+		q.file = nil
+	}
+	if err := os.Rename(tmp.Name(), q.path); err != nil {
+		return err
+	}
+
+	q.file, err = os.OpenFile(q.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	q.fileBytes = q.bytes
+	q.lastSync, q.dirty = time.Now(), false
+	return err
+}
+
+// sync fsyncs the segment if it changed and the Fsync option asks for it now.
+// With FsyncInterval what is skipped here is synced by the reporter loop.
+func (q *eventQueue) sync(force bool) error {
+	switch {
+	case q.file == nil || !q.dirty || q.fsync == FsyncNever && !force:
+		return nil
+	case q.fsync == FsyncInterval && !force && time.Since(q.lastSync) < q.fsyncInterval:
+		return nil
+	}
+	q.lastSync, q.dirty = time.Now(), false
+	return q.file.Sync()
+}
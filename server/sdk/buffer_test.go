@@ -0,0 +1,181 @@
+package sdk
+
+import (
+	"os"
+	"path/filepath"
+	models "server/model"
+	"slices"
+	"testing"
+	"time"
+)
+
+func openQueue(t *testing.T, cfg ReporterConfig) *eventQueue {
+	t.Helper()
+	q, err := newEventQueue(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { q.file.Close() })
+	return q
+}
+
+func services(q *eventQueue) []string {
+	var out []string
+	for _, e := range q.events {
+		out = append(out, e.Event.Service)
+	}
+	return out
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Size()
+}
+
+func TestEventQueueReplay(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    ReporterConfig
+		push   []string
+		remove int // eventos del frente que confirma el backend
+		want   []string
+	}{
+		{name: "pending events in order", push: []string{"a", "b", "c"}, want: []string{"a", "b", "c"}},
+		{name: "acked events are not replayed", push: []string{"a", "b", "c", "d"}, remove: 2, want: []string{"c", "d"}},
+		{name: "everything acked", push: []string{"a", "b"}, remove: 2},
+		{name: "dropped events are not replayed", cfg: ReporterConfig{QueueSize: 2}, push: []string{"a", "b", "c", "d"}, want: []string{"c", "d"}},
+		{name: "dropped and acked", cfg: ReporterConfig{QueueSize: 3}, push: []string{"a", "b", "c", "d"}, remove: 1, want: []string{"c", "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.BufferPath = filepath.Join(t.TempDir(), "events.log")
+			tt.cfg.Fsync = FsyncNever
+			q := openQueue(t, tt.cfg)
+			for _, service := range tt.push {
+				if _, err := q.push(models.Event{Type: models.EventAppDown, Service: service}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := q.remove(q.peek(tt.remove)); err != nil {
+				t.Fatal(err)
+			}
+			q.file.Close()
+
+			replayed := openQueue(t, tt.cfg)
+			if got := services(replayed); !slices.Equal(got, tt.want) {
+				t.Errorf("replayed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventQueueAppendsOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	q := openQueue(t, ReporterConfig{BufferPath: path, Fsync: FsyncNever})
+
+	for i := 0; i < 3; i++ {
+		q.push(models.Event{Type: models.EventAppDown, Service: "api"})
+	}
+	if size := fileSize(t, path); size != q.bytes {
+		t.Fatalf("file has %d bytes, the events %d", size, q.bytes)
+	}
+
+	// confirmar un batch agrega una linea, no reescribe el archivo
+	before := fileSize(t, path)
+	q.remove(q.peek(1))
+	if size := fileSize(t, path); size <= before || size > before+32 {
+		t.Errorf("file went from %d to %d bytes after an ack", before, size)
+	}
+
+	// con todo confirmado el segmento se trunca
+	q.remove(q.peek(2))
+	if size := fileSize(t, path); size != 0 {
+		t.Errorf("file has %d bytes with nothing pending", size)
+	}
+	q.push(models.Event{Type: models.EventAppDown, Service: "worker"})
+	if size := fileSize(t, path); size != q.bytes {
+		t.Errorf("file has %d bytes after the truncate, the events %d", size, q.bytes)
+	}
+}
+
+func TestEventQueueCompacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	cfg := ReporterConfig{BufferPath: path, Fsync: FsyncNever}
+	q := openQueue(t, cfg)
+
+	// siempre queda uno pendiente: el segmento nunca se vacia y solo se puede compactar
+	q.push(models.Event{Type: models.EventAppDown, Service: "event-0"})
+	for i := 1; i <= 5000; i++ {
+		q.push(models.Event{Type: models.EventAppDown, Service: "event-" + string(rune('a'+i%26))})
+		if err := q.remove(q.peek(1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if size := fileSize(t, path); size > 2*compactMinBytes+q.bytes {
+		t.Errorf("file grew to %d bytes with %d pending", size, q.bytes)
+	}
+	if q.fileBytes != fileSize(t, path) {
+		t.Errorf("fileBytes = %d, file has %d", q.fileBytes, fileSize(t, path))
+	}
+	q.file.Close()
+
+	replayed := openQueue(t, cfg)
+	if replayed.len() != 1 || replayed.events[0].Seq != 5001 {
+		t.Errorf("replayed %+v, want only seq 5001", replayed.events)
+	}
+}
+
+func TestEventQueueSkipsCutLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	cfg := ReporterConfig{BufferPath: path, Fsync: FsyncNever}
+	q := openQueue(t, cfg)
+	q.push(models.Event{Type: models.EventAppDown, Service: "api"})
+	q.file.Write([]byte(`{"seq":2,"event":{"ty`)) // el proceso murio a mitad de la linea
+	q.file.Close()
+
+	q = openQueue(t, cfg)
+	q.push(models.Event{Type: models.EventAppDown, Service: "worker"})
+	q.file.Close()
+
+	if got := services(openQueue(t, cfg)); !slices.Equal(got, []string{"api", "worker"}) {
+		t.Errorf("replayed %v", got)
+	}
+}
+
+func TestEventQueueSync(t *testing.T) {
+	tests := []struct {
+		name      string
+		fsync     string
+		wantDirty bool // despues del segundo push, sin forzar
+	}{
+		{name: "always", fsync: FsyncAlways},
+		{name: "interval waits for the loop", fsync: FsyncInterval, wantDirty: true},
+		{name: "never", fsync: FsyncNever, wantDirty: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := openQueue(t, ReporterConfig{BufferPath: filepath.Join(t.TempDir(), "events.log"), Fsync: tt.fsync, FsyncInterval: time.Hour})
+			if q.dirty {
+				t.Fatal("dirty right after opening")
+			}
+
+			q.push(models.Event{Type: models.EventAppDown, Service: "a"})
+			q.push(models.Event{Type: models.EventAppDown, Service: "b"})
+			if q.dirty != tt.wantDirty {
+				t.Fatalf("dirty = %v, want %v", q.dirty, tt.wantDirty)
+			}
+
+			// Flush fuerza el fsync con cualquier opcion
+			if err := q.sync(true); err != nil || q.dirty {
+				t.Errorf("forced sync: err = %v, dirty = %v", err, q.dirty)
+			}
+		})
+	}
+}
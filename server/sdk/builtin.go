@@ -0,0 +1,325 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	models "server/model"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Built-in handler types for ActionConfig.Type
+const (
+	HandlerCommand   = "command"   // runs Command (no shell)
+	HandlerSystemctl = "systemctl" // systemctl restart Unit
+	HandlerDocker    = "docker"    // docker restart Container
+	HandlerHTTP      = "http"      // calls HTTP.URL
+)
+
+const defaultHandlerTimeout = 30 * time.Second
+
+// ActionConfig maps an action on a target to a built-in handler. Every string is a
+// text/template over {{.Action}}, {{.Target}} and {{.Params.<name>}}; a param the
+// decision didn't send is an error, not an empty string.
+type ActionConfig struct {
+	Action    string            `yaml:"action"`
+	Target    string            `yaml:"target"` // AnyTarget ("*") for every target without its own entry
+	Type      string            `yaml:"type"`
+	Command   []string          `yaml:"command"`
+	Unit      string            `yaml:"unit"`
+	Container string            `yaml:"container"`
+	HTTP      *HTTPActionConfig `yaml:"http"`
+	Timeout   string            `yaml:"timeout"` // e.g. "30s"
+	Async     bool              `yaml:"async"`   // answer 202 and report the result later
+
+	// sent to the backend with the capabilities (see DescribeAction); set them on
+	// one entry of the action
+	Description string                        `yaml:"description"`
+	Params      map[string]models.ParamSchema `yaml:"params"`
+}
+
+type HTTPActionConfig struct {
+	Method       string            `yaml:"method"` // default POST
+	URL          string            `yaml:"url"`
+	Headers      map[string]string `yaml:"headers"`
+	Body         string            `yaml:"body"`
+	ExpectStatus int               `yaml:"expect_status"` // default any 2xx
+}
+
+// templateData is what the templates of an ActionConfig see
+type templateData struct {
+	Action string
+	Target string
+	Params map[string]interface{}
+}
+
+// builtinHandler is a configured handler ready to run
+type builtinHandler struct {
+	cfg        ActionConfig
+	timeout    time.Duration
+	httpClient *http.Client // the SDK's, for http handlers; the handler timeout bounds it
+	run        func(ctx context.Context, data templateData) (map[string]interface{}, error)
+}
+
+func newBuiltinHandler(cfg ActionConfig, httpClient *http.Client) (*builtinHandler, error) {
+	h := &builtinHandler{cfg: cfg, timeout: defaultHandlerTimeout, httpClient: untimed(httpClient)}
+	if cfg.Timeout != "" {
+		timeout, err := time.ParseDuration(cfg.Timeout)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("action %s/%s: invalid timeout %q", cfg.Action, cfg.Target, cfg.Timeout)
+		}
+		h.timeout = timeout
+	}
+
+	switch cfg.Type {
+	case HandlerCommand:
+		if len(cfg.Command) == 0 {
+			return nil, fmt.Errorf("action %s/%s: command is empty", cfg.Action, cfg.Target)
+		}
+		h.run = h.runCommand
+	case HandlerSystemctl:
+		if cfg.Unit == "" {
+			return nil, fmt.Errorf("action %s/%s: unit is required", cfg.Action, cfg.Target)
+		}
+		h.run = h.runSystemctl
+	case HandlerDocker:
+		if cfg.Container == "" {
+			return nil, fmt.Errorf("action %s/%s: container is required", cfg.Action, cfg.Target)
+		}
+		h.run = h.runDocker
+	case HandlerHTTP:
+		if cfg.HTTP == nil || cfg.HTTP.URL == "" {
+			return nil, fmt.Errorf("action %s/%s: http.url is required", cfg.Action, cfg.Target)
+		}
+		h.run = h.runHTTP
+	default:
+		return nil, fmt.Errorf("action %s/%s: unknown handler type %q", cfg.Action, cfg.Target, cfg.Type)
+	}
+
+	return h, nil
+}
+
+func (h *builtinHandler) handle(ctx context.Context, action, target string, params map[string]interface{}) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	return h.run(ctx, templateData{Action: action, Target: target, Params: params})
+}
+
+func (h *builtinHandler) runCommand(ctx context.Context, data templateData) (map[string]interface{}, error) {
+	args := make([]string, len(h.cfg.Command))
+	for i, arg := range h.cfg.Command {
+		rendered, err := render(arg, data)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = rendered
+	}
+	return runProcess(ctx, args[0], args[1:]...)
+}
+
+func (h *builtinHandler) runSystemctl(ctx context.Context, data templateData) (map[string]interface{}, error) {
+	unit, err := renderName(h.cfg.Unit, data)
+	if err != nil {
+		return nil, err
+	}
+	return runProcess(ctx, "systemctl", "restart", unit)
+}
+
+func (h *builtinHandler) runDocker(ctx context.Context, data templateData) (map[string]interface{}, error) {
+	container, err := renderName(h.cfg.Container, data)
+	if err != nil {
+		return nil, err
+	}
+	return runProcess(ctx, "docker", "restart", container)
+}
+
+func (h *builtinHandler) runHTTP(ctx context.Context, data templateData) (map[string]interface{}, error) {
+	spec := h.cfg.HTTP
+
+	url, err := render(spec.URL, data)
+	if err != nil {
+		return nil, err
+	}
+	body, err := render(spec.Body, data)
+	if err != nil {
+		return nil, err
+	}
+	method := spec.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range spec.Headers {
+		rendered, err := render(value, data)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(name, rendered)
+	}
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	answer, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	result := map[string]interface{}{"status_code": resp.StatusCode, "body": string(answer)}
+
+	if spec.ExpectStatus != 0 && resp.StatusCode != spec.ExpectStatus ||
+		spec.ExpectStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		return result, fmt.Errorf("%s %s answered %d", method, url, resp.StatusCode)
+	}
+	return result, nil
+}
+
+// runProcess runs the command without a shell, so params can't inject anything
+func runProcess(ctx context.Context, name string, args ...string) (map[string]interface{}, error) {
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	start := time.Now()
+	err := cmd.Run()
+
+	result := map[string]interface{}{
+		"command":     append([]string{name}, args...),
+		"exit_code":   cmd.ProcessState.ExitCode(),
+		"output":      truncate(strings.TrimSpace(out.String()), 4<<10),
+		"duration_ms": time.Since(start).Milliseconds(),
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return result, fmt.Errorf("%s timed out", name)
+	}
+	return result, err
+}
+
+func render(text string, data templateData) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tpl, err := template.New("param").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if err := tpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("template %q: %w", text, err)
+	}
+	return out.String(), nil
+}
+
+// renderName renders a unit or container name: a param can't turn it into a flag
+func renderName(text string, data templateData) (string, error) {
+	name, err := render(text, data)
+	if err != nil {
+		return "", err
+	}
+	if name == "" || strings.HasPrefix(name, "-") || strings.ContainsAny(name, " \t\n") {
+		return "", fmt.Errorf("invalid name %q", name)
+	}
+	return name, nil
+}
+
+// UseBuiltins registers the configured handlers. Entries for the same action are
+// picked by target, falling back to the AnyTarget entry.
+func (a *AgentSDK) UseBuiltins(configs []ActionConfig) error {
+	type byTarget map[string]*builtinHandler
+	syncHandlers, asyncHandlers := map[string]byTarget{}, map[string]byTarget{}
+	infos := map[string]actionInfo{}
+
+	for _, cfg := range configs {
+		if cfg.Action == "" {
+			return fmt.Errorf("action config without action")
+		}
+		if cfg.Target == "" {
+			cfg.Target = AnyTarget
+		}
+		h, err := newBuiltinHandler(cfg, a.httpClient)
+		if err != nil {
+			return err
+		}
+
+		group := syncHandlers
+		if cfg.Async {
+			group = asyncHandlers
+		}
+		if group[cfg.Action] == nil {
+			group[cfg.Action] = byTarget{}
+		}
+		if _, dup := group[cfg.Action][cfg.Target]; dup {
+			return fmt.Errorf("action %s/%s is configured twice", cfg.Action, cfg.Target)
+		}
+		group[cfg.Action][cfg.Target] = h
+
+		info := infos[cfg.Action]
+		info.targets = append(info.targets, cfg.Target)
+		if cfg.Description != "" {
+			info.description = cfg.Description
+		}
+		if cfg.Params != nil {
+			info.params = cfg.Params
+		}
+		infos[cfg.Action] = info
+	}
+
+	// dispatch prefers the async handler of an action, so an action is one or the other
+	for action := range asyncHandlers {
+		if _, ok := syncHandlers[action]; ok {
+			return fmt.Errorf("action %s mixes async and sync handlers", action)
+		}
+	}
+
+	pick := func(handlers byTarget, target string) (*builtinHandler, error) {
+		if h, ok := handlers[target]; ok {
+			return h, nil
+		}
+		if h, ok := handlers[AnyTarget]; ok {
+			return h, nil
+		}
+		return nil, fmt.Errorf("no handler configured for target %q", target)
+	}
+
+	for action, handlers := range syncHandlers {
+		a.On(action, func(target string, params map[string]interface{}) error {
+			h, err := pick(handlers, target)
+			if err != nil {
+				return err
+			}
+			result, err := h.handle(context.Background(), action, target, params)
+			if err != nil && result["output"] != nil {
+				return fmt.Errorf("%w: %v", err, result["output"])
+			}
+			return err
+		})
+	}
+	for action, handlers := range asyncHandlers {
+		a.OnAsync(action, func(ctx context.Context, target string, params map[string]interface{}, _ ProgressFunc) (map[string]interface{}, error) {
+			h, err := pick(handlers, target)
+			if err != nil {
+				return nil, err
+			}
+			return h.handle(ctx, action, target, params)
+		})
+	}
+
+	for action, info := range infos {
+		a.DescribeAction(action, info.description, info.params, builtinTargets(info.targets)...)
+	}
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package sdk
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuiltinCommand(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "out")
+	marker := filepath.Join(dir, "pwned")
+
+	a := NewSDK("key", "http://127.0.0.1:1", "secret")
+	err := a.UseBuiltins([]ActionConfig{
+		// sin shell: el param llega como un solo argumento
+		{Action: "restart", Target: "api", Type: HandlerCommand, Command: []string{"sh", "-c", `printf '%s %s' "$1" "$2" > "$3"`, "sh", "{{.Target}}", "{{.Params.reason}}", out}},
+		{Action: "restart", Target: AnyTarget, Type: HandlerCommand, Command: []string{"sh", "-c", `printf 'any %s' "$1" > "$2"`, "sh", "{{.Target}}", out}},
+		{Action: "scale", Target: "api", Type: HandlerCommand, Command: []string{"true"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		action  string
+		target  string
+		params  map[string]interface{}
+		want    string
+		wantErr string
+	}{
+		{name: "renders the templates", action: "restart", target: "api", params: map[string]interface{}{"reason": "oom"}, want: "api oom"},
+		{name: "params are not shell", action: "restart", target: "api", params: map[string]interface{}{"reason": "$(touch " + marker + "); `touch " + marker + "`"}, want: "api $(touch " + marker + "); `touch " + marker + "`"},
+		{name: "missing param", action: "restart", target: "api", wantErr: "reason"},
+		{name: "falls back to *", action: "restart", target: "web", want: "any web"},
+		{name: "no entry for the target", action: "scale", target: "web", wantErr: `no handler configured for target "web"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(out)
+
+			err := a.actions[tt.action](tt.target, tt.params)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := os.ReadFile(out)
+			if string(got) != tt.want {
+				t.Errorf("ran with %q, want %q", got, tt.want)
+			}
+			if _, err := os.Stat(marker); err == nil {
+				t.Fatal("a param ran a command")
+			}
+		})
+	}
+}
+
+func TestBuiltinCommandFailure(t *testing.T) {
+	a := NewSDK("key", "http://127.0.0.1:1", "secret")
+	err := a.UseBuiltins([]ActionConfig{
+		{Action: "restart", Type: HandlerCommand, Command: []string{"sh", "-c", "echo unit not found; exit 3"}},
+		{Action: "drain", Type: HandlerCommand, Command: []string{"sleep", "5"}, Timeout: "50ms"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.actions["restart"]("api", nil); err == nil || !strings.Contains(err.Error(), "unit not found") {
+		t.Errorf("err = %v, want the command output", err)
+	}
+
+	start := time.Now()
+	if err := a.actions["drain"]("api", nil); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("err = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("the timeout didn't kill the command (%s)", elapsed)
+	}
+}
+
+func TestBuiltinHTTP(t *testing.T) {
+	type request struct {
+		method, path, auth, body string
+	}
+	var got []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = append(got, request{r.Method, r.URL.Path, r.Header.Get("Authorization"), string(body)})
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	a := NewSDK("key", "http://127.0.0.1:1", "secret")
+	err := a.UseBuiltins([]ActionConfig{
+		{Action: "scale", Type: HandlerHTTP, HTTP: &HTTPActionConfig{
+			URL:     srv.URL + "/scale/{{.Target}}",
+			Headers: map[string]string{"Authorization": "Bearer token"},
+			Body:    `{"replicas": {{.Params.replicas}}}`,
+		}},
+		{Action: "flush", Type: HandlerHTTP, HTTP: &HTTPActionConfig{Method: http.MethodDelete, URL: srv.URL + "/cache", ExpectStatus: http.StatusNoContent}},
+		{Action: "restart", Type: HandlerHTTP, HTTP: &HTTPActionConfig{URL: srv.URL + "/fail"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.actions["scale"]("api", map[string]interface{}{"replicas": 4}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (request{http.MethodPost, "/scale/api", "Bearer token", `{"replicas": 4}`}); len(got) != 1 || got[0] != want {
+		t.Errorf("requests = %+v, want %+v", got, want)
+	}
+
+	if err := a.actions["flush"]("api", nil); err == nil || !strings.Contains(err.Error(), "answered 202") {
+		t.Errorf("unexpected status: err = %v", err)
+	}
+	if err := a.actions["restart"]("api", nil); err == nil || !strings.Contains(err.Error(), "answered 500") {
+		t.Errorf("5xx: err = %v", err)
+	}
+}
+
+func TestRenderName(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		wantErr bool
+	}{
+		{name: "plain", target: "payments-api"},
+		{name: "flag", target: "--all", wantErr: true},
+		{name: "space", target: "api --force", wantErr: true},
+		{name: "newline", target: "api\nreboot", wantErr: true},
+		{name: "empty", target: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, err := renderName("{{.Target}}", templateData{Target: tt.target})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && name != tt.target {
+				t.Errorf("name = %q", name)
+			}
+		})
+	}
+}
+
+func TestUseBuiltinsRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs []ActionConfig
+		wantErr string
+	}{
+		{name: "no action", configs: []ActionConfig{{Type: HandlerCommand, Command: []string{"true"}}}, wantErr: "without action"},
+		{name: "unknown type", configs: []ActionConfig{{Action: "restart", Type: "ansible"}}, wantErr: "unknown handler type"},
+		{name: "empty command", configs: []ActionConfig{{Action: "restart", Type: HandlerCommand}}, wantErr: "command is empty"},
+		{name: "systemctl without unit", configs: []ActionConfig{{Action: "restart", Type: HandlerSystemctl}}, wantErr: "unit is required"},
+		{name: "docker without container", configs: []ActionConfig{{Action: "restart", Type: HandlerDocker}}, wantErr: "container is required"},
+		{name: "http without url", configs: []ActionConfig{{Action: "restart", Type: HandlerHTTP, HTTP: &HTTPActionConfig{}}}, wantErr: "http.url is required"},
+		{name: "bad timeout", configs: []ActionConfig{{Action: "restart", Type: HandlerCommand, Command: []string{"true"}, Timeout: "soon"}}, wantErr: "invalid timeout"},
+		{name: "negative timeout", configs: []ActionConfig{{Action: "restart", Type: HandlerCommand, Command: []string{"true"}, Timeout: "-1s"}}, wantErr: "invalid timeout"},
+		{
+			name: "twice for a target",
+			configs: []ActionConfig{
+				{Action: "restart", Target: "api", Type: HandlerCommand, Command: []string{"true"}},
+				{Action: "restart", Target: "api", Type: HandlerCommand, Command: []string{"false"}},
+			},
+			wantErr: "configured twice",
+		},
+		{
+			name: "async and sync",
+			configs: []ActionConfig{
+				{Action: "restart", Target: "api", Type: HandlerCommand, Command: []string{"true"}},
+				{Action: "restart", Target: "web", Type: HandlerCommand, Command: []string{"true"}, Async: true},
+			},
+			wantErr: "mixes async and sync",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewSDK("key", "http://127.0.0.1:1", "secret")
+			err := a.UseBuiltins(tt.configs)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUseBuiltinsAsync(t *testing.T) {
+	a := NewSDK("key", "http://127.0.0.1:1", "secret")
+	err := a.UseBuiltins([]ActionConfig{{Action: "backup", Type: HandlerCommand, Command: []string{"true"}, Async: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := a.asyncActions["backup"]; !ok {
+		t.Error("async handler not registered")
+	}
+	if _, ok := a.actions["backup"]; ok {
+		t.Error("registered as sync too")
+	}
+}
@@ -0,0 +1,129 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	models "server/model"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Version is sent to the backend with the capabilities
+const Version = "1.0.0"
+
+// actionInfo is what DescribeAction adds to a handler for the backend
+type actionInfo struct {
+	description string
+	params      map[string]models.ParamSchema
+	targets     []string
+}
+
+// DescribeAction tells the backend what an action does and which params it takes,
+// so the agent proposes it with valid params (decisions with other params are
+// rejected before they are sent). Targets, if any, are the only ones it runs on.
+//
+//	agent.DescribeAction("scale", "Scale the deployment", map[string]models.ParamSchema{
+//		"replicas": {Type: "integer", Required: true, Min: &one, Max: &ten},
+//	})
+func (a *AgentSDK) DescribeAction(action, description string, params map[string]models.ParamSchema, targets ...string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.actionInfo[action] = actionInfo{description: description, params: params, targets: targets}
+}
+
+// Capabilities lists the registered handlers as the backend sees them
+func (a *AgentSDK) Capabilities() models.Capabilities {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	caps := models.Capabilities{SDKVersion: Version, Actions: []models.ActionCapability{}}
+	add := func(name string, async bool) {
+		info := a.actionInfo[name]
+		caps.Actions = append(caps.Actions, models.ActionCapability{
+			Name:        name,
+			Description: info.description,
+			Async:       async,
+			Targets:     info.targets,
+			Params:      info.params,
+		})
+	}
+	for name := range a.asyncActions {
+		add(name, true)
+	}
+	for name := range a.actions {
+		// dispatch prefiere el handler async de una accion
+		if _, ok := a.asyncActions[name]; !ok {
+			add(name, false)
+		}
+	}
+	sort.Slice(caps.Actions, func(i, j int) bool { return caps.Actions[i].Name < caps.Actions[j].Name })
+
+	return caps
+}
+
+// RegisterCapabilities sends Capabilities to the backend. Start and RunPull call
+// it on their own; call it again after registering handlers later on.
+func (a *AgentSDK) RegisterCapabilities(ctx context.Context) error {
+	payload, err := json.Marshal(a.Capabilities())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.backendURL+"/v1/capabilities", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	a.authorize(req)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("backend answered %d", resp.StatusCode)
+	}
+	return fmt.Errorf("%w: backend answered %d", errRejected, resp.StatusCode)
+}
+
+// announce registers the capabilities on startup, retrying while the backend is unreachable
+func (a *AgentSDK) announce(ctx context.Context) {
+	for attempt := 0; ; attempt++ {
+		err := a.RegisterCapabilities(ctx)
+		if err == nil {
+			return
+		}
+		if errors.Is(err, errRejected) {
+			// un backend viejo no tiene /v1/capabilities: sigue ofreciendo todas las acciones permitidas
+			fmt.Printf("[SDK] el backend no acepto las capacidades: %v\n", err)
+			return
+		}
+
+		wait := backoff(attempt, time.Second, time.Minute)
+		fmt.Printf("[SDK] no se pudieron registrar las capacidades: %v (reintento en %s)\n", err, wait.Round(time.Second))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// builtinTargets returns the targets of an action configured only for some of them
+func builtinTargets(targets []string) []string {
+	if slices.Contains(targets, AnyTarget) {
+		return nil
+	}
+	sort.Strings(targets)
+	return targets
+}
@@ -0,0 +1,493 @@
+package sdk
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Config is the YAML file of the standalone infragent-sdk binary. api_key and
+// webhook_secret can be left out and taken from INFRAGENT_API_KEY and
+// INFRAGENT_WEBHOOK_SECRET.
+//
+//	backend_url: https://api.infragent.io
+//	mode: push
+//	port: "9000"
+//	tls:
+//	  cert_file: /etc/infragent/webhook.crt
+//	  key_file: /etc/infragent/webhook.key
+//	  client_ca_file: /etc/infragent/backend-ca.pem
+//	policy_file: /etc/infragent/policy.yaml
+//	health_checks:
+//	  - {target: api, name: health, http: {url: "http://localhost:8080/health"}}
+//	monitors:
+//	  - {service: api, interval: 30s}
+//	actions:
+//	  - {action: restart, target: api, type: systemctl, unit: "payments-api.service"}
+//	  - {action: restart, target: "*", type: docker, container: "{{.Target}}", timeout: 60s}
+//	  - action: scale
+//	    target: api
+//	    type: command
+//	    command: [kubectl, scale, deployment/api, "--replicas={{.Params.replicas}}"]
+//	    description: Scale the api deployment
+//	    params:
+//	      replicas: {type: integer, required: true, min: 1, max: 10}
+//	approvals:
+//	  actions: [rollback]
+//	  timeout: 10m
+//	metrics:
+//	  cpu: {percent: 90, for: 5m}
+//	  disk: {percent: 95}
+//	  disk_paths: [/, /var/lib/postgresql]
+//	logs:
+//	  - {path: /var/log/payments-api.log, service: api, window: 1m, threshold: 20}
+//	reporter:
+//	  buffer_path: /var/lib/infragent/events.jsonl
+//	  drop_policy: lowest_severity
+//	  fsync: always
+//	diagnostics:
+//	  pprof_url: http://localhost:6060/debug/pprof
+//	  log_files: [/var/log/payments-api.log]
+//	  processes: true
+//	  commands:
+//	    sockets: [ss, -tanp]
+//	kubernetes:
+//	  namespace: payments
+//	  label_selector: app.kubernetes.io/part-of=payments
+//	  interval: 15s
+type Config struct {
+	BackendURL    string              `yaml:"backend_url"`
+	APIKey        string              `yaml:"api_key"`
+	WebhookSecret string              `yaml:"webhook_secret"`
+	Mode          string              `yaml:"mode"`   // "push" (default, exposes the webhook) or "pull"
+	Port          string              `yaml:"port"`   // push mode, default 9000
+	Listen        string              `yaml:"listen"` // push mode, e.g. "127.0.0.1:9000"; overrides port
+	TLS           *TLSSpec            `yaml:"tls"`
+	Policy        *Policy             `yaml:"policy"`
+	PolicyFile    string              `yaml:"policy_file"`
+	HealthChecks  []HealthCheckConfig `yaml:"health_checks"`
+	Monitors      []MonitorSpec       `yaml:"monitors"`
+	Actions       []ActionConfig      `yaml:"actions"`
+	Approvals     *ApprovalSpec       `yaml:"approvals"`
+	Metrics       *MetricsSpec        `yaml:"metrics"`
+	Logs          []LogWatchSpec      `yaml:"logs"`
+	Reporter      *ReporterSpec       `yaml:"reporter"`
+	Diagnostics   *DiagnosticsSpec    `yaml:"diagnostics"`
+	Kubernetes    *KubeWatchSpec      `yaml:"kubernetes"` // in-cluster pod and deployment watcher
+}
+
+// TLSSpec serves the webhook over HTTPS; client_ca_file turns on mTLS (only the
+// backend, with a certificate signed by that CA, can call the webhook)
+type TLSSpec struct {
+	CertFile     string   `yaml:"cert_file"`
+	KeyFile      string   `yaml:"key_file"`
+	ClientCAFile string   `yaml:"client_ca_file"`
+	BackendNames []string `yaml:"backend_names"` // allowed names in the backend certificate
+}
+
+// ReporterSpec is the YAML form of ReporterConfig; fields left out keep the defaults
+type ReporterSpec struct {
+	BatchSize      int    `yaml:"batch_size"`
+	FlushInterval  string `yaml:"flush_interval"` // e.g. "2s"
+	QueueSize      int    `yaml:"queue_size"`
+	BufferPath     string `yaml:"buffer_path"`      // keeps unsent events across restarts
+	MaxBufferBytes int64  `yaml:"max_buffer_bytes"` // default 16MB
+	DropPolicy     string `yaml:"drop_policy"`      // "oldest" or "lowest_severity"
+	Fsync          string `yaml:"fsync"`            // "always", "interval" or "never"
+	FsyncInterval  string `yaml:"fsync_interval"`   // e.g. "1s"
+}
+
+// DiagnosticsSpec is the YAML form of DiagnosticsConfig (enables collect_diagnostics)
+type DiagnosticsSpec struct {
+	Goroutines bool                `yaml:"goroutines"`
+	Heap       bool                `yaml:"heap"`
+	CPUProfile string              `yaml:"cpu_profile"` // e.g. "10s"
+	PprofURL   string              `yaml:"pprof_url"`
+	LogFiles   []string            `yaml:"log_files"`
+	LogLines   int                 `yaml:"log_lines"`
+	Processes  bool                `yaml:"processes"`
+	Commands   map[string][]string `yaml:"commands"`
+	Targets    []string            `yaml:"targets"`
+	Redact     []string            `yaml:"redact"`
+	Timeout    string              `yaml:"timeout"` // e.g. "2m"
+}
+
+// KubeWatchSpec is the YAML form of KubeWatchConfig
+type KubeWatchSpec struct {
+	Namespace      string `yaml:"namespace"` // empty = all namespaces
+	LabelSelector  string `yaml:"label_selector"`
+	Interval       string `yaml:"interval"`        // e.g. "15s"
+	ReadinessGrace string `yaml:"readiness_grace"` // e.g. "2m"
+	UnavailableFor string `yaml:"unavailable_for"` // e.g. "2m"
+	ServiceLabel   string `yaml:"service_label"`
+}
+
+// LogWatchSpec is the YAML form of LogWatch
+type LogWatchSpec struct {
+	Path      string   `yaml:"path"`
+	Service   string   `yaml:"service"`
+	Patterns  []string `yaml:"patterns"`
+	Window    string   `yaml:"window"` // e.g. "1m"
+	Threshold int      `yaml:"threshold"`
+	Samples   int      `yaml:"samples"`
+	Redact    []string `yaml:"redact"`
+}
+
+// MetricsSpec is the YAML form of MetricsConfig; thresholds left out keep the defaults
+type MetricsSpec struct {
+	Service   string         `yaml:"service"`
+	Interval  string         `yaml:"interval"`
+	CPU       *ThresholdSpec `yaml:"cpu"`
+	Memory    *ThresholdSpec `yaml:"memory"`
+	Disk      *ThresholdSpec `yaml:"disk"`
+	DiskPaths []string       `yaml:"disk_paths"`
+}
+
+type ThresholdSpec struct {
+	Percent  float64 `yaml:"percent"` // 0 turns the threshold off
+	For      string  `yaml:"for"`     // e.g. "5m"
+	Severity string  `yaml:"severity"`
+}
+
+// ApprovalSpec is the YAML form of ApprovalConfig; token can come from INFRAGENT_APPROVAL_TOKEN
+type ApprovalSpec struct {
+	Actions []string `yaml:"actions"`
+	Timeout string   `yaml:"timeout"` // e.g. "10m"
+	Listen  string   `yaml:"listen"`  // default 127.0.0.1:9001
+	Token   string   `yaml:"token"`
+}
+
+// HealthCheckConfig declares a check for AddHealthCheck; set exactly one of HTTP, TCP or Command
+type HealthCheckConfig struct {
+	Target  string           `yaml:"target"`
+	Name    string           `yaml:"name"`
+	HTTP    *HTTPCheckConfig `yaml:"http"`
+	TCP     string           `yaml:"tcp"` // "host:port"
+	Command []string         `yaml:"command"`
+}
+
+type HTTPCheckConfig struct {
+	URL    string `yaml:"url"`
+	Status int    `yaml:"status"`
+	Body   string `yaml:"body"`
+}
+
+// MonitorSpec runs Monitor over the health checks of Service
+type MonitorSpec struct {
+	Service          string `yaml:"service"`
+	Interval         string `yaml:"interval"` // e.g. "30s"
+	FailureThreshold int    `yaml:"failure_threshold"`
+}
+
+// LoadConfig reads and checks a config file
+func LoadConfig(path string) (*Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+
+	if cfg.APIKey == "" {
+		cfg.APIKey = os.Getenv("INFRAGENT_API_KEY")
+	}
+	if cfg.WebhookSecret == "" {
+		cfg.WebhookSecret = os.Getenv("INFRAGENT_WEBHOOK_SECRET")
+	}
+	if cfg.Mode == "" {
+		cfg.Mode = "push"
+	}
+	if cfg.Port == "" {
+		cfg.Port = "9000"
+	}
+	if cfg.Listen == "" {
+		cfg.Listen = ":" + cfg.Port
+	}
+
+	switch {
+	case cfg.BackendURL == "":
+		return nil, fmt.Errorf("config %s: backend_url is required", path)
+	case cfg.APIKey == "":
+		return nil, fmt.Errorf("config %s: api_key (or INFRAGENT_API_KEY) is required", path)
+	case cfg.Mode != "push" && cfg.Mode != "pull":
+		return nil, fmt.Errorf("config %s: mode must be push or pull", path)
+	case cfg.Mode == "push" && cfg.WebhookSecret == "":
+		return nil, fmt.Errorf("config %s: webhook_secret (or INFRAGENT_WEBHOOK_SECRET) is required in push mode", path)
+	case cfg.TLS != nil && (cfg.TLS.CertFile == "" || cfg.TLS.KeyFile == ""):
+		return nil, fmt.Errorf("config %s: tls needs cert_file and key_file", path)
+	case cfg.Policy != nil && cfg.PolicyFile != "":
+		return nil, fmt.Errorf("config %s: use policy or policy_file, not both", path)
+	}
+
+	if cfg.PolicyFile != "" {
+		if cfg.Policy, err = LoadPolicy(cfg.PolicyFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return &cfg, nil
+}
+
+// NewSDKFromConfig builds an SDK with the listener, reporter, policy, health
+// checks and built-in handlers of the config
+func NewSDKFromConfig(cfg *Config) (*AgentSDK, error) {
+	opts := []Option{WithAddr(cfg.Listen)}
+	if cfg.TLS != nil {
+		opts = append(opts, WithTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile))
+		if cfg.TLS.ClientCAFile != "" {
+			opts = append(opts, WithClientCA(cfg.TLS.ClientCAFile))
+		}
+		if len(cfg.TLS.BackendNames) > 0 {
+			opts = append(opts, WithBackendNames(cfg.TLS.BackendNames...))
+		}
+	}
+	a := NewSDK(cfg.APIKey, cfg.BackendURL, cfg.WebhookSecret, opts...)
+
+	if cfg.Reporter != nil {
+		reporter, err := cfg.Reporter.ReporterConfig()
+		if err != nil {
+			return nil, err
+		}
+		if err := a.SetReporterConfig(reporter); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := a.SetPolicy(cfg.Policy); err != nil {
+		return nil, err
+	}
+
+	for _, hc := range cfg.HealthChecks {
+		check, err := hc.check()
+		if err != nil {
+			return nil, err
+		}
+		a.AddHealthCheck(hc.Target, hc.Name, check)
+	}
+
+	if err := a.UseBuiltins(cfg.Actions); err != nil {
+		return nil, err
+	}
+
+	if cfg.Diagnostics != nil {
+		diagnostics, err := cfg.Diagnostics.DiagnosticsConfig()
+		if err != nil {
+			return nil, err
+		}
+		if err := a.EnableDiagnostics(diagnostics); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Approvals != nil {
+		approval, err := cfg.Approvals.ApprovalConfig()
+		if err != nil {
+			return nil, err
+		}
+		a.RequireApproval(approval)
+	}
+
+	return a, nil
+}
+
+func (hc HealthCheckConfig) check() (HealthCheck, error) {
+	if hc.Target == "" || hc.Name == "" {
+		return nil, fmt.Errorf("health check needs a target and a name")
+	}
+
+	var checks []HealthCheck
+	if hc.HTTP != nil {
+		checks = append(checks, HTTPCheck(hc.HTTP.URL, hc.HTTP.Status, hc.HTTP.Body))
+	}
+	if hc.TCP != "" {
+		checks = append(checks, TCPCheck(hc.TCP))
+	}
+	if len(hc.Command) > 0 {
+		checks = append(checks, CommandCheck(hc.Command[0], hc.Command[1:]...))
+	}
+
+	if len(checks) != 1 {
+		return nil, fmt.Errorf("health check %s/%s: set exactly one of http, tcp or command", hc.Target, hc.Name)
+	}
+	return checks[0], nil
+}
+
+// MonitorConfig turns the spec into the config of Monitor
+func (m MonitorSpec) MonitorConfig() (MonitorConfig, error) {
+	cfg := MonitorConfig{Service: m.Service, FailureThreshold: m.FailureThreshold}
+	if m.Interval != "" {
+		interval, err := time.ParseDuration(m.Interval)
+		if err != nil {
+			return cfg, fmt.Errorf("monitor %s: invalid interval %q", m.Service, m.Interval)
+		}
+		cfg.Interval = interval
+	}
+	return cfg, nil
+}
+
+// ApprovalConfig turns the spec into the config of RequireApproval
+func (s ApprovalSpec) ApprovalConfig() (ApprovalConfig, error) {
+	cfg := ApprovalConfig{Actions: s.Actions, Listen: s.Listen, Token: s.Token}
+	if cfg.Token == "" {
+		cfg.Token = os.Getenv("INFRAGENT_APPROVAL_TOKEN")
+	}
+	if cfg.Listen == "" {
+		cfg.Listen = defaultApprovalListen
+	}
+	if s.Timeout != "" {
+		timeout, err := time.ParseDuration(s.Timeout)
+		if err != nil {
+			return cfg, fmt.Errorf("approvals: invalid timeout %q", s.Timeout)
+		}
+		cfg.Timeout = timeout
+	}
+	return cfg, nil
+}
+
+// MetricsConfig turns the spec into the config of CollectMetrics
+func (s MetricsSpec) MetricsConfig() (MetricsConfig, error) {
+	cfg := DefaultMetricsConfig()
+	if s.Service != "" {
+		cfg.Service = s.Service
+	}
+	if len(s.DiskPaths) > 0 {
+		cfg.DiskPaths = s.DiskPaths
+	}
+	if s.Interval != "" {
+		interval, err := time.ParseDuration(s.Interval)
+		if err != nil {
+			return cfg, fmt.Errorf("metrics: invalid interval %q", s.Interval)
+		}
+		cfg.Interval = interval
+	}
+
+	for name, t := range map[string]struct {
+		spec      *ThresholdSpec
+		threshold *Threshold
+	}{"cpu": {s.CPU, &cfg.CPU}, "memory": {s.Memory, &cfg.Memory}, "disk": {s.Disk, &cfg.Disk}} {
+		if t.spec == nil {
+			continue
+		}
+		threshold := Threshold{Percent: t.spec.Percent, Severity: t.spec.Severity}
+		if threshold.Severity == "" {
+			threshold.Severity = t.threshold.Severity
+		}
+		if t.spec.For != "" {
+			d, err := time.ParseDuration(t.spec.For)
+			if err != nil {
+				return cfg, fmt.Errorf("metrics %s: invalid duration %q", name, t.spec.For)
+			}
+			threshold.For = d
+		}
+		*t.threshold = threshold
+	}
+
+	return cfg, nil
+}
+
+// LogWatch turns the spec into the config of TailLogs
+func (s LogWatchSpec) LogWatch() (LogWatch, error) {
+	watch := LogWatch{Path: s.Path, Service: s.Service, Patterns: s.Patterns, Threshold: s.Threshold, Samples: s.Samples, Redact: s.Redact}
+	if s.Window != "" {
+		window, err := time.ParseDuration(s.Window)
+		if err != nil {
+			return watch, fmt.Errorf("logs %s: invalid window %q", s.Path, s.Window)
+		}
+		watch.Window = window
+	}
+	return watch, nil
+}
+
+// DiagnosticsConfig turns the spec into the config of EnableDiagnostics
+func (s DiagnosticsSpec) DiagnosticsConfig() (DiagnosticsConfig, error) {
+	cfg := DiagnosticsConfig{
+		Goroutines: s.Goroutines,
+		Heap:       s.Heap,
+		PprofURL:   s.PprofURL,
+		LogFiles:   s.LogFiles,
+		LogLines:   s.LogLines,
+		Processes:  s.Processes,
+		Commands:   s.Commands,
+		Targets:    s.Targets,
+		Redact:     s.Redact,
+	}
+
+	for name, d := range map[string]struct {
+		spec string
+		dst  *time.Duration
+	}{"cpu_profile": {s.CPUProfile, &cfg.CPUProfile}, "timeout": {s.Timeout, &cfg.Timeout}} {
+		if d.spec == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.spec)
+		if err != nil {
+			return cfg, fmt.Errorf("diagnostics: invalid %s %q", name, d.spec)
+		}
+		*d.dst = parsed
+	}
+
+	return cfg, nil
+}
+
+// KubeWatchConfig turns the spec into the config of WatchKubernetes
+func (s KubeWatchSpec) KubeWatchConfig() (KubeWatchConfig, error) {
+	cfg := KubeWatchConfig{Namespace: s.Namespace, LabelSelector: s.LabelSelector, ServiceLabel: s.ServiceLabel}
+
+	for name, d := range map[string]struct {
+		spec string
+		dst  *time.Duration
+	}{"interval": {s.Interval, &cfg.Interval}, "readiness_grace": {s.ReadinessGrace, &cfg.ReadinessGrace}, "unavailable_for": {s.UnavailableFor, &cfg.UnavailableFor}} {
+		if d.spec == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.spec)
+		if err != nil {
+			return cfg, fmt.Errorf("kubernetes: invalid %s %q", name, d.spec)
+		}
+		*d.dst = parsed
+	}
+
+	return cfg, nil
+}
+
+// ReporterConfig turns the spec into the config of SetReporterConfig
+func (s ReporterSpec) ReporterConfig() (ReporterConfig, error) {
+	cfg := DefaultReporterConfig()
+	if s.BatchSize > 0 {
+		cfg.BatchSize = s.BatchSize
+	}
+	if s.QueueSize > 0 {
+		cfg.QueueSize = s.QueueSize
+	}
+	if s.MaxBufferBytes > 0 {
+		cfg.MaxBufferBytes = s.MaxBufferBytes
+	}
+	if s.DropPolicy != "" {
+		cfg.DropPolicy = s.DropPolicy
+	}
+	if s.Fsync != "" {
+		cfg.Fsync = s.Fsync
+	}
+	cfg.BufferPath = s.BufferPath
+
+	for name, d := range map[string]struct {
+		spec string
+		dst  *time.Duration
+	}{"flush_interval": {s.FlushInterval, &cfg.FlushInterval}, "fsync_interval": {s.FsyncInterval, &cfg.FsyncInterval}} {
+		if d.spec == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.spec)
+		if err != nil {
+			return cfg, fmt.Errorf("reporter: invalid %s %q", name, d.spec)
+		}
+		*d.dst = parsed
+	}
+
+	return cfg, nil
+}
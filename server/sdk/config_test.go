@@ -0,0 +1,157 @@
+package sdk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, yaml string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "infragent.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	policyFile := writeConfig(t, "targets: {api: {allowed_actions: [restart]}}")
+
+	tests := []struct {
+		name    string
+		yaml    string
+		env     map[string]string
+		wantErr string
+		check   func(t *testing.T, cfg *Config)
+	}{
+		{
+			name: "defaults",
+			yaml: "backend_url: https://api.infragent.io\napi_key: key\nwebhook_secret: secret\n",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Mode != "push" || cfg.Port != "9000" {
+					t.Errorf("mode = %q, port = %q", cfg.Mode, cfg.Port)
+				}
+			},
+		},
+		{
+			name: "secrets from the environment",
+			yaml: "backend_url: https://api.infragent.io\n",
+			env:  map[string]string{"INFRAGENT_API_KEY": "env-key", "INFRAGENT_WEBHOOK_SECRET": "env-secret"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.APIKey != "env-key" || cfg.WebhookSecret != "env-secret" {
+					t.Errorf("api key = %q, secret = %q", cfg.APIKey, cfg.WebhookSecret)
+				}
+			},
+		},
+		{name: "pull mode without a secret", yaml: "backend_url: https://api.infragent.io\napi_key: key\nmode: pull\n"},
+		{
+			name: "policy file",
+			yaml: "backend_url: https://api.infragent.io\napi_key: key\nmode: pull\npolicy_file: " + policyFile + "\n",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Policy == nil || cfg.Policy.Targets["api"].AllowedActions[0] != "restart" {
+					t.Errorf("policy = %+v", cfg.Policy)
+				}
+			},
+		},
+		{name: "without backend_url", yaml: "api_key: key\nwebhook_secret: secret\n", wantErr: "backend_url is required"},
+		{name: "without api_key", yaml: "backend_url: https://api.infragent.io\nwebhook_secret: secret\n", wantErr: "api_key"},
+		{name: "unknown mode", yaml: "backend_url: https://api.infragent.io\napi_key: key\nmode: poll\n", wantErr: "mode must be push or pull"},
+		{name: "push without a secret", yaml: "backend_url: https://api.infragent.io\napi_key: key\n", wantErr: "webhook_secret"},
+		{
+			name:    "policy and policy_file",
+			yaml:    "backend_url: https://api.infragent.io\napi_key: key\nmode: pull\npolicy_file: " + policyFile + "\npolicy: {targets: {}}\n",
+			wantErr: "not both",
+		},
+		{name: "invalid yaml", yaml: "backend_url: [", wantErr: "config"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INFRAGENT_API_KEY", "")
+			t.Setenv("INFRAGENT_WEBHOOK_SECRET", "")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			cfg, err := LoadConfig(writeConfig(t, tt.yaml))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.check != nil {
+				tt.check(t, cfg)
+			}
+		})
+	}
+}
+
+func TestNewSDKFromConfig(t *testing.T) {
+	path := writeConfig(t, `
+backend_url: https://api.infragent.io
+api_key: key
+webhook_secret: secret
+health_checks:
+  - {target: api, name: health, http: {url: "http://localhost:8080/health", status: 200}}
+  - {target: db, name: port, tcp: "localhost:5432"}
+  - {target: db, name: ready, command: [pg_isready, -h, localhost]}
+actions:
+  - {action: restart, target: api, type: systemctl, unit: "payments-api.service"}
+  - {action: restart, target: "*", type: docker, container: "{{.Target}}", timeout: 60s}
+`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewSDKFromConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.hasHealthChecks("api") || len(a.healthChecks["db"]) != 2 {
+		t.Errorf("health checks = %v", a.healthChecks)
+	}
+	if _, ok := a.actions["restart"]; !ok {
+		t.Error("restart not registered")
+	}
+}
+
+func TestNewSDKFromConfigRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{name: "check without a name", cfg: Config{HealthChecks: []HealthCheckConfig{{Target: "api", TCP: "localhost:80"}}}, wantErr: "target and a name"},
+		{name: "check without a kind", cfg: Config{HealthChecks: []HealthCheckConfig{{Target: "api", Name: "x"}}}, wantErr: "exactly one"},
+		{name: "check with two kinds", cfg: Config{HealthChecks: []HealthCheckConfig{{Target: "api", Name: "x", TCP: "localhost:80", Command: []string{"true"}}}}, wantErr: "exactly one"},
+		{name: "invalid action", cfg: Config{Actions: []ActionConfig{{Action: "restart", Type: "ansible"}}}, wantErr: "unknown handler type"},
+		{name: "invalid policy", cfg: Config{Policy: &Policy{Blackouts: []Blackout{{Start: "xx", End: "06:00"}}}}, wantErr: "blackout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.BackendURL, tt.cfg.APIKey, tt.cfg.WebhookSecret = "http://127.0.0.1:1", "key", "secret"
+			if _, err := NewSDKFromConfig(&tt.cfg); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMonitorSpec(t *testing.T) {
+	cfg, err := MonitorSpec{Service: "api", Interval: "15s", FailureThreshold: 3}.MonitorConfig()
+	if err != nil || cfg.Interval != 15*time.Second || cfg.FailureThreshold != 3 || cfg.Service != "api" {
+		t.Errorf("cfg = %+v, err = %v", cfg, err)
+	}
+	if _, err := (MonitorSpec{Service: "api", Interval: "often"}).MonitorConfig(); err == nil {
+		t.Error("accepted an invalid interval")
+	}
+}
@@ -0,0 +1,344 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/pprof"
+	models "server/model"
+	"strings"
+	"time"
+)
+
+// ActionCollectDiagnostics is the action EnableDiagnostics registers
+const ActionCollectDiagnostics = "collect_diagnostics"
+
+const (
+	defaultDiagnosticsTimeout = 2 * time.Minute
+	defaultDiagnosticsLines   = 200
+	maxDiagnosticOutput       = 10 << 20 // per artifact, the backend takes up to models.MaxArtifactSize
+	maxLogTailBytes           = 4 << 20  // how far back from the end of a log we look for the lines
+)
+
+var unsafeArtifactChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// DiagnosticsConfig says what collect_diagnostics captures. Everything text
+// (logs, processes, command output) is redacted like the log samples before it
+// leaves the host.
+type DiagnosticsConfig struct {
+	Goroutines bool                // goroutine dump of this process
+	Heap       bool                // heap profile of this process
+	CPUProfile time.Duration       // CPU profile of this process for that long, 0 = off
+	PprofURL   string              // net/http/pprof of another Go app, e.g. http://localhost:6060/debug/pprof
+	LogFiles   []string            // their last LogLines lines
+	LogLines   int                 // default 200
+	Processes  bool                // ps aux (tasklist on Windows)
+	Commands   map[string][]string // name -> command (no shell), uploaded as cmd-<name>.txt
+	Targets    []string            // only offer the action for these targets, default all
+	Redact     []string            // extra regexes to redact
+	Timeout    time.Duration       // for the whole collection, default 2m
+}
+
+// diagnostic is one artifact to upload
+type diagnostic struct {
+	name        string
+	contentType string
+	data        []byte
+}
+
+type collector struct {
+	name string
+	run  func(ctx context.Context) ([]diagnostic, error)
+}
+
+// EnableDiagnostics registers the async collect_diagnostics action: it captures
+// what cfg asks for and uploads each piece as an artifact of the action, which
+// the owner downloads from the dashboard.
+func (a *AgentSDK) EnableDiagnostics(cfg DiagnosticsConfig) error {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultDiagnosticsTimeout
+	}
+	if cfg.LogLines <= 0 {
+		cfg.LogLines = defaultDiagnosticsLines
+	}
+
+	patterns := append([]*regexp.Regexp{}, defaultRedactions...)
+	for _, pattern := range cfg.Redact {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("diagnostics: redact %q: %w", pattern, err)
+		}
+		patterns = append(patterns, re)
+	}
+
+	collectors := diagnosticCollectors(cfg, a.httpClient, patterns)
+	if len(collectors) == 0 {
+		return errors.New("diagnostics: nothing to collect")
+	}
+
+	a.OnAsync(ActionCollectDiagnostics, func(ctx context.Context, target string, params map[string]interface{}, progress ProgressFunc) (map[string]interface{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+		return a.collectDiagnostics(ctx, collectors, progress)
+	})
+	a.DescribeAction(ActionCollectDiagnostics,
+		"Collect goroutine dumps, profiles, recent logs and process lists as artifacts, without changing anything",
+		nil, cfg.Targets...)
+
+	return nil
+}
+
+func (a *AgentSDK) collectDiagnostics(ctx context.Context, collectors []collector, progress ProgressFunc) (map[string]interface{}, error) {
+	actionID := ActionID(ctx)
+	uploaded := []map[string]interface{}{}
+	failed := map[string]string{}
+
+	for _, c := range collectors {
+		diagnostics, err := c.run(ctx)
+		if err != nil {
+			failed[c.name] = err.Error()
+		}
+		for _, d := range diagnostics {
+			artifact, err := a.UploadArtifact(ctx, actionID, d.name, d.contentType, d.data)
+			if err != nil {
+				failed[d.name] = err.Error()
+				continue
+			}
+			uploaded = append(uploaded, map[string]interface{}{"id": artifact.ID, "name": artifact.Name, "size": artifact.Size})
+		}
+		progress(map[string]interface{}{"collected": c.name, "artifacts": len(uploaded)})
+	}
+
+	result := map[string]interface{}{"artifacts": uploaded}
+	if len(failed) > 0 {
+		result["errors"] = failed
+	}
+	if len(uploaded) == 0 {
+		return result, errors.New("no diagnostics could be collected")
+	}
+	return result, nil
+}
+
+// UploadArtifact attaches a file to an action (see ActionID) on the backend
+func (a *AgentSDK) UploadArtifact(ctx context.Context, actionID, name, contentType string, data []byte) (*models.Artifact, error) {
+	if actionID == "" {
+		return nil, errors.New("artifact without action id")
+	}
+
+	endpoint := a.backendURL + "/v1/actions/" + url.PathEscape(actionID) + "/artifacts?name=" + url.QueryEscape(name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	a.authorize(req)
+
+	// un dump grande tarda mas que el timeout de los eventos: manda el ctx
+	resp, err := untimed(a.httpClient).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("backend answered %d", resp.StatusCode)
+	}
+
+	var artifact models.Artifact
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&artifact); err != nil {
+		return nil, fmt.Errorf("invalid artifact response: %w", err)
+	}
+	return &artifact, nil
+}
+
+func diagnosticCollectors(cfg DiagnosticsConfig, httpClient *http.Client, patterns []*regexp.Regexp) []collector {
+	var collectors []collector
+	text := func(name string, data []byte) diagnostic {
+		return diagnostic{name: name, contentType: "text/plain; charset=utf-8", data: []byte(redact(string(capOutput(data)), patterns))}
+	}
+
+	if cfg.Goroutines {
+		collectors = append(collectors, collector{"goroutines", func(ctx context.Context) ([]diagnostic, error) {
+			var buf bytes.Buffer
+			if err := pprof.Lookup("goroutine").WriteTo(&buf, 2); err != nil {
+				return nil, err
+			}
+			return []diagnostic{{name: "goroutines.txt", contentType: "text/plain; charset=utf-8", data: capOutput(buf.Bytes())}}, nil
+		}})
+	}
+	if cfg.Heap {
+		collectors = append(collectors, collector{"heap", func(ctx context.Context) ([]diagnostic, error) {
+			var buf bytes.Buffer
+			if err := pprof.Lookup("heap").WriteTo(&buf, 0); err != nil {
+				return nil, err
+			}
+			return []diagnostic{{name: "heap.pb.gz", contentType: "application/octet-stream", data: buf.Bytes()}}, nil
+		}})
+	}
+	if cfg.CPUProfile > 0 {
+		collectors = append(collectors, collector{"cpu", func(ctx context.Context) ([]diagnostic, error) {
+			var buf bytes.Buffer
+			if err := pprof.StartCPUProfile(&buf); err != nil {
+				return nil, err // ya hay otro perfil corriendo
+			}
+			select {
+			case <-ctx.Done():
+			case <-time.After(cfg.CPUProfile):
+			}
+			pprof.StopCPUProfile()
+			return []diagnostic{{name: "cpu.pb.gz", contentType: "application/octet-stream", data: buf.Bytes()}}, nil
+		}})
+	}
+	if cfg.PprofURL != "" {
+		base := strings.TrimSuffix(cfg.PprofURL, "/")
+		collectors = append(collectors, collector{"pprof", func(ctx context.Context) ([]diagnostic, error) {
+			var out []diagnostic
+			var errs []error
+			for name, path := range map[string]string{"remote-goroutines.txt": "/goroutine?debug=2", "remote-heap.pb.gz": "/heap"} {
+				data, err := fetch(ctx, httpClient, base+path)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", path, err))
+					continue
+				}
+				contentType := "application/octet-stream"
+				if strings.HasSuffix(name, ".txt") {
+					contentType = "text/plain; charset=utf-8"
+				}
+				out = append(out, diagnostic{name: name, contentType: contentType, data: data})
+			}
+			return out, errors.Join(errs...)
+		}})
+	}
+	if len(cfg.LogFiles) > 0 {
+		collectors = append(collectors, collector{"logs", func(ctx context.Context) ([]diagnostic, error) {
+			var out []diagnostic
+			var errs []error
+			for _, path := range cfg.LogFiles {
+				lines, err := tailLines(path, cfg.LogLines)
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				out = append(out, text(artifactName("log-"+filepath.Base(path)), lines))
+			}
+			return out, errors.Join(errs...)
+		}})
+	}
+	if cfg.Processes {
+		collectors = append(collectors, collector{"processes", func(ctx context.Context) ([]diagnostic, error) {
+			name, args := "ps", []string{"aux"}
+			if runtime.GOOS == "windows" {
+				name, args = "tasklist", nil
+			}
+			output, err := commandOutput(ctx, name, args...)
+			if err != nil && len(output) == 0 {
+				return nil, err
+			}
+			return []diagnostic{text("processes.txt", output)}, nil
+		}})
+	}
+	for name, command := range cfg.Commands {
+		if len(command) == 0 {
+			continue
+		}
+		collectors = append(collectors, collector{"command " + name, func(ctx context.Context) ([]diagnostic, error) {
+			// la salida sirve aunque el comando termine con error
+			output, err := commandOutput(ctx, command[0], command[1:]...)
+			if err != nil {
+				output = append(output, fmt.Sprintf("\n[%v]\n", err)...)
+			}
+			return []diagnostic{text(artifactName("cmd-"+name+".txt"), output)}, nil
+		}})
+	}
+
+	return collectors
+}
+
+// commandOutput runs the command without a shell and returns stdout and stderr
+func commandOutput(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	return out.Bytes(), err
+}
+
+func fetch(ctx context.Context, httpClient *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	// el perfil puede tardar, manda el ctx
+	resp, err := untimed(httpClient).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("answered %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxDiagnosticOutput))
+}
+
+// tailLines returns the last n lines of the file
+func tailLines(path string, n int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := info.Size() - maxLogTailBytes
+	if offset < 0 {
+		offset = 0
+	}
+	data, err := io.ReadAll(io.NewSectionReader(f, offset, info.Size()-offset))
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimRight(data, "\n")
+	lines := bytes.Split(data, []byte("\n"))
+	if offset > 0 && len(lines) > 1 {
+		lines = lines[1:] // la primera puede estar cortada
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return append(bytes.Join(lines, []byte("\n")), '\n'), nil
+}
+
+func capOutput(data []byte) []byte {
+	if len(data) <= maxDiagnosticOutput {
+		return data
+	}
+	return append(data[:maxDiagnosticOutput:maxDiagnosticOutput], "\n[truncated]\n"...)
+}
+
+// artifactName makes name acceptable for the backend ([A-Za-z0-9._-], 128 chars)
+func artifactName(name string) string {
+	name = strings.TrimLeft(unsafeArtifactChars.ReplaceAllString(name, "_"), "._-")
+	if name == "" {
+		name = "artifact"
+	}
+	if len(name) > 128 {
+		name = name[:128]
+	}
+	return name
+}
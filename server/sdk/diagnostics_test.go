@@ -0,0 +1,261 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	models "server/model"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type uploadedArtifact struct {
+	actionID    string
+	name        string
+	contentType string
+	data        string
+}
+
+// artifactBackend recibe artifacts y resultados de acciones como el backend
+type artifactBackend struct {
+	*httptest.Server
+	results chan reportedResult
+
+	mu        sync.Mutex
+	artifacts []uploadedArtifact
+	status    int // respuesta a los uploads, default 201
+}
+
+func newArtifactBackend(t *testing.T) *artifactBackend {
+	t.Helper()
+	b := &artifactBackend{results: make(chan reportedResult, 10), status: http.StatusCreated}
+	b.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v1/actions/"), "/")
+		switch rest {
+		case "result":
+			var report models.ActionResultReport
+			json.NewDecoder(r.Body).Decode(&report)
+			b.results <- reportedResult{actionID: id, report: report}
+		case "artifacts":
+			data, _ := io.ReadAll(r.Body)
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if r.Header.Get("Authorization") != "Bearer key" || b.status != http.StatusCreated {
+				w.WriteHeader(b.status)
+				return
+			}
+			name := r.URL.Query().Get("name")
+			b.artifacts = append(b.artifacts, uploadedArtifact{actionID: id, name: name, contentType: r.Header.Get("Content-Type"), data: string(data)})
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(models.Artifact{ID: fmt.Sprintf("art-%d", len(b.artifacts)), ActionID: id, Name: name, Size: int64(len(data))})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(b.Close)
+	return b
+}
+
+func (b *artifactBackend) uploaded() map[string]uploadedArtifact {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := map[string]uploadedArtifact{}
+	for _, a := range b.artifacts {
+		out[a.name] = a
+	}
+	return out
+}
+
+// finalResult salta los reportes de progreso
+func (b *artifactBackend) finalResult(t *testing.T) reportedResult {
+	t.Helper()
+	for {
+		if r := waitResult(t, b.results); r.report.Status != models.ActionStatusRunning {
+			return r
+		}
+	}
+}
+
+func TestCollectDiagnostics(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "app.log")
+	var lines []string
+	for i := 1; i <= 10; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	lines = append(lines, "ERROR login password=hunter2 from 10.0.0.12")
+	if err := os.WriteFile(logFile, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	backend := newArtifactBackend(t)
+	a := NewSDK("key", backend.URL, "secret")
+	err := a.EnableDiagnostics(DiagnosticsConfig{
+		Goroutines: true,
+		LogFiles:   []string{logFile, filepath.Join(dir, "missing.log")},
+		LogLines:   3,
+		Commands: map[string][]string{
+			"disk":   {"sh", "-c", "echo used 42% token=abc123"},
+			"broken": {"sh", "-c", "echo partial; exit 3"},
+		},
+		Redact: []string{`used \d+%`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, body := a.dispatch(models.ActionEnvelope{ActionID: "act-1", Decision: models.LLMDecision{Action: ActionCollectDiagnostics, Target: "api", Confidence: 0.95}})
+	if status != http.StatusAccepted {
+		t.Fatalf("status = %d, body = %v", status, body)
+	}
+
+	result := backend.finalResult(t)
+	if result.actionID != "act-1" || result.report.Status != models.ActionStatusSuccess {
+		t.Fatalf("report = %+v", result.report)
+	}
+	// el log que no existe falla sin tirar abajo el resto
+	errs, _ := result.report.Result["errors"].(map[string]interface{})
+	if _, ok := errs["logs"]; !ok || len(errs) != 1 {
+		t.Errorf("errors = %v", result.report.Result["errors"])
+	}
+
+	uploaded := backend.uploaded()
+	var names []string
+	for name, artifact := range uploaded {
+		names = append(names, name)
+		if artifact.actionID != "act-1" {
+			t.Errorf("%s uploaded for %q", name, artifact.actionID)
+		}
+	}
+	sort.Strings(names)
+	if fmt.Sprint(names) != "[cmd-broken.txt cmd-disk.txt goroutines.txt log-app.log]" {
+		t.Fatalf("artifacts = %v", names)
+	}
+	if n := len(result.report.Result["artifacts"].([]interface{})); n != 4 {
+		t.Errorf("result lists %d artifacts", n)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "log-app.log", want: "line 9\nline 10\nERROR login password=" + redacted + " from " + redacted + "\n"},
+		{name: "cmd-disk.txt", want: redacted + " token=" + redacted + "\n"},
+		{name: "cmd-broken.txt", want: "partial\n\n[exit status 3]\n"},
+	}
+	for _, tt := range tests {
+		if got := uploaded[tt.name]; got.data != tt.want || got.contentType != "text/plain; charset=utf-8" {
+			t.Errorf("%s = %q (%s), want %q", tt.name, got.data, got.contentType, tt.want)
+		}
+	}
+	if !strings.Contains(uploaded["goroutines.txt"].data, "goroutine ") {
+		t.Errorf("goroutines.txt = %.100q", uploaded["goroutines.txt"].data)
+	}
+}
+
+func TestCollectDiagnosticsNothingUploaded(t *testing.T) {
+	backend := newArtifactBackend(t)
+	backend.status = http.StatusRequestEntityTooLarge
+	a := NewSDK("key", backend.URL, "secret")
+	if err := a.EnableDiagnostics(DiagnosticsConfig{Goroutines: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	a.dispatch(models.ActionEnvelope{ActionID: "act-1", Decision: models.LLMDecision{Action: ActionCollectDiagnostics, Target: "api", Confidence: 0.95}})
+
+	result := backend.finalResult(t)
+	errs, _ := result.report.Result["errors"].(map[string]interface{})
+	if result.report.Status != models.ActionStatusFailed || errs["goroutines.txt"] != "backend answered 413" {
+		t.Errorf("report = %+v", result.report)
+	}
+}
+
+func TestEnableDiagnosticsRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     DiagnosticsConfig
+		wantErr string
+	}{
+		{name: "nothing to collect", cfg: DiagnosticsConfig{}, wantErr: "nothing to collect"},
+		{name: "empty command only", cfg: DiagnosticsConfig{Commands: map[string][]string{"x": nil}}, wantErr: "nothing to collect"},
+		{name: "bad redact pattern", cfg: DiagnosticsConfig{Processes: true, Redact: []string{"("}}, wantErr: "redact"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewSDK("key", "http://127.0.0.1:1", "secret")
+			err := a.EnableDiagnostics(tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+			if _, ok := a.asyncActions[ActionCollectDiagnostics]; ok {
+				t.Error("action registered anyway")
+			}
+		})
+	}
+}
+
+func TestUploadArtifactNeedsAnActionID(t *testing.T) {
+	a := NewSDK("key", "http://127.0.0.1:1", "secret")
+	if _, err := a.UploadArtifact(context.Background(), "", "x.txt", "text/plain", []byte("x")); err == nil {
+		t.Error("uploaded without an action id")
+	}
+}
+
+func TestTailLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		n       int
+		want    string
+	}{
+		{name: "last lines", content: "a\nb\nc\nd\n", n: 2, want: "c\nd\n"},
+		{name: "fewer than n", content: "a\nb\n", n: 5, want: "a\nb\n"},
+		{name: "no trailing newline", content: "a\nb\nc", n: 2, want: "b\nc\n"},
+		{name: "empty", content: "", n: 3, want: "\n"},
+		// mas de maxLogTailBytes: la primera linea leida esta cortada y se descarta
+		{name: "huge file", content: strings.Repeat("x", maxLogTailBytes) + "\nlast\n", n: 5, want: "last\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := tailLines(path, tt.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("tailLines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArtifactName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "log-app.log", want: "log-app.log"},
+		{name: "cmd-disk usage.txt", want: "cmd-disk_usage.txt"},
+		{name: "../../etc/passwd", want: "etc_passwd"},
+		{name: "...", want: "artifact"},
+		{name: strings.Repeat("a", 200), want: strings.Repeat("a", 128)},
+	}
+
+	for _, tt := range tests {
+		if got := artifactName(tt.name); got != tt.want {
+			t.Errorf("artifactName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// timeout of each check run before an action
+const healthCheckTimeout = 5 * time.Second
+
+// HealthCheck returns nil while the checked thing is healthy
+type HealthCheck func(ctx context.Context) error
+
+// CheckResult is the outcome of one named check, sent back in the webhook response
+type CheckResult struct {
+	Name       string `json:"name"`
+	Healthy    bool   `json:"healthy"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+type namedCheck struct {
+	name  string
+	check HealthCheck
+}
+
+// AddHealthCheck registers a named check for a target (the service name the backend
+// uses in decisions, e.g. "api"). A target can have several; it is healthy when all pass.
+// Registering a name twice replaces the previous check.
+func (a *AgentSDK) AddHealthCheck(target, name string, check HealthCheck) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	checks := a.healthChecks[target]
+	for i := range checks {
+		if checks[i].name == name {
+			checks[i].check = check
+			return
+		}
+	}
+	a.healthChecks[target] = append(checks, namedCheck{name: name, check: check})
+}
+
+func (a *AgentSDK) hasHealthChecks(target string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.healthChecks[target]) > 0
+}
+
+// RunHealthChecks runs every check of the target in parallel. It returns nil when
+// the target has no checks.
+func (a *AgentSDK) RunHealthChecks(ctx context.Context, target string) []CheckResult {
+	a.mu.Lock()
+	checks := append([]namedCheck(nil), a.healthChecks[target]...)
+	a.mu.Unlock()
+
+	if len(checks) == 0 {
+		return nil
+	}
+
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(a.checkContext(ctx), healthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := c.check(checkCtx)
+			results[i] = CheckResult{Name: c.name, Healthy: err == nil, DurationMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// failedChecks joins the errors of the checks that failed (nil if none did)
+func failedChecks(results []CheckResult) error {
+	var errs []error
+	for _, r := range results {
+		if !r.Healthy {
+			errs = append(errs, fmt.Errorf("%s: %s", r.Name, r.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type httpClientKey struct{}
+
+// checkContext carries the SDK's HTTP client to the checks it runs
+func (a *AgentSDK) checkContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, httpClientKey{}, a.httpClient)
+}
+
+// checkClient is the client HTTPCheck uses: the SDK's when the SDK runs the check
+// (bounded by the check timeout), the default one when it's called on its own
+func checkClient(ctx context.Context) *http.Client {
+	if client, ok := ctx.Value(httpClientKey{}).(*http.Client); ok {
+		return untimed(client)
+	}
+	return http.DefaultClient
+}
+
+// HTTPHealthCheck is healthy while url answers 2xx
+func HTTPHealthCheck(url string) HealthCheck {
+	return HTTPCheck(url, 0, "")
+}
+
+// HTTPCheck is healthy while a GET to url answers expectStatus (any 2xx if 0)
+// and, when expectBody isn't empty, the body contains it. Run by the SDK it goes
+// through the client set with WithHTTPClient.
+func HTTPCheck(url string, expectStatus int, expectBody string) HealthCheck {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := checkClient(ctx).Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		switch {
+		case expectStatus == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299),
+			expectStatus != 0 && resp.StatusCode != expectStatus:
+			return fmt.Errorf("%s answered %d", url, resp.StatusCode)
+		}
+
+		if expectBody != "" {
+			body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+			if err != nil {
+				return err
+			}
+			if !strings.Contains(string(body), expectBody) {
+				return fmt.Errorf("%s body doesn't contain %q", url, expectBody)
+			}
+		}
+		return nil
+	}
+}
+
+// TCPCheck is healthy while something accepts connections on addr ("host:port")
+func TCPCheck(addr string) HealthCheck {
+	return func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// CommandCheck is healthy while the command exits with code 0
+// (e.g. CommandCheck("pg_isready", "-h", "localhost"))
+func CommandCheck(name string, args ...string) HealthCheck {
+	return func(ctx context.Context) error {
+		out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+		if err != nil {
+			if output := strings.TrimSpace(string(out)); output != "" {
+				return fmt.Errorf("%w: %s", err, truncate(output, 200))
+			}
+			return err
+		}
+		return nil
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,195 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	models "server/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunHealthChecks(t *testing.T) {
+	a := NewSDK("key", "http://127.0.0.1:1", "secret")
+
+	slow := func(err error) HealthCheck {
+		return func(ctx context.Context) error {
+			time.Sleep(100 * time.Millisecond)
+			return err
+		}
+	}
+	a.AddHealthCheck("api", "http", slow(nil))
+	a.AddHealthCheck("api", "db", slow(errors.New("connection refused")))
+	a.AddHealthCheck("api", "cache", slow(errors.New("timeout")))
+	// el mismo nombre reemplaza el check anterior
+	a.AddHealthCheck("api", "cache", slow(nil))
+
+	start := time.Now()
+	results := a.RunHealthChecks(context.Background(), "api")
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("checks took %s, they should run in parallel", elapsed)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("results = %+v", results)
+	}
+	want := map[string]bool{"http": true, "db": false, "cache": true}
+	for _, r := range results {
+		if healthy, ok := want[r.Name]; !ok || r.Healthy != healthy {
+			t.Errorf("result %+v", r)
+		}
+		if r.Name == "db" && r.Error != "connection refused" {
+			t.Errorf("db error = %q", r.Error)
+		}
+		if r.DurationMS < 100 {
+			t.Errorf("%s duration = %dms", r.Name, r.DurationMS)
+		}
+	}
+	if err := failedChecks(results); err == nil || !strings.Contains(err.Error(), "db: connection refused") {
+		t.Errorf("failedChecks = %v", err)
+	}
+
+	if results := a.RunHealthChecks(context.Background(), "web"); results != nil {
+		t.Errorf("target without checks = %+v", results)
+	}
+}
+
+func TestHTTPCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			w.Write([]byte(`{"status":"ok","db":"up"}`))
+		case "/degraded":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/created":
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name         string
+		path         string
+		expectStatus int
+		expectBody   string
+		wantErr      bool
+	}{
+		{name: "any 2xx", path: "/health"},
+		{name: "other 2xx", path: "/created"},
+		{name: "5xx", path: "/degraded", wantErr: true},
+		{name: "404", path: "/missing", wantErr: true},
+		{name: "expected status", path: "/degraded", expectStatus: http.StatusServiceUnavailable},
+		{name: "unexpected status", path: "/health", expectStatus: http.StatusCreated, wantErr: true},
+		{name: "body contains", path: "/health", expectBody: `"db":"up"`},
+		{name: "body doesn't contain", path: "/health", expectBody: `"db":"down"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := HTTPCheck(srv.URL+tt.path, tt.expectStatus, tt.expectBody)(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTCPCheck(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := ln.Addr().String()
+	if err := TCPCheck(open)(context.Background()); err != nil {
+		t.Errorf("open port: %v", err)
+	}
+
+	ln.Close()
+	if err := TCPCheck(open)(context.Background()); err == nil {
+		t.Error("closed port is healthy")
+	}
+}
+
+func TestCommandCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "exit 0", args: []string{"sh", "-c", "exit 0"}},
+		{name: "exit 1 with output", args: []string{"sh", "-c", "echo db not ready; exit 1"}, wantErr: "db not ready"},
+		{name: "missing binary", args: []string{"infragent-no-such-command"}, wantErr: "not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CommandCheck(tt.args[0], tt.args[1:]...)(context.Background())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDispatchChecksTheTargetFirst(t *testing.T) {
+	tests := []struct {
+		name       string
+		action     string
+		confidence float64
+		healthy    bool
+		noChecks   bool
+		wantRun    bool
+		wantChecks bool
+	}{
+		{name: "restart on a healthy target", action: "restart", confidence: 0.95, healthy: true, wantChecks: true},
+		{name: "low confidence on a healthy target", action: "scale", confidence: 0.6, healthy: true, wantChecks: true},
+		{name: "restart on a failing target", action: "restart", confidence: 0.95, wantRun: true, wantChecks: true},
+		{name: "confident non-restart is not checked", action: "scale", confidence: 0.95, healthy: true, wantRun: true},
+		{name: "target without checks", action: "restart", confidence: 0.5, noChecks: true, wantRun: true},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewSDK("key", "http://127.0.0.1:1", "secret")
+			ran := false
+			a.On(tt.action, func(target string, params map[string]interface{}) error {
+				ran = true
+				return nil
+			})
+			if !tt.noChecks {
+				a.AddHealthCheck("api", "http", func(context.Context) error {
+					if tt.healthy {
+						return nil
+					}
+					return errors.New("503")
+				})
+			}
+
+			status, body := a.dispatch(models.ActionEnvelope{
+				ActionID: "action-" + string(rune('a'+i)),
+				Decision: models.LLMDecision{Action: tt.action, Target: "api", Confidence: tt.confidence},
+			})
+
+			if ran != tt.wantRun {
+				t.Errorf("handler ran = %v, want %v (status %d, body %v)", ran, tt.wantRun, status, body)
+			}
+			if !tt.wantRun && body["status"] != "aborted" {
+				t.Errorf("body = %v", body)
+			}
+			if _, ok := body["health_checks"]; ok != tt.wantChecks {
+				t.Errorf("health_checks in the answer = %v, want %v", ok, tt.wantChecks)
+			}
+		})
+	}
+}
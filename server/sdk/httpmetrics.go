@@ -0,0 +1,342 @@
+package sdk
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	models "server/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	maxLatencySamples = 1000 // per route and window, reservoir sampled above that
+	maxRouteBreakdown = 10   // routes listed in Event.Data
+	otherRoute        = "other"
+)
+
+// LatencyThreshold fires when the Percentile latency of a window stays at or
+// above Max for For (0 fires on the first window)
+type LatencyThreshold struct {
+	Percentile float64 // default 95
+	Max        time.Duration
+	For        time.Duration
+	Severity   string // default warning
+}
+
+// HTTPMetricsConfig configures HTTPMiddleware; a zero threshold is not checked
+type HTTPMetricsConfig struct {
+	Service     string        // reported as Event.Service, default "http"
+	Window      time.Duration // default 1m
+	MinRequests int           // requests a window (or a route) needs before its numbers count, default 20
+	ErrorRate   Threshold     // percentage of 5xx answers
+	Latency     LatencyThreshold
+	MaxRoutes   int                          // routes tracked per window, the rest count as "other"; default 100
+	Route       func(r *http.Request) string // default the ServeMux pattern, or "METHOD /path"
+}
+
+// DefaultHTTPMetricsConfig is 5% of 5xx or a p95 over 1s in two 1 min windows in a row
+// (For counts from the end of the first window over the threshold)
+func DefaultHTTPMetricsConfig() HTTPMetricsConfig {
+	return HTTPMetricsConfig{
+		Service:     "http",
+		Window:      time.Minute,
+		MinRequests: 20,
+		ErrorRate:   Threshold{Percent: 5, For: time.Minute},
+		Latency:     LatencyThreshold{Percentile: 95, Max: time.Second, For: time.Minute},
+		MaxRoutes:   100,
+	}
+}
+
+// HTTPMiddleware observes a net/http server: it counts 5xx answers and latencies
+// per route and, at the end of every Window, reports error_spike and latency_high
+// when the whole server (or one route) is over its threshold. Like CollectMetrics
+// an event is sent once per episode. The events carry the busiest offending routes
+// in Data["routes"]. The windows stop with Shutdown.
+//
+//	mux := http.NewServeMux()
+//	mux.HandleFunc("GET /orders/{id}", getOrder)
+//	http.ListenAndServe(":8080", agent.HTTPMiddleware(sdk.DefaultHTTPMetricsConfig())(mux))
+func (a *AgentSDK) HTTPMiddleware(cfg HTTPMetricsConfig) func(http.Handler) http.Handler {
+	defaults := DefaultHTTPMetricsConfig()
+	if cfg.Service == "" {
+		cfg.Service = defaults.Service
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaults.Window
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = defaults.MinRequests
+	}
+	if cfg.MaxRoutes <= 0 {
+		cfg.MaxRoutes = defaults.MaxRoutes
+	}
+	if cfg.Latency.Percentile <= 0 || cfg.Latency.Percentile > 100 {
+		cfg.Latency.Percentile = defaults.Latency.Percentile
+	}
+
+	o := &httpObserver{sdk: a, cfg: cfg, routes: map[string]*routeStats{}, started: time.Now()}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			o.once.Do(func() { go o.loop() })
+
+			rec := &statusRecorder{ResponseWriter: w}
+			start := time.Now()
+			defer func() {
+				// un handler que entra en panico es un 500 para el cliente
+				if p := recover(); p != nil {
+					if p != http.ErrAbortHandler {
+						rec.status = http.StatusInternalServerError
+					}
+					o.record(o.route(r), rec.code(), time.Since(start))
+					panic(p)
+				}
+				o.record(o.route(r), rec.code(), time.Since(start))
+			}()
+
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+// httpObserver keeps the numbers of the current window
+type httpObserver struct {
+	sdk  *AgentSDK
+	cfg  HTTPMetricsConfig
+	once sync.Once
+
+	mu      sync.Mutex
+	routes  map[string]*routeStats
+	all     routeStats
+	started time.Time
+
+	errors  sustained
+	latency sustained
+}
+
+type routeStats struct {
+	requests  int
+	errors    int
+	latencies []time.Duration // reservoir of at most maxLatencySamples
+}
+
+func (s *routeStats) add(status int, latency time.Duration) {
+	s.requests++
+	if status >= 500 {
+		s.errors++
+	}
+	if len(s.latencies) < maxLatencySamples {
+		s.latencies = append(s.latencies, latency)
+	} else if i := rand.Intn(s.requests); i < maxLatencySamples {
+		s.latencies[i] = latency
+	}
+}
+
+func (s *routeStats) errorRate() float64 {
+	return percent(uint64(s.errors), uint64(s.requests))
+}
+
+// percentile of the sampled latencies, sorting them in place
+func (s *routeStats) percentile(p float64) time.Duration {
+	if len(s.latencies) == 0 {
+		return 0
+	}
+	sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+	i := int(math.Ceil(p/100*float64(len(s.latencies)))) - 1
+	return s.latencies[max(i, 0)]
+}
+
+func (o *httpObserver) route(r *http.Request) string {
+	if o.cfg.Route != nil {
+		if route := o.cfg.Route(r); route != "" {
+			return route
+		}
+	}
+	if r.Pattern != "" {
+		return r.Pattern
+	}
+	return r.Method + " " + r.URL.Path
+}
+
+func (o *httpObserver) record(route string, status int, latency time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	stats, ok := o.routes[route]
+	if !ok {
+		// sin patron de ServeMux cada path es una ruta: las que sobran van juntas
+		if len(o.routes) >= o.cfg.MaxRoutes {
+			route = otherRoute
+		}
+		if stats = o.routes[route]; stats == nil {
+			stats = &routeStats{}
+			o.routes[route] = stats
+		}
+	}
+	stats.add(status, latency)
+	o.all.add(status, latency)
+}
+
+func (o *httpObserver) loop() {
+	ticker := time.NewTicker(o.cfg.Window)
+	defer ticker.Stop()
+
+	for {
+		var now time.Time
+		select {
+		case <-o.sdk.closed:
+			return
+		case now = <-ticker.C:
+		}
+
+		o.mu.Lock()
+		routes, all, started := o.routes, o.all, o.started
+		o.routes, o.all, o.started = map[string]*routeStats{}, routeStats{}, now
+		o.mu.Unlock()
+
+		o.evaluate(routes, &all, now.Sub(started), now)
+	}
+}
+
+// evaluate checks a closed window. A route only counts with MinRequests, so a
+// single failed request on a quiet endpoint is not a spike.
+func (o *httpObserver) evaluate(routes map[string]*routeStats, all *routeStats, window time.Duration, now time.Time) {
+	cfg := o.cfg
+	p := cfg.Latency.Percentile
+
+	var errorRate float64
+	var latency time.Duration
+	var erroring, slow []string
+	if all.requests >= cfg.MinRequests {
+		errorRate, latency = all.errorRate(), all.percentile(p)
+	}
+	for route, stats := range routes {
+		if stats.requests < cfg.MinRequests {
+			continue
+		}
+		if rate := stats.errorRate(); cfg.ErrorRate.Percent > 0 && rate >= cfg.ErrorRate.Percent {
+			erroring = append(erroring, route)
+			errorRate = max(errorRate, rate)
+		}
+		if l := stats.percentile(p); cfg.Latency.Max > 0 && l >= cfg.Latency.Max {
+			slow = append(slow, route)
+			latency = max(latency, l)
+		}
+	}
+
+	base := map[string]interface{}{
+		"window_seconds":     int(window.Seconds()),
+		"requests":           all.requests,
+		"errors":             all.errors,
+		"error_rate_percent": all.errorRate(),
+	}
+
+	if since, fire := o.errors.update(cfg.ErrorRate, errorRate, now); fire {
+		o.report(models.EventErrorSpike, cfg.ErrorRate.Severity, withData(base,
+			"threshold_percent", cfg.ErrorRate.Percent,
+			"sustained_seconds", int(now.Sub(since).Seconds()),
+			"routes_over_threshold", sorted(erroring),
+			"routes", breakdown(routes, p, func(s *routeStats) float64 { return float64(s.errors) }),
+		))
+	}
+
+	// el umbral de latencia en ms para reusar sustained
+	limit := Threshold{Percent: milliseconds(cfg.Latency.Max), For: cfg.Latency.For}
+	if since, fire := o.latency.update(limit, milliseconds(latency), now); fire {
+		o.report(models.EventLatencyHigh, cfg.Latency.Severity, withData(base,
+			"percentile", p,
+			"latency_ms", milliseconds(all.percentile(p)),
+			"threshold_ms", milliseconds(cfg.Latency.Max),
+			"sustained_seconds", int(now.Sub(since).Seconds()),
+			"routes_over_threshold", sorted(slow),
+			"routes", breakdown(routes, p, func(s *routeStats) float64 { return float64(s.percentile(p)) }),
+		))
+	}
+}
+
+func (o *httpObserver) report(eventType, severity string, data map[string]interface{}) {
+	if severity == "" {
+		severity = models.SeverityWarning
+	}
+	o.sdk.Report(models.Event{Type: eventType, Service: o.cfg.Service, Severity: severity, Data: data})
+}
+
+// breakdown lists the top routes by key, with their numbers for the window
+func breakdown(routes map[string]*routeStats, p float64, key func(*routeStats) float64) []map[string]interface{} {
+	names := make([]string, 0, len(routes))
+	for route := range routes {
+		names = append(names, route)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ki, kj := key(routes[names[i]]), key(routes[names[j]])
+		if ki != kj {
+			return ki > kj
+		}
+		return names[i] < names[j]
+	})
+
+	out := make([]map[string]interface{}, 0, min(len(names), maxRouteBreakdown))
+	for _, route := range names[:min(len(names), maxRouteBreakdown)] {
+		stats := routes[route]
+		out = append(out, map[string]interface{}{
+			"route":              route,
+			"requests":           stats.requests,
+			"errors":             stats.errors,
+			"error_rate_percent": stats.errorRate(),
+			"p50_ms":             milliseconds(stats.percentile(50)),
+			"p95_ms":             milliseconds(stats.percentile(95)),
+			"p99_ms":             milliseconds(stats.percentile(99)),
+			"latency_ms":         milliseconds(stats.percentile(p)),
+		})
+	}
+	return out
+}
+
+func sorted(routes []string) []string {
+	routes = append([]string{}, routes...)
+	sort.Strings(routes)
+	return routes
+}
+
+func milliseconds(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*10) / 10
+}
+
+// statusRecorder remembers the status the handler answered with
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush keeps streaming handlers working through the middleware
+func (w *statusRecorder) Flush() {
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the original writer (Hijack, deadlines...)
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *statusRecorder) code() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
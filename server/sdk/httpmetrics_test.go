@@ -0,0 +1,324 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestObserver(t *testing.T, cfg HTTPMetricsConfig) (*httpObserver, *eventBackend) {
+	t.Helper()
+	backend := newEventBackend(t)
+	a := newTestSDK(t, backend, ReporterConfig{FlushInterval: time.Hour})
+	if cfg.MaxRoutes == 0 {
+		cfg.MaxRoutes = 100
+	}
+	if cfg.Latency.Percentile == 0 {
+		cfg.Latency.Percentile = 95
+	}
+	return &httpObserver{sdk: a, cfg: cfg, routes: map[string]*routeStats{}, started: time.Now()}, backend
+}
+
+// window es lo que llego en una ventana: ruta -> status y latencia de cada request
+type window map[string][]struct {
+	status  int
+	latency time.Duration
+}
+
+func requests(n, status int, latency time.Duration) []struct {
+	status  int
+	latency time.Duration
+} {
+	out := make([]struct {
+		status  int
+		latency time.Duration
+	}, n)
+	for i := range out {
+		out[i].status, out[i].latency = status, latency
+	}
+	return out
+}
+
+func join(parts ...[]struct {
+	status  int
+	latency time.Duration
+}) []struct {
+	status  int
+	latency time.Duration
+} {
+	var out []struct {
+		status  int
+		latency time.Duration
+	}
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+func TestHTTPObserverEvaluate(t *testing.T) {
+	errorsCfg := HTTPMetricsConfig{Service: "shop", MinRequests: 10, ErrorRate: Threshold{Percent: 10}}
+	latencyCfg := HTTPMetricsConfig{Service: "shop", MinRequests: 10, Latency: LatencyThreshold{Max: 500 * time.Millisecond}}
+
+	tests := []struct {
+		name    string
+		cfg     HTTPMetricsConfig
+		windows []window
+		want    []string // eventos por ventana, "-" si ninguno
+		check   func(t *testing.T, events []map[string]interface{})
+	}{
+		{
+			name:    "healthy",
+			cfg:     errorsCfg,
+			windows: []window{{"GET /orders": requests(50, 200, 10*time.Millisecond)}},
+			want:    []string{"-"},
+		},
+		{
+			name: "one route erroring",
+			cfg:  errorsCfg,
+			windows: []window{{
+				"GET /orders":   requests(100, 200, 10*time.Millisecond),
+				"POST /payment": join(requests(10, 200, time.Millisecond), requests(10, 502, time.Millisecond)),
+			}},
+			want: []string{"error_spike"},
+			check: func(t *testing.T, events []map[string]interface{}) {
+				data := events[0]
+				if fmt.Sprint(data["routes_over_threshold"]) != "[POST /payment]" || data["requests"] != 120.0 || data["errors"] != 10.0 {
+					t.Errorf("data = %v", data)
+				}
+				routes := data["routes"].([]interface{})
+				if routes[0].(map[string]interface{})["route"] != "POST /payment" {
+					t.Errorf("the erroring route should come first: %v", routes)
+				}
+			},
+		},
+		{
+			name:    "a quiet route doesn't count",
+			cfg:     errorsCfg,
+			windows: []window{{"GET /orders": requests(100, 200, time.Millisecond), "GET /admin": requests(3, 500, time.Millisecond)}},
+			want:    []string{"-"},
+		},
+		{
+			name:    "once per episode",
+			cfg:     errorsCfg,
+			windows: []window{{"GET /orders": requests(20, 500, 0)}, {"GET /orders": requests(20, 500, 0)}, {"GET /orders": requests(20, 200, 0)}, {"GET /orders": requests(20, 500, 0)}},
+			want:    []string{"error_spike", "-", "-", "error_spike"},
+		},
+		{
+			name:    "sustained for two windows",
+			cfg:     HTTPMetricsConfig{MinRequests: 10, ErrorRate: Threshold{Percent: 10, For: time.Minute}},
+			windows: []window{{"GET /orders": requests(20, 500, 0)}, {"GET /orders": requests(20, 500, 0)}},
+			want:    []string{"-", "error_spike"},
+		},
+		{
+			name: "slow p95",
+			cfg:  latencyCfg,
+			windows: []window{{
+				"GET /search": join(requests(90, 200, 100*time.Millisecond), requests(10, 200, 2*time.Second)),
+				"GET /orders": requests(100, 200, 20*time.Millisecond),
+			}},
+			want: []string{"latency_high"},
+			check: func(t *testing.T, events []map[string]interface{}) {
+				data := events[0]
+				if fmt.Sprint(data["routes_over_threshold"]) != "[GET /search]" || data["threshold_ms"] != 500.0 || data["percentile"] != 95.0 {
+					t.Errorf("data = %v", data)
+				}
+			},
+		},
+		{
+			name:    "p95 under the limit",
+			cfg:     latencyCfg,
+			windows: []window{{"GET /search": join(requests(97, 200, 100*time.Millisecond), requests(3, 200, 2*time.Second))}},
+			want:    []string{"-"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, backend := newTestObserver(t, tt.cfg)
+			now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+			var got []string
+			seen := 0
+			for _, w := range tt.windows {
+				for route, reqs := range w {
+					for _, r := range reqs {
+						o.record(route, r.status, r.latency)
+					}
+				}
+				now = now.Add(time.Minute)
+				o.evaluate(o.routes, &o.all, time.Minute, now)
+				o.routes, o.all = map[string]*routeStats{}, routeStats{}
+
+				if err := o.sdk.Flush(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+				events := backend.events()[seen:]
+				seen += len(events)
+				switch len(events) {
+				case 0:
+					got = append(got, "-")
+				case 1:
+					got = append(got, events[0].Type)
+				default:
+					t.Fatalf("%d events in a window: %+v", len(events), events)
+				}
+			}
+
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+			if tt.check != nil {
+				var data []map[string]interface{}
+				for _, e := range backend.events() {
+					data = append(data, e.Data)
+					if e.Service != tt.cfg.Service {
+						t.Errorf("service = %q", e.Service)
+					}
+				}
+				tt.check(t, data)
+			}
+		})
+	}
+}
+
+func TestRouteStatsPercentile(t *testing.T) {
+	var s routeStats
+	for i := 1; i <= 100; i++ {
+		s.add(http.StatusOK, time.Duration(i)*time.Millisecond)
+	}
+
+	for _, tt := range []struct {
+		p    float64
+		want time.Duration
+	}{{50, 50 * time.Millisecond}, {95, 95 * time.Millisecond}, {99, 99 * time.Millisecond}, {100, 100 * time.Millisecond}} {
+		if got := s.percentile(tt.p); got != tt.want {
+			t.Errorf("p%.0f = %s, want %s", tt.p, got, tt.want)
+		}
+	}
+
+	// mas de maxLatencySamples: se muestrea, no crece
+	for i := 0; i < 5000; i++ {
+		s.add(http.StatusOK, time.Millisecond)
+	}
+	if len(s.latencies) != maxLatencySamples || s.requests != 5100 {
+		t.Errorf("samples = %d, requests = %d", len(s.latencies), s.requests)
+	}
+}
+
+func TestHTTPObserverRoutes(t *testing.T) {
+	o, _ := newTestObserver(t, HTTPMetricsConfig{MaxRoutes: 2})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		o.record(o.route(r), http.StatusOK, 0)
+	})
+	for _, path := range []string{"/orders/1", "/orders/2"} {
+		mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	// sin patron la ruta es el path
+	for _, path := range []string{"/a", "/b", "/c"} {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		o.record(o.route(r), http.StatusOK, 0)
+	}
+
+	// el patron junta los ids; lo que pasa de MaxRoutes va a "other"
+	if len(o.routes) != 3 || o.routes["GET /orders/{id}"].requests != 2 || o.routes["GET /a"].requests != 1 || o.routes[otherRoute].requests != 2 {
+		t.Errorf("routes = %v", o.routes)
+	}
+}
+
+func TestStatusRecorder(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    int
+	}{
+		{name: "implicit 200", handler: func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) }, want: 200},
+		{name: "nothing written", handler: func(w http.ResponseWriter, r *http.Request) {}, want: 200},
+		{name: "explicit status", handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(503) }, want: 503},
+		{name: "first WriteHeader wins", handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(502); w.WriteHeader(200) }, want: 502},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &statusRecorder{ResponseWriter: httptest.NewRecorder()}
+			tt.handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if rec.code() != tt.want {
+				t.Errorf("code = %d, want %d", rec.code(), tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPMiddlewareReports(t *testing.T) {
+	backend := newEventBackend(t)
+	a := newTestSDK(t, backend, ReporterConfig{BatchSize: 1, FlushInterval: 10 * time.Millisecond})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ok", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("GET /fail", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusBadGateway) })
+	mux.HandleFunc("POST /panic", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+
+	// un handler en panico cuenta como 500 aunque el cliente solo vea la conexion cortada
+	srv := httptest.NewUnstartedServer(a.HTTPMiddleware(HTTPMetricsConfig{
+		Service:     "shop",
+		Window:      50 * time.Millisecond,
+		MinRequests: 5,
+		ErrorRate:   Threshold{Percent: 20},
+	})(mux))
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.Start()
+	defer srv.Close()
+
+	for i := 0; i < 10; i++ {
+		for _, path := range []string{"/ok", "/fail"} {
+			if resp, err := http.Get(srv.URL + path); err == nil {
+				resp.Body.Close()
+			}
+		}
+		if resp, err := http.Post(srv.URL+"/panic", "text/plain", nil); err == nil {
+			resp.Body.Close()
+		}
+	}
+
+	events := backend.waitEvents(t, 1)
+	e := events[0]
+	if e.Type != "error_spike" || e.Service != "shop" || e.Data["errors"] != 20.0 || fmt.Sprint(e.Data["routes_over_threshold"]) != "[GET /fail POST /panic]" {
+		t.Errorf("event = %+v", e)
+	}
+}
+
+func TestHTTPMiddlewareStopsWithShutdown(t *testing.T) {
+	backend := newEventBackend(t)
+	a := newTestSDK(t, backend, ReporterConfig{FlushInterval: time.Hour})
+
+	handler := a.HTTPMiddleware(HTTPMetricsConfig{Window: 10 * time.Millisecond, MinRequests: 1, ErrorRate: Threshold{Percent: 50}})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusBadGateway) }))
+	serve := func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	serve() // arranca las ventanas
+	if err := a.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	before := len(backend.events())
+
+	// sin el loop nadie cierra las ventanas: los 502 no se reportan
+	for i := 0; i < 5; i++ {
+		serve()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := a.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if events := backend.events()[before:]; len(events) != 0 {
+		t.Errorf("reported after Shutdown: %+v", events)
+	}
+}
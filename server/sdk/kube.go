@@ -0,0 +1,199 @@
+package sdk
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// where Kubernetes mounts the service account of the pod
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// KubeAPI is the part of the Kubernetes API the watcher reads. An empty namespace
+// means all namespaces. InClusterKubeAPI talks to the real API server; tests can
+// pass a fake that returns fixed objects.
+type KubeAPI interface {
+	ListPods(ctx context.Context, namespace, labelSelector string) ([]KubePod, error)
+	ListDeployments(ctx context.Context, namespace, labelSelector string) ([]KubeDeployment, error)
+}
+
+// Only the fields the watcher uses; the JSON matches the API objects
+
+type KubeObjectMeta struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+type KubeCondition struct {
+	Type               string    `json:"type"`
+	Status             string    `json:"status"` // "True", "False" or "Unknown"
+	Reason             string    `json:"reason,omitempty"`
+	Message            string    `json:"message,omitempty"`
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
+}
+
+type KubePod struct {
+	Metadata KubeObjectMeta `json:"metadata"`
+	Status   KubePodStatus  `json:"status"`
+}
+
+type KubePodStatus struct {
+	Phase             string                `json:"phase"`
+	Conditions        []KubeCondition       `json:"conditions,omitempty"`
+	ContainerStatuses []KubeContainerStatus `json:"containerStatuses,omitempty"`
+}
+
+type KubeContainerStatus struct {
+	Name         string             `json:"name"`
+	Ready        bool               `json:"ready"`
+	RestartCount int                `json:"restartCount"`
+	State        KubeContainerState `json:"state"`
+	LastState    KubeContainerState `json:"lastState"`
+}
+
+// KubeContainerState has at most one of Waiting or Terminated set (neither = running)
+type KubeContainerState struct {
+	Waiting    *KubeContainerWaiting    `json:"waiting,omitempty"`
+	Terminated *KubeContainerTerminated `json:"terminated,omitempty"`
+}
+
+type KubeContainerWaiting struct {
+	Reason  string `json:"reason"`
+	Message string `json:"message,omitempty"`
+}
+
+type KubeContainerTerminated struct {
+	Reason     string    `json:"reason"`
+	Message    string    `json:"message,omitempty"`
+	ExitCode   int       `json:"exitCode"`
+	FinishedAt time.Time `json:"finishedAt"`
+}
+
+type KubeDeployment struct {
+	Metadata KubeObjectMeta       `json:"metadata"`
+	Spec     KubeDeploymentSpec   `json:"spec"`
+	Status   KubeDeploymentStatus `json:"status"`
+}
+
+type KubeDeploymentSpec struct {
+	Replicas *int `json:"replicas,omitempty"` // nil = 1
+}
+
+type KubeDeploymentStatus struct {
+	Replicas            int             `json:"replicas"`
+	ReadyReplicas       int             `json:"readyReplicas"`
+	AvailableReplicas   int             `json:"availableReplicas"`
+	UnavailableReplicas int             `json:"unavailableReplicas"`
+	Conditions          []KubeCondition `json:"conditions,omitempty"`
+}
+
+// condition returns the condition of that type, nil if the object doesn't have it
+func condition(conditions []KubeCondition, conditionType string) *KubeCondition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// kubeRESTClient reads the API server over REST with the pod's service account
+// (no client-go, like the backend's KubeClient)
+type kubeRESTClient struct {
+	server     string
+	tokenFile  string // re-read on every request: projected tokens rotate
+	httpClient *http.Client
+}
+
+// InClusterKubeAPI is the KubeAPI of an SDK running in a pod. The service account
+// needs get/list on pods and deployments (apps) of the watched namespaces.
+func InClusterKubeAPI() (KubeAPI, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running in a Kubernetes pod (KUBERNETES_SERVICE_HOST is not set)")
+	}
+
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("reading the service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("invalid service account CA")
+	}
+
+	return &kubeRESTClient{
+		server:    "https://" + net.JoinHostPort(host, port),
+		tokenFile: serviceAccountDir + "/token",
+		httpClient: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}},
+		},
+	}, nil
+}
+
+func (k *kubeRESTClient) ListPods(ctx context.Context, namespace, labelSelector string) ([]KubePod, error) {
+	var list struct {
+		Items []KubePod `json:"items"`
+	}
+	err := k.list(ctx, "/api/v1", "pods", namespace, labelSelector, &list)
+	return list.Items, err
+}
+
+func (k *kubeRESTClient) ListDeployments(ctx context.Context, namespace, labelSelector string) ([]KubeDeployment, error) {
+	var list struct {
+		Items []KubeDeployment `json:"items"`
+	}
+	err := k.list(ctx, "/apis/apps/v1", "deployments", namespace, labelSelector, &list)
+	return list.Items, err
+}
+
+func (k *kubeRESTClient) list(ctx context.Context, group, resource, namespace, labelSelector string, out interface{}) error {
+	path := group + "/" + resource
+	if namespace != "" {
+		path = group + "/namespaces/" + url.PathEscape(namespace) + "/" + resource
+	}
+	if labelSelector != "" {
+		path += "?labelSelector=" + url.QueryEscape(labelSelector)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.server+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	token, err := os.ReadFile(k.tokenFile)
+	if err != nil {
+		return fmt.Errorf("reading the service account token: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// la API devuelve un objeto Status con el motivo
+		var status struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&status)
+		return fmt.Errorf("kubernetes GET %s: %d %s", path, resp.StatusCode, status.Message)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, 64<<20)).Decode(out)
+}
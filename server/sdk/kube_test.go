@@ -0,0 +1,79 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKubeRESTClientList(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		selector  string
+		wantPath  string
+		wantQuery string
+	}{
+		{name: "all namespaces", wantPath: "/api/v1/pods"},
+		{name: "one namespace", namespace: "prod", wantPath: "/api/v1/namespaces/prod/pods"},
+		{name: "label selector", namespace: "prod", selector: "app=payments,tier in (web)", wantPath: "/api/v1/namespaces/prod/pods", wantQuery: "app=payments,tier in (web)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.wantPath || r.URL.Query().Get("labelSelector") != tt.wantQuery {
+					t.Errorf("GET %s?%s", r.URL.Path, r.URL.RawQuery)
+				}
+				if r.Header.Get("Authorization") != "Bearer token-1" {
+					t.Errorf("authorization = %q", r.Header.Get("Authorization"))
+				}
+				json.NewEncoder(w).Encode(map[string]interface{}{"items": []KubePod{{Metadata: KubeObjectMeta{Name: "api-1", Namespace: "prod"}}}})
+			}))
+			defer srv.Close()
+
+			tokenFile := filepath.Join(t.TempDir(), "token")
+			os.WriteFile(tokenFile, []byte("token-1\n"), 0o600)
+			k := &kubeRESTClient{server: srv.URL, tokenFile: tokenFile, httpClient: srv.Client()}
+
+			pods, err := k.ListPods(context.Background(), tt.namespace, tt.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pods) != 1 || pods[0].Metadata.Name != "api-1" {
+				t.Errorf("pods = %+v", pods)
+			}
+		})
+	}
+}
+
+func TestKubeRESTClientErrors(t *testing.T) {
+	var token string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"message": `deployments.apps is forbidden: User "system:serviceaccount:prod:infragent" cannot list resource "deployments"`})
+	}))
+	defer srv.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	os.WriteFile(tokenFile, []byte("token-1"), 0o600)
+	k := &kubeRESTClient{server: srv.URL, tokenFile: tokenFile, httpClient: srv.Client()}
+
+	_, err := k.ListDeployments(context.Background(), "prod", "")
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), `cannot list resource "deployments"`) {
+		t.Fatalf("err = %v", err)
+	}
+
+	// el token proyectado rota: se vuelve a leer en cada request
+	os.WriteFile(tokenFile, []byte("token-2"), 0o600)
+	k.ListDeployments(context.Background(), "prod", "")
+	if token != "Bearer token-2" {
+		t.Errorf("authorization = %q, want the rotated token", token)
+	}
+}
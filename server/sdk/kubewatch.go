@@ -0,0 +1,299 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	models "server/model"
+	"time"
+)
+
+const (
+	reasonCrashLoop = "CrashLoopBackOff"
+	reasonOOMKilled = "OOMKilled"
+)
+
+// KubeWatchConfig is what WatchKubernetes looks at
+type KubeWatchConfig struct {
+	Namespace      string        // empty = all namespaces (the service account needs cluster-wide list)
+	LabelSelector  string        // e.g. "app.kubernetes.io/part-of=payments"
+	Interval       time.Duration // default 15s
+	ReadinessGrace time.Duration // how long a running pod can stay not ready, default 2m
+	UnavailableFor time.Duration // how long a deployment can miss replicas, default 2m
+	ServiceLabel   string        // label reported as Event.Service, default app.kubernetes.io/name, then app
+}
+
+// WatchKubernetes lists pods and deployments every Interval and reports:
+//
+//   - crash_loop: a container in CrashLoopBackOff
+//   - oom_killed: a container restarted after being OOMKilled
+//   - readiness_failed: a running pod not ready for longer than ReadinessGrace
+//   - replicas_unavailable: a deployment missing available replicas for longer
+//     than UnavailableFor, and app_recovered when it has them all again
+//
+// Each problem is reported once, when it starts. Events carry the namespace, the
+// pod or deployment and the reason Kubernetes gives. Blocks until ctx is cancelled.
+func (a *AgentSDK) WatchKubernetes(ctx context.Context, api KubeAPI, cfg KubeWatchConfig) error {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 15 * time.Second
+	}
+	w := newKubeWatcher(cfg, time.Now())
+
+	scope := cfg.Namespace
+	if scope == "" {
+		scope = "todos los namespaces"
+	}
+	fmt.Printf("[SDK] vigilando pods y deployments de %s cada %s\n", scope, cfg.Interval)
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		pods, err := api.ListPods(ctx, cfg.Namespace, cfg.LabelSelector)
+		if err == nil {
+			var deployments []KubeDeployment
+			if deployments, err = api.ListDeployments(ctx, cfg.Namespace, cfg.LabelSelector); err == nil {
+				for _, event := range w.observe(pods, deployments, time.Now()) {
+					a.Report(event)
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			// un error de la API no es un problema de la app, reintentamos en el proximo tick
+			fmt.Printf("[SDK] no se pudo leer el estado de Kubernetes: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// kubeWatcher turns snapshots of pods and deployments into events, remembering
+// what was already reported
+type kubeWatcher struct {
+	cfg     KubeWatchConfig
+	started time.Time
+
+	crashLooping     map[string]bool      // namespace/pod/container
+	restarts         map[string]int       // namespace/pod/container -> restart count last seen
+	notReady         map[string]bool      // namespace/pod
+	unavailableSince map[string]time.Time // namespace/deployment
+	unavailable      map[string]bool      // namespace/deployment already reported
+}
+
+func newKubeWatcher(cfg KubeWatchConfig, started time.Time) *kubeWatcher {
+	if cfg.ReadinessGrace <= 0 {
+		cfg.ReadinessGrace = 2 * time.Minute
+	}
+	if cfg.UnavailableFor <= 0 {
+		cfg.UnavailableFor = 2 * time.Minute
+	}
+	return &kubeWatcher{
+		cfg:              cfg,
+		started:          started,
+		crashLooping:     make(map[string]bool),
+		restarts:         make(map[string]int),
+		notReady:         make(map[string]bool),
+		unavailableSince: make(map[string]time.Time),
+		unavailable:      make(map[string]bool),
+	}
+}
+
+// observe compares a snapshot with the previous ones and returns the new problems
+func (w *kubeWatcher) observe(pods []KubePod, deployments []KubeDeployment, now time.Time) []models.Event {
+	var events []models.Event
+	present := make(map[string]bool)
+
+	for _, pod := range pods {
+		podKey := pod.Metadata.Namespace + "/" + pod.Metadata.Name
+		present[podKey] = true
+		crashing := false
+
+		for _, c := range pod.Status.ContainerStatuses {
+			key := podKey + "/" + c.Name
+			present[key] = true
+
+			if c.State.Waiting != nil && c.State.Waiting.Reason == reasonCrashLoop {
+				crashing = true
+				if !w.crashLooping[key] {
+					w.crashLooping[key] = true
+					data := w.podData(pod, c.State.Waiting.Reason, c.State.Waiting.Message)
+					data["container"] = c.Name
+					data["restart_count"] = c.RestartCount
+					if last := c.LastState.Terminated; last != nil {
+						data["last_exit_reason"] = last.Reason
+						data["last_exit_code"] = last.ExitCode
+					}
+					events = append(events, w.event(models.EventCrashLoop, models.SeverityCritical, pod.Metadata, data))
+				}
+			} else if c.Ready {
+				// un crash loop pasa por running entre reinicios: se cierra cuando vuelve a estar ready
+				delete(w.crashLooping, key)
+			}
+
+			// en un crash loop el crash_loop ya trae el OOM como last_exit_reason
+			if oom := oomKill(c); oom != nil && !w.crashLooping[key] {
+				previous, seen := w.restarts[key]
+				// la primera vez que vemos el container solo cuenta si el OOM fue despues de arrancar
+				if seen && c.RestartCount > previous || !seen && oom.FinishedAt.After(w.started) {
+					data := w.podData(pod, oom.Reason, oom.Message)
+					data["container"] = c.Name
+					data["restart_count"] = c.RestartCount
+					data["exit_code"] = oom.ExitCode
+					data["killed_at"] = oom.FinishedAt
+					events = append(events, w.event(models.EventOOMKilled, models.SeverityCritical, pod.Metadata, data))
+				}
+			}
+			w.restarts[key] = c.RestartCount
+		}
+
+		ready := condition(pod.Status.Conditions, "Ready")
+		switch {
+		case ready == nil || ready.Status == "True" || pod.Status.Phase != "Running":
+			delete(w.notReady, podKey)
+		case crashing || w.notReady[podKey]:
+			// el crash loop ya explica por que no esta ready
+		case now.Sub(ready.LastTransitionTime) >= w.cfg.ReadinessGrace:
+			w.notReady[podKey] = true
+			data := w.podData(pod, ready.Reason, ready.Message)
+			data["not_ready_for_s"] = int(now.Sub(ready.LastTransitionTime).Seconds())
+			var containers []string
+			for _, c := range pod.Status.ContainerStatuses {
+				if !c.Ready {
+					containers = append(containers, c.Name)
+				}
+			}
+			data["containers"] = containers
+			events = append(events, w.event(models.EventReadinessFailed, models.SeverityWarning, pod.Metadata, data))
+		}
+	}
+
+	for _, d := range deployments {
+		key := d.Metadata.Namespace + "/" + d.Metadata.Name
+		present[key] = true
+
+		desired := 1
+		if d.Spec.Replicas != nil {
+			desired = *d.Spec.Replicas
+		}
+		missing := max(d.Status.UnavailableReplicas, desired-d.Status.AvailableReplicas)
+
+		data := map[string]interface{}{
+			"namespace":  d.Metadata.Namespace,
+			"deployment": d.Metadata.Name,
+			"desired":    desired,
+			"available":  d.Status.AvailableReplicas,
+			"ready":      d.Status.ReadyReplicas,
+			"missing":    missing,
+		}
+
+		if missing <= 0 {
+			if w.unavailable[key] {
+				data["down_for_s"] = int(now.Sub(w.unavailableSince[key]).Seconds())
+				events = append(events, w.event(models.EventAppRecovered, models.SeverityInfo, d.Metadata, data))
+			}
+			delete(w.unavailableSince, key)
+			delete(w.unavailable, key)
+			continue
+		}
+
+		since, ok := w.unavailableSince[key]
+		if !ok {
+			since = now
+			w.unavailableSince[key] = now
+		}
+		if w.unavailable[key] || now.Sub(since) < w.cfg.UnavailableFor {
+			continue
+		}
+
+		w.unavailable[key] = true
+		// Available dice por que faltan replicas; Progressing si el rollout se trabo
+		for _, t := range []string{"Progressing", "Available"} {
+			if c := condition(d.Status.Conditions, t); c != nil && c.Status != "True" {
+				data["reason"], data["message"] = c.Reason, c.Message
+			}
+		}
+		if _, ok := data["reason"]; !ok {
+			data["reason"] = "MinimumReplicasUnavailable"
+		}
+		data["unavailable_for_s"] = int(now.Sub(since).Seconds())
+
+		severity := models.SeverityWarning
+		if d.Status.AvailableReplicas == 0 {
+			severity = models.SeverityCritical
+		}
+		events = append(events, w.event(models.EventReplicasUnavailable, severity, d.Metadata, data))
+	}
+
+	// lo que ya no existe (pods borrados, deployments eliminados) se olvida
+	for _, m := range []map[string]bool{w.crashLooping, w.notReady, w.unavailable} {
+		for key := range m {
+			if !present[key] {
+				delete(m, key)
+			}
+		}
+	}
+	for key := range w.restarts {
+		if !present[key] {
+			delete(w.restarts, key)
+		}
+	}
+	for key := range w.unavailableSince {
+		if !present[key] {
+			delete(w.unavailableSince, key)
+		}
+	}
+
+	return events
+}
+
+// oomKill returns the termination if the container was (or just got) OOMKilled
+func oomKill(c KubeContainerStatus) *KubeContainerTerminated {
+	for _, t := range []*KubeContainerTerminated{c.State.Terminated, c.LastState.Terminated} {
+		if t != nil && t.Reason == reasonOOMKilled {
+			return t
+		}
+	}
+	return nil
+}
+
+func (w *kubeWatcher) podData(pod KubePod, reason, message string) map[string]interface{} {
+	data := map[string]interface{}{
+		"namespace": pod.Metadata.Namespace,
+		"pod":       pod.Metadata.Name,
+		"phase":     pod.Status.Phase,
+		"reason":    reason,
+	}
+	if message != "" {
+		data["message"] = truncate(message, maxSampleLineSize)
+	}
+	return data
+}
+
+func (w *kubeWatcher) event(eventType, severity string, meta KubeObjectMeta, data map[string]interface{}) models.Event {
+	return models.Event{
+		Type:     eventType,
+		Service:  w.service(meta),
+		Severity: severity,
+		Data:     data,
+	}
+}
+
+// service is the app the object belongs to, from its labels or its name
+func (w *kubeWatcher) service(meta KubeObjectMeta) string {
+	labels := []string{"app.kubernetes.io/name", "app"}
+	if w.cfg.ServiceLabel != "" {
+		labels = []string{w.cfg.ServiceLabel}
+	}
+	for _, label := range labels {
+		if value := meta.Labels[label]; value != "" {
+			return value
+		}
+	}
+	return meta.Name
+}
@@ -0,0 +1,329 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	models "server/model"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+var watchStart = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// fakeKubeAPI devuelve siempre el mismo estado (o el error)
+type fakeKubeAPI struct {
+	mu          sync.Mutex
+	pods        []KubePod
+	deployments []KubeDeployment
+	err         error
+	calls       int
+}
+
+func (f *fakeKubeAPI) ListPods(ctx context.Context, namespace, labelSelector string) ([]KubePod, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	return f.pods, f.err
+}
+
+func (f *fakeKubeAPI) ListDeployments(ctx context.Context, namespace, labelSelector string) ([]KubeDeployment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.deployments, f.err
+}
+
+func pod(name string, ready bool, containers ...KubeContainerStatus) KubePod {
+	status := "True"
+	if !ready {
+		status = "False"
+	}
+	return KubePod{
+		Metadata: KubeObjectMeta{Name: name, Namespace: "prod", Labels: map[string]string{"app": "payments"}},
+		Status: KubePodStatus{
+			Phase:             "Running",
+			Conditions:        []KubeCondition{{Type: "Ready", Status: status, Reason: "ContainersNotReady", LastTransitionTime: watchStart}},
+			ContainerStatuses: containers,
+		},
+	}
+}
+
+func running(restarts int) KubeContainerStatus {
+	return KubeContainerStatus{Name: "app", Ready: true, RestartCount: restarts}
+}
+
+func crashLooping(restarts int, lastReason string) KubeContainerStatus {
+	return KubeContainerStatus{
+		Name:         "app",
+		RestartCount: restarts,
+		State:        KubeContainerState{Waiting: &KubeContainerWaiting{Reason: reasonCrashLoop, Message: "back-off 5m0s restarting failed container"}},
+		LastState:    KubeContainerState{Terminated: &KubeContainerTerminated{Reason: lastReason, ExitCode: 137, FinishedAt: watchStart}},
+	}
+}
+
+func oomKilled(restarts int, at time.Time) KubeContainerStatus {
+	c := running(restarts)
+	c.LastState.Terminated = &KubeContainerTerminated{Reason: reasonOOMKilled, ExitCode: 137, FinishedAt: at}
+	return c
+}
+
+func deployment(name string, desired, available int) KubeDeployment {
+	return KubeDeployment{
+		Metadata: KubeObjectMeta{Name: name, Namespace: "prod"},
+		Spec:     KubeDeploymentSpec{Replicas: &desired},
+		Status: KubeDeploymentStatus{
+			Replicas:          desired,
+			ReadyReplicas:     available,
+			AvailableReplicas: available,
+			Conditions: []KubeCondition{
+				{Type: "Available", Status: "False", Reason: "MinimumReplicasUnavailable", Message: "Deployment does not have minimum availability."},
+				{Type: "Progressing", Status: "True", Reason: "NewReplicaSetAvailable"},
+			},
+		},
+	}
+}
+
+// snapshot es una pasada del watcher: lo que devuelve la API y los eventos que esperamos
+type snapshot struct {
+	after       time.Duration // desde que arranco el watcher
+	pods        []KubePod
+	deployments []KubeDeployment
+	want        []string // tipos de evento, en orden
+}
+
+func TestKubeWatcherObserve(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []snapshot
+		check func(t *testing.T, events []models.Event)
+	}{
+		{
+			name: "crash loop is reported once",
+			steps: []snapshot{
+				{pods: []KubePod{pod("api-1", true, running(0))}},
+				{after: time.Minute, pods: []KubePod{pod("api-1", false, crashLooping(3, "Error"))}, want: []string{models.EventCrashLoop}},
+				{after: 2 * time.Minute, pods: []KubePod{pod("api-1", false, crashLooping(4, "Error"))}},
+			},
+			check: func(t *testing.T, events []models.Event) {
+				e := events[0]
+				if e.Service != "payments" || e.Severity != models.SeverityCritical {
+					t.Errorf("service = %q, severity = %q", e.Service, e.Severity)
+				}
+				if e.Data["namespace"] != "prod" || e.Data["pod"] != "api-1" || e.Data["container"] != "app" ||
+					e.Data["reason"] != reasonCrashLoop || e.Data["last_exit_reason"] != "Error" || e.Data["restart_count"] != 3 {
+					t.Errorf("data = %v", e.Data)
+				}
+			},
+		},
+		{
+			name: "a new crash loop after recovering is reported again",
+			steps: []snapshot{
+				{pods: []KubePod{pod("api-1", false, crashLooping(3, "Error"))}, want: []string{models.EventCrashLoop}},
+				{after: time.Minute, pods: []KubePod{pod("api-1", true, running(4))}},
+				{after: 2 * time.Minute, pods: []KubePod{pod("api-1", false, crashLooping(5, "Error"))}, want: []string{models.EventCrashLoop}},
+			},
+		},
+		{
+			name: "oom kill after a restart",
+			steps: []snapshot{
+				{pods: []KubePod{pod("api-1", true, running(0))}},
+				{after: time.Minute, pods: []KubePod{pod("api-1", true, oomKilled(1, watchStart.Add(50*time.Second)))}, want: []string{models.EventOOMKilled}},
+				{after: 2 * time.Minute, pods: []KubePod{pod("api-1", true, oomKilled(1, watchStart.Add(50*time.Second)))}},
+			},
+			check: func(t *testing.T, events []models.Event) {
+				if events[0].Data["exit_code"] != 137 || events[0].Data["reason"] != reasonOOMKilled {
+					t.Errorf("data = %v", events[0].Data)
+				}
+			},
+		},
+		{
+			name: "oom kill from before the watcher started is not reported",
+			steps: []snapshot{
+				{pods: []KubePod{pod("api-1", true, oomKilled(2, watchStart.Add(-time.Hour)))}},
+			},
+		},
+		{
+			name: "oom kill seen first after the watcher started is reported",
+			steps: []snapshot{
+				{after: time.Minute, pods: []KubePod{pod("api-1", true, oomKilled(2, watchStart.Add(30*time.Second)))}, want: []string{models.EventOOMKilled}},
+			},
+		},
+		{
+			name: "oom kill inside a crash loop comes with the crash loop",
+			steps: []snapshot{
+				{pods: []KubePod{pod("api-1", true, running(0))}},
+				{after: time.Minute, pods: []KubePod{pod("api-1", false, crashLooping(1, reasonOOMKilled))}, want: []string{models.EventCrashLoop}},
+				{after: 2 * time.Minute, pods: []KubePod{pod("api-1", false, crashLooping(2, reasonOOMKilled))}},
+			},
+		},
+		{
+			name: "readiness failure waits for the grace period",
+			steps: []snapshot{
+				{after: time.Minute, pods: []KubePod{pod("api-1", false, running(0))}},
+				{after: 3 * time.Minute, pods: []KubePod{pod("api-1", false, running(0))}, want: []string{models.EventReadinessFailed}},
+				{after: 4 * time.Minute, pods: []KubePod{pod("api-1", false, running(0))}},
+			},
+			check: func(t *testing.T, events []models.Event) {
+				if events[0].Severity != models.SeverityWarning || events[0].Data["not_ready_for_s"] != 180 {
+					t.Errorf("severity = %q, data = %v", events[0].Severity, events[0].Data)
+				}
+			},
+		},
+		{
+			name: "missing replicas, then recovered",
+			steps: []snapshot{
+				{deployments: []KubeDeployment{deployment("api", 3, 1)}},
+				{after: time.Minute, deployments: []KubeDeployment{deployment("api", 3, 1)}},
+				{after: 3 * time.Minute, deployments: []KubeDeployment{deployment("api", 3, 1)}, want: []string{models.EventReplicasUnavailable}},
+				{after: 4 * time.Minute, deployments: []KubeDeployment{deployment("api", 3, 1)}},
+				{after: 5 * time.Minute, deployments: []KubeDeployment{deployment("api", 3, 3)}, want: []string{models.EventAppRecovered}},
+			},
+			check: func(t *testing.T, events []models.Event) {
+				down := events[0]
+				if down.Severity != models.SeverityWarning || down.Service != "api" || down.Data["missing"] != 2 ||
+					down.Data["reason"] != "MinimumReplicasUnavailable" || down.Data["unavailable_for_s"] != 180 {
+					t.Errorf("severity = %q, service = %q, data = %v", down.Severity, down.Service, down.Data)
+				}
+				if events[1].Data["down_for_s"] != 300 {
+					t.Errorf("recovered data = %v", events[1].Data)
+				}
+			},
+		},
+		{
+			name: "no replicas available is critical",
+			steps: []snapshot{
+				{deployments: []KubeDeployment{deployment("api", 2, 0)}},
+				{after: 2 * time.Minute, deployments: []KubeDeployment{deployment("api", 2, 0)}, want: []string{models.EventReplicasUnavailable}},
+			},
+			check: func(t *testing.T, events []models.Event) {
+				if events[0].Severity != models.SeverityCritical {
+					t.Errorf("severity = %q", events[0].Severity)
+				}
+			},
+		},
+		{
+			name: "a short dip during a rollout is not reported",
+			steps: []snapshot{
+				{deployments: []KubeDeployment{deployment("api", 3, 2)}},
+				{after: time.Minute, deployments: []KubeDeployment{deployment("api", 3, 3)}},
+				{after: 5 * time.Minute, deployments: []KubeDeployment{deployment("api", 3, 2)}},
+			},
+		},
+		{
+			name: "a deleted pod is forgotten",
+			steps: []snapshot{
+				{pods: []KubePod{pod("api-1", false, crashLooping(3, "Error"))}, want: []string{models.EventCrashLoop}},
+				{after: time.Minute},
+				{after: 2 * time.Minute, pods: []KubePod{pod("api-1", false, crashLooping(1, "Error"))}, want: []string{models.EventCrashLoop}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newKubeWatcher(KubeWatchConfig{}, watchStart)
+
+			var all []models.Event
+			for i, step := range tt.steps {
+				events := w.observe(step.pods, step.deployments, watchStart.Add(step.after))
+				var got []string
+				for _, e := range events {
+					got = append(got, e.Type)
+				}
+				if !slices.Equal(got, step.want) {
+					t.Fatalf("step %d: events = %v, want %v", i, got, step.want)
+				}
+				all = append(all, events...)
+			}
+			if tt.check != nil {
+				tt.check(t, all)
+			}
+		})
+	}
+}
+
+func TestKubeWatcherServiceLabel(t *testing.T) {
+	meta := KubeObjectMeta{Name: "api-7d9f", Labels: map[string]string{"app": "payments", "team": "billing"}}
+
+	tests := []struct {
+		label string
+		meta  KubeObjectMeta
+		want  string
+	}{
+		{meta: meta, want: "payments"},
+		{meta: KubeObjectMeta{Name: "api-7d9f", Labels: map[string]string{"app.kubernetes.io/name": "checkout", "app": "payments"}}, want: "checkout"},
+		{label: "team", meta: meta, want: "billing"},
+		{label: "missing", meta: meta, want: "api-7d9f"},
+	}
+
+	for _, tt := range tests {
+		w := newKubeWatcher(KubeWatchConfig{ServiceLabel: tt.label}, watchStart)
+		if got := w.service(tt.meta); got != tt.want {
+			t.Errorf("service(label %q) = %q, want %q", tt.label, got, tt.want)
+		}
+	}
+}
+
+func TestWatchKubernetesReportsEvents(t *testing.T) {
+	var mu sync.Mutex
+	var received []models.Event
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch models.EventBatch
+		json.NewDecoder(r.Body).Decode(&batch)
+		mu.Lock()
+		received = append(received, batch.Events...)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer backend.Close()
+
+	api := &fakeKubeAPI{err: errors.New("connection refused")}
+	a := NewSDK("key", backend.URL, "secret")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.WatchKubernetes(ctx, api, KubeWatchConfig{Interval: 5 * time.Millisecond}) }()
+
+	// un error de la API no corta el watcher: cuando vuelve se reporta lo que ve
+	time.Sleep(20 * time.Millisecond)
+	api.mu.Lock()
+	api.err = nil
+	api.pods = []KubePod{pod("api-1", false, crashLooping(3, "Error"))}
+	api.mu.Unlock()
+
+	deadline := time.After(2 * time.Second)
+	for {
+		a.Flush(context.Background())
+		mu.Lock()
+		n := len(received)
+		mu.Unlock()
+		if n > 0 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("no event reached the backend")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("WatchKubernetes returned %v", err)
+	}
+	a.Flush(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 1 || received[0].Type != models.EventCrashLoop {
+		t.Fatalf("received %+v, want a single crash_loop", received)
+	}
+	if api.calls < 3 {
+		t.Errorf("the watcher stopped polling after the api error (%d calls)", api.calls)
+	}
+}
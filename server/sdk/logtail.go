@@ -0,0 +1,296 @@
+package sdk
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	models "server/model"
+	"time"
+)
+
+// default error lines: error, fatal, panic, exception, or a 5xx status
+var defaultLogPatterns = []string{`(?i)\b(error|fatal|panic|exception)\b`, `\s5\d\d\s`}
+
+// what never leaves the host in a sample line
+var defaultRedactions = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(authorization|token|password|passwd|secret|api[_-]?key)(["']?\s*[:=]\s*)("[^"]*"|'[^']*'|bearer\s+\S+|\S+)`),
+	regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`), // JWT
+	regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),    // emails
+	regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`),                       // IPv4
+	regexp.MustCompile(`\b[A-Za-z0-9+/_-]{32,}={0,2}`),                      // keys, hashes
+}
+
+const (
+	redacted          = "[REDACTED]"
+	maxSampleLineSize = 300
+	maxLogLineSize    = 64 << 10
+)
+
+// LogWatch follows one log file and reports error_spike when the matching lines
+// in the last Window reach Threshold
+type LogWatch struct {
+	Path         string
+	Service      string        // reported as Event.Service
+	Patterns     []string      // regexes of an error line, default defaultLogPatterns
+	Window       time.Duration // sliding window, default 1m
+	Threshold    int           // matches in the window, default 10
+	Samples      int           // redacted lines sent with the event, default 5
+	Redact       []string      // extra regexes to redact from the samples
+	PollInterval time.Duration // default 1s
+}
+
+// TailLogs follows watch.Path from its current end, surviving rotation (the file
+// is renamed and a new one created) and truncation. An error_spike is sent once
+// per spike: the rate has to go back under the threshold to fire again.
+// Blocks until ctx is cancelled.
+func (a *AgentSDK) TailLogs(ctx context.Context, watch LogWatch) error {
+	spike, err := newSpikeDetector(watch)
+	if err != nil {
+		return err
+	}
+	if watch.PollInterval <= 0 {
+		watch.PollInterval = time.Second
+	}
+
+	tail := &fileTail{path: watch.Path}
+	defer tail.close()
+	if err := tail.open(true); err != nil {
+		fmt.Printf("[SDK] todavia no existe %s, esperando: %v\n", watch.Path, err)
+	}
+
+	ticker := time.NewTicker(watch.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		err := tail.readLines(func(line string) {
+			if data, fire := spike.add(line, time.Now()); fire {
+				a.Report(models.Event{Type: models.EventErrorSpike, Service: watch.Service, Severity: models.SeverityWarning, Data: data})
+			}
+		})
+		if err != nil {
+			fmt.Printf("[SDK] error leyendo %s: %v\n", watch.Path, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// fileTail reads the lines appended to a file
+type fileTail struct {
+	path    string
+	file    *os.File
+	reader  *bufio.Reader
+	offset  int64
+	partial []byte // end of a line still being written
+}
+
+// open opens the file, at its end if atEnd (when we start) or at the start (a new file after rotation)
+func (t *fileTail) open(atEnd bool) error {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+
+	t.offset = 0
+	if atEnd {
+		if t.offset, err = f.Seek(0, io.SeekEnd); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	t.file, t.reader, t.partial = f, bufio.NewReader(f), nil
+	return nil
+}
+
+func (t *fileTail) close() {
+	if t.file != nil {
+		t.file.Close()
+		t.file = nil
+	}
+}
+
+// readLines hands every complete new line to fn, then checks for rotation or truncation
+func (t *fileTail) readLines(fn func(line string)) error {
+	if t.file == nil {
+		if err := t.open(false); err != nil {
+			return nil // sigue sin existir
+		}
+	}
+
+	if err := t.drain(fn); err != nil {
+		return err
+	}
+
+	current, err := os.Stat(t.path)
+	if err != nil {
+		return nil // rotado y el nuevo todavia no existe
+	}
+	opened, err := t.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !os.SameFile(current, opened):
+		// rotado: lo que quedaba en el viejo ya se leyo, seguimos con el nuevo desde el principio
+		t.close()
+		if err := t.open(false); err != nil {
+			return nil
+		}
+		return t.drain(fn)
+	case current.Size() < t.offset:
+		// truncado (copytruncate)
+		if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		t.offset, t.partial = 0, nil
+		t.reader.Reset(t.file)
+		return t.drain(fn)
+	}
+	return nil
+}
+
+func (t *fileTail) drain(fn func(line string)) error {
+	for {
+		chunk, err := t.reader.ReadSlice('\n')
+		t.offset += int64(len(chunk))
+
+		if len(t.partial)+len(chunk) <= maxLogLineSize {
+			t.partial = append(t.partial, chunk...)
+		}
+
+		switch {
+		case err == nil:
+			fn(string(bytes.TrimRight(t.partial, "\r\n")))
+			t.partial = t.partial[:0]
+		case errors.Is(err, bufio.ErrBufferFull):
+			// linea larguisima: seguimos juntando
+		case errors.Is(err, io.EOF):
+			return nil
+		default:
+			return err
+		}
+	}
+}
+
+// spikeDetector counts matching lines over a sliding window
+type spikeDetector struct {
+	watch    LogWatch
+	patterns []*regexp.Regexp
+	redact   []*regexp.Regexp
+	matches  []time.Time
+	samples  []string
+	fired    bool
+}
+
+func newSpikeDetector(watch LogWatch) (*spikeDetector, error) {
+	if watch.Path == "" {
+		return nil, errors.New("log watch needs a path")
+	}
+	if watch.Window <= 0 {
+		watch.Window = time.Minute
+	}
+	if watch.Threshold <= 0 {
+		watch.Threshold = 10
+	}
+	if watch.Samples <= 0 {
+		watch.Samples = 5
+	}
+	if len(watch.Patterns) == 0 {
+		watch.Patterns = defaultLogPatterns
+	}
+
+	d := &spikeDetector{watch: watch, redact: defaultRedactions}
+	for _, pattern := range watch.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("log watch %s: pattern %q: %w", watch.Path, pattern, err)
+		}
+		d.patterns = append(d.patterns, re)
+	}
+	for _, pattern := range watch.Redact {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("log watch %s: redact %q: %w", watch.Path, pattern, err)
+		}
+		d.redact = append(d.redact, re)
+	}
+	return d, nil
+}
+
+// add counts the line if it matches and returns the event data when a spike starts
+func (d *spikeDetector) add(line string, now time.Time) (map[string]interface{}, bool) {
+	cutoff := now.Add(-d.watch.Window)
+	keep := 0
+	for keep < len(d.matches) && d.matches[keep].Before(cutoff) {
+		keep++
+	}
+	d.matches = d.matches[keep:]
+
+	if !d.matching(line) {
+		if len(d.matches) < d.watch.Threshold {
+			d.fired = false
+		}
+		return nil, false
+	}
+
+	d.matches = append(d.matches, now)
+	d.samples = append(d.samples, d.redactLine(line))
+	if len(d.samples) > d.watch.Samples {
+		d.samples = d.samples[len(d.samples)-d.watch.Samples:]
+	}
+
+	if len(d.matches) < d.watch.Threshold {
+		d.fired = false
+		return nil, false
+	}
+	if d.fired {
+		return nil, false
+	}
+	d.fired = true
+
+	return map[string]interface{}{
+		"path":            d.watch.Path,
+		"matches":         len(d.matches),
+		"window_seconds":  int(d.watch.Window.Seconds()),
+		"rate_per_minute": float64(len(d.matches)) / d.watch.Window.Minutes(),
+		"threshold":       d.watch.Threshold,
+		"samples":         append([]string(nil), d.samples...),
+	}, true
+}
+
+func (d *spikeDetector) matching(line string) bool {
+	for _, re := range d.patterns {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *spikeDetector) redactLine(line string) string {
+	return truncate(redact(line, d.redact), maxSampleLineSize)
+}
+
+// redact replaces whatever matches the patterns with [REDACTED]
+func redact(text string, patterns []*regexp.Regexp) string {
+	for _, re := range patterns {
+		text = re.ReplaceAllStringFunc(text, func(match string) string {
+			// en "password=xxx" dejamos el nombre del campo
+			if sub := re.FindStringSubmatch(match); len(sub) == 4 {
+				return sub[1] + sub[2] + redacted
+			}
+			return redacted
+		})
+	}
+	return text
+}
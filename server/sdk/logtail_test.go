@@ -0,0 +1,240 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func appendLog(t *testing.T, path, text string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(text); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileTail(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps func(t *testing.T, path string) // escribe entre dos lecturas
+		want  []string
+	}{
+		{
+			name:  "appended lines",
+			steps: func(t *testing.T, path string) { appendLog(t, path, "a\nb\r\n") },
+			want:  []string{"a", "b"},
+		},
+		{
+			name: "a partial line waits for its newline",
+			steps: func(t *testing.T, path string) {
+				appendLog(t, path, "par")
+			},
+			want: nil,
+		},
+		{
+			name: "rotation: the rest of the old file, then the new one from the start",
+			steps: func(t *testing.T, path string) {
+				appendLog(t, path, "last old\n")
+				os.Rename(path, path+".1")
+				appendLog(t, path, "first new\n")
+			},
+			want: []string{"last old", "first new"},
+		},
+		{
+			name: "copytruncate",
+			steps: func(t *testing.T, path string) {
+				os.Truncate(path, 0)
+				appendLog(t, path, "after truncate\n")
+			},
+			want: []string{"after truncate"},
+		},
+		{
+			name: "removed and not created yet",
+			steps: func(t *testing.T, path string) {
+				os.Remove(path)
+			},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log")
+			// lo que ya estaba cuando arrancamos no se lee
+			appendLog(t, path, "old line that was there before\nanother one\n")
+
+			tail := &fileTail{path: path}
+			defer tail.close()
+			if err := tail.open(true); err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			read := func() {
+				if err := tail.readLines(func(line string) { got = append(got, line) }); err != nil {
+					t.Fatal(err)
+				}
+			}
+			read()
+			tt.steps(t, path)
+			read()
+
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("lines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileTailPartialLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	tail := &fileTail{path: path}
+	defer tail.close()
+
+	var got []string
+	read := func() {
+		tail.readLines(func(line string) { got = append(got, line) })
+	}
+
+	// el archivo todavia no existe: cuando aparece se lee desde el principio
+	read()
+	appendLog(t, path, "first\nsec")
+	read()
+	appendLog(t, path, "ond\n")
+	read()
+
+	if fmt.Sprint(got) != "[first second]" {
+		t.Errorf("lines = %q", got)
+	}
+}
+
+func TestSpikeDetector(t *testing.T) {
+	d, err := newSpikeDetector(LogWatch{Path: "app.log", Threshold: 3, Window: time.Minute, Samples: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+
+	steps := []struct {
+		second   int
+		line     string
+		wantFire bool
+	}{
+		{0, "ERROR db timeout", false},
+		{5, "GET /health 200 1ms", false},
+		{10, "panic: nil map", false},
+		{20, `GET /api 503 12ms user=ana@example.com`, true},
+		{25, "ERROR again", false}, // sigue en el mismo pico
+		{200, "INFO all good", false},
+		// la ventana se vacio: un pico nuevo vuelve a avisar
+		{210, "FATAL one", false},
+		{211, "FATAL two", false},
+		{212, "FATAL three token=abc123", true},
+	}
+
+	var last map[string]interface{}
+	for _, s := range steps {
+		data, fire := d.add(s.line, at(s.second))
+		if fire != s.wantFire {
+			t.Errorf("second %d %q: fire = %v, want %v", s.second, s.line, fire, s.wantFire)
+		}
+		if fire {
+			last = data
+		}
+	}
+
+	if last["matches"] != 3 || last["threshold"] != 3 || last["window_seconds"] != 60 || last["rate_per_minute"] != 3.0 {
+		t.Errorf("data = %v", last)
+	}
+	samples := last["samples"].([]string)
+	if len(samples) != 2 || samples[0] != "FATAL two" || samples[1] != "FATAL three token="+redacted {
+		t.Errorf("samples = %q", samples)
+	}
+}
+
+func TestSpikeDetectorRejectsBadPatterns(t *testing.T) {
+	tests := []LogWatch{
+		{},
+		{Path: "app.log", Patterns: []string{"("}},
+		{Path: "app.log", Redact: []string{"["}},
+	}
+	for _, watch := range tests {
+		if _, err := newSpikeDetector(watch); err == nil {
+			t.Errorf("accepted %+v", watch)
+		}
+	}
+}
+
+func TestRedactSamples(t *testing.T) {
+	d, err := newSpikeDetector(LogWatch{Path: "app.log", Redact: []string{`card=\d+`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{name: "password", line: "login failed password=hunter2 for user", want: "login failed password=" + redacted + " for user"},
+		{name: "quoted secret", line: `{"api_key": "sk_live_1"}`, want: `{"api_key": ` + redacted + `}`},
+		{name: "bearer header", line: "Authorization: Bearer abc.def", want: "Authorization: " + redacted},
+		{name: "jwt", line: "got eyJhbGciOi.eyJzdWIiOiIx.c2lnbmF0dXJl", want: "got " + redacted},
+		{name: "email", line: "ERROR sending to ana@example.com", want: "ERROR sending to " + redacted},
+		{name: "ipv4", line: "ERROR from 10.0.0.12", want: "ERROR from " + redacted},
+		{name: "long key", line: "ERROR key " + strings.Repeat("a1B2", 10), want: "ERROR key " + redacted},
+		{name: "extra pattern", line: "ERROR card=4111111111111111 declined", want: "ERROR " + redacted + " declined"},
+		{name: "long line is cut", line: strings.Repeat("x ", 400), want: truncate(strings.Repeat("x ", 400), maxSampleLineSize)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.redactLine(tt.line); got != tt.want {
+				t.Errorf("redactLine(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTailLogsReportsSpikes(t *testing.T) {
+	backend := newEventBackend(t)
+	a := newTestSDK(t, backend, ReporterConfig{BatchSize: 1, FlushInterval: 10 * time.Millisecond})
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendLog(t, path, "ERROR before we started\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- a.TailLogs(ctx, LogWatch{Path: path, Service: "api", Threshold: 3, PollInterval: 5 * time.Millisecond})
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	appendLog(t, path, "ERROR one\nINFO fine\nERROR two\n")
+	os.Rename(path, path+".1")
+	appendLog(t, path, "ERROR three password=x\n")
+
+	events := backend.waitEvents(t, 1)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("TailLogs returned %v", err)
+	}
+
+	if len(events) != 1 || events[0].Type != "error_spike" || events[0].Service != "api" || events[0].Data["matches"] != 3.0 {
+		t.Fatalf("events = %+v", events)
+	}
+	if samples := fmt.Sprint(events[0].Data["samples"]); strings.Contains(samples, "before we started") || strings.Contains(samples, "password=x") {
+		t.Errorf("samples = %s", samples)
+	}
+}
@@ -0,0 +1,181 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	models "server/model"
+	"time"
+)
+
+var errMetricsUnsupported = errors.New("host metrics are only collected on linux")
+
+// HostMetrics is one sample of the host
+type HostMetrics struct {
+	CPUPercent       float64              `json:"cpu_percent"`
+	MemoryPercent    float64              `json:"memory_percent"`
+	MemoryUsedBytes  uint64               `json:"memory_used_bytes"`
+	MemoryTotalBytes uint64               `json:"memory_total_bytes"`
+	Disks            map[string]DiskUsage `json:"disks"` // mount path -> usage
+	Load1            float64              `json:"load1"`
+	Load5            float64              `json:"load5"`
+	Load15           float64              `json:"load15"`
+	OpenFDs          uint64               `json:"open_fds"`
+	MaxFDs           uint64               `json:"max_fds"`
+	CollectedAt      time.Time            `json:"collected_at"`
+}
+
+type DiskUsage struct {
+	Percent    float64 `json:"percent"`
+	UsedBytes  uint64  `json:"used_bytes"`
+	TotalBytes uint64  `json:"total_bytes"`
+}
+
+// Threshold fires when a percentage stays at or above Percent for For (0 fires on the first sample)
+type Threshold struct {
+	Percent  float64
+	For      time.Duration
+	Severity string // default warning (critical for disks)
+}
+
+// MetricsConfig configures CollectMetrics; a zero Threshold is not checked
+type MetricsConfig struct {
+	Service   string        // reported as Event.Service, default "host"
+	Interval  time.Duration // default 15s
+	CPU       Threshold
+	Memory    Threshold
+	Disk      Threshold
+	DiskPaths []string          // mount points to watch, default "/"
+	OnSample  func(HostMetrics) // optional, sees every sample
+}
+
+// DefaultMetricsConfig is CPU 90% for 5 min, memory 90% for 5 min and disk 95%
+func DefaultMetricsConfig() MetricsConfig {
+	return MetricsConfig{
+		Service:   "host",
+		Interval:  15 * time.Second,
+		CPU:       Threshold{Percent: 90, For: 5 * time.Minute},
+		Memory:    Threshold{Percent: 90, For: 5 * time.Minute},
+		Disk:      Threshold{Percent: 95, Severity: models.SeverityCritical},
+		DiskPaths: []string{"/"},
+	}
+}
+
+// CollectMetrics samples the host every Interval and reports high_cpu, high_memory
+// and disk_full when a threshold is held for its duration. An event is sent once
+// per episode: the metric has to go back under the threshold to fire again.
+// Blocks until ctx is cancelled.
+func (a *AgentSDK) CollectMetrics(ctx context.Context, cfg MetricsConfig) error {
+	if cfg.Service == "" {
+		cfg.Service = "host"
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 15 * time.Second
+	}
+	if len(cfg.DiskPaths) == 0 {
+		cfg.DiskPaths = []string{"/"}
+	}
+
+	collector := newHostCollector(cfg.DiskPaths)
+	conditions := map[string]*sustained{}
+	condition := func(key string) *sustained {
+		if conditions[key] == nil {
+			conditions[key] = &sustained{}
+		}
+		return conditions[key]
+	}
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		sample, err := collector.collect()
+		switch {
+		case errors.Is(err, errMetricsUnsupported):
+			return err
+		case err != nil:
+			fmt.Printf("[SDK] no se pudieron leer las metricas del host: %v\n", err)
+		case !sample.CollectedAt.IsZero():
+			if cfg.OnSample != nil {
+				cfg.OnSample(sample)
+			}
+
+			base := map[string]interface{}{"load1": sample.Load1, "load5": sample.Load5, "load15": sample.Load15,
+				"open_fds": sample.OpenFDs, "max_fds": sample.MaxFDs}
+
+			if since, fire := condition("cpu").update(cfg.CPU, sample.CPUPercent, sample.CollectedAt); fire {
+				a.reportThreshold(cfg, models.EventHighCPU, cfg.CPU, models.SeverityWarning, since, sample.CollectedAt,
+					withData(base, "cpu_percent", sample.CPUPercent))
+			}
+			if since, fire := condition("memory").update(cfg.Memory, sample.MemoryPercent, sample.CollectedAt); fire {
+				a.reportThreshold(cfg, models.EventHighMemory, cfg.Memory, models.SeverityWarning, since, sample.CollectedAt,
+					withData(base, "memory_percent", sample.MemoryPercent, "memory_used_bytes", sample.MemoryUsedBytes, "memory_total_bytes", sample.MemoryTotalBytes))
+			}
+			for path, disk := range sample.Disks {
+				if since, fire := condition("disk:"+path).update(cfg.Disk, disk.Percent, sample.CollectedAt); fire {
+					a.reportThreshold(cfg, models.EventDiskFull, cfg.Disk, models.SeverityCritical, since, sample.CollectedAt,
+						withData(base, "path", path, "disk_percent", disk.Percent, "disk_used_bytes", disk.UsedBytes, "disk_total_bytes", disk.TotalBytes))
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *AgentSDK) reportThreshold(cfg MetricsConfig, eventType string, threshold Threshold, defaultSeverity string,
+	since, now time.Time, data map[string]interface{}) {
+
+	severity := threshold.Severity
+	if severity == "" {
+		severity = defaultSeverity
+	}
+	data["threshold_percent"] = threshold.Percent
+	data["sustained_seconds"] = int(now.Sub(since).Seconds())
+
+	a.Report(models.Event{Type: eventType, Service: cfg.Service, Severity: severity, Data: data})
+}
+
+// sustained tracks how long a value has been over its threshold
+type sustained struct {
+	since time.Time // zero while under the threshold
+	fired bool
+}
+
+// update returns true (once) when the value has been over the threshold long enough
+func (s *sustained) update(t Threshold, value float64, now time.Time) (time.Time, bool) {
+	if t.Percent <= 0 || value < t.Percent {
+		s.since, s.fired = time.Time{}, false
+		return time.Time{}, false
+	}
+	if s.since.IsZero() {
+		s.since = now
+	}
+	if s.fired || now.Sub(s.since) < t.For {
+		return s.since, false
+	}
+	s.fired = true
+	return s.since, true
+}
+
+func withData(base map[string]interface{}, kv ...interface{}) map[string]interface{} {
+	data := make(map[string]interface{}, len(base)+len(kv)/2+2)
+	for k, v := range base {
+		data[k] = v
+	}
+	for i := 0; i+1 < len(kv); i += 2 {
+		data[kv[i].(string)] = kv[i+1]
+	}
+	return data
+}
+
+func percent(part, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(total)
+}
@@ -0,0 +1,176 @@
+//go:build linux
+
+package sdk
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// procRoot is where procfs is mounted (a container can mount the host's elsewhere)
+var procRoot = "/proc"
+
+// hostCollector reads /proc and statfs; CPU usage is the delta between two samples
+type hostCollector struct {
+	diskPaths []string
+	prevIdle  uint64
+	prevTotal uint64
+}
+
+func newHostCollector(diskPaths []string) *hostCollector {
+	return &hostCollector{diskPaths: diskPaths}
+}
+
+// collect returns a zero sample (no error) the first time, while there is no CPU delta yet
+func (c *hostCollector) collect() (HostMetrics, error) {
+	var m HostMetrics
+
+	idle, total, err := readCPUTimes()
+	if err != nil {
+		return m, err
+	}
+	first := c.prevTotal == 0
+	deltaIdle, deltaTotal := idle-c.prevIdle, total-c.prevTotal
+	c.prevIdle, c.prevTotal = idle, total
+	if first || deltaTotal == 0 {
+		return m, nil
+	}
+	m.CPUPercent = percent(deltaTotal-deltaIdle, deltaTotal)
+
+	if err := readMemory(&m); err != nil {
+		return m, err
+	}
+	if err := readLoad(&m); err != nil {
+		return m, err
+	}
+	if err := readFDs(&m); err != nil {
+		return m, err
+	}
+
+	m.Disks = make(map[string]DiskUsage, len(c.diskPaths))
+	for _, path := range c.diskPaths {
+		var fs syscall.Statfs_t
+		if err := syscall.Statfs(path, &fs); err != nil {
+			return m, fmt.Errorf("statfs %s: %w", path, err)
+		}
+		total := fs.Blocks * uint64(fs.Bsize)
+		// lo que ve df: usado sobre (usado + disponible para usuarios comunes)
+		used := (fs.Blocks - fs.Bfree) * uint64(fs.Bsize)
+		avail := fs.Bavail * uint64(fs.Bsize)
+		m.Disks[path] = DiskUsage{Percent: percent(used, used+avail), UsedBytes: used, TotalBytes: total}
+	}
+
+	m.CollectedAt = time.Now()
+	return m, nil
+}
+
+// readCPUTimes returns the idle (idle + iowait) and total jiffies of the "cpu" line of /proc/stat
+func readCPUTimes() (idle, total uint64, err error) {
+	f, err := os.Open(filepath.Join(procRoot, "stat"))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+		// user nice system idle iowait irq softirq steal (guest ya esta sumado en user)
+		for i, field := range fields[1:min(len(fields), 9)] {
+			v, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("parse /proc/stat: %w", err)
+			}
+			total += v
+			if i == 3 || i == 4 {
+				idle += v
+			}
+		}
+		return idle, total, nil
+	}
+	return 0, 0, fmt.Errorf("no cpu line in /proc/stat")
+}
+
+func readMemory(m *HostMetrics) error {
+	f, err := os.Open(filepath.Join(procRoot, "meminfo"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	values := map[string]uint64{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[strings.TrimSuffix(fields[0], ":")] = v * 1024 // kB
+		}
+	}
+
+	total, ok := values["MemTotal"]
+	if !ok {
+		return fmt.Errorf("no MemTotal in /proc/meminfo")
+	}
+	available, ok := values["MemAvailable"]
+	if !ok { // kernels viejos
+		available = values["MemFree"] + values["Buffers"] + values["Cached"]
+	}
+
+	m.MemoryTotalBytes = total
+	m.MemoryUsedBytes = total - min(available, total)
+	m.MemoryPercent = percent(m.MemoryUsedBytes, total)
+	return nil
+}
+
+func readLoad(m *HostMetrics) error {
+	raw, err := os.ReadFile(filepath.Join(procRoot, "loadavg"))
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(string(raw))
+	if len(fields) < 3 {
+		return fmt.Errorf("unexpected /proc/loadavg: %q", raw)
+	}
+	loads := []*float64{&m.Load1, &m.Load5, &m.Load15}
+	for i, load := range loads {
+		if *load, err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return fmt.Errorf("parse /proc/loadavg: %w", err)
+		}
+	}
+	return nil
+}
+
+// readFDs reads the file handles of the whole host from /proc/sys/fs/file-nr
+// ("allocated unused max")
+func readFDs(m *HostMetrics) error {
+	raw, err := os.ReadFile(filepath.Join(procRoot, "sys", "fs", "file-nr"))
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(string(raw))
+	if len(fields) < 3 {
+		return fmt.Errorf("unexpected /proc/sys/fs/file-nr: %q", raw)
+	}
+	allocated, err1 := strconv.ParseUint(fields[0], 10, 64)
+	unused, err2 := strconv.ParseUint(fields[1], 10, 64)
+	maxFDs, err3 := strconv.ParseUint(fields[2], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return fmt.Errorf("unexpected /proc/sys/fs/file-nr: %q", raw)
+	}
+
+	m.OpenFDs = allocated - min(unused, allocated)
+	m.MaxFDs = maxFDs
+	return nil
+}
@@ -0,0 +1,143 @@
+//go:build linux
+
+package sdk
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeProc arma un procfs en un directorio temporal y apunta procRoot ahi
+func fakeProc(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	root := t.TempDir()
+	for name, content := range files {
+		writeProc(t, root, name, content)
+	}
+	prev := procRoot
+	procRoot = root
+	t.Cleanup(func() { procRoot = prev })
+	return root
+}
+
+func writeProc(t *testing.T, root, name, content string) {
+	t.Helper()
+	path := filepath.Join(root, name)
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func near(a, b float64) bool { return math.Abs(a-b) < 0.01 }
+
+const testMeminfo = `MemTotal:       16000000 kB
+MemFree:         1000000 kB
+MemAvailable:    4000000 kB
+Buffers:          500000 kB
+Cached:          3000000 kB
+`
+
+func TestHostCollector(t *testing.T) {
+	root := fakeProc(t, map[string]string{
+		// user nice system idle iowait irq softirq steal guest guest_nice
+		"stat":           "cpu  100 0 100 700 100 0 0 0 50 0\ncpu0 50 0 50 350 50 0 0 0 25 0\nintr 12345\n",
+		"meminfo":        testMeminfo,
+		"loadavg":        "1.50 0.75 0.25 2/345 6789\n",
+		"sys/fs/file-nr": "3072\t72\t100000\n",
+	})
+
+	c := newHostCollector([]string{"/"})
+	first, err := c.collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sin delta de CPU todavia no hay muestra
+	if !first.CollectedAt.IsZero() {
+		t.Fatalf("first sample = %+v, want zero", first)
+	}
+
+	// +300 de user, +100 de idle, +100 de iowait: 60% ocupado
+	writeProc(t, root, "stat", "cpu  400 0 100 800 200 0 0 0 300 0\n")
+	m, err := c.collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !near(m.CPUPercent, 60) {
+		t.Errorf("cpu = %.2f%%, want 60%% (guest is already in user)", m.CPUPercent)
+	}
+	if m.MemoryTotalBytes != 16000000*1024 || m.MemoryUsedBytes != 12000000*1024 || !near(m.MemoryPercent, 75) {
+		t.Errorf("memory = %d/%d (%.2f%%)", m.MemoryUsedBytes, m.MemoryTotalBytes, m.MemoryPercent)
+	}
+	if m.Load1 != 1.5 || m.Load5 != 0.75 || m.Load15 != 0.25 {
+		t.Errorf("load = %v %v %v", m.Load1, m.Load5, m.Load15)
+	}
+	if m.OpenFDs != 3000 || m.MaxFDs != 100000 {
+		t.Errorf("fds = %d/%d", m.OpenFDs, m.MaxFDs)
+	}
+	if disk, ok := m.Disks["/"]; !ok || disk.TotalBytes == 0 || disk.Percent < 0 || disk.Percent > 100 {
+		t.Errorf("disks = %+v", m.Disks)
+	}
+	if m.CollectedAt.IsZero() {
+		t.Error("no collected_at")
+	}
+}
+
+func TestReadMemoryWithoutMemAvailable(t *testing.T) {
+	// kernels viejos: libre = MemFree + Buffers + Cached
+	fakeProc(t, map[string]string{"meminfo": "MemTotal: 1000 kB\nMemFree: 100 kB\nBuffers: 100 kB\nCached: 300 kB\n"})
+
+	var m HostMetrics
+	if err := readMemory(&m); err != nil {
+		t.Fatal(err)
+	}
+	if m.MemoryUsedBytes != 500*1024 || !near(m.MemoryPercent, 50) {
+		t.Errorf("used = %d (%.2f%%)", m.MemoryUsedBytes, m.MemoryPercent)
+	}
+}
+
+func TestHostCollectorMalformedProc(t *testing.T) {
+	valid := map[string]string{
+		"stat":           "cpu  100 0 100 700 100 0 0 0\n",
+		"meminfo":        testMeminfo,
+		"loadavg":        "0.10 0.20 0.30 1/100 42\n",
+		"sys/fs/file-nr": "100 0 1000\n",
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		first   bool // falla ya en la primera lectura (la de /proc/stat)
+	}{
+		{name: "no cpu line", file: "stat", content: "cpu0 1 2 3 4 5\n", first: true},
+		{name: "bad cpu value", file: "stat", content: "cpu  1 x 3 4 5\n", first: true},
+		{name: "no MemTotal", file: "meminfo", content: "MemFree: 100 kB\n"},
+		{name: "short loadavg", file: "loadavg", content: "0.10\n"},
+		{name: "bad loadavg", file: "loadavg", content: "a b c\n"},
+		{name: "short file-nr", file: "sys/fs/file-nr", content: "100\n"},
+		{name: "bad file-nr", file: "sys/fs/file-nr", content: "100 x 1000\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := fakeProc(t, valid)
+			c := newHostCollector([]string{"/"})
+			if !tt.first {
+				if _, err := c.collect(); err != nil {
+					t.Fatal(err)
+				}
+				writeProc(t, root, "stat", "cpu  200 0 200 800 100 0 0 0\n")
+			}
+			writeProc(t, root, tt.file, tt.content)
+
+			if _, err := c.collect(); err == nil {
+				t.Error("collected a malformed /proc")
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package sdk
+
+type hostCollector struct{}
+
+func newHostCollector(diskPaths []string) *hostCollector {
+	return &hostCollector{}
+}
+
+func (c *hostCollector) collect() (HostMetrics, error) {
+	return HostMetrics{}, errMetricsUnsupported
+}
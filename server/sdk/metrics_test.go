@@ -0,0 +1,85 @@
+package sdk
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSustained(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	type sample struct {
+		minute   int
+		value    float64
+		wantFire bool
+	}
+	tests := []struct {
+		name      string
+		threshold Threshold
+		samples   []sample
+	}{
+		{
+			name:      "fires once it held for the duration",
+			threshold: Threshold{Percent: 90, For: 5 * time.Minute},
+			samples:   []sample{{0, 95, false}, {3, 92, false}, {5, 91, true}, {6, 99, false}, {10, 99, false}},
+		},
+		{
+			name:      "a dip restarts the count",
+			threshold: Threshold{Percent: 90, For: 5 * time.Minute},
+			samples:   []sample{{0, 95, false}, {3, 50, false}, {5, 95, false}, {9, 95, false}, {10, 95, true}},
+		},
+		{
+			name:      "fires again after going back under",
+			threshold: Threshold{Percent: 90},
+			samples:   []sample{{0, 95, true}, {1, 96, false}, {2, 80, false}, {3, 91, true}},
+		},
+		{
+			name:      "at the threshold counts",
+			threshold: Threshold{Percent: 90},
+			samples:   []sample{{0, 90, true}},
+		},
+		{
+			name:      "zero threshold is off",
+			threshold: Threshold{},
+			samples:   []sample{{0, 100, false}, {10, 100, false}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s sustained
+			for _, sm := range tt.samples {
+				if _, fire := s.update(tt.threshold, sm.value, at(sm.minute)); fire != sm.wantFire {
+					t.Errorf("minute %d (%.0f%%): fire = %v, want %v", sm.minute, sm.value, fire, sm.wantFire)
+				}
+			}
+		})
+	}
+}
+
+func TestReportThreshold(t *testing.T) {
+	backend := newEventBackend(t)
+	a := newTestSDK(t, backend, ReporterConfig{FlushInterval: time.Hour})
+
+	now := time.Now()
+	cfg := MetricsConfig{Service: "web-1"}
+	a.reportThreshold(cfg, "high_cpu", Threshold{Percent: 90}, "warning", now.Add(-5*time.Minute), now, map[string]interface{}{"cpu_percent": 97.5})
+	a.reportThreshold(cfg, "disk_full", Threshold{Percent: 95, Severity: "info"}, "critical", now, now, map[string]interface{}{})
+	if err := a.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	events := backend.events()
+	if len(events) != 2 {
+		t.Fatalf("events = %+v", events)
+	}
+	cpu := events[0]
+	if cpu.Service != "web-1" || cpu.Severity != "warning" || cpu.Data["threshold_percent"] != 90.0 || cpu.Data["sustained_seconds"] != 300.0 || cpu.Data["cpu_percent"] != 97.5 {
+		t.Errorf("cpu event = %+v", cpu)
+	}
+	if events[1].Severity != "info" {
+		t.Errorf("the configured severity wins: %+v", events[1])
+	}
+}
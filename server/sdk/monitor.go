@@ -0,0 +1,94 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	models "server/model"
+	"time"
+)
+
+// MonitorConfig is one service watched by Monitor
+type MonitorConfig struct {
+	Service          string        // reported as Event.Service, e.g. "api"
+	Check            HealthCheck   // defaults to HTTPHealthCheck(HealthURL), then to the checks registered for Service
+	HealthURL        string        // e.g. "http://localhost:8080/health"
+	Interval         time.Duration // default 30s
+	Timeout          time.Duration // per check, default 5s
+	FailureThreshold int           // failed checks in a row before app_down, default 1
+}
+
+// Monitor runs the health check every Interval and reports app_down when the
+// service goes down and app_recovered when it comes back (only on transitions,
+// not on every check). Blocks until ctx is cancelled.
+func (a *AgentSDK) Monitor(ctx context.Context, cfg MonitorConfig) error {
+	if cfg.Check == nil {
+		switch {
+		case cfg.HealthURL != "":
+			cfg.Check = HTTPHealthCheck(cfg.HealthURL)
+		case a.hasHealthChecks(cfg.Service):
+			cfg.Check = func(ctx context.Context) error { return failedChecks(a.RunHealthChecks(ctx, cfg.Service)) }
+		default:
+			return fmt.Errorf("monitor %q: needs a Check, a HealthURL or registered health checks", cfg.Service)
+		}
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 1
+	}
+
+	fmt.Printf("[SDK] monitoreando %s cada %s\n", cfg.Service, cfg.Interval)
+
+	var (
+		failures  int
+		down      bool
+		downSince time.Time
+	)
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		checkCtx, cancel := context.WithTimeout(a.checkContext(ctx), cfg.Timeout)
+		err := cfg.Check(checkCtx)
+		cancel()
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		switch {
+		case err != nil:
+			failures++
+			if !down && failures >= cfg.FailureThreshold {
+				down, downSince = true, time.Now()
+				a.Report(models.Event{
+					Type:     models.EventAppDown,
+					Service:  cfg.Service,
+					Severity: models.SeverityCritical,
+					Data:     map[string]interface{}{"error": err.Error(), "consecutive_failures": failures},
+				})
+			}
+		case down:
+			a.Report(models.Event{
+				Type:     models.EventAppRecovered,
+				Service:  cfg.Service,
+				Severity: models.SeverityInfo,
+				Data:     map[string]interface{}{"downtime_seconds": int(time.Since(downSince).Seconds())},
+			})
+			failures, down = 0, false
+		default:
+			failures = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMonitorReportsTransitions(t *testing.T) {
+	down := errors.New("connection refused")
+
+	tests := []struct {
+		name      string
+		results   []error // resultado de cada check, en orden; despues quedan sanos
+		threshold int
+		want      []string
+	}{
+		{name: "healthy", results: []error{nil, nil, nil}},
+		{name: "down and back", results: []error{nil, down, nil}, want: []string{"app_down", "app_recovered"}},
+		{name: "reported once while down", results: []error{down, down, down, down}, want: []string{"app_down", "app_recovered"}},
+		{name: "below the threshold", results: []error{down, nil, down, nil}, threshold: 2},
+		{name: "at the threshold", results: []error{down, down, nil}, threshold: 2, want: []string{"app_down", "app_recovered"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newEventBackend(t)
+			a := newTestSDK(t, backend, ReporterConfig{FlushInterval: time.Hour})
+
+			var mu sync.Mutex
+			checks := 0
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			check := func(context.Context) error {
+				mu.Lock()
+				defer mu.Unlock()
+				checks++
+				if checks > len(tt.results)+2 {
+					cancel()
+				}
+				if checks <= len(tt.results) {
+					return tt.results[checks-1]
+				}
+				return nil
+			}
+
+			err := a.Monitor(ctx, MonitorConfig{Service: "api", Check: check, Interval: time.Millisecond, FailureThreshold: tt.threshold})
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("Monitor returned %v", err)
+			}
+			if err := a.Flush(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, e := range backend.events() {
+				if e.Service != "api" {
+					t.Errorf("event for %q", e.Service)
+				}
+				got = append(got, e.Type)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("events = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonitorNeedsACheck(t *testing.T) {
+	a := NewSDK("key", "http://127.0.0.1:1", "secret")
+
+	err := a.Monitor(context.Background(), MonitorConfig{Service: "api"})
+	if err == nil || !strings.Contains(err.Error(), "needs a Check") {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,296 @@
+package sdk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	models "server/model"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// AnyTarget is the policy entry used for targets that don't have their own
+const AnyTarget = "*"
+
+// ErrPolicyDenied is returned when the local policy refuses a decision
+var ErrPolicyDenied = errors.New("denied by local policy")
+
+// Policy is enforced by the SDK before running any action, whatever the backend
+// sent, so a compromised backend still can't do more than the client allowed.
+// Targets not listed (and without a "*" entry) are denied.
+//
+//	targets:
+//	  api:
+//	    allowed_actions: [restart, scale]
+//	    max_per_hour: {restart: 3}
+//	    params:
+//	      replicas: {min: 1, max: 10}
+//	  "*":
+//	    allowed_actions: [notify]
+//	blackouts:
+//	  - {days: [fri, sat], start: "22:00", end: "06:00", timezone: America/Argentina/Buenos_Aires}
+type Policy struct {
+	Targets   map[string]TargetPolicy `yaml:"targets"`
+	Blackouts []Blackout              `yaml:"blackouts"` // apply to every target
+}
+
+// TargetPolicy is what may run on one target
+type TargetPolicy struct {
+	AllowedActions []string               `yaml:"allowed_actions"` // empty allows any action
+	MaxPerHour     map[string]int         `yaml:"max_per_hour"`    // action -> executions in the last hour
+	Params         map[string]ParamBounds `yaml:"params"`          // param -> bounds, for any action that sends it
+	Blackouts      []Blackout             `yaml:"blackouts"`
+}
+
+// ParamBounds limits one decision parameter: numbers to [Min, Max], strings to Allowed
+type ParamBounds struct {
+	Min     *float64 `yaml:"min"`
+	Max     *float64 `yaml:"max"`
+	Allowed []string `yaml:"allowed"`
+}
+
+// Blackout is a daily window where nothing (or only Actions) may run. End before
+// Start wraps past midnight.
+type Blackout struct {
+	Days     []string `yaml:"days"`  // "mon".."sun", empty is every day
+	Start    string   `yaml:"start"` // "HH:MM"
+	End      string   `yaml:"end"`   // "HH:MM"
+	Timezone string   `yaml:"timezone"`
+	Actions  []string `yaml:"actions"` // empty blocks every action
+
+	start, end int // minutes since midnight
+	loc        *time.Location
+}
+
+// LoadPolicy reads a YAML policy file
+func LoadPolicy(path string) (*Policy, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policy Policy
+	if err := yaml.Unmarshal(raw, &policy); err != nil {
+		return nil, fmt.Errorf("policy %s: %w", path, err)
+	}
+	return &policy, nil
+}
+
+// SetPolicy validates and enables the local policy (nil turns it off)
+func (a *AgentSDK) SetPolicy(policy *Policy) error {
+	if policy != nil {
+		if err := policy.compile(); err != nil {
+			return err
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.policy = policy
+	return nil
+}
+
+func (p *Policy) compile() error {
+	for i := range p.Blackouts {
+		if err := p.Blackouts[i].compile(); err != nil {
+			return err
+		}
+	}
+	for target, tp := range p.Targets {
+		for i := range tp.Blackouts {
+			if err := tp.Blackouts[i].compile(); err != nil {
+				return fmt.Errorf("target %s: %w", target, err)
+			}
+		}
+		for param, bounds := range tp.Params {
+			if bounds.Min != nil && bounds.Max != nil && *bounds.Min > *bounds.Max {
+				return fmt.Errorf("target %s: param %s: min is greater than max", target, param)
+			}
+		}
+	}
+	return nil
+}
+
+func (b *Blackout) compile() error {
+	var err error
+	if b.start, err = parseClock(b.Start); err != nil {
+		return fmt.Errorf("blackout start: %w", err)
+	}
+	if b.end, err = parseClock(b.End); err != nil {
+		return fmt.Errorf("blackout end: %w", err)
+	}
+	b.loc = time.Local
+	if b.Timezone != "" {
+		if b.loc, err = time.LoadLocation(b.Timezone); err != nil {
+			return fmt.Errorf("blackout timezone: %w", err)
+		}
+	}
+	for _, day := range b.Days {
+		if _, ok := weekdays[strings.ToLower(day)]; !ok {
+			return fmt.Errorf("blackout: unknown day %q", day)
+		}
+	}
+	return nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// active reports whether now falls in the window for action. A window that wraps
+// past midnight belongs to the day it started.
+func (b *Blackout) active(action string, now time.Time) bool {
+	if len(b.Actions) > 0 && !slices.Contains(b.Actions, action) {
+		return false
+	}
+
+	now = now.In(b.loc)
+	minute := now.Hour()*60 + now.Minute()
+	day := now.Weekday()
+
+	switch {
+	case b.start <= b.end:
+		if minute < b.start || minute >= b.end {
+			return false
+		}
+	case minute >= b.start:
+	case minute < b.end:
+		day = (day + 6) % 7 // de madrugada: la ventana empezo ayer
+	default:
+		return false
+	}
+
+	if len(b.Days) == 0 {
+		return true
+	}
+	for _, d := range b.Days {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// check decides whether the decision may run now. executions is how many times
+// the action ran on the target in the last hour.
+func (p *Policy) check(decision models.LLMDecision, executions int, now time.Time) error {
+	tp, ok := p.Targets[decision.Target]
+	if !ok {
+		if tp, ok = p.Targets[AnyTarget]; !ok {
+			return fmt.Errorf("%w: target %q is not in the policy", ErrPolicyDenied, decision.Target)
+		}
+	}
+
+	if len(tp.AllowedActions) > 0 && !slices.Contains(tp.AllowedActions, decision.Action) {
+		return fmt.Errorf("%w: action %q is not allowed on %q", ErrPolicyDenied, decision.Action, decision.Target)
+	}
+
+	for _, windows := range [][]Blackout{p.Blackouts, tp.Blackouts} {
+		for i := range windows {
+			if windows[i].active(decision.Action, now) {
+				return fmt.Errorf("%w: blackout window %s-%s", ErrPolicyDenied, windows[i].Start, windows[i].End)
+			}
+		}
+	}
+
+	if limit, ok := tp.MaxPerHour[decision.Action]; ok && executions >= limit {
+		return fmt.Errorf("%w: %q already ran %d times on %q in the last hour (max %d)", ErrPolicyDenied, decision.Action, executions, decision.Target, limit)
+	}
+
+	for param, bounds := range tp.Params {
+		value, ok := decision.Params[param]
+		if !ok {
+			continue
+		}
+		if err := bounds.check(value); err != nil {
+			return fmt.Errorf("%w: param %s: %v", ErrPolicyDenied, param, err)
+		}
+	}
+
+	return nil
+}
+
+func (b ParamBounds) check(value interface{}) error {
+	switch v := value.(type) {
+	case float64:
+		return b.checkNumber(v)
+	case int:
+		return b.checkNumber(float64(v))
+	case string:
+		if len(b.Allowed) > 0 && !slices.Contains(b.Allowed, v) {
+			return fmt.Errorf("%q is not one of %v", v, b.Allowed)
+		}
+		if b.Min != nil || b.Max != nil {
+			return fmt.Errorf("%q is not a number", v)
+		}
+		return nil
+	default:
+		if b.Min != nil || b.Max != nil || len(b.Allowed) > 0 {
+			return fmt.Errorf("unexpected value %v", v)
+		}
+		return nil
+	}
+}
+
+func (b ParamBounds) checkNumber(v float64) error {
+	if b.Min != nil && v < *b.Min {
+		return fmt.Errorf("%v is below the minimum %v", v, *b.Min)
+	}
+	if b.Max != nil && v > *b.Max {
+		return fmt.Errorf("%v is above the maximum %v", v, *b.Max)
+	}
+	if len(b.Allowed) > 0 {
+		return fmt.Errorf("%v is not one of %v", v, b.Allowed)
+	}
+	return nil
+}
+
+// enforcePolicy checks the decision against the local policy and, if it may run,
+// counts it for max_per_hour
+func (a *AgentSDK) enforcePolicy(decision models.LLMDecision) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.policy == nil {
+		return nil
+	}
+
+	now := time.Now()
+	key := decision.Target + "/" + decision.Action
+	recent := a.executions[key][:0]
+	for _, at := range a.executions[key] {
+		if now.Sub(at) < time.Hour {
+			recent = append(recent, at)
+		}
+	}
+
+	if err := a.policy.check(decision, len(recent), now); err != nil {
+		a.executions[key] = recent
+		return err
+	}
+
+	a.executions[key] = append(recent, now)
+	return nil
+}
+
+// forgetExecution undoes the count of enforcePolicy when the action didn't run after all
+func (a *AgentSDK) forgetExecution(decision models.LLMDecision) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := decision.Target + "/" + decision.Action
+	if n := len(a.executions[key]); n > 0 {
+		a.executions[key] = a.executions[key][:n-1]
+	}
+}
@@ -0,0 +1,177 @@
+package sdk
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	models "server/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+func float(v float64) *float64 { return &v }
+
+func TestPolicyCheck(t *testing.T) {
+	policy := &Policy{
+		Targets: map[string]TargetPolicy{
+			"api": {
+				AllowedActions: []string{"restart", "scale", "rollback"},
+				MaxPerHour:     map[string]int{"restart": 3},
+				Params: map[string]ParamBounds{
+					"replicas": {Min: float(1), Max: float(10)},
+					"version":  {Allowed: []string{"v1", "v2"}},
+				},
+				Blackouts: []Blackout{{Start: "12:00", End: "13:00", Actions: []string{"rollback"}, Timezone: "UTC"}},
+			},
+			AnyTarget: {AllowedActions: []string{"notify"}},
+		},
+		// viernes y sabado de 22 a 6 (hora de Buenos Aires, UTC-3)
+		Blackouts: []Blackout{{Days: []string{"fri", "sat"}, Start: "22:00", End: "06:00", Timezone: "America/Argentina/Buenos_Aires"}},
+	}
+	if err := policy.compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	thursdayNoon := time.Date(2026, 10, 15, 15, 30, 0, 0, time.UTC) // 12:30 en Buenos Aires
+
+	tests := []struct {
+		name       string
+		decision   models.LLMDecision
+		executions int
+		now        time.Time
+		wantErr    string
+	}{
+		{name: "allowed", decision: models.LLMDecision{Action: "restart", Target: "api"}, now: thursdayNoon},
+		{name: "action not allowed", decision: models.LLMDecision{Action: "stop", Target: "api"}, now: thursdayNoon, wantErr: `action "stop" is not allowed`},
+		{name: "falls back to *", decision: models.LLMDecision{Action: "notify", Target: "web"}, now: thursdayNoon},
+		{name: "* limits the actions", decision: models.LLMDecision{Action: "restart", Target: "web"}, now: thursdayNoon, wantErr: "not allowed"},
+		{name: "under max per hour", decision: models.LLMDecision{Action: "restart", Target: "api"}, executions: 2, now: thursdayNoon},
+		{name: "at max per hour", decision: models.LLMDecision{Action: "restart", Target: "api"}, executions: 3, now: thursdayNoon, wantErr: "max 3"},
+		{name: "param in bounds", decision: models.LLMDecision{Action: "scale", Target: "api", Params: map[string]interface{}{"replicas": 4.0}}, now: thursdayNoon},
+		{name: "param below min", decision: models.LLMDecision{Action: "scale", Target: "api", Params: map[string]interface{}{"replicas": 0.0}}, now: thursdayNoon, wantErr: "below the minimum"},
+		{name: "param above max", decision: models.LLMDecision{Action: "scale", Target: "api", Params: map[string]interface{}{"replicas": 50.0}}, now: thursdayNoon, wantErr: "above the maximum"},
+		{name: "string for a number", decision: models.LLMDecision{Action: "scale", Target: "api", Params: map[string]interface{}{"replicas": "50"}}, now: thursdayNoon, wantErr: "not a number"},
+		{name: "allowed value", decision: models.LLMDecision{Action: "rollback", Target: "api", Params: map[string]interface{}{"version": "v1"}}, now: thursdayNoon.Add(-4 * time.Hour)},
+		{name: "value not allowed", decision: models.LLMDecision{Action: "rollback", Target: "api", Params: map[string]interface{}{"version": "v9"}}, now: thursdayNoon.Add(-4 * time.Hour), wantErr: "not one of"},
+		{name: "target blackout for the action", decision: models.LLMDecision{Action: "rollback", Target: "api"}, now: time.Date(2026, 10, 15, 12, 30, 0, 0, time.UTC), wantErr: "blackout window 12:00-13:00"},
+		{name: "target blackout for other actions", decision: models.LLMDecision{Action: "restart", Target: "api"}, now: time.Date(2026, 10, 15, 12, 30, 0, 0, time.UTC)},
+		{name: "friday night", decision: models.LLMDecision{Action: "restart", Target: "api"}, now: time.Date(2026, 10, 17, 2, 0, 0, 0, time.UTC), wantErr: "blackout window 22:00-06:00"},
+		// sabado 3am: la ventana empezo el viernes
+		{name: "after midnight belongs to friday", decision: models.LLMDecision{Action: "restart", Target: "api"}, now: time.Date(2026, 10, 17, 6, 0, 0, 0, time.UTC), wantErr: "blackout"},
+		// lunes 3am: la ventana del domingo no existe
+		{name: "after midnight on monday", decision: models.LLMDecision{Action: "restart", Target: "api"}, now: time.Date(2026, 10, 19, 6, 0, 0, 0, time.UTC)},
+		{name: "thursday night", decision: models.LLMDecision{Action: "restart", Target: "api"}, now: time.Date(2026, 10, 16, 2, 0, 0, 0, time.UTC)},
+		{name: "friday before the window", decision: models.LLMDecision{Action: "restart", Target: "api"}, now: time.Date(2026, 10, 17, 0, 59, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.check(tt.decision, tt.executions, tt.now)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrPolicyDenied) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicyWithoutTargetsDeniesEverything(t *testing.T) {
+	policy := &Policy{}
+	err := policy.check(models.LLMDecision{Action: "restart", Target: "api"}, 0, time.Now())
+	if !errors.Is(err, ErrPolicyDenied) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestSetPolicyValidates(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+	}{
+		{name: "bad clock", policy: Policy{Blackouts: []Blackout{{Start: "25:00", End: "06:00"}}}},
+		{name: "bad day", policy: Policy{Blackouts: []Blackout{{Days: []string{"friday"}, Start: "22:00", End: "06:00"}}}},
+		{name: "bad timezone", policy: Policy{Blackouts: []Blackout{{Start: "22:00", End: "06:00", Timezone: "Mars/Olympus"}}}},
+		{name: "bad target blackout", policy: Policy{Targets: map[string]TargetPolicy{"api": {Blackouts: []Blackout{{Start: "x", End: "06:00"}}}}}},
+		{name: "min over max", policy: Policy{Targets: map[string]TargetPolicy{"api": {Params: map[string]ParamBounds{"replicas": {Min: float(5), Max: float(1)}}}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewSDK("key", "http://127.0.0.1:1", "secret")
+			if err := a.SetPolicy(&tt.policy); err == nil {
+				t.Error("accepted an invalid policy")
+			}
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	os.WriteFile(path, []byte(`
+targets:
+  api:
+    allowed_actions: [restart, scale]
+    max_per_hour: {restart: 2}
+    params:
+      replicas: {min: 1, max: 10}
+blackouts:
+  - {days: [fri], start: "22:00", end: "06:00", timezone: UTC}
+`), 0o600)
+
+	policy, err := LoadPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := policy.Targets["api"]
+	if len(api.AllowedActions) != 2 || api.MaxPerHour["restart"] != 2 || *api.Params["replicas"].Max != 10 || policy.Blackouts[0].Start != "22:00" {
+		t.Errorf("policy = %+v", policy)
+	}
+
+	os.WriteFile(path, []byte("targets: [not, a, map]"), 0o600)
+	if _, err := LoadPolicy(path); err == nil {
+		t.Error("loaded an invalid policy")
+	}
+}
+
+func TestDispatchEnforcesPolicy(t *testing.T) {
+	a := NewSDK("key", "http://127.0.0.1:1", "secret")
+	runs := 0
+	a.On("restart", func(target string, params map[string]interface{}) error {
+		runs++
+		return nil
+	})
+	if err := a.SetPolicy(&Policy{Targets: map[string]TargetPolicy{"api": {MaxPerHour: map[string]int{"restart": 2}}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		id         string
+		target     string
+		wantStatus int
+	}{
+		{id: "a1", target: "api", wantStatus: http.StatusOK},
+		{id: "a2", target: "api", wantStatus: http.StatusOK},
+		{id: "a3", target: "api", wantStatus: http.StatusForbidden}, // tercera en la hora
+		{id: "a4", target: "db", wantStatus: http.StatusForbidden},  // no esta en la politica
+	}
+
+	for _, tt := range tests {
+		status, body := a.dispatch(models.ActionEnvelope{ActionID: tt.id, Decision: models.LLMDecision{Action: "restart", Target: tt.target, Confidence: 0.99}})
+		if status != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d (%v)", tt.id, status, tt.wantStatus, body)
+		}
+		if status == http.StatusForbidden && body["reason"] != "local_policy" {
+			t.Errorf("%s: body = %v", tt.id, body)
+		}
+	}
+	if runs != 2 {
+		t.Errorf("ran %d times, want 2", runs)
+	}
+}
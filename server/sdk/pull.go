@@ -0,0 +1,137 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	models "server/model"
+	"time"
+)
+
+const (
+	pullWait       = 30 * time.Second // how long the backend holds each long-poll
+	pullMaxBackoff = 30 * time.Second
+)
+
+// RunPull fetches commands from the backend instead of exposing a webhook, for
+// hosts behind NAT/firewalls (register the client with delivery_mode "pull").
+// Blocks until ctx is cancelled.
+func (a *AgentSDK) RunPull(ctx context.Context) error {
+	fmt.Println("[SDK] esperando comandos del backend (modo pull)")
+	go a.announce(ctx)
+
+	backoff := time.Second
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		commands, err := a.pollCommands(ctx)
+		if err != nil {
+			fmt.Printf("[SDK] error pidiendo comandos: %v (reintento en %s)\n", err, backoff)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, pullMaxBackoff)
+			continue
+		}
+		backoff = time.Second
+
+		for _, cmd := range commands {
+			// sin ack el backend nos lo vuelve a mandar, no lo ejecutamos
+			if err := a.ackCommand(ctx, cmd.ActionID); err != nil {
+				fmt.Printf("[SDK] no se pudo confirmar el comando %s: %v\n", cmd.ActionID, err)
+				continue
+			}
+			go a.runPulled(cmd)
+		}
+	}
+}
+
+func (a *AgentSDK) pollCommands(ctx context.Context) ([]models.PendingCommand, error) {
+	ctx, cancel := context.WithTimeout(ctx, pullWait+15*time.Second)
+	defer cancel()
+
+	url := fmt.Sprintf("%s/v1/commands?wait=%d", a.backendURL, int(pullWait.Seconds()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	a.authorize(req)
+
+	// el long-poll dura mas que el Timeout del cliente: lo corta el contexto
+	resp, err := untimed(a.httpClient).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("backend answered %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Commands []models.PendingCommand `json:"commands"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return body.Commands, nil
+}
+
+func (a *AgentSDK) ackCommand(ctx context.Context, actionID string) error {
+	url := a.backendURL + "/v1/commands/" + actionID + "/ack"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return err
+	}
+	a.authorize(req)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("backend answered %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// runPulled executes a pulled command and reports the outcome, since there is
+// no webhook response to carry it (async handlers report on their own)
+func (a *AgentSDK) runPulled(cmd models.PendingCommand) {
+	envelope := models.ActionEnvelope{ActionID: cmd.ActionID, Decision: cmd.Decision}
+	if cmd.Envelope != nil {
+		envelope = *cmd.Envelope
+	}
+
+	status, body := a.dispatch(envelope)
+	if status == http.StatusAccepted {
+		return
+	}
+
+	a.reportOutcome(cmd.ActionID, status, body)
+}
+
+// reportOutcome reports a dispatch outcome that had no webhook response to carry it
+func (a *AgentSDK) reportOutcome(actionID string, status int, body map[string]interface{}) {
+	report := models.ActionResultReport{Status: models.ActionStatusSuccess, Result: body}
+	if status >= 300 {
+		report.Status = models.ActionStatusFailed
+		report.Error = fmt.Sprintf("sdk answered %d", status)
+		if reason, ok := body["reason"].(string); ok {
+			report.Error = reason
+		} else if reason, ok := body["error"].(string); ok {
+			report.Error = reason
+		}
+	}
+
+	if err := a.ReportResult(context.Background(), actionID, report); err != nil {
+		fmt.Printf("[SDK] no se pudo reportar el resultado de %s: %v\n", actionID, err)
+	}
+}
@@ -0,0 +1,84 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	models "server/model"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingTransport cuenta las requests que pasan por el cliente del SDK
+type countingTransport struct {
+	requests atomic.Int32
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.requests.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestRequestsUseTheSDKClient(t *testing.T) {
+	// contesta despues del Timeout del cliente, como un long-poll
+	slow := 100 * time.Millisecond
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(slow)
+		if r.URL.Path == "/v1/commands" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"commands": []models.PendingCommand{{ActionID: "action-1"}}})
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		call func(a *AgentSDK) error
+	}{
+		{
+			name: "pull long-poll",
+			call: func(a *AgentSDK) error {
+				commands, err := a.pollCommands(context.Background())
+				if err == nil && (len(commands) != 1 || commands[0].ActionID != "action-1") {
+					t.Errorf("commands = %+v", commands)
+				}
+				return err
+			},
+		},
+		{
+			name: "http health check",
+			call: func(a *AgentSDK) error {
+				a.AddHealthCheck("api", "http", HTTPHealthCheck(srv.URL+"/health"))
+				return failedChecks(a.RunHealthChecks(context.Background(), "api"))
+			},
+		},
+		{
+			name: "http builtin handler",
+			call: func(a *AgentSDK) error {
+				h, err := newBuiltinHandler(ActionConfig{Action: "restart", Type: "http", HTTP: &HTTPActionConfig{URL: srv.URL + "/restart"}}, a.httpClient)
+				if err != nil {
+					return err
+				}
+				_, err = h.handle(context.Background(), "restart", "api", nil)
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &countingTransport{}
+			a := NewSDK("key", srv.URL, "secret", WithHTTPClient(&http.Client{Timeout: slow / 2, Transport: transport}))
+
+			if err := tt.call(a); err != nil {
+				t.Fatalf("the client Timeout cut the request: %v", err)
+			}
+			if transport.requests.Load() != 1 {
+				t.Errorf("%d requests through the SDK client, want 1", transport.requests.Load())
+			}
+		})
+	}
+}
@@ -0,0 +1,348 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	models "server/model"
+	"sync"
+	"time"
+)
+
+// ReporterConfig tunes how Report batches and retries events
+type ReporterConfig struct {
+	BatchSize     int           // events per request, at most models.MaxEventBatch
+	FlushInterval time.Duration // how long a partial batch waits before it is sent
+	QueueSize     int           // events kept while the backend is unreachable
+	MaxRetries    int           // attempts per batch before waiting for the next flush
+	MinBackoff    time.Duration
+	MaxBackoff    time.Duration
+
+	DropPolicy     string        // DropOldest (default) or DropLowestSeverity, when the queue is full
+	BufferPath     string        // file that keeps the queue across restarts; empty keeps it in memory
+	MaxBufferBytes int64         // size limit of the queue, on top of QueueSize
+	Fsync          string        // FsyncAlways, FsyncInterval (default) or FsyncNever
+	FsyncInterval  time.Duration // default 1s
+}
+
+func DefaultReporterConfig() ReporterConfig {
+	return ReporterConfig{
+		BatchSize:     50,
+		FlushInterval: 2 * time.Second,
+		QueueSize:     1000,
+		MaxRetries:    5,
+		MinBackoff:    500 * time.Millisecond,
+		MaxBackoff:    30 * time.Second,
+
+		DropPolicy:     DropOldest,
+		MaxBufferBytes: 16 << 20,
+		Fsync:          FsyncInterval,
+		FsyncInterval:  time.Second,
+	}
+}
+
+// errRejected is a batch the backend will never accept (bad payload, bad API key):
+// retrying it would only block the events behind it
+var errRejected = errors.New("batch rejected by the backend")
+
+// reporter sends events to /v1/events in the background
+type reporter struct {
+	sdk *AgentSDK
+	cfg ReporterConfig
+
+	mu    sync.Mutex
+	queue *eventQueue
+	wake  chan struct{}
+	once  sync.Once // starts loop
+
+	// stop ends loop: quit tells it to return, cancel aborts the flush it is in, done is closed when it returned
+	quit     chan struct{}
+	stopOnce sync.Once
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	sendMu sync.Mutex // a batch at a time, so events arrive in order
+}
+
+func newReporter(sdk *AgentSDK, cfg ReporterConfig) (*reporter, error) {
+	defaults := DefaultReporterConfig()
+	if cfg.BatchSize <= 0 || cfg.BatchSize > models.MaxEventBatch {
+		cfg.BatchSize = defaults.BatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaults.FlushInterval
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaults.QueueSize
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = defaults.MaxRetries
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = defaults.MinBackoff
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = max(defaults.MaxBackoff, cfg.MinBackoff)
+	}
+	switch cfg.DropPolicy {
+	case "":
+		cfg.DropPolicy = defaults.DropPolicy
+	case DropOldest, DropLowestSeverity:
+	default:
+		return nil, fmt.Errorf("unknown drop policy %q", cfg.DropPolicy)
+	}
+	if cfg.MaxBufferBytes <= 0 {
+		cfg.MaxBufferBytes = defaults.MaxBufferBytes
+	}
+	switch cfg.Fsync {
+	case "":
+		cfg.Fsync = defaults.Fsync
+	case FsyncAlways, FsyncInterval, FsyncNever:
+	default:
+		return nil, fmt.Errorf("unknown fsync option %q", cfg.Fsync)
+	}
+	if cfg.FsyncInterval <= 0 {
+		cfg.FsyncInterval = defaults.FsyncInterval
+	}
+
+	queue, err := newEventQueue(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("event buffer: %w", err)
+	}
+
+	r := &reporter{sdk: sdk, cfg: cfg, queue: queue, wake: make(chan struct{}, 1), quit: make(chan struct{}), done: make(chan struct{})}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	if queue.len() > 0 {
+		// lo que quedo de la corrida anterior sale sin esperar al primer Report
+		r.once.Do(func() { go r.loop() })
+	}
+	return r, nil
+}
+
+// SetReporterConfig replaces the reporter settings. Call it before the first Report:
+// the previous reporter is stopped and the events still queued in it are dropped.
+// With a BufferPath the events left there by a previous run are sent first.
+func (a *AgentSDK) SetReporterConfig(cfg ReporterConfig) error {
+	r, err := newReporter(a, cfg)
+	if err != nil {
+		return err
+	}
+	// sin esto su goroutine seguiria mandando por su cuenta
+	a.reporter.stop(context.Background())
+	a.reporter = r
+	return nil
+}
+
+// Report queues an event for the backend and returns right away. Events are sent
+// in batches from a background goroutine, retried with backoff while the backend
+// is unreachable, and keep the time they were reported as created_at.
+func (a *AgentSDK) Report(event models.Event) {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	if event.Severity == "" {
+		event.Severity = models.SeverityInfo
+	}
+	a.reporter.enqueue(event)
+}
+
+// ReportEvent is a shorthand for Report
+func (a *AgentSDK) ReportEvent(eventType, service, severity string, data map[string]interface{}) {
+	a.Report(models.Event{Type: eventType, Service: service, Severity: severity, Data: data})
+}
+
+// Flush sends every queued event now, e.g. before the process exits. With a
+// BufferPath whatever could not be sent is synced to disk for the next run.
+func (a *AgentSDK) Flush(ctx context.Context) error {
+	err := a.reporter.flush(ctx, true)
+
+	a.reporter.mu.Lock()
+	defer a.reporter.mu.Unlock()
+	if syncErr := a.reporter.queue.sync(true); err == nil && syncErr != nil {
+		err = fmt.Errorf("event buffer: %w", syncErr)
+	}
+	return err
+}
+
+func (r *reporter) enqueue(event models.Event) {
+	r.once.Do(func() { go r.loop() })
+
+	r.mu.Lock()
+	dropped, err := r.queue.push(event)
+	full := r.queue.len() >= r.cfg.BatchSize
+	r.mu.Unlock()
+
+	if err != nil {
+		fmt.Printf("[SDK] no se pudo guardar el evento en el buffer: %v\n", err)
+	}
+	if dropped > 0 {
+		fmt.Printf("[SDK] buffer de eventos lleno, se descartaron %d eventos (%s)\n", dropped, r.cfg.DropPolicy)
+	}
+
+	if full {
+		select {
+		case r.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (r *reporter) loop() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	// con FsyncInterval el ultimo evento de una rafaga no espera al proximo Report
+	var syncTick <-chan time.Time
+	if r.cfg.BufferPath != "" && r.cfg.Fsync == FsyncInterval {
+		syncTicker := time.NewTicker(r.cfg.FsyncInterval)
+		defer syncTicker.Stop()
+		syncTick = syncTicker.C
+	}
+
+	for {
+		select {
+		case <-r.quit:
+			r.syncBuffer()
+			return
+		case <-syncTick:
+			r.syncBuffer()
+			continue
+		case <-ticker.C:
+		case <-r.wake:
+		}
+		if err := r.flush(r.ctx, false); err != nil && r.ctx.Err() == nil {
+			fmt.Printf("[SDK] no se pudieron enviar los eventos: %v\n", err)
+		}
+	}
+}
+
+func (r *reporter) syncBuffer() {
+	if r.cfg.Fsync == FsyncNever {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.queue.sync(true); err != nil {
+		fmt.Printf("[SDK] no se pudo sincronizar el buffer de eventos: %v\n", err)
+	}
+}
+
+// stop ends the batching goroutine, aborting the flush it is in (the batch stays
+// queued), and fsyncs the buffer. It waits for it to return until ctx is done.
+// Events reported after stop stay queued until a Flush.
+func (r *reporter) stop(ctx context.Context) error {
+	r.stopOnce.Do(func() {
+		close(r.quit)
+		r.cancel()
+	})
+	r.once.Do(func() { close(r.done) }) // loop never started: and now it won't
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("event reporter still running: %w", ctx.Err())
+	}
+}
+
+// flush sends queued batches, oldest first, until the queue is empty or a batch
+// fails every retry (it stays at the front for the next flush). A batch leaves
+// the queue only once the backend has it.
+func (r *reporter) flush(ctx context.Context, force bool) error {
+	r.sendMu.Lock()
+	defer r.sendMu.Unlock()
+
+	for {
+		r.mu.Lock()
+		batch := r.queue.peek(r.cfg.BatchSize)
+		r.mu.Unlock()
+
+		if len(batch) == 0 {
+			return nil
+		}
+
+		events := make([]models.Event, len(batch))
+		for i, e := range batch {
+			events[i] = e.Event
+		}
+
+		err := r.sendWithRetry(ctx, events)
+		if errors.Is(err, errRejected) {
+			fmt.Printf("[SDK] el backend rechazo %d eventos: %v\n", len(batch), err)
+		} else if err != nil {
+			return err
+		}
+
+		r.mu.Lock()
+		err = r.queue.remove(batch)
+		r.mu.Unlock()
+		if err != nil {
+			return fmt.Errorf("event buffer: %w", err)
+		}
+
+		if !force && len(batch) < r.cfg.BatchSize {
+			return nil // lo que llego mientras tanto espera al proximo flush
+		}
+	}
+}
+
+func (r *reporter) sendWithRetry(ctx context.Context, batch []models.Event) error {
+	var err error
+	for attempt := 0; attempt < r.cfg.MaxRetries; attempt++ {
+		if err = r.send(ctx, batch); err == nil || errors.Is(err, errRejected) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff(attempt, r.cfg.MinBackoff, r.cfg.MaxBackoff)):
+		}
+	}
+	return err
+}
+
+func (r *reporter) send(ctx context.Context, batch []models.Event) error {
+	payload, err := json.Marshal(models.EventBatch{Events: batch})
+	if err != nil {
+		return fmt.Errorf("%w: %v", errRejected, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.sdk.backendURL+"/v1/events", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	r.sdk.authorize(req)
+
+	resp, err := r.sdk.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("backend answered %d", resp.StatusCode)
+	default:
+		return fmt.Errorf("%w: backend answered %d", errRejected, resp.StatusCode)
+	}
+}
+
+// backoff is exponential with full jitter: a random wait in [min, min*2^attempt], capped at max
+func backoff(attempt int, minWait, maxWait time.Duration) time.Duration {
+	wait := minWait << min(attempt, 16)
+	if wait <= 0 || wait > maxWait {
+		wait = maxWait
+	}
+	return minWait + time.Duration(rand.Int63n(int64(wait-minWait)+1))
+}
@@ -0,0 +1,286 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	models "server/model"
+	"sync"
+	"testing"
+	"time"
+)
+
+// eventBackend es un /v1/events que contesta statuses en orden y despues 202
+type eventBackend struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests int
+	batches  [][]models.Event
+}
+
+func newEventBackend(t *testing.T, statuses ...int) *eventBackend {
+	t.Helper()
+
+	b := &eventBackend{statuses: statuses}
+	b.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		b.requests++
+		if len(b.statuses) > 0 {
+			status := b.statuses[0]
+			b.statuses = b.statuses[1:]
+			if status != http.StatusAccepted {
+				w.WriteHeader(status)
+				return
+			}
+		}
+		var batch models.EventBatch
+		json.NewDecoder(r.Body).Decode(&batch)
+		b.batches = append(b.batches, batch.Events)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(b.Close)
+	return b
+}
+
+func (b *eventBackend) events() []models.Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var events []models.Event
+	for _, batch := range b.batches {
+		events = append(events, batch...)
+	}
+	return events
+}
+
+// waitEvents espera a que lleguen al menos n eventos
+func (b *eventBackend) waitEvents(t *testing.T, n int) []models.Event {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if events := b.events(); len(events) >= n {
+			return events
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("got %d events, want %d", len(b.events()), n)
+	return nil
+}
+
+// newTestSDK apunta un SDK al backend con la config del reporter dada
+func newTestSDK(t *testing.T, backend *eventBackend, cfg ReporterConfig) *AgentSDK {
+	t.Helper()
+
+	a := NewSDK("key", backend.URL, "secret")
+	if err := a.SetReporterConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func eventServices(events []models.Event) []string {
+	var names []string
+	for _, e := range events {
+		names = append(names, e.Service)
+	}
+	return names
+}
+
+func TestReporterBatches(t *testing.T) {
+	backend := newEventBackend(t)
+	a := newTestSDK(t, backend, ReporterConfig{BatchSize: 3, FlushInterval: time.Hour})
+
+	reportedAt := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	a.Report(models.Event{Type: models.EventAppDown, Service: "s0", CreatedAt: reportedAt})
+	for i := 1; i < 7; i++ {
+		a.ReportEvent(models.EventAppDown, fmt.Sprintf("s%d", i), models.SeverityCritical, nil)
+	}
+
+	// los lotes llenos salen sin esperar al FlushInterval
+	backend.waitEvents(t, 6)
+	if err := a.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	events := backend.events()
+	if got := fmt.Sprint(eventServices(events)); got != "[s0 s1 s2 s3 s4 s5 s6]" {
+		t.Errorf("events = %s, want them in order and once", got)
+	}
+	for _, batch := range backend.batches {
+		if len(batch) > 3 {
+			t.Errorf("batch of %d events, max 3", len(batch))
+		}
+	}
+	if !events[0].CreatedAt.Equal(reportedAt) || events[0].Severity != models.SeverityInfo {
+		t.Errorf("first event = %+v, want created_at %s and default severity", events[0], reportedAt)
+	}
+}
+
+func TestReporterFlushInterval(t *testing.T) {
+	backend := newEventBackend(t)
+	a := newTestSDK(t, backend, ReporterConfig{BatchSize: 50, FlushInterval: 20 * time.Millisecond})
+
+	a.ReportEvent(models.EventAppDown, "api", models.SeverityCritical, nil)
+	a.ReportEvent(models.EventAppRecovered, "api", models.SeverityInfo, nil)
+
+	backend.waitEvents(t, 2)
+	if len(backend.batches) != 1 {
+		t.Errorf("sent %d batches, want 1", len(backend.batches))
+	}
+}
+
+func TestReporterRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantErr      bool
+		wantRequests int
+		wantEvents   []string // despues del primer Flush
+	}{
+		{name: "retries 5xx", statuses: []int{503, 502}, wantRequests: 3, wantEvents: []string{"api"}},
+		{name: "retries 429", statuses: []int{429}, wantRequests: 2, wantEvents: []string{"api"}},
+		{name: "drops a rejected batch", statuses: []int{400}, wantRequests: 1},
+		{name: "keeps the batch after the last retry", statuses: []int{503, 503, 503}, wantErr: true, wantRequests: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newEventBackend(t, tt.statuses...)
+			a := newTestSDK(t, backend, ReporterConfig{FlushInterval: time.Hour, MaxRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+
+			a.ReportEvent(models.EventAppDown, "api", models.SeverityCritical, nil)
+			err := a.Flush(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Flush err = %v, wantErr %v", err, tt.wantErr)
+			}
+			backend.mu.Lock()
+			requests := backend.requests
+			backend.mu.Unlock()
+			if requests != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", requests, tt.wantRequests)
+			}
+			if got, want := fmt.Sprint(eventServices(backend.events())), fmt.Sprint(tt.wantEvents); got != want {
+				t.Errorf("events = %s, want %s", got, want)
+			}
+
+			// lo que no se rechazo sale en el proximo flush, y lo rechazado no vuelve
+			a.ReportEvent(models.EventAppRecovered, "web", models.SeverityInfo, nil)
+			if err := a.Flush(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			want := append(tt.wantEvents, "web")
+			if tt.wantErr {
+				want = []string{"api", "web"}
+			}
+			if got := fmt.Sprint(eventServices(backend.events())); got != fmt.Sprint(want) {
+				t.Errorf("events = %s, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestReporterDropsOldestWhenFull(t *testing.T) {
+	backend := newEventBackend(t)
+	a := newTestSDK(t, backend, ReporterConfig{FlushInterval: time.Hour, QueueSize: 3})
+
+	for i := 0; i < 5; i++ {
+		a.ReportEvent(models.EventAppDown, fmt.Sprintf("s%d", i), models.SeverityCritical, nil)
+	}
+	if err := a.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := fmt.Sprint(eventServices(backend.events())); got != "[s2 s3 s4]" {
+		t.Errorf("events = %s, want the 3 newest", got)
+	}
+}
+
+func TestSetReporterConfigStopsThePreviousReporter(t *testing.T) {
+	backend := newEventBackend(t)
+	a := newTestSDK(t, backend, ReporterConfig{FlushInterval: time.Hour})
+	a.ReportEvent(models.EventAppDown, "api", models.SeverityCritical, nil) // arranca su goroutine
+	previous := a.reporter
+
+	if err := a.SetReporterConfig(ReporterConfig{FlushInterval: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-previous.done:
+	case <-time.After(time.Second):
+		t.Fatal("the previous reporter goroutine is still running")
+	}
+
+	// una config invalida deja el reporter que estaba
+	current := a.reporter
+	if err := a.SetReporterConfig(ReporterConfig{DropPolicy: "newest"}); err == nil {
+		t.Fatal("accepted an unknown drop policy")
+	}
+	if a.reporter != current {
+		t.Error("a failed SetReporterConfig replaced the reporter")
+	}
+	select {
+	case <-current.quit:
+		t.Error("a failed SetReporterConfig stopped the reporter")
+	default:
+	}
+}
+
+func TestReporterSyncsTheBuffer(t *testing.T) {
+	tests := []struct {
+		name          string
+		fsyncInterval time.Duration
+		stop          bool
+	}{
+		{name: "in the background", fsyncInterval: 20 * time.Millisecond},
+		{name: "on stop", fsyncInterval: time.Hour, stop: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// backend caido: los eventos quedan en el buffer
+			a := NewSDK("key", "http://127.0.0.1:1", "secret")
+			err := a.SetReporterConfig(ReporterConfig{
+				FlushInterval: time.Hour,
+				BufferPath:    filepath.Join(t.TempDir(), "events.log"),
+				Fsync:         FsyncInterval,
+				FsyncInterval: tt.fsyncInterval,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := a.reporter
+			t.Cleanup(func() { r.queue.file.Close() })
+
+			// el segundo cae dentro del intervalo: push no lo sincroniza
+			a.ReportEvent(models.EventAppDown, "api", models.SeverityCritical, nil)
+			a.ReportEvent(models.EventAppDown, "web", models.SeverityCritical, nil)
+			dirty := func() bool {
+				r.mu.Lock()
+				defer r.mu.Unlock()
+				return r.queue.dirty
+			}
+			if !dirty() {
+				t.Fatal("synced on push")
+			}
+
+			if tt.stop {
+				if err := r.stop(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for deadline := time.Now().Add(time.Second); dirty(); time.Sleep(5 * time.Millisecond) {
+				if time.Now().After(deadline) {
+					t.Fatal("the buffer was never synced")
+				}
+			}
+		})
+	}
+}
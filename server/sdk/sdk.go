@@ -1,9 +1,15 @@
 package sdk
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	models "server/model"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -11,22 +17,65 @@ import (
 
 //this is the folder which the users can install
 
+// how long an action ID is remembered to drop redeliveries
+const seenTTL = time.Hour
+
 type ActionFunc func(target string, params map[string]interface{}) error
 
+// ProgressFunc lets a long-running action tell the backend how it's going
+type ProgressFunc func(progress map[string]interface{})
+
+// AsyncActionFunc runs in background after the SDK answered 202 to the backend.
+// The returned result (or error) is reported as the final state of the action.
+// To make the action undoable put a "pre_state" map in the result with an "undo"
+// entry, e.g. {"undo": {"action": "set_flag", "params": {"value": false}}}.
+type AsyncActionFunc func(ctx context.Context, target string, params map[string]interface{}, progress ProgressFunc) (map[string]interface{}, error)
+
 type AgentSDK struct {
 	apiKey        string
 	webHookSecret string
 	backendURL    string
 	actions       map[string]ActionFunc
-	healthCheck   string // url para verificar el /health
+	asyncActions  map[string]AsyncActionFunc
+	actionInfo    map[string]actionInfo // descriptions and param schemas sent with the capabilities
+	httpClient    *http.Client
+	reporter      *reporter               // events waiting to be sent to /v1/events
+	healthChecks  map[string][]namedCheck // target -> checks run before acting on it
+	policy        *Policy                 // local guardrails, nil allows everything
+	executions    map[string][]time.Time  // "target/action" -> when it ran, for max_per_hour
+	approval      *ApprovalConfig
+	pending       map[string]*PendingApproval // action ID -> action waiting for a human
+	server        webhookServer
+	inflight      sync.WaitGroup // async actions that haven't reported their result yet
+	closed        chan struct{}  // closed by Shutdown, stops the loops that don't take a ctx (HTTPMiddleware)
+	closeOnce     sync.Once
+
+	mu   sync.Mutex
+	seen map[string]time.Time // action IDs already dispatched, so a redelivery doesn't run twice
 }
 
-func NewSDK(apiKey, backendURL string, webHookSecret string) *AgentSDK {
-	return &AgentSDK{
+// NewSDK builds the SDK; options set the HTTP client, the listen address and TLS.
+func NewSDK(apiKey, backendURL string, webHookSecret string, opts ...Option) *AgentSDK {
+	a := &AgentSDK{
 		apiKey:        apiKey,
-		backendURL:    backendURL,
+		backendURL:    strings.TrimSuffix(backendURL, "/"),
 		webHookSecret: webHookSecret,
+		actions:       make(map[string]ActionFunc),
+		asyncActions:  make(map[string]AsyncActionFunc),
+		actionInfo:    make(map[string]actionInfo),
+		httpClient:    &http.Client{Timeout: 10 * time.Second},
+		seen:          make(map[string]time.Time),
+		healthChecks:  make(map[string][]namedCheck),
+		executions:    make(map[string][]time.Time),
+		pending:       make(map[string]*PendingApproval),
+		server:        webhookServer{addr: defaultListenAddr},
+		closed:        make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(a)
 	}
+	a.reporter, _ = newReporter(a, DefaultReporterConfig()) // en memoria, no puede fallar
+	return a
 }
 
 func (a *AgentSDK) On(action string, fn ActionFunc) {
@@ -35,59 +84,247 @@ func (a *AgentSDK) On(action string, fn ActionFunc) {
 
 }
 
-func (a *AgentSDK) Run(port string) error {
-	r := gin.Default()
+// OnAsync registers a long-running action (rollback, scale-up...). The webhook
+// answers 202 right away and the result is posted later to /v1/actions/:id/result
+func (a *AgentSDK) OnAsync(action string, fn AsyncActionFunc) {
+
+	a.asyncActions[action] = fn
 
-	r.POST("/webhook/agent", a.handleWebhook)
-	fmt.Printf("[SDK] escuchando en el puerto %s \n", port)
-	return r.Run(":" + port)
+}
+
+// Run serves the webhook on port until the process exits; Start is the same with
+// a context, TLS and a graceful shutdown
+func (a *AgentSDK) Run(port string) error {
+	a.server.addr = ":" + port
+	return a.Start(context.Background())
 }
 
 func (a *AgentSDK) handleWebhook(c *gin.Context) {
-	var decision models.LLMDecision
+	// we always tell the backend the newest envelope version we understand
+	c.Header(models.ProtocolHeader, strconv.Itoa(models.ProtocolVersion))
 
-	if err := c.ShouldBindJSON(&decision); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+	// the backend checking whether a suspended webhook is back: nothing to run
+	if c.GetHeader(models.ProbeHeader) != "" {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 		return
 	}
 
-	if decision.Confidence < 0.9 || decision.Action == "restart" {
-		fmt.Printf("[AGENTE] verificando si de verdad la %s esta caido", decision.Target)
+	// the backend checking that this webhook is ours: prove we hold the webhook secret
+	if challenge := c.GetHeader(models.ChallengeHeader); challenge != "" {
+		status, body := a.answerChallenge(challenge, c.GetHeader(models.SignatureHeader))
+		c.JSON(status, body)
+		return
+	}
 
-		isHealthy := a.checkLocalHealth()
+	var envelope models.ActionEnvelope
 
-		if isHealthy {
-			c.JSON(http.StatusOK, gin.H{"status": "aborted", "reason": "local health passed, works normally"})
+	if models.NegotiateProtocol(c.GetHeader(models.ProtocolHeader)) >= models.ProtocolV1 {
+		if err := c.ShouldBindJSON(&envelope); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+	} else {
+		// legacy backend: the body is the bare decision and the ID comes in a header
+		if err := c.ShouldBindJSON(&envelope.Decision); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
 			return
 		}
+		envelope.ActionID = c.GetHeader(models.ActionIDHeader)
+	}
+
+	status, body := a.dispatch(envelope)
+	c.JSON(status, body)
+}
+
+// dispatch runs a command coming from the webhook (push) or from /v1/commands (pull)
+// and returns the HTTP status and body that describe the outcome
+func (a *AgentSDK) dispatch(envelope models.ActionEnvelope) (int, gin.H) {
+	decision := envelope.Decision
+	actionID := envelope.ActionID
+
+	if envelope.Deadline != nil && time.Now().After(*envelope.Deadline) {
+		return http.StatusConflict, gin.H{"status": "aborted", "reason": "action deadline already passed"}
+	}
+	if actionID != "" && !a.markSeen(actionID) {
+		return http.StatusConflict, gin.H{"status": "aborted", "reason": "action already received"}
+	}
+
+	// the client's own guardrails go first: the backend can't override them
+	if err := a.enforcePolicy(decision); err != nil {
+		fmt.Printf("[SDK] accion %s sobre %s rechazada por la politica local: %v\n", decision.Action, decision.Target, err)
+		return http.StatusForbidden, gin.H{"status": "denied", "reason": "local_policy", "policy_violation": err.Error()}
+	}
+
+	// before acting on a low-confidence decision (or a restart) check the target ourselves:
+	// if every check registered for it passes the LLM got it wrong
+	var checks []CheckResult
+	if decision.Confidence < 0.9 || decision.Action == "restart" {
+		fmt.Printf("[AGENTE] verificando si de verdad %s esta caido\n", decision.Target)
+
+		checks = a.RunHealthChecks(context.Background(), decision.Target)
+		if len(checks) > 0 && failedChecks(checks) == nil {
+			a.forgetExecution(decision)
+			return http.StatusOK, gin.H{"status": "aborted", "reason": "local health passed, works normally", "health_checks": checks}
+		}
+	}
+
+	var status int
+	var body gin.H
+	if a.needsApproval(decision.Action) {
+		status, body = a.hold(actionID, decision, envelope.Deadline)
+	} else {
+		status, body = a.run(actionID, decision)
+	}
+	if checks != nil {
+		body["health_checks"] = checks
+	}
+	return status, body
+}
+
+// run hands the decision to its registered handler
+func (a *AgentSDK) run(actionID string, decision models.LLMDecision) (int, gin.H) {
+	if asyncHandler, exists := a.asyncActions[decision.Action]; exists {
+		if actionID == "" {
+			return http.StatusBadRequest, gin.H{"status": "aborted", "reason": "missing action id"}
+		}
+
+		a.inflight.Add(1)
+		go a.runAsync(actionID, decision, asyncHandler)
+
+		return http.StatusAccepted, gin.H{"status": "accepted", "handle": actionID}
 	}
 
 	handler, exists := a.actions[decision.Action]
 
 	if !exists { // una accion que no existe
-		c.JSON(http.StatusNotImplemented, gin.H{"status": "aborted", "reason": "this action doesnt exists"})
-		return
+		return http.StatusNotImplemented, gin.H{"status": "aborted", "reason": "this action doesnt exists"}
 	}
 
 	if err := handler(decision.Target, decision.Params); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return http.StatusInternalServerError, gin.H{"error": err.Error()}
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "success"})
+	return http.StatusOK, gin.H{"status": "success"}
+}
+
+// answerChallenge signs the backend's ownership challenge, only if the backend
+// signed it with the same secret (otherwise anyone could use us as an oracle)
+func (a *AgentSDK) answerChallenge(challenge, signature string) (int, interface{}) {
+	if a.webHookSecret == "" || !models.VerifyChallenge(a.webHookSecret, models.ChallengeFromBackend, challenge, signature) {
+		return http.StatusUnauthorized, gin.H{"error": "invalid challenge signature"}
+	}
+	return http.StatusOK, models.WebhookChallengeResponse{
+		Challenge: challenge,
+		Signature: models.SignChallenge(a.webHookSecret, models.ChallengeFromSDK, challenge),
+	}
 }
 
-func (a *AgentSDK) checkLocalHealth() bool {
+// markSeen records the action ID and reports whether it was new
+func (a *AgentSDK) markSeen(actionID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 
-	client := http.Client{Timeout: 2 * time.Second}
-	resp, err := client.Get(a.healthCheck)
+	now := time.Now()
+	for id, at := range a.seen {
+		if now.Sub(at) > seenTTL {
+			delete(a.seen, id)
+		}
+	}
 
-	if err != nil || resp.StatusCode != 200 {
+	if _, ok := a.seen[actionID]; ok {
 		return false
 	}
+	a.seen[actionID] = now
 	return true
 }
 
+func (a *AgentSDK) runAsync(actionID string, decision models.LLMDecision, fn AsyncActionFunc) {
+	defer a.inflight.Done()
+	ctx := context.WithValue(context.Background(), actionIDKey{}, actionID)
+
+	progress := func(p map[string]interface{}) {
+		report := models.ActionResultReport{Status: models.ActionStatusRunning, Progress: p}
+		if err := a.ReportResult(ctx, actionID, report); err != nil {
+			fmt.Printf("[SDK] no se pudo reportar el progreso de %s: %v\n", actionID, err)
+		}
+	}
+
+	progress(nil) // pending -> running
+
+	result, err := fn(ctx, decision.Target, decision.Params, progress)
+
+	report := models.ActionResultReport{Status: models.ActionStatusSuccess, Result: result}
+	// a "pre_state" entry in the result is the snapshot the backend uses to undo the action
+	if state, ok := result["pre_state"].(map[string]interface{}); ok {
+		report.PreState = state
+		delete(result, "pre_state")
+	}
+	if err != nil {
+		report.Status = models.ActionStatusFailed
+		report.Error = err.Error()
+	}
+
+	// el resultado final es importante, reintentamos un par de veces
+	for attempt := 0; attempt < 3; attempt++ {
+		if err = a.ReportResult(ctx, actionID, report); err == nil {
+			return
+		}
+		time.Sleep(time.Duration(attempt+1) * 2 * time.Second)
+	}
+	fmt.Printf("[SDK] no se pudo reportar el resultado de %s: %v\n", actionID, err)
+}
+
+type actionIDKey struct{}
+
+// ActionID returns the ID of the action an async handler is running for, e.g.
+// to attach artifacts to it with UploadArtifact
+func ActionID(ctx context.Context) string {
+	id, _ := ctx.Value(actionIDKey{}).(string)
+	return id
+}
+
+// ReportResult posts progress or the final state of an async action to the backend
+func (a *AgentSDK) ReportResult(ctx context.Context, actionID string, report models.ActionResultReport) error {
+	payload, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	url := a.backendURL + "/v1/actions/" + actionID + "/result"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	a.authorize(req)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("backend answered %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// untimed is client without its Timeout, for requests bounded by their context
+// instead (a long-poll, a large upload, a handler with its own timeout). It keeps
+// the transport, so a client set with WithHTTPClient (mTLS, proxy) still applies.
+func untimed(client *http.Client) *http.Client {
+	c := *client
+	c.Timeout = 0
+	return &c
+}
+
+// authorize adds the API key and the protocol version to a request to the backend
+func (a *AgentSDK) authorize(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	req.Header.Set(models.ProtocolHeader, strconv.Itoa(models.ProtocolVersion))
+}
+
 /*
 
 SDK :=  agent.NewSDK(
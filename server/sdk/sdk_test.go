@@ -0,0 +1,43 @@
+package sdk
+
+import (
+	"net/http"
+	models "server/model"
+	"testing"
+)
+
+func TestAnswerChallenge(t *testing.T) {
+	const challenge = "5f2b9c"
+
+	tests := []struct {
+		name       string
+		sdkSecret  string
+		signature  string
+		wantStatus int
+	}{
+		{name: "signed by the backend", sdkSecret: "secret", signature: models.SignChallenge("secret", models.ChallengeFromBackend, challenge), wantStatus: http.StatusOK},
+		// sin la firma del backend cualquiera nos usaria para firmar lo que quiera
+		{name: "unsigned", sdkSecret: "secret", signature: "", wantStatus: http.StatusUnauthorized},
+		{name: "signed with another secret", sdkSecret: "secret", signature: models.SignChallenge("other", models.ChallengeFromBackend, challenge), wantStatus: http.StatusUnauthorized},
+		{name: "an sdk answer replayed", sdkSecret: "secret", signature: models.SignChallenge("secret", models.ChallengeFromSDK, challenge), wantStatus: http.StatusUnauthorized},
+		{name: "sdk without a secret", sdkSecret: "", signature: models.SignChallenge("", models.ChallengeFromBackend, challenge), wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewSDK("key", "http://127.0.0.1:1", tt.sdkSecret)
+
+			status, body := a.answerChallenge(challenge, tt.signature)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if status != http.StatusOK {
+				return
+			}
+			answer := body.(models.WebhookChallengeResponse)
+			if answer.Challenge != challenge || !models.VerifyChallenge("secret", models.ChallengeFromSDK, challenge, answer.Signature) {
+				t.Errorf("answer = %+v", answer)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package sdk
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookPath is where the backend posts actions
+const WebhookPath = "/webhook/agent"
+
+const defaultListenAddr = ":9000"
+
+// Option configures NewSDK
+type Option func(*AgentSDK)
+
+// WithHTTPClient replaces the client used to call the backend
+func WithHTTPClient(client *http.Client) Option {
+	return func(a *AgentSDK) { a.httpClient = client }
+}
+
+// WithAddr is the address Start listens on, default ":9000"
+func WithAddr(addr string) Option {
+	return func(a *AgentSDK) { a.server.addr = addr }
+}
+
+// WithTLS makes Start serve HTTPS with the given certificate and key files
+func WithTLS(certFile, keyFile string) Option {
+	return func(a *AgentSDK) { a.server.certFile, a.server.keyFile = certFile, keyFile }
+}
+
+// WithTLSConfig makes Start serve HTTPS with a ready tls.Config (certificates
+// included); WithClientCA and WithBackendNames still apply on top of it
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(a *AgentSDK) { a.server.tlsConfig = cfg }
+}
+
+// WithClientCA turns on mTLS: only callers with a certificate signed by the CA
+// in caFile (PEM) can reach the webhook. Needs WithTLS or WithTLSConfig.
+func WithClientCA(caFile string) Option {
+	return func(a *AgentSDK) { a.server.clientCAFile = caFile }
+}
+
+// WithBackendNames additionally requires the client certificate to be issued to
+// one of names (a DNS SAN or the common name)
+func WithBackendNames(names ...string) Option {
+	return func(a *AgentSDK) { a.server.clientNames = names }
+}
+
+// webhookServer is the listener Start runs
+type webhookServer struct {
+	addr         string
+	certFile     string
+	keyFile      string
+	tlsConfig    *tls.Config
+	clientCAFile string
+	clientNames  []string
+
+	http *http.Server // set while Start is serving
+}
+
+// Handler is the webhook receiver, to mount on an existing server:
+//
+//	mux.Handle(sdk.WebhookPath, agent.Handler())
+//
+// TLS and mTLS are up to that server; see Start for a standalone listener.
+func (a *AgentSDK) Handler() http.Handler {
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.POST(WebhookPath, a.handleWebhook)
+	return r
+}
+
+// Start serves Handler on the configured address (HTTPS with WithTLS) until ctx
+// is cancelled or Shutdown is called, and then shuts down gracefully.
+func (a *AgentSDK) Start(ctx context.Context) error {
+	tlsConfig, err := a.server.tls()
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", a.server.addr)
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	server := &http.Server{Handler: a.Handler(), TLSConfig: tlsConfig, ReadHeaderTimeout: 10 * time.Second}
+	a.mu.Lock()
+	if a.server.http != nil {
+		a.mu.Unlock()
+		listener.Close()
+		return errors.New("webhook server already started")
+	}
+	a.server.http = server
+	a.mu.Unlock()
+
+	shutdown := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		defer close(shutdown)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := a.Shutdown(shutdownCtx); err != nil {
+			fmt.Printf("[SDK] error cerrando el webhook: %v\n", err)
+		}
+	})
+	defer func() {
+		// cancelado por ctx: volvemos cuando termino el shutdown, no cuando se cerro el listener
+		if !stop() {
+			<-shutdown
+		}
+	}()
+
+	// el backend tiene que saber que acciones tenemos antes de mandarnos alguna
+	go a.announce(ctx)
+
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+	fmt.Printf("[SDK] escuchando en %s://%s%s\n", scheme, listener.Addr(), WebhookPath)
+
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops the webhook server started by Start: no new actions are accepted,
+// the running ones (async included) finish and report their result, the event
+// reporter and the HTTPMiddleware windows stop and the queued events are flushed.
+// Gives up when ctx is done; with a BufferPath what could not be sent is kept for
+// the next run.
+func (a *AgentSDK) Shutdown(ctx context.Context) error {
+	a.closeOnce.Do(func() { close(a.closed) })
+
+	a.mu.Lock()
+	server := a.server.http
+	a.server.http = nil
+	a.mu.Unlock()
+
+	var errs []error
+	if server != nil {
+		errs = append(errs, server.Shutdown(ctx))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		a.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("async actions still running: %w", ctx.Err()))
+	}
+
+	// los eventos que quedaron en cola salen ahora y no en el proximo tick del reporter
+	errs = append(errs, a.reporter.stop(ctx), a.Flush(ctx))
+	return errors.Join(errs...)
+}
+
+// tls builds the server TLS config, nil for plain HTTP
+func (s *webhookServer) tls() (*tls.Config, error) {
+	cfg := s.tlsConfig
+	if cfg != nil {
+		cfg = cfg.Clone()
+	}
+
+	if s.certFile != "" || s.keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls certificate: %w", err)
+		}
+		if cfg == nil {
+			cfg = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+
+	if s.clientCAFile == "" && len(s.clientNames) == 0 {
+		return cfg, nil
+	}
+	if cfg == nil {
+		return nil, errors.New("client certificate verification needs TLS (WithTLS or WithTLSConfig)")
+	}
+
+	if s.clientCAFile != "" {
+		pem, err := os.ReadFile(s.clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("client ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("client ca: no certificates in %s", s.clientCAFile)
+		}
+		cfg.ClientCAs = pool
+	}
+	if cfg.ClientCAs == nil {
+		return nil, errors.New("WithBackendNames needs WithClientCA (or ClientCAs in the tls config)")
+	}
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+
+	if names := s.clientNames; len(names) > 0 {
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			// la cadena ya la verifico ClientCAs, aca solo miramos a quien se emitio
+			cert := cs.PeerCertificates[0]
+			for _, name := range names {
+				if cert.Subject.CommonName == name || slices.Contains(cert.DNSNames, name) {
+					return nil
+				}
+			}
+			return fmt.Errorf("client certificate %q is not one of the allowed backend names", cert.Subject.CommonName)
+		}
+	}
+	return cfg, nil
+}
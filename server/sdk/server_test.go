@@ -0,0 +1,81 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	models "server/model"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShutdownFlushesEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		hang     bool // el backend no contesta
+		wantSent int
+		wantErr  bool
+	}{
+		{name: "queued events are sent", wantSent: 3},
+		{name: "a hung backend doesn't block past ctx", hang: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			var mu sync.Mutex
+			sent := 0
+			backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.hang {
+					<-release
+					return
+				}
+				var batch models.EventBatch
+				json.NewDecoder(r.Body).Decode(&batch)
+				mu.Lock()
+				sent += len(batch.Events)
+				mu.Unlock()
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer backend.Close()
+			defer close(release)
+
+			a := NewSDK("key", backend.URL, "secret")
+			// sin Shutdown los eventos esperarian al proximo tick
+			if err := a.SetReporterConfig(ReporterConfig{FlushInterval: time.Hour, MinBackoff: 10 * time.Millisecond, MaxBackoff: 10 * time.Millisecond}); err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 3; i++ {
+				a.ReportEvent(models.EventAppDown, "api", models.SeverityCritical, nil)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			err := a.Shutdown(ctx)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Shutdown err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Shutdown took %s", elapsed)
+			}
+			select {
+			case <-a.reporter.done:
+			default:
+				t.Error("the reporter goroutine is still running")
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if sent != tt.wantSent {
+				t.Errorf("sent %d events, want %d", sent, tt.wantSent)
+			}
+			if tt.hang && a.reporter.queue.len() != 3 {
+				t.Errorf("%d events queued after a failed flush, want 3", a.reporter.queue.len())
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	models "server/model"
+	"server/repositories"
+	executor "server/service/exec"
+	"time"
+)
+
+var (
+	ErrActionFinished      = errors.New("action already finished")
+	ErrInvalidActionStatus = errors.New("status must be running, success or failed")
+)
+
+// ActionResults recibe el progreso y el resultado de las acciones asincronas que reporta el SDK
+type ActionResults struct {
+	actions repositories.ActionStorage
+	queue   *executor.ExecutionQueue // se entera cuando una accion termina para liberar su target
+}
+
+func NewActionResults(actions repositories.ActionStorage, queue *executor.ExecutionQueue) *ActionResults {
+	return &ActionResults{actions: actions, queue: queue}
+}
+
+// ReportResult aplica la transicion pending -> running -> success/failed pedida por el SDK.
+// Una accion terminada (o vencida) no se puede volver a modificar.
+func (ar *ActionResults) ReportResult(ctx context.Context, clientID string, actionID string, report *models.ActionResultReport) (*models.Action, error) {
+
+	switch report.Status {
+	case models.ActionStatusRunning, models.ActionStatusSuccess, models.ActionStatusFailed:
+	default:
+		return nil, ErrInvalidActionStatus
+	}
+
+	action, err := ar.actions.GetAction(ctx, actionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// un cliente no puede reportar sobre acciones de otro cliente
+	if action.ClientID != clientID {
+		return nil, repositories.ErrActionNotFound
+	}
+
+	if action.Status != models.ActionStatusPending && action.Status != models.ActionStatusRunning {
+		return nil, ErrActionFinished
+	}
+
+	now := time.Now()
+
+	if action.DeadlineAt != nil && now.After(*action.DeadlineAt) {
+		action.Status = models.ActionStatusFailed
+		action.Result = withResult(action.Result, "error", "action_timed_out")
+		if err := ar.actions.UpdateActionStatus(ctx, action); err != nil {
+			return nil, err
+		}
+		if ar.queue != nil {
+			ar.queue.Finished(action)
+		}
+		return nil, ErrActionFinished
+	}
+
+	if action.ExecutedAt == nil {
+		action.ExecutedAt = &now
+	}
+
+	action.Status = report.Status
+
+	if report.Progress != nil {
+		action.Result = withResult(action.Result, "progress", report.Progress)
+	}
+	for k, v := range report.Result {
+		action.Result = withResult(action.Result, k, v)
+	}
+	if report.Error != "" {
+		action.Result = withResult(action.Result, "error", report.Error)
+	}
+	if report.PreState != nil {
+		action.PreState = report.PreState
+	}
+
+	if err := ar.actions.UpdateActionStatus(ctx, action); err != nil {
+		return nil, err
+	}
+
+	if ar.queue != nil {
+		ar.queue.Finished(action)
+	}
+
+	return action, nil
+}
+
+// FailStalled marca como fallidas las acciones que no reportaron antes del deadline
+func (ar *ActionResults) FailStalled(ctx context.Context) (int64, error) {
+	return ar.actions.FailStalledActions(ctx, time.Now())
+}
+
+// StartReaper corre FailStalled cada "interval" hasta que se cancele el contexto
+func (ar *ActionResults) StartReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := ar.FailStalled(ctx)
+			if err != nil {
+				log.Printf("[Actions] error marcando acciones vencidas: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("[Actions] %d acciones vencidas marcadas como failed", n)
+			}
+		}
+	}
+}
+
+func withResult(result map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	if result == nil {
+		result = map[string]interface{}{}
+	}
+	result[key] = value
+	return result
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"log"
 	models "server/model"
 	"server/repositories"
 	"server/service/agent/llm" // go importa por path del modulo + carpetas
@@ -15,22 +16,87 @@ import (
 
 type AgentEngine struct {
 	gemini  *llm.GeminiClient
-	events  repositories.EventRepository
+	events  repositories.EventStorage
 	actions repositories.ActionStorage
 	agents  repositories.AgentStorage
 	client  repositories.ClientStorage
+	configs repositories.ClientConfigStorage
+	caps    repositories.CapabilityStorage
+	targets repositories.TargetStorage
+	queue   *service.ExecutionQueue
 }
 
-func (e *AgentEngine) assembleContext(ctx context.Context, agent *models.Agent, events []models.Event) models.AgentRunContext {
+func NewAgentEngine(gemini *llm.GeminiClient, events repositories.EventStorage, actions repositories.ActionStorage,
+	agents repositories.AgentStorage, client repositories.ClientStorage, configs repositories.ClientConfigStorage,
+	caps repositories.CapabilityStorage, targets repositories.TargetStorage, queue *service.ExecutionQueue) *AgentEngine {
+	return &AgentEngine{
+		gemini:  gemini,
+		events:  events,
+		actions: actions,
+		agents:  agents,
+		client:  client,
+		configs: configs,
+		caps:    caps,
+		targets: targets,
+		queue:   queue,
+	}
+}
+
+// Start corre un tick de cada agente con eventos pendientes cada "interval" hasta que se cancele el contexto
+func (e *AgentEngine) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			agentIDs, err := e.agents.GetAgentsToTick(ctx, time.Now())
+			if err != nil {
+				log.Printf("[Agent] error buscando agentes con eventos pendientes: %v", err)
+				continue
+			}
+			for _, id := range agentIDs {
+				if err := e.RunTick(ctx, id); err != nil {
+					log.Printf("[Agent] error en el tick del agente %s: %v", id, err)
+				}
+			}
+		}
+	}
+}
+
+// assembleContext arma lo que ve el LLM. La config y las capabilities del cliente van
+// siempre: ValidateDecision cruza AllowedActions con lo que registro el SDK.
+func (e *AgentEngine) assembleContext(ctx context.Context, agent *models.Agent, events []models.Event) (models.AgentRunContext, error) {
+
+	cfg, err := e.configs.GetClientConfig(ctx, agent.ID)
+	if err != nil {
+		return models.AgentRunContext{}, fmt.Errorf("error getting client config: %w", err)
+	}
+
+	// lo que registro el SDK: el LLM solo ve (y solo puede elegir) esas acciones
+	caps, err := e.caps.GetCapabilities(ctx, agent.ClientID)
+	if err != nil {
+		return models.AgentRunContext{}, fmt.Errorf("error getting capabilities: %w", err)
+	}
+
+	// sobre los targets que manejamos nosotros el SDK no tiene nada que decir
+	bound, err := serverTargets(ctx, e.targets, agent.ClientID)
+	if err != nil {
+		return models.AgentRunContext{}, err
+	}
 
 	since := time.Now().Add(-1 * time.Hour)
-	restartCount, _ := e.actions.CountActionsSince(context.Background(), agent.ID, "restart", since)
+	restartCount, _ := e.actions.CountActionsSince(ctx, agent.ID, "restart", since)
 
 	return models.AgentRunContext{
 		CurrentEvents:    events,
 		RestartCountHour: restartCount,
-		ClientConfig:     models.ClientConfig{},
-	}
+		ClientConfig:     cfg,
+		Capabilities:     caps,
+		ServerTargets:    bound,
+	}, nil
 
 }
 
@@ -52,10 +118,9 @@ func (e *AgentEngine) RunTick(ctx context.Context, agentId string) error {
 		return nil
 	}
 
-	runCtx := models.AgentRunContext{
-		CurrentEvents:    events,
-		RestartCountHour: 0,
-		ClientConfig:     models.ClientConfig{},
+	runCtx, err := e.assembleContext(ctx, agent, events)
+	if err != nil {
+		return err
 	}
 
 	decision, err := e.gemini.Decide(ctx, runCtx)
@@ -64,9 +129,15 @@ func (e *AgentEngine) RunTick(ctx context.Context, agentId string) error {
 		return err
 	}
 
-	executor := &service.Executor{}
-	result := executor.Execute(ctx, decision, agent, client) // en execute tiene que ir algun switch con las opciones
-	e.actions.SaveAction(ctx, result)                        // guardar esa accion en la base de datos
+	decision.EventIDs = make([]string, 0, len(events))
+	for _, ev := range events {
+		decision.EventIDs = append(decision.EventIDs, ev.ID)
+	}
+
+	// la cola respeta el lock del target; el executor guarda la accion y su resultado en la base de datos
+	if _, err := e.queue.Submit(ctx, decision, agent, client, models.SourceTick); err != nil {
+		return err
+	}
 
 	for _, ev := range events {
 		e.events.MarkEventProcessed(ctx, ev.ID) // marcamos el evento como procesado
@@ -6,7 +6,11 @@ import (
 	"fmt"
 	"log"
 	models "server/model"
+	executor "server/service/exec"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"google.golang.org/genai"
 )
@@ -107,6 +111,10 @@ func (g *GeminiClient) CreatePrompt(agentCtx models.AgentRunContext) (string, er
 			sb.WriteString(fmt.Sprintf("%d. Tipo: %s\n", i+1, event.Type))
 			sb.WriteString(fmt.Sprintf("   Servicio: %s\n", event.Service))
 			sb.WriteString(fmt.Sprintf("   Severidad: %s\n", event.Severity))
+			if event.Late() {
+				// el SDK lo tuvo en su buffer sin conexion: puede que ya no este pasando
+				sb.WriteString(fmt.Sprintf("   Llegó con retraso: ocurrió hace %s, puede que ya no siga pasando\n", event.Delay().Round(time.Second)))
+			}
 
 			if len(event.Data) > 0 {
 				dataJSON, _ := json.Marshal(event.Data)
@@ -157,7 +165,7 @@ func (g *GeminiClient) CreatePrompt(agentCtx models.AgentRunContext) (string, er
 	sb.WriteString("## REGLAS Y LÍMITES\n")
 	sb.WriteString(fmt.Sprintf("- Máximo de reinicios por hora: %d\n", agentCtx.ClientConfig.MaxRestartsPerHour))
 	sb.WriteString(fmt.Sprintf("- Reinicios actuales: %d\n", agentCtx.RestartCountHour))
-	sb.WriteString(fmt.Sprintf("- Acciones permitidas: %v\n", agentCtx.ClientConfig.AllowedActions))
+	sb.WriteString(fmt.Sprintf("- Acciones permitidas: %v\n", agentCtx.AvailableActions()))
 	sb.WriteString(fmt.Sprintf("- Notificar al usuario en el reinicio #%d\n", agentCtx.ClientConfig.NotifyOnNthRestart))
 	sb.WriteString("\n")
 
@@ -165,11 +173,15 @@ func (g *GeminiClient) CreatePrompt(agentCtx models.AgentRunContext) (string, er
 	// ACCIONES DISPONIBLES
 	// ============================================================
 	sb.WriteString("## ACCIONES DISPONIBLES\n")
-	sb.WriteString("1. restart - Reinicia un servicio (úsalo con moderación)\n")
-	sb.WriteString("2. notify - Alerta al dueño (para problemas críticos)\n")
-	sb.WriteString("3. wait - No hacer nada y observar (cuando no estés seguro)\n")
-	sb.WriteString("4. scale - Escalar réplicas hacia arriba (para alta carga)\n")
-	sb.WriteString("5. rollback - Volver a versión anterior (si deploy reciente falló)\n")
+	if agentCtx.Capabilities == nil {
+		for i, action := range defaultActions {
+			sb.WriteString(fmt.Sprintf("%d. %s - %s\n", i+1, action, actionDescriptions[action]))
+		}
+	} else {
+		// solo lo que el SDK del cliente registro (y el cliente permite), salvo en los
+		// targets que manejamos nosotros (kubernetes, docker, ssh, gitops)
+		writeCapabilities(&sb, agentCtx)
+	}
 	sb.WriteString("\n")
 
 	// ============================================================
@@ -203,6 +215,69 @@ func (g *GeminiClient) CreatePrompt(agentCtx models.AgentRunContext) (string, er
 	return sb.String(), nil
 }
 
+// acciones que se ofrecen cuando el SDK del cliente no mando sus capacidades
+var defaultActions = []string{"restart", "notify", "wait", "scale", "rollback"}
+
+var actionDescriptions = map[string]string{
+	"restart":             "Reinicia un servicio (úsalo con moderación)",
+	"notify":              "Alerta al dueño (para problemas críticos)",
+	"wait":                "No hacer nada y observar (cuando no estés seguro)",
+	"scale":               "Escalar réplicas hacia arriba (para alta carga)",
+	"rollback":            "Volver a versión anterior (si deploy reciente falló)",
+	"collect_diagnostics": "Juntar goroutines, perfiles, logs y procesos para investigar, sin tocar nada",
+}
+
+func writeCapabilities(sb *strings.Builder, agentCtx models.AgentRunContext) {
+	supported := models.SupportedActions(agentCtx.ClientConfig.AllowedActions, agentCtx.Capabilities)
+	for i, action := range agentCtx.AvailableActions() {
+		capability := agentCtx.Capabilities.Action(action)
+
+		description := actionDescriptions[action]
+		if capability != nil && capability.Description != "" {
+			description = capability.Description
+		}
+		sb.WriteString(fmt.Sprintf("%d. %s - %s\n", i+1, action, description))
+		if !slices.Contains(supported, action) {
+			// el SDK no la tiene: solo sirve en los targets que no pasan por el
+			sb.WriteString(fmt.Sprintf("   Solo sobre: %v\n", agentCtx.ServerTargets))
+			continue
+		}
+		if capability == nil {
+			continue
+		}
+
+		if len(capability.Targets) > 0 {
+			sb.WriteString(fmt.Sprintf("   Solo sobre: %v\n", append(slices.Clone(capability.Targets), agentCtx.ServerTargets...)))
+		}
+		names := make([]string, 0, len(capability.Params))
+		for name := range capability.Params {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			param := capability.Params[name]
+			sb.WriteString(fmt.Sprintf("   - param %s (%s", name, param.Type))
+			if param.Required {
+				sb.WriteString(", obligatorio")
+			}
+			if param.Min != nil {
+				sb.WriteString(fmt.Sprintf(", min %v", *param.Min))
+			}
+			if param.Max != nil {
+				sb.WriteString(fmt.Sprintf(", max %v", *param.Max))
+			}
+			if len(param.Enum) > 0 {
+				sb.WriteString(fmt.Sprintf(", uno de %v", param.Enum))
+			}
+			sb.WriteString(")")
+			if param.Description != "" {
+				sb.WriteString(": " + param.Description)
+			}
+			sb.WriteString("\n")
+		}
+	}
+}
+
 // ParseResponse extrae y parsea el JSON de la respuesta de Gemini
 func (g *GeminiClient) ParseResponse(responseText string) (*models.LLMDecision, error) {
 	// Limpiar respuesta (remover markdown si existe)
@@ -228,18 +303,31 @@ func (g *GeminiClient) ParseResponse(responseText string) (*models.LLMDecision,
 
 // ValidateDecision valida que la decisión sea segura y siga las reglas
 func (g *GeminiClient) ValidateDecision(decision *models.LLMDecision, ctx models.AgentRunContext) error {
-	// 1. Validar que la acción esté en la lista permitida
-	allowed := false
-	for _, allowedAction := range ctx.ClientConfig.AllowedActions {
-		if decision.Action == allowedAction {
-			allowed = true
-			break
-		}
-	}
-	if !allowed {
+	return ValidateDecision(decision, ctx)
+}
+
+// ValidateDecision son las reglas que cumple toda accion, la decida el LLM o la pida una persona (undo)
+func ValidateDecision(decision *models.LLMDecision, ctx models.AgentRunContext) error {
+	// 1. Validar que la acción esté en la lista permitida y que el SDK del cliente la tenga
+	if !slices.Contains(ctx.ClientConfig.AllowedActions, decision.Action) {
 		return fmt.Errorf("acción '%s' no está en la lista permitida: %v",
 			decision.Action, ctx.ClientConfig.AllowedActions)
 	}
+	// las capabilities son del SDK: un target con binding a kubernetes/docker/ssh/gitops no pasa por el
+	if ctx.SDKTarget(decision.Target) && !slices.Contains(models.SupportedActions(ctx.ClientConfig.AllowedActions, ctx.Capabilities), decision.Action) {
+		return fmt.Errorf("acción '%s' no está registrada en el SDK del cliente", decision.Action)
+	}
+	if capability := ctx.Capabilities.Action(decision.Action); capability != nil && ctx.SDKTarget(decision.Target) {
+		if decision.Target != "" && !capability.Supports(decision.Target) {
+			return fmt.Errorf("el SDK del cliente no soporta '%s' sobre '%s' (solo %v)",
+				decision.Action, decision.Target, capability.Targets)
+		}
+		if capability.Params != nil {
+			if err := executor.ValidateParams(capability.Params, decision.Params); err != nil {
+				return fmt.Errorf("params inválidos para '%s': %v", decision.Action, err)
+			}
+		}
+	}
 
 	// 2. Validar límite de reinicios
 	if decision.Action == "restart" {
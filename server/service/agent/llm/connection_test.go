@@ -0,0 +1,90 @@
+package llm
+
+import (
+	models "server/model"
+	"strings"
+	"testing"
+)
+
+func TestValidateDecision(t *testing.T) {
+	config := models.ClientConfig{MaxRestartsPerHour: 3, AllowedActions: []string{"restart", "scale", "notify"}}
+	caps := &models.Capabilities{Actions: []models.ActionCapability{
+		{Name: "restart", Targets: []string{"api", "worker"}},
+		{Name: "rollback"},
+	}}
+
+	tests := []struct {
+		name     string
+		decision models.LLMDecision
+		ctx      models.AgentRunContext
+		wantErr  bool
+	}{
+		{name: "allowed and registered", decision: models.LLMDecision{Action: "restart", Target: "api"}, ctx: models.AgentRunContext{ClientConfig: config, Capabilities: caps}},
+		{name: "registered but not allowed", decision: models.LLMDecision{Action: "rollback", Target: "api"}, ctx: models.AgentRunContext{ClientConfig: config, Capabilities: caps}, wantErr: true},
+		{name: "allowed but not registered", decision: models.LLMDecision{Action: "scale", Target: "api"}, ctx: models.AgentRunContext{ClientConfig: config, Capabilities: caps}, wantErr: true},
+		{name: "agent actions need no handler", decision: models.LLMDecision{Action: "notify"}, ctx: models.AgentRunContext{ClientConfig: config, Capabilities: caps}},
+		{name: "target the handler doesn't support", decision: models.LLMDecision{Action: "restart", Target: "db"}, ctx: models.AgentRunContext{ClientConfig: config, Capabilities: caps}, wantErr: true},
+		{name: "unknown capabilities allow the config", decision: models.LLMDecision{Action: "scale", Target: "api"}, ctx: models.AgentRunContext{ClientConfig: config}},
+		{name: "empty config allows nothing", decision: models.LLMDecision{Action: "restart", Target: "api"}, ctx: models.AgentRunContext{Capabilities: caps}, wantErr: true},
+		{name: "restart limit", decision: models.LLMDecision{Action: "restart", Target: "api"}, ctx: models.AgentRunContext{ClientConfig: config, Capabilities: caps, RestartCountHour: 3}, wantErr: true},
+		// payments esta en kubernetes: lo que registro el SDK no cuenta
+		{name: "server target ignores the sdk capabilities", decision: models.LLMDecision{Action: "scale", Target: "payments"}, ctx: models.AgentRunContext{ClientConfig: config, Capabilities: caps, ServerTargets: []string{"payments"}}},
+		{name: "server target ignores the handler targets", decision: models.LLMDecision{Action: "restart", Target: "payments"}, ctx: models.AgentRunContext{ClientConfig: config, Capabilities: caps, ServerTargets: []string{"payments"}}},
+		{name: "server target still needs the config", decision: models.LLMDecision{Action: "rollback", Target: "payments"}, ctx: models.AgentRunContext{ClientConfig: config, Capabilities: caps, ServerTargets: []string{"payments"}}, wantErr: true},
+		{name: "sdk target next to a server target", decision: models.LLMDecision{Action: "scale", Target: "api"}, ctx: models.AgentRunContext{ClientConfig: config, Capabilities: caps, ServerTargets: []string{"payments"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.decision.Reasoning = "api is down"
+			tt.decision.Confidence = 0.9
+			err := ValidateDecision(&tt.decision, tt.ctx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPromptActions(t *testing.T) {
+	config := models.ClientConfig{AllowedActions: []string{"restart", "scale", "notify"}}
+	caps := &models.Capabilities{Actions: []models.ActionCapability{{Name: "restart", Targets: []string{"api"}}}}
+
+	tests := []struct {
+		name    string
+		ctx     models.AgentRunContext
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "only what the sdk registered",
+			ctx:     models.AgentRunContext{ClientConfig: config, Capabilities: caps},
+			want:    []string{"Acciones permitidas: [restart notify]", "Solo sobre: [api]"},
+			notWant: []string{"scale"},
+		},
+		{
+			name: "server targets take every allowed action",
+			ctx:  models.AgentRunContext{ClientConfig: config, Capabilities: caps, ServerTargets: []string{"payments"}},
+			want: []string{"Acciones permitidas: [restart scale notify]", "Solo sobre: [api payments]", "scale - Escalar réplicas hacia arriba (para alta carga)\n   Solo sobre: [payments]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt, err := (&GeminiClient{}).CreatePrompt(tt.ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(prompt, want) {
+					t.Errorf("prompt without %q:\n%s", want, prompt)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(prompt, notWant) {
+					t.Errorf("prompt with %q:\n%s", notWant, prompt)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ArtifactStore guarda el contenido de los artifacts; la metadata va a la base de datos
+type ArtifactStore interface {
+	// Put guarda size bytes de body bajo key; sha256Hex es el hash del contenido
+	Put(ctx context.Context, key string, body io.Reader, size int64, sha256Hex, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// NewArtifactStoreFromEnv arma el store segun el entorno:
+//
+//	ARTIFACT_STORE=local (default)   ARTIFACT_DIR=./artifacts
+//	ARTIFACT_STORE=s3                S3_BUCKET, S3_REGION (default us-east-1), S3_ACCESS_KEY_ID,
+//	                                 S3_SECRET_ACCESS_KEY, S3_ENDPOINT (MinIO, R2...), S3_FORCE_PATH_STYLE=true
+func NewArtifactStoreFromEnv() (ArtifactStore, error) {
+	switch os.Getenv("ARTIFACT_STORE") {
+	case "", "local":
+		dir := os.Getenv("ARTIFACT_DIR")
+		if dir == "" {
+			dir = "./artifacts"
+		}
+		return NewLocalArtifactStore(dir), nil
+	case "s3":
+		return NewS3ArtifactStore(S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			Region:          os.Getenv("S3_REGION"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			ForcePathStyle:  os.Getenv("S3_FORCE_PATH_STYLE") == "true",
+		})
+	default:
+		return nil, fmt.Errorf("unknown ARTIFACT_STORE %q (local or s3)", os.Getenv("ARTIFACT_STORE"))
+	}
+}
+
+// LocalArtifactStore guarda cada artifact como un archivo bajo dir
+type LocalArtifactStore struct {
+	dir string
+}
+
+func NewLocalArtifactStore(dir string) *LocalArtifactStore {
+	return &LocalArtifactStore{dir: dir}
+}
+
+func (s *LocalArtifactStore) path(key string) (string, error) {
+	// las keys las arma el servidor, pero igual no dejamos salir de dir
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if clean == "." || filepath.IsAbs(clean) || strings.HasPrefix(clean, "..") {
+		return "", fmt.Errorf("invalid artifact key %q", key)
+	}
+	return filepath.Join(s.dir, clean), nil
+}
+
+func (s *LocalArtifactStore) Put(ctx context.Context, key string, body io.Reader, size int64, sha256Hex, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	// temp + rename: un upload cortado no deja un archivo a medias
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *LocalArtifactStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// S3Config es un bucket de S3 o de algo compatible (MinIO, R2, Ceph...)
+type S3Config struct {
+	Endpoint        string // default https://s3.<region>.amazonaws.com
+	Bucket          string
+	Region          string // default us-east-1
+	AccessKeyID     string
+	SecretAccessKey string
+	ForcePathStyle  bool // endpoint/bucket/key en vez de bucket.endpoint/key
+}
+
+// S3ArtifactStore habla con la API REST de S3 firmando con SigV4 (sin el SDK de AWS)
+type S3ArtifactStore struct {
+	cfg        S3Config
+	endpoint   *url.URL
+	httpClient *http.Client
+}
+
+func NewS3ArtifactStore(cfg S3Config) (*S3ArtifactStore, error) {
+	if cfg.Bucket == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("s3 artifact store needs S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://s3." + cfg.Region + ".amazonaws.com"
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3_ENDPOINT %q", cfg.Endpoint)
+	}
+
+	// el endpoint lo configura el operador (puede ser un MinIO interno): sin NetGuard
+	return &S3ArtifactStore{cfg: cfg, endpoint: endpoint, httpClient: &http.Client{Timeout: 5 * time.Minute}}, nil
+}
+
+func (s *S3ArtifactStore) Put(ctx context.Context, key string, body io.Reader, size int64, sha256Hex, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := s.do(req, sha256Hex)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3ArtifactStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req, emptySHA256)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3ArtifactStore) objectURL(key string) string {
+	u := *s.endpoint
+	if s.cfg.ForcePathStyle {
+		u.Path += "/" + s.cfg.Bucket + "/" + key
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path += "/" + key
+	}
+	return u.String()
+}
+
+func (s *S3ArtifactStore) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.sign(req, payloadHash, time.Now().UTC())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		resp.Body.Close()
+		return nil, fmt.Errorf("s3 %s answered %d: %s", req.Method, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// sign agrega la firma AWS Signature Version 4 (solo host y x-amz-* firmados)
+func (s *S3ArtifactStore) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + s.cfg.Region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretAccessKey), day)
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
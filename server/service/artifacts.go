@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	models "server/model"
+	"server/repositories"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidArtifact  = errors.New("invalid artifact")
+	ErrArtifactTooLarge = fmt.Errorf("artifact larger than %d bytes", models.MaxArtifactSize)
+)
+
+// maximo de artifacts por accion (un SDK roto no nos llena el disco)
+const maxArtifactsPerAction = 50
+
+var artifactName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// ArtifactsStorage es lo que necesita Artifacts de la base de datos
+type ArtifactsStorage interface {
+	repositories.ArtifactStorage
+	GetAction(ctx context.Context, id string) (*models.Action, error)
+}
+
+// Artifacts recibe la evidencia que junta el SDK para una accion (dumps, logs...) y la
+// guarda en el ArtifactStore; el dueño la descarga desde la API
+type Artifacts struct {
+	storage ArtifactsStorage
+	store   ArtifactStore
+}
+
+func NewArtifacts(storage ArtifactsStorage, store ArtifactStore) *Artifacts {
+	return &Artifacts{storage: storage, store: store}
+}
+
+// Upload guarda un artifact de una accion del cliente
+func (a *Artifacts) Upload(ctx context.Context, clientID, actionID, name, contentType string, body io.Reader) (*models.Artifact, error) {
+	if !artifactName.MatchString(name) {
+		return nil, fmt.Errorf("%w: name must be 1-128 letters, digits, '.', '_' or '-'", ErrInvalidArtifact)
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	if _, err := a.action(ctx, clientID, actionID); err != nil {
+		return nil, err
+	}
+	existing, err := a.storage.ListArtifacts(ctx, actionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxArtifactsPerAction {
+		return nil, fmt.Errorf("%w: an action has at most %d artifacts", ErrInvalidArtifact, maxArtifactsPerAction)
+	}
+
+	// primero a un temporal: asi sabemos tamaño y hash antes de subirlo (S3 los pide)
+	tmp, err := os.CreateTemp("", "artifact-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(body, models.MaxArtifactSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if size > models.MaxArtifactSize {
+		return nil, ErrArtifactTooLarge
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	artifact := &models.Artifact{
+		ID:          uuid.New().String(),
+		ClientID:    clientID,
+		ActionID:    actionID,
+		Name:        name,
+		ContentType: contentType,
+		Size:        size,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		CreatedAt:   time.Now(),
+	}
+	artifact.StorageKey = clientID + "/" + actionID + "/" + artifact.ID
+
+	if err := a.store.Put(ctx, artifact.StorageKey, tmp, size, artifact.SHA256, contentType); err != nil {
+		return nil, fmt.Errorf("error storing artifact: %w", err)
+	}
+	if err := a.storage.SaveArtifact(ctx, artifact); err != nil {
+		return nil, err
+	}
+	return artifact, nil
+}
+
+// List devuelve los artifacts de una accion del cliente
+func (a *Artifacts) List(ctx context.Context, clientID, actionID string) ([]models.Artifact, error) {
+	if _, err := a.action(ctx, clientID, actionID); err != nil {
+		return nil, err
+	}
+	return a.storage.ListArtifacts(ctx, actionID)
+}
+
+// Open abre el contenido de un artifact del cliente; hay que cerrarlo
+func (a *Artifacts) Open(ctx context.Context, clientID, artifactID string) (*models.Artifact, io.ReadCloser, error) {
+	artifact, err := a.storage.GetArtifact(ctx, artifactID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if artifact.ClientID != clientID {
+		return nil, nil, repositories.ErrArtifactNotFound
+	}
+
+	content, err := a.store.Open(ctx, artifact.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening artifact: %w", err)
+	}
+	return artifact, content, nil
+}
+
+// action devuelve la accion solo si es del cliente
+func (a *Artifacts) action(ctx context.Context, clientID, actionID string) (*models.Action, error) {
+	if _, err := uuid.Parse(actionID); err != nil {
+		return nil, repositories.ErrActionNotFound
+	}
+	action, err := a.storage.GetAction(ctx, actionID)
+	if err != nil {
+		return nil, err
+	}
+	if action.ClientID != clientID {
+		return nil, repositories.ErrActionNotFound
+	}
+	return action, nil
+}
@@ -0,0 +1,289 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	models "server/model"
+	"server/repositories"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// fakeArtifactStorage guarda acciones y artifacts en memoria
+type fakeArtifactStorage struct {
+	mu        sync.Mutex
+	actions   map[string]models.Action
+	artifacts map[string]models.Artifact
+}
+
+func (f *fakeArtifactStorage) GetAction(ctx context.Context, id string) (*models.Action, error) {
+	if action, ok := f.actions[id]; ok {
+		return &action, nil
+	}
+	return nil, repositories.ErrActionNotFound
+}
+
+func (f *fakeArtifactStorage) SaveArtifact(ctx context.Context, artifact *models.Artifact) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.artifacts[artifact.ID] = *artifact
+	return nil
+}
+
+func (f *fakeArtifactStorage) GetArtifact(ctx context.Context, id string) (*models.Artifact, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if artifact, ok := f.artifacts[id]; ok {
+		return &artifact, nil
+	}
+	return nil, repositories.ErrArtifactNotFound
+}
+
+func (f *fakeArtifactStorage) ListArtifacts(ctx context.Context, actionID string) ([]models.Artifact, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []models.Artifact
+	for _, artifact := range f.artifacts {
+		if artifact.ActionID == actionID {
+			out = append(out, artifact)
+		}
+	}
+	return out, nil
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestArtifactsUpload(t *testing.T) {
+	actionID, foreignID, fullID := uuid.NewString(), uuid.NewString(), uuid.NewString()
+
+	tests := []struct {
+		name     string
+		actionID string
+		artifact string
+		body     io.Reader
+		wantErr  error
+	}{
+		{name: "ok", actionID: actionID, artifact: "goroutines.txt", body: strings.NewReader("goroutine 1 [running]")},
+		{name: "empty file", actionID: actionID, artifact: "empty.txt", body: strings.NewReader("")},
+		{name: "name with a slash", actionID: actionID, artifact: "../etc/passwd", body: strings.NewReader("x"), wantErr: ErrInvalidArtifact},
+		{name: "name starting with a dot", actionID: actionID, artifact: ".hidden", body: strings.NewReader("x"), wantErr: ErrInvalidArtifact},
+		{name: "name too long", actionID: actionID, artifact: strings.Repeat("a", 129), body: strings.NewReader("x"), wantErr: ErrInvalidArtifact},
+		{name: "action of another client", actionID: foreignID, artifact: "x.txt", body: strings.NewReader("x"), wantErr: repositories.ErrActionNotFound},
+		{name: "unknown action", actionID: uuid.NewString(), artifact: "x.txt", body: strings.NewReader("x"), wantErr: repositories.ErrActionNotFound},
+		{name: "action id is not a uuid", actionID: "act-1", artifact: "x.txt", body: strings.NewReader("x"), wantErr: repositories.ErrActionNotFound},
+		{name: "too many artifacts", actionID: fullID, artifact: "x.txt", body: strings.NewReader("x"), wantErr: ErrInvalidArtifact},
+		{name: "too large", actionID: actionID, artifact: "big.bin", body: io.LimitReader(zeros{}, models.MaxArtifactSize+1), wantErr: ErrArtifactTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := &fakeArtifactStorage{
+				actions: map[string]models.Action{
+					actionID:  {ID: actionID, ClientID: "client-1"},
+					foreignID: {ID: foreignID, ClientID: "client-2"},
+					fullID:    {ID: fullID, ClientID: "client-1"},
+				},
+				artifacts: map[string]models.Artifact{},
+			}
+			for i := 0; i < maxArtifactsPerAction; i++ {
+				id := uuid.NewString()
+				storage.artifacts[id] = models.Artifact{ID: id, ActionID: fullID}
+			}
+			dir := t.TempDir()
+			artifacts := NewArtifacts(storage, NewLocalArtifactStore(dir))
+
+			artifact, err := artifacts.Upload(context.Background(), "client-1", tt.actionID, tt.artifact, "", tt.body)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+					t.Errorf("stored something: %v", entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, content, err := artifacts.Open(context.Background(), "client-1", artifact.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(content)
+			content.Close()
+
+			sum := sha256.Sum256(body)
+			if artifact.Size != int64(len(body)) || artifact.SHA256 != hex.EncodeToString(sum[:]) || artifact.ContentType != "application/octet-stream" {
+				t.Errorf("artifact = %+v", artifact)
+			}
+			if artifact.StorageKey != "client-1/"+tt.actionID+"/"+artifact.ID {
+				t.Errorf("storage key = %q", artifact.StorageKey)
+			}
+			if _, ok := storage.artifacts[artifact.ID]; !ok {
+				t.Error("metadata not saved")
+			}
+
+			// otro cliente no lo ve
+			if _, _, err := artifacts.Open(context.Background(), "client-2", artifact.ID); !errors.Is(err, repositories.ErrArtifactNotFound) {
+				t.Errorf("open by another client: err = %v", err)
+			}
+			if _, err := artifacts.List(context.Background(), "client-2", tt.actionID); !errors.Is(err, repositories.ErrActionNotFound) {
+				t.Errorf("list by another client: err = %v", err)
+			}
+			if list, err := artifacts.List(context.Background(), "client-1", tt.actionID); err != nil || len(list) != 1 {
+				t.Errorf("list = %v, err = %v", list, err)
+			}
+		})
+	}
+}
+
+func TestLocalArtifactStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewLocalArtifactStore(dir)
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "client-1/act-1/art-1", strings.NewReader("dump"), 4, "", "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	content, err := store.Open(ctx, "client-1/act-1/art-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(content)
+	content.Close()
+	if string(data) != "dump" {
+		t.Errorf("content = %q", data)
+	}
+
+	// no quedan temporales
+	entries, _ := os.ReadDir(filepath.Join(dir, "client-1", "act-1"))
+	if len(entries) != 1 {
+		t.Errorf("entries = %v", entries)
+	}
+
+	for _, key := range []string{"", ".", "../outside", "a/../../outside", "/etc/passwd"} {
+		if err := store.Put(ctx, key, strings.NewReader("x"), 1, "", ""); err == nil {
+			t.Errorf("Put(%q) accepted", key)
+		}
+		if _, err := store.Open(ctx, key); err == nil {
+			t.Errorf("Open(%q) accepted", key)
+		}
+	}
+}
+
+func TestS3ArtifactStore(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=") {
+			http.Error(w, "<Error>SignatureDoesNotMatch</Error>", http.StatusForbidden)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			sum := sha256.Sum256(data)
+			if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) || r.ContentLength != int64(len(data)) {
+				http.Error(w, "<Error>XAmzContentSHA256Mismatch</Error>", http.StatusBadRequest)
+				return
+			}
+			objects[r.URL.Path] = data
+		case http.MethodGet:
+			if r.Header.Get("X-Amz-Content-Sha256") != emptySHA256 {
+				http.Error(w, "bad hash", http.StatusBadRequest)
+				return
+			}
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				http.Error(w, "<Error>NoSuchKey</Error>", http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		}
+	}))
+	defer srv.Close()
+
+	store, err := NewS3ArtifactStore(S3Config{Endpoint: srv.URL, Bucket: "evidence", Region: "eu-west-1", AccessKeyID: "AKID", SecretAccessKey: "secret", ForcePathStyle: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	data := []byte("heap profile")
+	sum := sha256.Sum256(data)
+	if err := store.Put(ctx, "client-1/act-1/art-1", bytes.NewReader(data), int64(len(data)), hex.EncodeToString(sum[:]), "application/octet-stream"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := objects["/evidence/client-1/act-1/art-1"]; !ok {
+		t.Fatalf("objects = %v", objects)
+	}
+
+	content, err := store.Open(ctx, "client-1/act-1/art-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(content)
+	content.Close()
+	if !bytes.Equal(got, data) {
+		t.Errorf("content = %q", got)
+	}
+
+	_, err = store.Open(ctx, "client-1/act-1/missing")
+	if err == nil || !strings.Contains(err.Error(), "answered 404: <Error>NoSuchKey</Error>") {
+		t.Errorf("missing object: err = %v", err)
+	}
+	if err := store.Put(ctx, "client-1/act-1/art-2", bytes.NewReader(data), int64(len(data)), emptySHA256, ""); err == nil {
+		t.Error("accepted a wrong hash")
+	}
+}
+
+func TestS3ObjectURL(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  S3Config
+		want string
+	}{
+		{name: "aws", cfg: S3Config{Bucket: "evidence", Region: "eu-west-1"}, want: "https://evidence.s3.eu-west-1.amazonaws.com/c/a/1"},
+		{name: "default region", cfg: S3Config{Bucket: "evidence"}, want: "https://evidence.s3.us-east-1.amazonaws.com/c/a/1"},
+		{name: "path style", cfg: S3Config{Endpoint: "http://minio:9000/", Bucket: "evidence", ForcePathStyle: true}, want: "http://minio:9000/evidence/c/a/1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.AccessKeyID, tt.cfg.SecretAccessKey = "AKID", "secret"
+			store, err := NewS3ArtifactStore(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := store.objectURL("c/a/1"); got != tt.want {
+				t.Errorf("objectURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	for _, cfg := range []S3Config{{AccessKeyID: "AKID", SecretAccessKey: "secret"}, {Bucket: "evidence"}, {Bucket: "evidence", AccessKeyID: "AKID", SecretAccessKey: "secret", Endpoint: "not a url"}} {
+		if _, err := NewS3ArtifactStore(cfg); err == nil {
+			t.Errorf("NewS3ArtifactStore(%+v) accepted", cfg)
+		}
+	}
+}
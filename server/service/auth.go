@@ -7,7 +7,6 @@ import (
 	models "server/model"
 	"server/repositories"
 	"server/utils"
-	"strings"
 
 	"github.com/markbates/goth"
 )
@@ -105,20 +104,33 @@ func (l *Login) LoginWithGoogle(gothUser goth.User) (string, error) {
 	return token, nil
 }
 
-func (l *Login) CompleteRegistration(ctx context.Context, userID string, companyName string, webhookURL string) (*models.CompleteRegistrationResponse, error) {
+func (l *Login) CompleteRegistration(ctx context.Context, userID string, companyName string, webhookURL string, deliveryMode string) (*models.CompleteRegistrationResponse, error) {
 	// Validar company_name
 	if companyName == "" {
 		return nil, errors.New("company_name is required")
 	}
 
-	// Validar webhook_url
-	if webhookURL == "" {
-		return nil, errors.New("webhook_url is required")
+	if deliveryMode == "" {
+		deliveryMode = models.DeliveryPush
 	}
+	if deliveryMode != models.DeliveryPush && deliveryMode != models.DeliveryPull {
+		return nil, errors.New("delivery_mode must be push or pull")
+	}
+
+	// en modo pull el SDK pide los comandos, no hace falta exponer un webhook
+	if deliveryMode == models.DeliveryPush {
+		// Validar webhook_url
+		if webhookURL == "" {
+			return nil, errors.New("webhook_url is required")
+		}
 
-	// Validar que webhook_url sea HTTPS
-	if !strings.HasPrefix(webhookURL, "https://") {
-		return nil, errors.New("webhook_url must use HTTPS")
+		// https y que no apunte a nuestra red interna (el executor le va a hacer POST)
+		if err := utils.OutboundGuard().ValidateURL(ctx, webhookURL); err != nil {
+			return nil, fmt.Errorf("invalid webhook_url: %w", err)
+		}
+	} else if webhookURL != "" {
+		// nunca se valido ni se le hace POST: no lo guardamos
+		return nil, errors.New("webhook_url must be empty in pull mode")
 	}
 
 	// Buscar usuario por ID
@@ -132,8 +144,8 @@ func (l *Login) CompleteRegistration(ctx context.Context, userID string, company
 		return nil, errors.New("only google users can complete registration this way")
 	}
 
-	// Verificar que no haya completado el registro antes
-	if user.WebhookURL != "" {
+	// Verificar que no haya completado el registro antes (en modo pull no hay webhook_url)
+	if user.WebhookURL != "" || user.APIKeyHash != "" {
 		return nil, errors.New("registration already completed")
 	}
 
@@ -152,6 +164,7 @@ func (l *Login) CompleteRegistration(ctx context.Context, userID string, company
 	// Actualizar usuario con company_name, webhook_url y nuevas credenciales
 	user.CompanyName = companyName
 	user.WebhookURL = webhookURL
+	user.DeliveryMode = deliveryMode
 	user.APIKeyHash = apiKeyHashed
 	user.WebhookSecret = webhookSecret
 
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	models "server/model"
+	"server/repositories"
+	executor "server/service/exec"
+	"time"
+)
+
+var ErrInvalidCapabilities = errors.New("invalid capabilities")
+
+// maximo de acciones que puede registrar un SDK
+const maxCapabilityActions = 200
+
+// CapabilityRegistry guarda lo que sabe hacer el SDK de cada cliente (el handshake que
+// manda al arrancar); el prompt y ValidateDecision lo cruzan con las acciones permitidas
+type CapabilityRegistry struct {
+	storage repositories.CapabilityStorage
+}
+
+func NewCapabilityRegistry(storage repositories.CapabilityStorage) *CapabilityRegistry {
+	return &CapabilityRegistry{storage: storage}
+}
+
+// Register valida y guarda las capacidades, reemplazando las del arranque anterior
+func (r *CapabilityRegistry) Register(ctx context.Context, client *models.Client, caps *models.Capabilities) error {
+	if len(caps.Actions) > maxCapabilityActions {
+		return fmt.Errorf("%w: at most %d actions", ErrInvalidCapabilities, maxCapabilityActions)
+	}
+
+	seen := make(map[string]bool, len(caps.Actions))
+	for _, action := range caps.Actions {
+		if action.Name == "" {
+			return fmt.Errorf("%w: action without name", ErrInvalidCapabilities)
+		}
+		if seen[action.Name] {
+			return fmt.Errorf("%w: action %q registered twice", ErrInvalidCapabilities, action.Name)
+		}
+		seen[action.Name] = true
+
+		if err := executor.ValidateParamsSchema(action.Params); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidCapabilities, action.Name, err)
+		}
+	}
+
+	caps.ClientID = client.ID.String()
+	caps.UpdatedAt = time.Now()
+	return r.storage.SaveCapabilities(ctx, caps)
+}
+
+// Get devuelve las capacidades del cliente (nil si su SDK nunca las mando)
+func (r *CapabilityRegistry) Get(ctx context.Context, clientID string) (*models.Capabilities, error) {
+	return r.storage.GetCapabilities(ctx, clientID)
+}
+
+// serverTargets son los targets del cliente con binding a un backend nuestro (kubernetes,
+// docker, ssh, gitops): las capabilities del SDK no limitan lo que se hace sobre ellos
+func serverTargets(ctx context.Context, targets repositories.TargetStorage, clientID string) ([]string, error) {
+	bindings, err := targets.ListTargetBindings(ctx, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting target bindings: %w", err)
+	}
+	var names []string
+	for _, binding := range bindings {
+		if models.ServerSideBackend(binding.Backend) {
+			names = append(names, binding.Target)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	models "server/model"
+	"server/repositories"
+	service "server/service/exec"
+	"time"
+)
+
+const (
+	maxPollWait      = 60 * time.Second
+	pollRecheckEvery = 5 * time.Second // por si la accion la creo otra instancia del servidor
+	maxCommandsBatch = 10
+)
+
+// Commands es el canal de comandos para los SDK en modo pull
+type Commands struct {
+	actions repositories.ActionStorage
+	broker  *service.CommandBroker
+}
+
+func NewCommands(actions repositories.ActionStorage, broker *service.CommandBroker) *Commands {
+	return &Commands{actions: actions, broker: broker}
+}
+
+// Poll devuelve los comandos pendientes del cliente. Si no hay, espera hasta "wait"
+// a que aparezca alguno (long-polling). Los comandos se repiten hasta que el SDK haga Ack.
+// version es la del protocolo que negocio el SDK (con ProtocolV1 cada comando trae su envelope).
+func (cs *Commands) Poll(ctx context.Context, clientID string, version int, wait time.Duration) ([]models.PendingCommand, error) {
+	if wait > maxPollWait {
+		wait = maxPollWait
+	}
+
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+
+	for {
+		// nos suscribimos antes de leer para no perder un Notify entre la query y la espera
+		notified, cancel := cs.broker.Subscribe(clientID)
+
+		actions, err := cs.actions.GetUndeliveredActions(ctx, clientID, maxCommandsBatch)
+		if err != nil || len(actions) > 0 {
+			cancel()
+			return toCommands(actions, version), err
+		}
+
+		recheck := time.NewTimer(pollRecheckEvery)
+		select {
+		case <-notified:
+		case <-recheck.C:
+		case <-timeout.C:
+			cancel()
+			recheck.Stop()
+			return []models.PendingCommand{}, nil
+		case <-ctx.Done():
+			cancel()
+			recheck.Stop()
+			return nil, ctx.Err()
+		}
+		cancel()
+		recheck.Stop()
+	}
+}
+
+// Ack marca el comando como recibido por el SDK (pending -> running).
+// El resultado llega despues por /v1/actions/:id/result.
+func (cs *Commands) Ack(ctx context.Context, clientID, actionID string) error {
+	return cs.actions.MarkActionDelivered(ctx, clientID, actionID)
+}
+
+func toCommands(actions []models.Action, version int) []models.PendingCommand {
+	commands := make([]models.PendingCommand, 0, len(actions))
+	for i := range actions {
+		a := &actions[i]
+		command := models.PendingCommand{
+			ActionID: a.ID,
+			Decision: service.DecisionOf(a),
+		}
+		// los SDK que negociaron el envelope lo reciben ademas de la decision
+		if version >= models.ProtocolV1 {
+			envelope := service.NewEnvelope(a, command.Decision, a.DeadlineAt)
+			command.Envelope = &envelope
+		}
+		commands = append(commands, command)
+	}
+	return commands
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	models "server/model"
+	"time"
+)
+
+// helpers para leer la config (JSONB) de un TargetBinding y los params de una decision
+
+func configString(cfg map[string]interface{}, key, def string) string {
+	if v, ok := cfg[key].(string); ok && v != "" {
+		return v
+	}
+	return def
+}
+
+func configInt(cfg map[string]interface{}, key string, def int) int {
+	if n, err := toInt(cfg[key]); err == nil {
+		return n
+	}
+	return def
+}
+
+func configBool(cfg map[string]interface{}, key string, def bool) bool {
+	if v, ok := cfg[key].(bool); ok {
+		return v
+	}
+	return def
+}
+
+// ValidateBindingConfig chequea la config de un binding antes de guardarla: lo que el
+// cliente pone ahi decide a que se conecta el servidor
+func ValidateBindingConfig(backend string, cfg map[string]interface{}) error {
+	switch backend {
+	case models.BackendDocker:
+		return validateDockerConfig(cfg)
+	case models.BackendGitOps:
+		return validateGitOpsConfig(cfg)
+	}
+	return nil
+}
+
+// JSON trae los numeros como float64
+func toInt(v interface{}) (int, error) {
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case int64:
+		return int(n), nil
+	case float64:
+		if n != math.Trunc(n) {
+			return 0, fmt.Errorf("%v is not an integer", n)
+		}
+		return int(n), nil
+	case nil:
+		return 0, fmt.Errorf("missing value")
+	default:
+		return 0, fmt.Errorf("%v is not a number", v)
+	}
+}
+
+// setResult deja la accion terminada con el resultado del backend (o el error)
+func setResult(action *models.Action, result map[string]interface{}, err error) {
+	executedAt := time.Now()
+	action.ExecutedAt = &executedAt
+
+	if result == nil {
+		result = map[string]interface{}{}
+	}
+	action.Result = result
+
+	if err != nil {
+		action.Status = models.ActionStatusFailed
+		action.Result["error"] = err.Error()
+		return
+	}
+	action.Status = models.ActionStatusSuccess
+}
+
+// setPreState guarda como estaba el target antes de actuar y, si undoAction no es "",
+// la accion que lo deja como estaba (ver models.PreStateUndoKey)
+func setPreState(action *models.Action, state map[string]interface{}, undoAction string, undoParams map[string]interface{}) {
+	if state == nil {
+		state = map[string]interface{}{}
+	}
+	if undoAction != "" {
+		state[models.PreStateUndoKey] = map[string]interface{}{"action": undoAction, "params": undoParams}
+	}
+	action.PreState = state
+}
+
+// takePreState saca el pre_state que manda el SDK dentro de su respuesta
+func takePreState(action *models.Action, result map[string]interface{}) {
+	if state, ok := result["pre_state"].(map[string]interface{}); ok {
+		action.PreState = state
+		delete(result, "pre_state")
+	}
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	models "server/model"
+	"server/repositories"
+	"server/utils"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMain(m *testing.M) {
+	// los fakes (httptest) escuchan en loopback, que el guard bloquea por defecto
+	os.Setenv("OUTBOUND_ALLOWED_CIDRS", "127.0.0.0/8")
+
+	key := make([]byte, 32)
+	rand.Read(key)
+	os.Setenv("CREDENTIALS_KEY", base64.StdEncoding.EncodeToString(key))
+
+	os.Exit(m.Run())
+}
+
+// fakeCredentials guarda las credenciales en memoria, cifradas como en la base
+type fakeCredentials map[string]*models.Credential
+
+func (f fakeCredentials) GetCredential(ctx context.Context, clientID, kind, name string) (*models.Credential, error) {
+	if c, ok := f[clientID+"/"+kind+"/"+name]; ok {
+		return c, nil
+	}
+	return nil, repositories.ErrCredentialNotFound
+}
+
+func (f fakeCredentials) SaveCredential(ctx context.Context, credential *models.Credential) error {
+	f[credential.ClientID+"/"+credential.Kind+"/"+credential.Name] = credential
+	return nil
+}
+
+func (f fakeCredentials) put(t *testing.T, client *models.Client, kind, name string, data []byte) {
+	t.Helper()
+	secret, err := utils.EncryptSecret(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.SaveCredential(context.Background(), &models.Credential{ClientID: client.ID.String(), Kind: kind, Name: name, Secret: secret})
+}
+
+func testClient() *models.Client {
+	return &models.Client{ID: uuid.New(), Nombre: "acme"}
+}
+
+func TestValidateBindingConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		backend    string
+		cfg        map[string]interface{}
+		selfHosted bool
+		wantErr    bool
+	}{
+		{name: "docker without host", backend: models.BackendDocker, cfg: map[string]interface{}{}, wantErr: true},
+		{name: "docker local socket", backend: models.BackendDocker, cfg: map[string]interface{}{"host": "unix:///var/run/docker.sock"}, wantErr: true},
+		{name: "docker local socket self-hosted", backend: models.BackendDocker, cfg: map[string]interface{}{"host": "unix:///var/run/docker.sock"}, selfHosted: true},
+		{name: "docker plain tcp", backend: models.BackendDocker, cfg: map[string]interface{}{"host": "tcp://10.0.0.5:2375"}, wantErr: true},
+		{name: "docker tcp with tls", backend: models.BackendDocker, cfg: map[string]interface{}{"host": "tcp://docker.acme.io:2376", "tls": "prod"}},
+		{name: "docker tcp without port", backend: models.BackendDocker, cfg: map[string]interface{}{"host": "tcp://docker.acme.io", "tls": "prod"}, wantErr: true},
+		{name: "docker http scheme", backend: models.BackendDocker, cfg: map[string]interface{}{"host": "http://docker.acme.io:2376", "tls": "prod"}, wantErr: true},
+		{name: "gitops without repo", backend: models.BackendGitOps, cfg: map[string]interface{}{}, wantErr: true},
+		{name: "gitops https", backend: models.BackendGitOps, cfg: map[string]interface{}{"repo": "https://github.com/acme/deploy.git"}},
+		{name: "gitops local path", backend: models.BackendGitOps, cfg: map[string]interface{}{"repo": "/srv/git/deploy.git"}, wantErr: true},
+		{name: "gitops local path self-hosted", backend: models.BackendGitOps, cfg: map[string]interface{}{"repo": "/srv/git/deploy.git"}, selfHosted: true},
+		{name: "gitops branches", backend: models.BackendGitOps, cfg: map[string]interface{}{"repo": "https://github.com/acme/deploy.git", "branch": "release/2024", "revert_branch": "ops/revert-"}},
+		{name: "gitops branch as an option", backend: models.BackendGitOps, cfg: map[string]interface{}{"repo": "https://github.com/acme/deploy.git", "branch": "--upload-pack=touch /tmp/pwned"}, wantErr: true},
+		{name: "gitops revert branch as an option", backend: models.BackendGitOps, cfg: map[string]interface{}{"repo": "https://github.com/acme/deploy.git", "revert_branch": "--receive-pack=touch /tmp/pwned"}, wantErr: true},
+		{name: "gitops invalid ref", backend: models.BackendGitOps, cfg: map[string]interface{}{"repo": "https://github.com/acme/deploy.git", "branch": "main..dev"}, wantErr: true},
+		{name: "gitops previous branch syntax", backend: models.BackendGitOps, cfg: map[string]interface{}{"repo": "https://github.com/acme/deploy.git", "branch": "@{-1}"}, wantErr: true},
+		{name: "webhook has nothing to check", backend: models.BackendWebhook, cfg: map[string]interface{}{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.selfHosted {
+				t.Setenv("INFRAGENT_SELF_HOSTED", "true")
+			}
+			err := ValidateBindingConfig(tt.backend, tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
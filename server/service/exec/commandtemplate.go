@@ -0,0 +1,184 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	models "server/model"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const (
+	defaultCommandTimeout = 60
+	maxCommandTimeout     = 600 // hard limit, no importa lo que diga el template
+)
+
+// RenderCommand valida los params de la decision contra el schema del template y arma
+// el comando. Todos los valores van entre comillas simples: nunca se interpretan en el shell.
+func RenderCommand(tpl *models.CommandTemplate, params map[string]interface{}) (string, error) {
+	values, err := validateParams(tpl.ParamsSchema, params)
+	if err != nil {
+		return "", err
+	}
+
+	t, err := template.New("command").Option("missingkey=error").Parse(tpl.Template)
+	if err != nil {
+		return "", fmt.Errorf("invalid command template: %w", err)
+	}
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, values); err != nil {
+		return "", fmt.Errorf("render command: %w", err)
+	}
+	return sb.String(), nil
+}
+
+// ValidateCommandTemplate chequea al guardar que el template compile y que solo use params del schema
+func ValidateCommandTemplate(tpl *models.CommandTemplate) error {
+	t, err := template.New("command").Option("missingkey=error").Parse(tpl.Template)
+	if err != nil {
+		return fmt.Errorf("invalid command template: %w", err)
+	}
+
+	if err := ValidateParamsSchema(tpl.ParamsSchema); err != nil {
+		return err
+	}
+
+	sample := make(map[string]string, len(tpl.ParamsSchema))
+	for name := range tpl.ParamsSchema {
+		sample[name] = "x"
+	}
+
+	if err := t.Execute(&strings.Builder{}, sample); err != nil {
+		return fmt.Errorf("template uses params outside params_schema: %w", err)
+	}
+
+	if tpl.TimeoutSeconds < 0 || tpl.TimeoutSeconds > maxCommandTimeout {
+		return fmt.Errorf("timeout_seconds must be between 0 (default) and %d", maxCommandTimeout)
+	}
+	return nil
+}
+
+func commandTimeout(tpl *models.CommandTemplate) int {
+	if tpl.TimeoutSeconds <= 0 {
+		return defaultCommandTimeout
+	}
+	return min(tpl.TimeoutSeconds, maxCommandTimeout)
+}
+
+// ValidateParamsSchema chequea los tipos y patterns de un schema de params
+// (el de un template o el que registro el SDK para una accion)
+func ValidateParamsSchema(schema map[string]models.ParamSchema) error {
+	for name, s := range schema {
+		switch s.Type {
+		case "string", "integer", "boolean":
+		default:
+			return fmt.Errorf("param %q: type must be string, integer or boolean", name)
+		}
+		if s.Pattern != "" {
+			if _, err := regexp.Compile(s.Pattern); err != nil {
+				return fmt.Errorf("param %q: invalid pattern: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateParams valida los params de una decision contra el schema, sin armar nada
+func ValidateParams(schema map[string]models.ParamSchema, params map[string]interface{}) error {
+	_, err := validateParams(schema, params)
+	return err
+}
+
+func validateParams(schema map[string]models.ParamSchema, params map[string]interface{}) (map[string]string, error) {
+	// params que no estan en el schema se rechazan, no se ignoran
+	for name := range params {
+		if _, ok := schema[name]; !ok {
+			return nil, fmt.Errorf("param %q is not allowed", name)
+		}
+	}
+
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make(map[string]string, len(schema))
+	for _, name := range names {
+		s := schema[name]
+
+		value, ok := params[name]
+		if !ok || value == nil {
+			if s.Default != nil {
+				value = s.Default
+			} else if s.Required {
+				return nil, fmt.Errorf("param %q is required", name)
+			} else {
+				values[name] = "''"
+				continue
+			}
+		}
+
+		str, err := paramString(name, s, value)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = shellQuote(str)
+	}
+	return values, nil
+}
+
+func paramString(name string, s models.ParamSchema, value interface{}) (string, error) {
+	var str string
+
+	switch s.Type {
+	case "integer":
+		n, err := toInt(value)
+		if err != nil {
+			return "", fmt.Errorf("param %q: %w", name, err)
+		}
+		if s.Min != nil && float64(n) < *s.Min {
+			return "", fmt.Errorf("param %q: %d is below min %v", name, n, *s.Min)
+		}
+		if s.Max != nil && float64(n) > *s.Max {
+			return "", fmt.Errorf("param %q: %d is above max %v", name, n, *s.Max)
+		}
+		str = strconv.Itoa(n)
+	case "boolean":
+		b, ok := value.(bool)
+		if !ok {
+			return "", fmt.Errorf("param %q must be a boolean", name)
+		}
+		str = strconv.FormatBool(b)
+	case "string":
+		v, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("param %q must be a string", name)
+		}
+		if s.Pattern != "" {
+			re, err := regexp.Compile("^(?:" + s.Pattern + ")$")
+			if err != nil {
+				return "", fmt.Errorf("param %q: invalid pattern: %w", name, err)
+			}
+			if !re.MatchString(v) {
+				return "", fmt.Errorf("param %q does not match %s", name, s.Pattern)
+			}
+		}
+		str = v
+	default:
+		return "", fmt.Errorf("param %q: unknown type %q", name, s.Type)
+	}
+
+	if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+		return "", fmt.Errorf("param %q must be one of %v", name, s.Enum)
+	}
+	return str, nil
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
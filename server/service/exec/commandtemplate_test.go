@@ -0,0 +1,147 @@
+package service
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	models "server/model"
+	"strings"
+	"testing"
+)
+
+func TestRenderCommandQuoting(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "pwned")
+	tpl := &models.CommandTemplate{Template: "printf '%s' {{.msg}}", ParamsSchema: map[string]models.ParamSchema{"msg": {Type: "string"}}}
+
+	tests := []struct {
+		name string
+		msg  string
+	}{
+		{name: "single quotes", msg: "it's 'quoted'"},
+		{name: "double quotes", msg: `say "hi"`},
+		{name: "command substitution", msg: "$(touch " + marker + ")"},
+		{name: "backticks", msg: "`touch " + marker + "`"},
+		{name: "variables", msg: "$HOME ${PATH}"},
+		{name: "newline", msg: "line1\ntouch " + marker},
+		{name: "separators", msg: "x; touch " + marker + " && echo || true | cat"},
+		{name: "empty", msg: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, err := RenderCommand(tpl, map[string]interface{}{"msg": tt.msg})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// el shell del otro lado tiene que recibir el valor tal cual, sin interpretarlo
+			out, err := exec.Command("sh", "-c", command).Output()
+			if err != nil {
+				t.Fatalf("sh -c %q: %v", command, err)
+			}
+			if string(out) != tt.msg {
+				t.Errorf("the shell got %q, want %q (command %q)", out, tt.msg, command)
+			}
+			if _, err := os.Stat(marker); err == nil {
+				t.Fatalf("%q ran a command", command)
+			}
+		})
+	}
+}
+
+func TestRenderCommandParams(t *testing.T) {
+	min, max := 1.0, 10.0
+	tpl := &models.CommandTemplate{
+		Template: "scale {{.service}} {{.replicas}} {{.force}} {{.mode}}",
+		ParamsSchema: map[string]models.ParamSchema{
+			"service":  {Type: "string", Required: true, Pattern: "[a-z0-9-]+"},
+			"replicas": {Type: "integer", Min: &min, Max: &max, Default: 2.0},
+			"force":    {Type: "boolean"},
+			"mode":     {Type: "string", Enum: []string{"fast", "safe"}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		want    string
+		wantErr string
+	}{
+		{name: "all params", params: map[string]interface{}{"service": "api", "replicas": 3.0, "force": true, "mode": "safe"}, want: "scale 'api' '3' 'true' 'safe'"},
+		{name: "default and empty optional", params: map[string]interface{}{"service": "api"}, want: "scale 'api' '2' '' ''"},
+		{name: "unknown param", params: map[string]interface{}{"service": "api", "cmd": "ls"}, wantErr: `param "cmd" is not allowed`},
+		{name: "missing required", params: map[string]interface{}{}, wantErr: `param "service" is required`},
+		{name: "pattern is anchored", params: map[string]interface{}{"service": "api; ls"}, wantErr: "does not match"},
+		{name: "below min", params: map[string]interface{}{"service": "api", "replicas": 0.0}, wantErr: "below min"},
+		{name: "above max", params: map[string]interface{}{"service": "api", "replicas": 11.0}, wantErr: "above max"},
+		{name: "fractional integer", params: map[string]interface{}{"service": "api", "replicas": 2.5}, wantErr: "not an integer"},
+		{name: "string for an integer", params: map[string]interface{}{"service": "api", "replicas": "3"}, wantErr: "not a number"},
+		{name: "string for a boolean", params: map[string]interface{}{"service": "api", "force": "true"}, wantErr: "must be a boolean"},
+		{name: "number for a string", params: map[string]interface{}{"service": 1.0}, wantErr: "must be a string"},
+		{name: "outside the enum", params: map[string]interface{}{"service": "api", "mode": "yolo"}, wantErr: "must be one of"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, err := RenderCommand(tpl, tt.params)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if command != tt.want {
+				t.Errorf("command = %q, want %q", command, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCommandTemplate(t *testing.T) {
+	schema := map[string]models.ParamSchema{"unit": {Type: "string"}}
+
+	tests := []struct {
+		name    string
+		tpl     models.CommandTemplate
+		wantErr bool
+	}{
+		{name: "valid", tpl: models.CommandTemplate{Template: "systemctl restart {{.unit}}", ParamsSchema: schema, TimeoutSeconds: 30}},
+		{name: "param outside the schema", tpl: models.CommandTemplate{Template: "systemctl restart {{.service}}", ParamsSchema: schema}, wantErr: true},
+		{name: "does not compile", tpl: models.CommandTemplate{Template: "systemctl restart {{.unit", ParamsSchema: schema}, wantErr: true},
+		{name: "unknown type", tpl: models.CommandTemplate{Template: "echo {{.n}}", ParamsSchema: map[string]models.ParamSchema{"n": {Type: "float"}}}, wantErr: true},
+		{name: "invalid pattern", tpl: models.CommandTemplate{Template: "echo {{.n}}", ParamsSchema: map[string]models.ParamSchema{"n": {Type: "string", Pattern: "[a-z"}}}, wantErr: true},
+		{name: "timeout over the cap", tpl: models.CommandTemplate{Template: "uptime", TimeoutSeconds: maxCommandTimeout + 1}, wantErr: true},
+		{name: "negative timeout", tpl: models.CommandTemplate{Template: "uptime", TimeoutSeconds: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCommandTemplate(&tt.tpl)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCommandTimeout(t *testing.T) {
+	tests := []struct {
+		seconds int
+		want    int
+	}{
+		{seconds: 0, want: defaultCommandTimeout},
+		{seconds: -5, want: defaultCommandTimeout},
+		{seconds: 30, want: 30},
+		// un template guardado antes del limite no lo saltea
+		{seconds: 100000, want: maxCommandTimeout},
+	}
+
+	for _, tt := range tests {
+		if got := commandTimeout(&models.CommandTemplate{TimeoutSeconds: tt.seconds}); got != tt.want {
+			t.Errorf("commandTimeout(%d) = %d, want %d", tt.seconds, got, tt.want)
+		}
+	}
+}
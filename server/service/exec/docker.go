@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	models "server/model"
+	"server/repositories"
+	"server/utils"
+	"strings"
+	"time"
+)
+
+// DockerBackend reinicia, para, arranca e inspecciona contenedores de un Docker Engine del cliente.
+//
+// Config del binding (la regla que mapea el target a contenedores):
+//
+//	host          tcp://host:2376 (requerido); unix:///var/run/docker.sock solo self-hosted
+//	tls           nombre de la credencial docker_tls (requerida para tcp://)
+//	container     nombre exacto del contenedor (default: el target si no hay label)
+//	label         label que deben tener los contenedores, ej "app=payments"
+//	stop_timeout  segundos que espera docker antes de matar el contenedor (default 10)
+//
+// stop y start se deshacen con la accion contraria sobre el mismo target.
+type DockerBackend struct {
+	credentials repositories.CredentialStorage
+	timeout     time.Duration
+}
+
+func NewDockerBackend(credentials repositories.CredentialStorage) *DockerBackend {
+	return &DockerBackend{credentials: credentials, timeout: 2 * time.Minute}
+}
+
+func (d *DockerBackend) Deliver(ctx context.Context, action *models.Action, decision *models.LLMDecision, client *models.Client, binding *models.TargetBinding) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	cfg := map[string]interface{}{}
+	if binding != nil {
+		cfg = binding.Config
+	}
+
+	result := map[string]interface{}{"backend": models.BackendDocker}
+
+	docker, err := d.clientFor(ctx, client, cfg)
+	if err != nil {
+		setResult(action, result, err)
+		return
+	}
+
+	label := configString(cfg, "label", "")
+	name := configString(cfg, "container", "")
+	if name == "" && label == "" {
+		name = action.Target
+	}
+
+	containers, err := docker.FindContainers(ctx, name, label)
+	if err != nil {
+		setResult(action, result, err)
+		return
+	}
+	if len(containers) == 0 {
+		setResult(action, result, fmt.Errorf("no containers match target %q", action.Target))
+		return
+	}
+
+	stopTimeout := configInt(cfg, "stop_timeout", 10)
+
+	states := make([]map[string]interface{}, 0, len(containers))
+	before := make([]map[string]interface{}, 0, len(containers))
+	wasRunning, wasStopped := false, false
+	var errs []error
+
+	for _, c := range containers {
+		// antes de actuar: si estaba corriendo o no (para poder deshacer stop/start)
+		if info, inspectErr := docker.Inspect(ctx, c.ID); inspectErr == nil {
+			before = append(before, map[string]interface{}{"id": c.ID, "state": info.State.Status, "running": info.State.Running})
+			if info.State.Running {
+				wasRunning = true
+			} else {
+				wasStopped = true
+			}
+		}
+
+		switch action.Type {
+		case "restart":
+			err = docker.Restart(ctx, c.ID, stopTimeout)
+		case "stop":
+			err = docker.Stop(ctx, c.ID, stopTimeout)
+		case "start":
+			err = docker.Start(ctx, c.ID)
+		case "inspect":
+			err = nil
+		default:
+			setResult(action, result, fmt.Errorf("action %q not supported by docker backend", action.Type))
+			return
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		// despues de actuar guardamos como quedo el contenedor
+		state := map[string]interface{}{"id": c.ID, "name": strings.TrimPrefix(firstName(c.Names), "/")}
+		if info, inspectErr := docker.Inspect(ctx, c.ID); inspectErr == nil {
+			state["state"] = info.State.Status
+			state["running"] = info.State.Running
+			state["exit_code"] = info.State.ExitCode
+			state["restart_count"] = info.RestartCount
+			state["started_at"] = info.State.StartedAt
+			if info.State.Error != "" {
+				state["error"] = info.State.Error
+			}
+		} else {
+			state["inspect_error"] = inspectErr.Error()
+		}
+		states = append(states, state)
+	}
+
+	result["containers"] = states
+
+	switch {
+	case action.Type == "stop" && wasRunning:
+		setPreState(action, map[string]interface{}{"containers": before}, "start", map[string]interface{}{})
+	case action.Type == "start" && wasStopped:
+		setPreState(action, map[string]interface{}{"containers": before}, "stop", map[string]interface{}{})
+	case action.Type != "inspect":
+		setPreState(action, map[string]interface{}{"containers": before}, "", nil)
+	}
+
+	setResult(action, result, errors.Join(errs...))
+}
+
+func (d *DockerBackend) clientFor(ctx context.Context, client *models.Client, cfg map[string]interface{}) (*DockerClient, error) {
+	if err := validateDockerConfig(cfg); err != nil {
+		return nil, err
+	}
+	host := configString(cfg, "host", "")
+
+	var tlsCreds *DockerTLS
+	if name := configString(cfg, "tls", ""); name != "" {
+		credential, err := d.credentials.GetCredential(ctx, client.ID.String(), models.CredentialDockerTLS, name)
+		if err != nil {
+			if errors.Is(err, repositories.ErrCredentialNotFound) {
+				return nil, fmt.Errorf("docker tls credential %q not configured", name)
+			}
+			return nil, err
+		}
+
+		data, err := utils.DecryptSecret(credential.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt docker tls: %w", err)
+		}
+		if tlsCreds, err = ParseDockerTLS(data); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewDockerClient(host, tlsCreds)
+}
+
+// validateDockerConfig se corre al guardar el binding y otra vez antes de conectar
+// (un binding viejo pudo guardarse sin host)
+func validateDockerConfig(cfg map[string]interface{}) error {
+	return ValidateDockerHost(configString(cfg, "host", ""), configString(cfg, "tls", "") != "")
+}
+
+func firstName(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	models "server/model"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDocker responde como un Docker Engine en un socket unix
+type fakeDocker struct {
+	mu         sync.Mutex
+	containers []fakeContainer
+	calls      []string // "restart api-1 t=10"
+	fail       string   // id que devuelve 500 en cualquier accion
+}
+
+type fakeContainer struct {
+	id, name string
+	labels   map[string]string
+	running  bool
+}
+
+func (f *fakeDocker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == http.MethodGet && r.URL.Path == "/containers/json" {
+		var filters map[string][]string
+		json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters)
+
+		list := []DockerContainer{}
+		for _, c := range f.containers {
+			// como docker: name por substring, label exacto
+			if len(filters["name"]) > 0 && !strings.Contains(c.name, filters["name"][0]) {
+				continue
+			}
+			if len(filters["label"]) > 0 {
+				key, value, _ := strings.Cut(filters["label"][0], "=")
+				if c.labels[key] != value {
+					continue
+				}
+			}
+			list = append(list, DockerContainer{ID: c.id, Names: []string{"/" + c.name}, State: state(c.running)})
+		}
+		json.NewEncoder(w).Encode(list)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/containers/"), "/")
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	c := f.find(parts[0])
+	if c == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "No such container: " + parts[0]})
+		return
+	}
+
+	if r.Method == http.MethodGet && parts[1] == "json" {
+		info := DockerInspect{ID: c.id, Name: "/" + c.name, RestartCount: 1}
+		info.State.Status, info.State.Running = state(c.running), c.running
+		json.NewEncoder(w).Encode(info)
+		return
+	}
+
+	f.calls = append(f.calls, strings.TrimSpace(parts[1]+" "+c.id+" "+r.URL.RawQuery))
+	if c.id == f.fail {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "cannot kill container: permission denied"})
+		return
+	}
+
+	switch parts[1] {
+	case "restart":
+		c.running = true
+	case "stop", "start":
+		want := parts[1] == "start"
+		if c.running == want {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		c.running = want
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeDocker) find(id string) *fakeContainer {
+	for i := range f.containers {
+		if f.containers[i].id == id {
+			return &f.containers[i]
+		}
+	}
+	return nil
+}
+
+func state(running bool) string {
+	if running {
+		return "running"
+	}
+	return "exited"
+}
+
+// serveDocker levanta el fake en un socket unix y devuelve el host para el binding
+func serveDocker(t *testing.T, docker *fakeDocker) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(docker)
+	srv.Listener = listener
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return "unix://" + socket
+}
+
+func TestDockerBackendDeliver(t *testing.T) {
+	tests := []struct {
+		name      string
+		action    string
+		cfg       map[string]interface{}
+		fail      string
+		wantErr   string
+		wantCalls []string
+		wantUndo  string // accion compensatoria en el pre_state ("" = sin undo)
+		noPre     bool
+	}{
+		{
+			name:      "restart by exact name",
+			action:    "restart",
+			wantCalls: []string{"restart c-api t=10"},
+		},
+		{
+			name:      "restart every container with the label",
+			action:    "restart",
+			cfg:       map[string]interface{}{"label": "app=payments", "stop_timeout": float64(30)},
+			wantCalls: []string{"restart c-pay-1 t=30", "restart c-pay-2 t=30"},
+		},
+		{
+			name:      "stop a running container can be undone with start",
+			action:    "stop",
+			wantCalls: []string{"stop c-api t=10"},
+			wantUndo:  "start",
+		},
+		{
+			name:      "start a stopped container can be undone with stop",
+			action:    "start",
+			cfg:       map[string]interface{}{"container": "worker"},
+			wantCalls: []string{"start c-worker"},
+			wantUndo:  "stop",
+		},
+		{
+			name:      "stop an already stopped container is not an error",
+			action:    "stop",
+			cfg:       map[string]interface{}{"container": "worker"},
+			wantCalls: []string{"stop c-worker t=10"},
+		},
+		{
+			name:   "inspect doesn't touch anything",
+			action: "inspect",
+			noPre:  true,
+		},
+		{
+			name:    "no containers match",
+			action:  "restart",
+			cfg:     map[string]interface{}{"container": "db"},
+			wantErr: `no containers match target "api"`,
+			noPre:   true,
+		},
+		{
+			name:      "docker error carries the message",
+			action:    "restart",
+			fail:      "c-api",
+			wantErr:   "permission denied",
+			wantCalls: []string{"restart c-api t=10"},
+		},
+		{
+			name:    "unsupported action",
+			action:  "scale",
+			wantErr: "not supported by docker backend",
+			noPre:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("INFRAGENT_SELF_HOSTED", "true")
+
+			docker := &fakeDocker{fail: tt.fail, containers: []fakeContainer{
+				{id: "c-api", name: "api", running: true},
+				{id: "c-api-worker", name: "api-worker", running: true},
+				{id: "c-worker", name: "worker"},
+				{id: "c-pay-1", name: "payments-1", labels: map[string]string{"app": "payments"}, running: true},
+				{id: "c-pay-2", name: "payments-2", labels: map[string]string{"app": "payments"}, running: true},
+			}}
+
+			cfg := map[string]interface{}{"host": serveDocker(t, docker)}
+			for k, v := range tt.cfg {
+				cfg[k] = v
+			}
+			action := &models.Action{Type: tt.action, Target: "api", Status: models.ActionStatusPending}
+
+			NewDockerBackend(fakeCredentials{}).Deliver(context.Background(), action, &models.LLMDecision{Action: tt.action, Target: "api"}, testClient(), &models.TargetBinding{Backend: models.BackendDocker, Config: cfg})
+
+			if tt.wantErr != "" {
+				errMsg, _ := action.Result["error"].(string)
+				if action.Status != models.ActionStatusFailed || !strings.Contains(errMsg, tt.wantErr) {
+					t.Fatalf("status = %s, error = %q, want %q", action.Status, errMsg, tt.wantErr)
+				}
+			} else if action.Status != models.ActionStatusSuccess {
+				t.Fatalf("status = %s, result = %v", action.Status, action.Result)
+			}
+
+			docker.mu.Lock()
+			defer docker.mu.Unlock()
+			if strings.Join(docker.calls, ",") != strings.Join(tt.wantCalls, ",") {
+				t.Errorf("calls = %v, want %v", docker.calls, tt.wantCalls)
+			}
+
+			if tt.noPre {
+				if action.PreState != nil {
+					t.Errorf("pre_state = %v, want none", action.PreState)
+				}
+				return
+			}
+			undo, _ := action.PreState[models.PreStateUndoKey].(map[string]interface{})
+			if got, _ := undo["action"].(string); got != tt.wantUndo {
+				t.Errorf("undo = %v, want %q", undo, tt.wantUndo)
+			}
+		})
+	}
+}
+
+func TestDockerBackendRefusesServerSocket(t *testing.T) {
+	docker := &fakeDocker{containers: []fakeContainer{{id: "c-api", name: "api", running: true}}}
+	cfg := map[string]interface{}{"host": serveDocker(t, docker)}
+	action := &models.Action{Type: "restart", Target: "api"}
+
+	NewDockerBackend(fakeCredentials{}).Deliver(context.Background(), action, &models.LLMDecision{Action: "restart"}, testClient(), &models.TargetBinding{Backend: models.BackendDocker, Config: cfg})
+
+	if action.Status != models.ActionStatusFailed || len(docker.calls) != 0 {
+		t.Fatalf("status = %s, calls = %v: the server's own socket must need self-hosted", action.Status, docker.calls)
+	}
+}
+
+func TestNewDockerClientTCP(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    string
+		tls     *DockerTLS
+		wantErr bool
+	}{
+		{name: "plain tcp", host: "tcp://10.0.0.5:2375", wantErr: true},
+		{name: "tls without key", host: "tcp://10.0.0.5:2376", tls: &DockerTLS{CA: "ca", Cert: "cert"}, wantErr: true},
+		{name: "http scheme", host: "http://10.0.0.5:2375", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewDockerClient(tt.host, tt.tls)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"server/utils"
+	"strings"
+	"time"
+)
+
+// DockerTLS son los certificados para hablar con un Docker Engine por TCP+TLS
+type DockerTLS struct {
+	CA   string `json:"ca"`
+	Cert string `json:"cert"`
+	Key  string `json:"key"`
+}
+
+// config exige CA y certificado de cliente: la API de Docker equivale a root en el host,
+// no hablamos con un daemon que no verifica quien somos ni con uno que no podemos verificar
+func (d DockerTLS) config() (*tls.Config, error) {
+	if d.CA == "" || d.Cert == "" || d.Key == "" {
+		return nil, errors.New("docker tls needs ca, cert and key")
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(d.CA)) {
+		return nil, errors.New("invalid docker ca")
+	}
+	cert, err := tls.X509KeyPair([]byte(d.Cert), []byte(d.Key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker client certificate: %w", err)
+	}
+
+	return &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: pool, Certificates: []tls.Certificate{cert}}, nil
+}
+
+// ParseDockerTLS valida la credencial docker_tls
+func ParseDockerTLS(data []byte) (*DockerTLS, error) {
+	var d DockerTLS
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("invalid docker_tls credential: %w", err)
+	}
+	if _, err := d.config(); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// DockerClient habla con la Docker Engine API (unix:///var/run/docker.sock o tcp://host:2376)
+type DockerClient struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// ValidateDockerHost chequea el host de un binding: tcp:// con credencial TLS, o el socket
+// unix del servidor solo si la instalacion es self-hosted (si no seria el Docker de InfrAgent)
+func ValidateDockerHost(host string, hasTLS bool) error {
+	if host == "" {
+		return errors.New("docker host is required")
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return fmt.Errorf("invalid docker host: %w", err)
+	}
+
+	switch u.Scheme {
+	case "unix":
+		if !utils.SelfHosted() {
+			return errors.New("unix:// docker hosts are only allowed on self-hosted installs")
+		}
+	case "tcp":
+		if u.Hostname() == "" || u.Port() == "" {
+			return fmt.Errorf("docker host must be tcp://host:port, got %q", host)
+		}
+		if !hasTLS {
+			return errors.New("tcp:// docker hosts need a docker_tls credential (plain tcp is not allowed)")
+		}
+	default:
+		return fmt.Errorf("docker host must be unix:// or tcp://, got %q", host)
+	}
+	return nil
+}
+
+// NewDockerClient: tcp:// siempre con TLS y a traves del OutboundGuard (el host lo elige el cliente)
+func NewDockerClient(host string, tlsCreds *DockerTLS) (*DockerClient, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host: %w", err)
+	}
+
+	var transport *http.Transport
+	client := &DockerClient{}
+
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		}
+		client.baseURL = "http://docker"
+	case "tcp":
+		if tlsCreds == nil {
+			return nil, errors.New("tcp:// docker hosts need tls")
+		}
+		cfg, err := tlsCreds.config()
+		if err != nil {
+			return nil, err
+		}
+		transport = utils.OutboundGuard().Transport(cfg)
+		client.baseURL = "https://" + u.Host
+	default:
+		return nil, fmt.Errorf("docker host must be unix:// or tcp://, got %q", host)
+	}
+
+	client.httpClient = &http.Client{Timeout: 60 * time.Second, Transport: transport}
+	return client, nil
+}
+
+func (d *DockerClient) do(ctx context.Context, method, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, d.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 304 = el contenedor ya estaba en ese estado (start/stop)
+	if resp.StatusCode >= 400 {
+		var body struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body)
+		return fmt.Errorf("docker %s %s: %d %s", method, path, resp.StatusCode, body.Message)
+	}
+
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+type DockerContainer struct {
+	ID    string   `json:"Id"`
+	Names []string `json:"Names"`
+	State string   `json:"State"`
+}
+
+// FindContainers busca por nombre exacto o por label ("app=payments")
+func (d *DockerClient) FindContainers(ctx context.Context, name, label string) ([]DockerContainer, error) {
+	filters := map[string][]string{}
+	if name != "" {
+		filters["name"] = []string{name}
+	}
+	if label != "" {
+		filters["label"] = []string{label}
+	}
+	if len(filters) == 0 {
+		return nil, errors.New("container name or label is required")
+	}
+
+	filtersJSON, _ := json.Marshal(filters)
+
+	var containers []DockerContainer
+	if err := d.do(ctx, http.MethodGet, "/containers/json?all=1&filters="+url.QueryEscape(string(filtersJSON)), &containers); err != nil {
+		return nil, err
+	}
+
+	// el filtro "name" de docker matchea por substring
+	if name == "" {
+		return containers, nil
+	}
+	exact := containers[:0]
+	for _, c := range containers {
+		for _, n := range c.Names {
+			if strings.TrimPrefix(n, "/") == name {
+				exact = append(exact, c)
+				break
+			}
+		}
+	}
+	return exact, nil
+}
+
+func (d *DockerClient) Restart(ctx context.Context, id string, stopTimeout int) error {
+	return d.do(ctx, http.MethodPost, fmt.Sprintf("/containers/%s/restart?t=%d", url.PathEscape(id), stopTimeout), nil)
+}
+
+func (d *DockerClient) Stop(ctx context.Context, id string, stopTimeout int) error {
+	return d.do(ctx, http.MethodPost, fmt.Sprintf("/containers/%s/stop?t=%d", url.PathEscape(id), stopTimeout), nil)
+}
+
+func (d *DockerClient) Start(ctx context.Context, id string) error {
+	return d.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/start", nil)
+}
+
+type DockerInspect struct {
+	ID           string `json:"Id"`
+	Name         string `json:"Name"`
+	RestartCount int    `json:"RestartCount"`
+	State        struct {
+		Status     string `json:"Status"`
+		Running    bool   `json:"Running"`
+		ExitCode   int    `json:"ExitCode"`
+		Error      string `json:"Error"`
+		StartedAt  string `json:"StartedAt"`
+		FinishedAt string `json:"FinishedAt"`
+	} `json:"State"`
+}
+
+func (d *DockerClient) Inspect(ctx context.Context, id string) (*DockerInspect, error) {
+	var info DockerInspect
+	if err := d.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/json", &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
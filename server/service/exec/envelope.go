@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	models "server/model"
+	"time"
+)
+
+// ProtocolStorage guarda la version del envelope que negocio el SDK del cliente
+type ProtocolStorage interface {
+	UpdateProtocolVersion(ctx context.Context, id string, version int) error
+}
+
+// NewEnvelope arma el envelope (ProtocolV1) de una accion ya guardada
+func NewEnvelope(action *models.Action, decision models.LLMDecision, deadline *time.Time) models.ActionEnvelope {
+	eventIDs := action.EventIDs
+	if eventIDs == nil {
+		eventIDs = []string{}
+	}
+	decision.UndoOf = ""
+	if action.UndoOf != nil {
+		decision.UndoOf = *action.UndoOf
+	}
+
+	return models.ActionEnvelope{
+		ProtocolVersion: models.ProtocolV1,
+		ActionID:        action.ID,
+		AgentID:         action.AgentID,
+		EventIDs:        eventIDs,
+		Deadline:        deadline,
+		IssuedAt:        time.Now(),
+		Decision:        decision,
+	}
+}
+
+// DecisionOf rearma la decision de una accion guardada (para los comandos en modo pull)
+func DecisionOf(action *models.Action) models.LLMDecision {
+	decision := models.LLMDecision{
+		Action:     action.Type,
+		Target:     action.Target,
+		Params:     action.Params,
+		Reasoning:  action.Reasoning,
+		Confidence: action.Confidence,
+	}
+	if action.UndoOf != nil {
+		decision.UndoOf = *action.UndoOf
+	}
+	return decision
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	models "server/model"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNegotiateProtocol(t *testing.T) {
+	tests := []struct {
+		header string
+		want   int
+	}{
+		{header: "", want: models.ProtocolLegacy},
+		{header: "v1", want: models.ProtocolLegacy},
+		{header: "-1", want: models.ProtocolLegacy},
+		{header: "0", want: models.ProtocolLegacy},
+		{header: "1", want: models.ProtocolV1},
+		// un SDK mas nuevo que el backend habla la version del backend
+		{header: strconv.Itoa(models.ProtocolVersion + 1), want: models.ProtocolVersion},
+	}
+
+	for _, tt := range tests {
+		if got := models.NegotiateProtocol(tt.header); got != tt.want {
+			t.Errorf("NegotiateProtocol(%q) = %d, want %d", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestNewEnvelope(t *testing.T) {
+	undoOf := "action-0"
+	deadline := time.Now().Add(time.Minute)
+	action := &models.Action{ID: "action-1", AgentID: "agent-1", UndoOf: &undoOf}
+	decision := models.LLMDecision{Action: "start", Target: "api", UndoOf: "ignored"}
+
+	envelope := NewEnvelope(action, decision, &deadline)
+
+	if envelope.ProtocolVersion != models.ProtocolV1 || envelope.ActionID != "action-1" || envelope.AgentID != "agent-1" {
+		t.Errorf("envelope = %+v", envelope)
+	}
+	// el SDK siempre recibe una lista, nunca null
+	if envelope.EventIDs == nil {
+		t.Error("event_ids is nil")
+	}
+	// undo_of sale de la accion guardada, no de lo que diga la decision
+	if envelope.Decision.UndoOf != undoOf {
+		t.Errorf("undo_of = %q, want %q", envelope.Decision.UndoOf, undoOf)
+	}
+}
+
+// fakeProtocols recuerda las versiones que se guardaron
+type fakeProtocols struct {
+	mu      sync.Mutex
+	updates []int
+}
+
+func (f *fakeProtocols) UpdateProtocolVersion(ctx context.Context, id string, version int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.updates = append(f.updates, version)
+	return nil
+}
+
+func TestWebhookProtocolNegotiation(t *testing.T) {
+	tests := []struct {
+		name   string
+		stored int // version guardada del cliente
+		// sdkVersion es la que anuncia el SDK; -1 = un SDK viejo sin header que no entiende el envelope
+		sdkVersion  int
+		wantBodies  []int // version de cada body que recibio el SDK
+		wantUpdates []int // versiones guardadas despues de la respuesta
+	}{
+		{name: "v1 sdk gets the envelope", stored: models.ProtocolV1, sdkVersion: models.ProtocolV1, wantBodies: []int{models.ProtocolV1}},
+		{name: "legacy client upgrades when the sdk announces v1", stored: models.ProtocolLegacy, sdkVersion: models.ProtocolV1, wantBodies: []int{models.ProtocolLegacy}, wantUpdates: []int{models.ProtocolV1}},
+		{name: "old sdk gets the bare decision again and is downgraded", stored: models.ProtocolV1, sdkVersion: -1, wantBodies: []int{models.ProtocolV1, models.ProtocolLegacy}, wantUpdates: []int{models.ProtocolLegacy}},
+		{name: "newer sdk stays on the backend version", stored: models.ProtocolV1, sdkVersion: models.ProtocolVersion + 1, wantBodies: []int{models.ProtocolV1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var bodies []int
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]interface{}
+				json.NewDecoder(r.Body).Decode(&body)
+
+				// el header tiene que coincidir con lo que va en el body
+				version := models.ProtocolLegacy
+				if _, ok := body["decision"]; ok {
+					version = models.ProtocolV1
+				}
+				if r.Header.Get(models.ProtocolHeader) != strconv.Itoa(version) {
+					t.Errorf("header %q for a v%d body", r.Header.Get(models.ProtocolHeader), version)
+				}
+				mu.Lock()
+				bodies = append(bodies, version)
+				mu.Unlock()
+
+				if tt.sdkVersion < 0 {
+					if version != models.ProtocolLegacy {
+						w.WriteHeader(http.StatusBadRequest)
+						return
+					}
+				} else {
+					w.Header().Set(models.ProtocolHeader, strconv.Itoa(tt.sdkVersion))
+				}
+				json.NewEncoder(w).Encode(map[string]interface{}{"ok": true})
+			}))
+			defer srv.Close()
+
+			protocols := &fakeProtocols{}
+			client := testClient()
+			client.WebhookURL = srv.URL
+			client.ProtocolVersion = tt.stored
+			action := &models.Action{ID: "action-1", Type: "restart", Target: "api"}
+
+			NewWebhookBackend(time.Minute, protocols, nil).Deliver(context.Background(), action, &models.LLMDecision{Action: "restart", Target: "api"}, client, nil)
+
+			if action.Status != models.ActionStatusSuccess {
+				t.Fatalf("status = %s, result = %v", action.Status, action.Result)
+			}
+			if !slices.Equal(bodies, tt.wantBodies) {
+				t.Errorf("bodies = %v, want %v", bodies, tt.wantBodies)
+			}
+			if !slices.Equal(protocols.updates, tt.wantUpdates) {
+				t.Errorf("updates = %v, want %v", protocols.updates, tt.wantUpdates)
+			}
+		})
+	}
+}
@@ -1,40 +1,197 @@
 package service
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"net/http"
+	"errors"
+	"fmt"
+	"log"
 	models "server/model"
+	"server/repositories"
 	"time"
+
+	"github.com/google/uuid"
 )
 
-type Executor struct{}
+// tiempo maximo que le damos al SDK para reportar el resultado de una accion asincrona
+const DefaultAsyncTimeout = 15 * time.Minute
+
+// Backend entrega una accion a la infraestructura del cliente y deja en action su estado y resultado.
+// binding es la configuracion del target para ese backend (nil si el target no tiene binding).
+type Backend interface {
+	Deliver(ctx context.Context, action *models.Action, decision *models.LLMDecision, client *models.Client, binding *models.TargetBinding)
+}
+
+// notifier lo implementan los backends que despiertan al SDK para que venga a buscar la
+// accion: si lo hicieran dentro de Deliver, el SDK podria tomarla antes de que se guarde
+type notifier interface {
+	Notify(client *models.Client)
+}
+
+// Storage es todo lo que leen y escriben el executor y sus backends
+type Storage interface {
+	repositories.ActionStorage
+	repositories.TargetStorage
+	repositories.CredentialStorage
+	repositories.CommandTemplateStorage
+	ProtocolStorage
+	HealthStorage
+}
+
+type Executor struct {
+	actions  repositories.ActionStorage
+	targets  repositories.TargetStorage
+	monitor  *WebhookMonitor
+	backends map[string]Backend
+}
 
+func NewExecutor(storage Storage, broker *CommandBroker, asyncTimeout time.Duration) *Executor {
+	if asyncTimeout <= 0 {
+		asyncTimeout = DefaultAsyncTimeout
+	}
+	monitor := NewWebhookMonitor(storage, DefaultSuspendThreshold)
+	return &Executor{
+		actions: storage,
+		targets: storage,
+		monitor: monitor,
+		backends: map[string]Backend{
+			models.BackendWebhook:    NewWebhookBackend(asyncTimeout, storage, monitor),
+			models.BackendPull:       NewPullBackend(broker, asyncTimeout),
+			models.BackendKubernetes: NewKubernetesBackend(storage),
+			models.BackendDocker:     NewDockerBackend(storage),
+			models.BackendSSH:        NewSSHBackend(storage, storage),
+			models.BackendGitOps:     NewGitOpsBackend(storage, ""),
+		},
+	}
+}
+
+// WebhookMonitor devuelve el monitor de salud de los webhooks (para la API y el prober)
+func (e *Executor) WebhookMonitor() *WebhookMonitor {
+	return e.monitor
+}
+
+// RegisterBackend agrega o reemplaza un backend (ej: uno falso para probar el executor)
+func (e *Executor) RegisterBackend(name string, backend Backend) {
+	e.backends[name] = backend
+}
+
+// Execute guarda la accion como pending, la entrega por webhook (push) o la deja en cola
+// para el SDK (pull) segun el cliente, y guarda el estado resultante salvo que el SDK
+// ya haya reportado uno.
 func (e *Executor) Execute(ctx context.Context, decision *models.LLMDecision, agent *models.Agent, client *models.Client) *models.Action {
 
-	action := &models.Action{
-		AgentID:   agent.ID,
-		Type:      decision.Action,
-		Target:    decision.Target,
-		Reasoning: decision.Reasoning,
-		Status:    "pending",
+	action := newAction(decision, agent, client)
+
+	backend, binding, err := e.backendFor(ctx, client, decision.Target)
+	_, viaWebhook := backend.(*WebhookBackend)
+	if binding != nil {
+		action.Backend = binding.Backend
+	} else if err == nil {
+		action.Backend = backendName(client)
 	}
 
-	payload, _ := json.Marshal(decision)
+	// la guardamos antes de entregarla para que el SDK pueda reportar sobre ella
+	// (con su backend: /v1/commands solo sirve las del backend pull)
+	if err := e.actions.SaveAction(ctx, action); err != nil {
+		log.Printf("[Executor] no se pudo guardar la accion %s: %v", action.ID, err)
+	}
 
-	req, _ := http.NewRequestWithContext(ctx, "POST", client.WebhookURL, bytes.NewBuffer(payload))
-	req.Header.Set("Content-type", "application/json")
+	delivered := false
+	switch {
+	case err != nil:
+		action.Status = models.ActionStatusFailed
+		action.Result = map[string]interface{}{"error": err.Error()}
+	case viaWebhook && client.WebhookVerifiedAt == nil:
+		// el SDK todavia no demostro que el webhook es suyo (o cambio la url)
+		action.Status = models.ActionStatusFailed
+		action.Result = map[string]interface{}{"error": "webhook_unverified"}
+	case viaWebhook && e.monitor.Suspended(ctx, client.ID.String()):
+		// webhook caido: el agente queda notify-only hasta que el prober lo vea responder
+		e.monitor.NotifyOnly(ctx, action, client)
+	default:
+		backend.Deliver(ctx, action, decision, client, binding)
+		delivered = true
+	}
 
-	cliente := &http.Client{Timeout: 10 * time.Second}
-	response, err := cliente.Do(req)
+	updated, err := e.actions.UpdateDeliveredAction(ctx, action)
+	if err != nil {
+		log.Printf("[Executor] no se pudo actualizar la accion %s: %v", action.ID, err)
+	} else if !updated {
+		// el SDK reporto antes de que Deliver volviera (ej: runAsync antes del 202): vale lo suyo
+		if current, err := e.actions.GetAction(ctx, action.ID); err == nil {
+			action = current
+		}
+	}
 
-	if err != nil || response.StatusCode != 200 {
-		action.Status = "failed"
-		action.Result = map[string]interface{}{"error": "client_webhook_unreacheable"}
-	} else {
-		action.Status = "success"
+	if n, ok := backend.(notifier); ok && delivered {
+		n.Notify(client)
 	}
 
 	return action
 }
+
+// Reject guarda la accion como failed sin entregarla (ej: el target esta ocupado),
+// asi queda en el historial igual que las que se ejecutaron
+func (e *Executor) Reject(ctx context.Context, decision *models.LLMDecision, agent *models.Agent, client *models.Client, reason string) *models.Action {
+
+	action := newAction(decision, agent, client)
+	action.Status = models.ActionStatusFailed
+	action.Result = map[string]interface{}{"error": reason}
+
+	if err := e.actions.SaveAction(ctx, action); err != nil {
+		log.Printf("[Executor] no se pudo guardar la accion %s: %v", action.ID, err)
+	}
+
+	return action
+}
+
+func newAction(decision *models.LLMDecision, agent *models.Agent, client *models.Client) *models.Action {
+	now := time.Now()
+	action := &models.Action{
+		ID:         uuid.New().String(),
+		AgentID:    agent.ID,
+		ClientID:   client.ID.String(),
+		Type:       decision.Action,
+		Target:     decision.Target,
+		Params:     decision.Params,
+		Reasoning:  decision.Reasoning,
+		Confidence: decision.Confidence,
+		EventIDs:   decision.EventIDs,
+		Status:     models.ActionStatusPending,
+		Result:     map[string]interface{}{},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if decision.UndoOf != "" {
+		undoOf := decision.UndoOf
+		action.UndoOf = &undoOf
+	}
+	return action
+}
+
+// backendFor elige el backend del target: el de su binding si tiene uno,
+// si no el SDK del cliente segun su delivery mode (push/pull)
+func (e *Executor) backendFor(ctx context.Context, client *models.Client, target string) (Backend, *models.TargetBinding, error) {
+	binding, err := e.targets.GetTargetBinding(ctx, client.ID.String(), target)
+	if err != nil && !errors.Is(err, repositories.ErrTargetNotFound) {
+		return nil, nil, fmt.Errorf("target_binding_error: %w", err)
+	}
+
+	name := backendName(client)
+	if binding != nil {
+		name = binding.Backend
+	}
+
+	backend, ok := e.backends[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("backend_not_available: %s", name)
+	}
+	return backend, binding, nil
+}
+
+// backendName es el backend de los targets sin binding: el SDK del cliente por push o pull
+func backendName(client *models.Client) string {
+	if client.DeliveryMode == models.DeliveryPull {
+		return models.BackendPull
+	}
+	return models.BackendWebhook
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	models "server/model"
+	"server/repositories"
+	"testing"
+	"time"
+)
+
+// racingBackend hace que el SDK reporte antes de que Deliver vuelva, como runAsync
+// corriendo antes de que el 202 llegue al executor
+type racingBackend struct {
+	actions *fakeActions
+	queue   *ExecutionQueue
+	report  string // lo que reporta el SDK ("" = nada)
+}
+
+func (b *racingBackend) Deliver(ctx context.Context, action *models.Action, decision *models.LLMDecision, client *models.Client, binding *models.TargetBinding) {
+	if b.report != "" {
+		reported := *action
+		reported.Status = b.report
+		reported.Result = map[string]interface{}{"replicas": 3.0}
+		b.actions.UpdateActionStatus(ctx, &reported)
+		b.queue.Finished(&reported)
+	}
+
+	deadline := time.Now().Add(time.Minute)
+	action.Status = models.ActionStatusRunning
+	action.DeadlineAt = &deadline
+	action.Result = map[string]interface{}{"status": "accepted"}
+}
+
+func TestExecuteKeepsWhatTheSDKReported(t *testing.T) {
+	tests := []struct {
+		name       string
+		report     string
+		wantStatus string
+		wantResult string // clave que tiene que quedar en el resultado guardado
+		wantLocked bool
+	}{
+		{name: "sdk reported nothing yet", wantStatus: models.ActionStatusRunning, wantResult: "status", wantLocked: true},
+		{name: "sdk reported running", report: models.ActionStatusRunning, wantStatus: models.ActionStatusRunning, wantResult: "replicas", wantLocked: true},
+		{name: "sdk reported success", report: models.ActionStatusSuccess, wantStatus: models.ActionStatusSuccess, wantResult: "replicas"},
+		{name: "sdk reported failed", report: models.ActionStatusFailed, wantStatus: models.ActionStatusFailed, wantResult: "replicas"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions := newFakeActions()
+			backend := &racingBackend{actions: actions, report: tt.report}
+			executor := &Executor{actions: actions, targets: fakeTargets{}, backends: map[string]Backend{"test": backend}}
+			queue := NewExecutionQueue(executor, fakeConfigs{config: models.ClientConfig{ConflictRule: models.ConflictQueue}})
+			backend.queue = queue
+			client := testClient()
+
+			ticket, err := queue.Submit(context.Background(), &models.LLMDecision{Action: "scale", Target: "api"}, &models.Agent{ID: "agent-1"}, client, models.SourceTick)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stored, _ := actions.GetAction(context.Background(), ticket.Action.ID)
+			if stored.Status != tt.wantStatus || ticket.Action.Status != tt.wantStatus {
+				t.Errorf("stored %s, returned %s, want %s", stored.Status, ticket.Action.Status, tt.wantStatus)
+			}
+			if _, ok := stored.Result[tt.wantResult]; !ok {
+				t.Errorf("result = %v, want %q in it", stored.Result, tt.wantResult)
+			}
+			// el deadline del 202 queda aunque el SDK haya pasado la accion a running
+			if tt.wantStatus == models.ActionStatusRunning && stored.DeadlineAt == nil {
+				t.Error("running action without a deadline")
+			}
+			if locked := len(queue.State(client.ID.String())) == 1; locked != tt.wantLocked {
+				t.Errorf("target locked = %v, want %v", locked, tt.wantLocked)
+			}
+		})
+	}
+}
+
+// noBindings deja todos los targets en el SDK del cliente
+type noBindings struct {
+	fakeTargets
+}
+
+func (noBindings) GetTargetBinding(ctx context.Context, clientID, target string) (*models.TargetBinding, error) {
+	return nil, repositories.ErrTargetNotFound
+}
+
+// orderedActions anota si el SDK ya habia sido despertado cuando se guardo la entrega
+type orderedActions struct {
+	*fakeActions
+	notified      <-chan struct{}
+	notifiedFirst bool
+}
+
+func (o *orderedActions) UpdateDeliveredAction(ctx context.Context, action *models.Action) (bool, error) {
+	select {
+	case <-o.notified:
+		o.notifiedFirst = true
+	default:
+	}
+	return o.fakeActions.UpdateDeliveredAction(ctx, action)
+}
+
+func TestPullNotifiesAfterSave(t *testing.T) {
+	broker := NewCommandBroker()
+	client := testClient()
+	client.DeliveryMode = models.DeliveryPull
+	notified, cancel := broker.Subscribe(client.ID.String())
+	defer cancel()
+
+	actions := &orderedActions{fakeActions: newFakeActions(), notified: notified}
+	executor := &Executor{actions: actions, targets: noBindings{}, backends: map[string]Backend{models.BackendPull: NewPullBackend(broker, time.Minute)}}
+
+	action := executor.Execute(context.Background(), &models.LLMDecision{Action: "restart", Target: "api"}, &models.Agent{ID: "agent-1"}, client)
+
+	select {
+	case <-notified:
+	default:
+		t.Fatal("the SDK was not notified")
+	}
+	if actions.notifiedFirst {
+		t.Error("the SDK was notified before the action was saved")
+	}
+	stored, _ := actions.GetAction(context.Background(), action.ID)
+	if stored.Status != models.ActionStatusPending || stored.Backend != models.BackendPull || stored.DeadlineAt == nil {
+		t.Errorf("stored = %+v", stored)
+	}
+}
@@ -0,0 +1,405 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	models "server/model"
+	"server/repositories"
+	"server/utils"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitCredential para clonar/pushear el repo de config del cliente
+type GitCredential struct {
+	Username   string `json:"username,omitempty"`
+	Token      string `json:"token,omitempty"`
+	SSHKey     string `json:"ssh_key,omitempty"`
+	KnownHosts string `json:"known_hosts,omitempty"`
+}
+
+// ParseGitCredential valida la credencial git (token para https o clave ssh con known_hosts)
+func ParseGitCredential(data []byte) (*GitCredential, error) {
+	var c GitCredential
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid git credential: %w", err)
+	}
+	if c.Token == "" && c.SSHKey == "" {
+		return nil, errors.New("git credential: token or ssh_key is required")
+	}
+	if c.SSHKey != "" && c.KnownHosts == "" {
+		return nil, errors.New("git credential: known_hosts is required with ssh_key")
+	}
+	return &c, nil
+}
+
+// GitOpsBackend: para clientes que despliegan con GitOps, rollback = revertir el ultimo
+// commit que toco el path del target en el repo de config (y pushearlo en una rama).
+//
+// Config del binding:
+//
+//	repo           url del repo (https:// o ssh://; un path local solo en instalaciones self-hosted)
+//	branch         rama que se despliega (default "main")
+//	path           path del target dentro del repo (default: el target)
+//	revert_branch  prefijo de la rama del revert (default "infragent/revert-")
+//	credential     nombre de la credencial git (opcional)
+//	dry_run        true = commitea local y no pushea
+type GitOpsBackend struct {
+	credentials repositories.CredentialStorage
+	workdir     string
+	timeout     time.Duration
+	allowLocal  bool // repos en un path del servidor: solo self-hosted (y los tests)
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex // un clone por repo, no lo tocamos en paralelo
+}
+
+func NewGitOpsBackend(credentials repositories.CredentialStorage, workdir string) *GitOpsBackend {
+	if workdir == "" {
+		workdir = os.Getenv("GITOPS_WORKDIR")
+	}
+	if workdir == "" {
+		workdir = filepath.Join(os.TempDir(), "infragent-gitops")
+	}
+	return &GitOpsBackend{
+		credentials: credentials,
+		workdir:     workdir,
+		timeout:     2 * time.Minute,
+		allowLocal:  utils.SelfHosted(),
+		locks:       make(map[string]*sync.Mutex),
+	}
+}
+
+func (g *GitOpsBackend) Deliver(ctx context.Context, action *models.Action, decision *models.LLMDecision, client *models.Client, binding *models.TargetBinding) {
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+
+	cfg := map[string]interface{}{}
+	if binding != nil {
+		cfg = binding.Config
+	}
+
+	result := map[string]interface{}{"backend": models.BackendGitOps}
+
+	if action.Type != "rollback" {
+		setResult(action, result, fmt.Errorf("action %q not supported by gitops backend", action.Type))
+		return
+	}
+
+	repo := configString(cfg, "repo", "")
+	if repo == "" {
+		setResult(action, result, errors.New("gitops repo not configured for target"))
+		return
+	}
+
+	var credential *GitCredential
+	if name := configString(cfg, "credential", ""); name != "" {
+		var err error
+		if credential, err = g.credentialFor(ctx, client, name); err != nil {
+			setResult(action, result, err)
+			return
+		}
+	}
+
+	revert, err := g.revertLastCommit(ctx, client.ID.String(), repo, credential, revertOptions{
+		branch:       configString(cfg, "branch", "main"),
+		path:         configString(cfg, "path", action.Target),
+		revertBranch: configString(cfg, "revert_branch", "infragent/revert-") + shortID(action.ID),
+		dryRun:       configBool(cfg, "dry_run", false),
+		message:      fmt.Sprintf("InfrAgent: rollback %s\n\n%s\n\nAction: %s", action.Target, action.Reasoning, action.ID),
+	})
+	for k, v := range revert {
+		result[k] = v
+	}
+	setResult(action, result, err)
+}
+
+type revertOptions struct {
+	branch       string
+	path         string
+	revertBranch string
+	dryRun       bool
+	message      string
+}
+
+func (g *GitOpsBackend) revertLastCommit(ctx context.Context, clientID, repo string, credential *GitCredential, opts revertOptions) (map[string]interface{}, error) {
+	result := map[string]interface{}{"branch": opts.revertBranch, "dry_run": opts.dryRun, "pushed": false}
+
+	// las ramas salen del binding: un "--receive-pack=..." no puede llegar a git como opcion
+	for _, branch := range []string{opts.branch, opts.revertBranch} {
+		if err := validateGitBranch(ctx, branch); err != nil {
+			return result, err
+		}
+	}
+
+	// el repo lo eligio el cliente: nada de paths del servidor (los clones de otros
+	// clientes estan en el workdir) ni hosts internos
+	host, err := validateGitRepo(repo, g.allowLocal)
+	if err != nil {
+		return result, err
+	}
+	if host != "" {
+		if err := utils.OutboundGuard().CheckHost(ctx, host); err != nil {
+			return result, fmt.Errorf("gitops repo: %w", err)
+		}
+	}
+
+	sum := sha256.Sum256([]byte(clientID + "\x00" + repo))
+	dir := filepath.Join(g.workdir, hex.EncodeToString(sum[:8]))
+
+	lock := g.lockFor(dir)
+	lock.Lock()
+	defer lock.Unlock()
+
+	git, cleanup, err := newGitRunner(credential, g.allowLocal)
+	if err != nil {
+		return result, err
+	}
+	defer cleanup()
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		os.RemoveAll(dir)
+		if err := os.MkdirAll(g.workdir, 0o700); err != nil {
+			return result, err
+		}
+		if _, err := git.run(ctx, g.workdir, "clone", "--", repo, dir); err != nil {
+			return result, err
+		}
+	} else if _, err := git.run(ctx, dir, "fetch", "--prune", "origin"); err != nil {
+		return result, err
+	}
+
+	upstream := "origin/" + opts.branch
+	if _, err := git.run(ctx, dir, "checkout", "--force", "-B", opts.revertBranch, upstream); err != nil {
+		return result, err
+	}
+
+	reverted, err := git.run(ctx, dir, "log", "-n", "1", "--format=%H", upstream, "--", opts.path)
+	if err != nil {
+		return result, err
+	}
+	if reverted == "" {
+		return result, fmt.Errorf("no commits touch %q on %s", opts.path, opts.branch)
+	}
+	result["reverted_commit"] = reverted
+
+	// para un merge hay que decir contra que padre revertir
+	args := []string{"revert", "--no-edit"}
+	if parents, err := git.run(ctx, dir, "rev-list", "--parents", "-n", "1", reverted); err == nil && len(strings.Fields(parents)) > 2 {
+		args = append(args, "-m", "1")
+	}
+	if _, err := git.run(ctx, dir, append(args, reverted)...); err != nil {
+		git.run(ctx, dir, "revert", "--abort")
+		return result, err
+	}
+
+	message := opts.message + "\n\nThis reverts commit " + reverted + "."
+	if _, err := git.run(ctx, dir, "commit", "--amend", "-m", message); err != nil {
+		return result, err
+	}
+
+	sha, err := git.run(ctx, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return result, err
+	}
+	result["commit_sha"] = sha
+
+	if opts.dryRun {
+		return result, nil
+	}
+
+	ref := "refs/heads/" + opts.revertBranch
+	if _, err := git.run(ctx, dir, "push", "--force", "origin", ref+":"+ref); err != nil {
+		return result, err
+	}
+	result["pushed"] = true
+
+	return result, nil
+}
+
+// validateGitRepo acepta https:// y ssh:// (y un path absoluto si allowLocal) y devuelve
+// el host a validar con el OutboundGuard ("" para un path local)
+func validateGitRepo(repo string, allowLocal bool) (string, error) {
+	if allowLocal && filepath.IsAbs(repo) {
+		return "", nil
+	}
+
+	u, err := url.Parse(repo)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "ssh") {
+		return "", errors.New("gitops repo must be an https:// or ssh:// url")
+	}
+	if u.Hostname() == "" {
+		return "", errors.New("gitops repo: missing host")
+	}
+	if _, hasPassword := u.User.Password(); hasPassword || (u.Scheme == "https" && u.User != nil) {
+		return "", errors.New("gitops repo: credentials go in a git credential, not in the url")
+	}
+	return u.Hostname(), nil
+}
+
+// validateGitBranch acepta un nombre de rama valido para git que no se pueda leer como opcion
+func validateGitBranch(ctx context.Context, branch string) error {
+	if branch == "" || strings.HasPrefix(branch, "-") || strings.Contains(branch, "@{") {
+		return fmt.Errorf("invalid gitops branch %q", branch)
+	}
+	if err := exec.CommandContext(ctx, "git", "check-ref-format", "--branch", branch).Run(); err != nil {
+		return fmt.Errorf("invalid gitops branch %q", branch)
+	}
+	return nil
+}
+
+func validateGitOpsConfig(cfg map[string]interface{}) error {
+	repo := configString(cfg, "repo", "")
+	if repo == "" {
+		return errors.New("gitops repo is required")
+	}
+	if _, err := validateGitRepo(repo, utils.SelfHosted()); err != nil {
+		return err
+	}
+
+	// la rama del revert es el prefijo + el id corto de la accion
+	for _, branch := range []string{configString(cfg, "branch", "main"), configString(cfg, "revert_branch", "infragent/revert-") + "00000000"} {
+		if err := validateGitBranch(context.Background(), branch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *GitOpsBackend) lockFor(dir string) *sync.Mutex {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.locks[dir] == nil {
+		g.locks[dir] = &sync.Mutex{}
+	}
+	return g.locks[dir]
+}
+
+func (g *GitOpsBackend) credentialFor(ctx context.Context, client *models.Client, name string) (*GitCredential, error) {
+	credential, err := g.credentials.GetCredential(ctx, client.ID.String(), models.CredentialGit, name)
+	if err != nil {
+		if errors.Is(err, repositories.ErrCredentialNotFound) {
+			return nil, fmt.Errorf("git credential %q not configured", name)
+		}
+		return nil, err
+	}
+
+	data, err := utils.DecryptSecret(credential.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt git credential: %w", err)
+	}
+	return ParseGitCredential(data)
+}
+
+// gitRunner corre el binario git con la identidad y credenciales del agente
+type gitRunner struct {
+	env     []string
+	args    []string // -c ... que van antes de cada subcomando
+	configs int      // config pasada por GIT_CONFIG_KEY_n/GIT_CONFIG_VALUE_n
+}
+
+func newGitRunner(credential *GitCredential, allowLocal bool) (*gitRunner, func(), error) {
+	// solo https y ssh: ni ext:: (corre comandos) ni file:// (lee el disco del servidor),
+	// tampoco via submodules; sin redirects, que podrian llevar a un host interno
+	protocols := "https:ssh"
+	if allowLocal {
+		protocols += ":file"
+	}
+	g := &gitRunner{
+		env: append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ALLOW_PROTOCOL="+protocols),
+		args: []string{
+			"-c", "user.name=InfrAgent",
+			"-c", "user.email=agent@infragent.local",
+			"-c", "commit.gpgsign=false",
+			"-c", "protocol.allow=never",
+			"-c", "protocol.https.allow=always",
+			"-c", "protocol.ssh.allow=always",
+			"-c", "http.followRedirects=false",
+		},
+	}
+	if allowLocal {
+		g.args = append(g.args, "-c", "protocol.file.allow=always")
+	}
+	cleanup := func() {}
+
+	if credential == nil {
+		return g, cleanup, nil
+	}
+
+	if credential.Token != "" {
+		username := credential.Username
+		if username == "" {
+			username = "x-access-token"
+		}
+		basic := base64.StdEncoding.EncodeToString([]byte(username + ":" + credential.Token))
+		// por el entorno y no con -c: los argumentos de cualquier proceso se ven en ps
+		g.config("http.extraHeader", "Authorization: Basic "+basic)
+	}
+
+	if credential.SSHKey != "" {
+		// la clave y los known_hosts van a archivos temporales solo mientras dura la accion
+		tmp, err := os.MkdirTemp("", "infragent-git-")
+		if err != nil {
+			return nil, nil, err
+		}
+		cleanup = func() { os.RemoveAll(tmp) }
+
+		keyFile := filepath.Join(tmp, "id")
+		knownHosts := filepath.Join(tmp, "known_hosts")
+		if err := os.WriteFile(keyFile, []byte(credential.SSHKey), 0o600); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		if err := os.WriteFile(knownHosts, []byte(credential.KnownHosts), 0o600); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+
+		g.env = append(g.env, fmt.Sprintf("GIT_SSH_COMMAND=ssh -i %s -o IdentitiesOnly=yes -o UserKnownHostsFile=%s -o StrictHostKeyChecking=yes", keyFile, knownHosts))
+	}
+
+	return g, cleanup, nil
+}
+
+// config agrega una variable de config por el entorno (git >= 2.31)
+func (g *gitRunner) config(key, value string) {
+	g.env = append(g.env,
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", g.configs, key),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", g.configs, value),
+		fmt.Sprintf("GIT_CONFIG_COUNT=%d", g.configs+1))
+	g.configs++
+}
+
+func (g *gitRunner) run(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append(append([]string{}, g.args...), args...)...)
+	cmd.Dir = dir
+	cmd.Env = g.env
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
@@ -0,0 +1,371 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"server/utils"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// solo lo que necesitamos del kubeconfig (clusters, users y contexts con datos embebidos)
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string `yaml:"token"`
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKeyData         string `yaml:"client-key-data"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			User      string `yaml:"user"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// KubeClient habla con la API de Kubernetes por REST (sin client-go)
+type KubeClient struct {
+	server     string
+	token      string
+	namespace  string // namespace del context, si no viene en el binding
+	httpClient *http.Client
+}
+
+// ParseKubeconfig arma un cliente con el context indicado (o el current-context).
+// Los archivos referenciados por path no se soportan: todo tiene que venir embebido.
+func ParseKubeconfig(data []byte, contextName string) (*KubeClient, error) {
+	var cfg kubeconfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+
+	if contextName == "" {
+		contextName = cfg.CurrentContext
+	}
+
+	var clusterName, userName, namespace string
+	found := false
+	for _, c := range cfg.Contexts {
+		if c.Name == contextName {
+			clusterName, userName, namespace = c.Context.Cluster, c.Context.User, c.Context.Namespace
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("kubeconfig context %q not found", contextName)
+	}
+
+	client := &KubeClient{namespace: namespace}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	found = false
+	for _, c := range cfg.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+		found = true
+		client.server = strings.TrimSuffix(c.Cluster.Server, "/")
+		tlsConfig.InsecureSkipVerify = c.Cluster.InsecureSkipTLSVerify
+
+		if c.Cluster.CertificateAuthorityData != "" {
+			ca, err := base64.StdEncoding.DecodeString(c.Cluster.CertificateAuthorityData)
+			if err != nil {
+				return nil, fmt.Errorf("invalid certificate-authority-data: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(ca) {
+				return nil, errors.New("invalid certificate-authority-data")
+			}
+			tlsConfig.RootCAs = pool
+		}
+	}
+	if !found || client.server == "" {
+		return nil, fmt.Errorf("kubeconfig cluster %q not found", clusterName)
+	}
+
+	for _, u := range cfg.Users {
+		if u.Name != userName {
+			continue
+		}
+		client.token = u.User.Token
+
+		if u.User.ClientCertificateData != "" {
+			certPEM, err := base64.StdEncoding.DecodeString(u.User.ClientCertificateData)
+			if err != nil {
+				return nil, fmt.Errorf("invalid client-certificate-data: %w", err)
+			}
+			keyPEM, err := base64.StdEncoding.DecodeString(u.User.ClientKeyData)
+			if err != nil {
+				return nil, fmt.Errorf("invalid client-key-data: %w", err)
+			}
+			cert, err := tls.X509KeyPair(certPEM, keyPEM)
+			if err != nil {
+				return nil, fmt.Errorf("invalid client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+	}
+
+	// el server del kubeconfig lo eligio el cliente: mismo guard que los webhooks
+	client.httpClient = &http.Client{
+		Timeout:   20 * time.Second,
+		Transport: utils.OutboundGuard().Transport(tlsConfig),
+	}
+
+	return client, nil
+}
+
+// Namespace devuelve el namespace por defecto del context
+func (k *KubeClient) Namespace() string {
+	if k.namespace == "" {
+		return "default"
+	}
+	return k.namespace
+}
+
+func (k *KubeClient) do(ctx context.Context, method, path, contentType string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, k.server+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if k.token != "" {
+		req.Header.Set("Authorization", "Bearer "+k.token)
+	}
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		// la API devuelve un objeto Status con el motivo
+		var status struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&status)
+		return fmt.Errorf("kubernetes %s %s: %d %s", method, path, resp.StatusCode, status.Message)
+	}
+
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+type kubeObjectMeta struct {
+	Name            string            `json:"name"`
+	UID             string            `json:"uid"`
+	Annotations     map[string]string `json:"annotations"`
+	OwnerReferences []struct {
+		UID string `json:"uid"`
+	} `json:"ownerReferences"`
+}
+
+type kubeDeployment struct {
+	Metadata kubeObjectMeta `json:"metadata"`
+	Spec     struct {
+		Replicas *int `json:"replicas"`
+		Selector struct {
+			MatchLabels map[string]string `json:"matchLabels"`
+		} `json:"selector"`
+		Template map[string]interface{} `json:"template"`
+	} `json:"spec"`
+}
+
+type kubeReplicaSet struct {
+	Metadata kubeObjectMeta `json:"metadata"`
+	Spec     struct {
+		Template map[string]interface{} `json:"template"`
+	} `json:"spec"`
+}
+
+const revisionAnnotation = "deployment.kubernetes.io/revision"
+
+func deploymentPath(namespace, name string) string {
+	return "/apis/apps/v1/namespaces/" + url.PathEscape(namespace) + "/deployments/" + url.PathEscape(name)
+}
+
+func (k *KubeClient) getDeployment(ctx context.Context, namespace, name string) (*kubeDeployment, error) {
+	var d kubeDeployment
+	if err := k.do(ctx, http.MethodGet, deploymentPath(namespace, name), "", nil, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// RestartDeployment hace lo mismo que "kubectl rollout restart": cambia una annotation del template
+func (k *KubeClient) RestartDeployment(ctx context.Context, namespace, name string) (string, error) {
+	restartedAt := time.Now().UTC().Format(time.RFC3339)
+	patch := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{"kubectl.kubernetes.io/restartedAt": restartedAt},
+				},
+			},
+		},
+	}
+
+	err := k.do(ctx, http.MethodPatch, deploymentPath(namespace, name), "application/strategic-merge-patch+json", patch, nil)
+	return restartedAt, err
+}
+
+// ScaleDeployment cambia las replicas y devuelve las que tenia antes
+func (k *KubeClient) ScaleDeployment(ctx context.Context, namespace, name string, replicas int) (int, error) {
+	var scale struct {
+		Spec struct {
+			Replicas int `json:"replicas"`
+		} `json:"spec"`
+	}
+	if err := k.do(ctx, http.MethodGet, deploymentPath(namespace, name)+"/scale", "", nil, &scale); err != nil {
+		return 0, err
+	}
+
+	patch := map[string]interface{}{"spec": map[string]interface{}{"replicas": replicas}}
+	if err := k.do(ctx, http.MethodPatch, deploymentPath(namespace, name)+"/scale", "application/merge-patch+json", patch, nil); err != nil {
+		return scale.Spec.Replicas, err
+	}
+	return scale.Spec.Replicas, nil
+}
+
+// KubeRollback es lo que hizo RollbackDeployment
+type KubeRollback struct {
+	FromRevision   int
+	ToRevision     int
+	PreviousImages map[string]string // imagen de cada container antes del rollback
+}
+
+// RollbackDeployment hace lo mismo que "kubectl rollout undo [--to-revision]": vuelve al template
+// del ReplicaSet con la revision pedida (0 = la anterior a la actual).
+func (k *KubeClient) RollbackDeployment(ctx context.Context, namespace, name string, toRevision int) (*KubeRollback, error) {
+	rollback := &KubeRollback{}
+
+	deployment, err := k.getDeployment(ctx, namespace, name)
+	if err != nil {
+		return rollback, err
+	}
+	rollback.FromRevision, _ = strconv.Atoi(deployment.Metadata.Annotations[revisionAnnotation])
+	rollback.PreviousImages = templateImages(deployment.Spec.Template)
+
+	selector := make([]string, 0, len(deployment.Spec.Selector.MatchLabels))
+	for key, value := range deployment.Spec.Selector.MatchLabels {
+		selector = append(selector, key+"="+value)
+	}
+	sort.Strings(selector)
+
+	var list struct {
+		Items []kubeReplicaSet `json:"items"`
+	}
+	path := "/apis/apps/v1/namespaces/" + url.PathEscape(namespace) + "/replicasets?labelSelector=" + url.QueryEscape(strings.Join(selector, ","))
+	if err := k.do(ctx, http.MethodGet, path, "", nil, &list); err != nil {
+		return rollback, err
+	}
+
+	var previous *kubeReplicaSet
+	for i := range list.Items {
+		rs := &list.Items[i]
+		if !ownedBy(rs.Metadata, deployment.Metadata.UID) {
+			continue
+		}
+		revision, err := strconv.Atoi(rs.Metadata.Annotations[revisionAnnotation])
+		if err != nil || revision == rollback.FromRevision {
+			continue
+		}
+		if toRevision > 0 {
+			if revision == toRevision {
+				previous, rollback.ToRevision = rs, revision
+			}
+			continue
+		}
+		if revision < rollback.FromRevision && revision > rollback.ToRevision {
+			previous, rollback.ToRevision = rs, revision
+		}
+	}
+	if previous == nil {
+		if toRevision > 0 {
+			return rollback, fmt.Errorf("revision %d not found", toRevision)
+		}
+		return rollback, errors.New("no previous revision to roll back to")
+	}
+
+	// el pod-template-hash lo agrega el controller, no es parte del template del deployment
+	template := previous.Spec.Template
+	if metadata, ok := template["metadata"].(map[string]interface{}); ok {
+		if labels, ok := metadata["labels"].(map[string]interface{}); ok {
+			delete(labels, "pod-template-hash")
+		}
+	}
+
+	patch := []map[string]interface{}{{"op": "replace", "path": "/spec/template", "value": template}}
+	if err := k.do(ctx, http.MethodPatch, deploymentPath(namespace, name), "application/json-patch+json", patch, nil); err != nil {
+		return rollback, err
+	}
+	return rollback, nil
+}
+
+// imagen de cada container del pod template
+func templateImages(template map[string]interface{}) map[string]string {
+	images := map[string]string{}
+	spec, _ := template["spec"].(map[string]interface{})
+	containers, _ := spec["containers"].([]interface{})
+	for _, c := range containers {
+		container, _ := c.(map[string]interface{})
+		name, _ := container["name"].(string)
+		image, _ := container["image"].(string)
+		if name != "" {
+			images[name] = image
+		}
+	}
+	return images
+}
+
+func ownedBy(meta kubeObjectMeta, uid string) bool {
+	for _, owner := range meta.OwnerReferences {
+		if owner.UID == uid {
+			return true
+		}
+	}
+	return false
+}
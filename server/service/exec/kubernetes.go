@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	models "server/model"
+	"server/repositories"
+	"server/utils"
+	"time"
+)
+
+// KubernetesBackend actua directo sobre el cluster del cliente con el kubeconfig que nos dio.
+//
+// Config del binding:
+//
+//	namespace     namespace del deployment (default: el del context del kubeconfig)
+//	deployment    nombre del deployment (default: el target)
+//	kubeconfig    nombre de la credencial kubeconfig (default: "default")
+//	context       context del kubeconfig (default: current-context)
+//	min_replicas  minimo para scale (default 1)
+//	max_replicas  maximo para scale (default 10)
+//
+// scale y rollback guardan el pre-state (replicas, revision e imagenes) para poder deshacerlos.
+type KubernetesBackend struct {
+	credentials repositories.CredentialStorage
+	timeout     time.Duration
+}
+
+func NewKubernetesBackend(credentials repositories.CredentialStorage) *KubernetesBackend {
+	return &KubernetesBackend{credentials: credentials, timeout: 30 * time.Second}
+}
+
+func (k *KubernetesBackend) Deliver(ctx context.Context, action *models.Action, decision *models.LLMDecision, client *models.Client, binding *models.TargetBinding) {
+	ctx, cancel := context.WithTimeout(ctx, k.timeout)
+	defer cancel()
+
+	cfg := map[string]interface{}{}
+	if binding != nil {
+		cfg = binding.Config
+	}
+
+	kube, err := k.clientFor(ctx, client, cfg)
+	if err != nil {
+		setResult(action, map[string]interface{}{"backend": models.BackendKubernetes}, err)
+		return
+	}
+
+	namespace := configString(cfg, "namespace", kube.Namespace())
+	deployment := configString(cfg, "deployment", action.Target)
+
+	result := map[string]interface{}{
+		"backend":    models.BackendKubernetes,
+		"namespace":  namespace,
+		"deployment": deployment,
+	}
+
+	switch action.Type {
+	case "restart":
+		restartedAt, err := kube.RestartDeployment(ctx, namespace, deployment)
+		result["restarted_at"] = restartedAt
+		setResult(action, result, err)
+
+	case "scale":
+		replicas, err := toInt(decision.Params["replicas"])
+		if err != nil {
+			setResult(action, result, fmt.Errorf("invalid replicas param: %w", err))
+			return
+		}
+		minReplicas, maxReplicas := configInt(cfg, "min_replicas", 1), configInt(cfg, "max_replicas", 10)
+		if replicas < minReplicas || replicas > maxReplicas {
+			setResult(action, result, fmt.Errorf("replicas %d out of bounds [%d, %d]", replicas, minReplicas, maxReplicas))
+			return
+		}
+
+		previous, err := kube.ScaleDeployment(ctx, namespace, deployment, replicas)
+		result["previous_replicas"] = previous
+		result["replicas"] = replicas
+		if err == nil {
+			setPreState(action, map[string]interface{}{"replicas": previous}, "scale", map[string]interface{}{"replicas": previous})
+		}
+		setResult(action, result, err)
+
+	case "rollback":
+		// to_revision es opcional, sin el vuelve a la revision anterior (lo que usa el undo)
+		toRevision := 0
+		if v, ok := decision.Params["to_revision"]; ok {
+			if toRevision, err = toInt(v); err != nil {
+				setResult(action, result, fmt.Errorf("invalid to_revision param: %w", err))
+				return
+			}
+		}
+
+		rollback, err := kube.RollbackDeployment(ctx, namespace, deployment, toRevision)
+		result["from_revision"] = rollback.FromRevision
+		result["to_revision"] = rollback.ToRevision
+		if err == nil {
+			setPreState(action, map[string]interface{}{"revision": rollback.FromRevision, "images": rollback.PreviousImages},
+				"rollback", map[string]interface{}{"to_revision": rollback.FromRevision})
+		}
+		setResult(action, result, err)
+
+	default:
+		setResult(action, result, fmt.Errorf("action %q not supported by kubernetes backend", action.Type))
+	}
+}
+
+func (k *KubernetesBackend) clientFor(ctx context.Context, client *models.Client, cfg map[string]interface{}) (*KubeClient, error) {
+	name := configString(cfg, "kubeconfig", "default")
+
+	credential, err := k.credentials.GetCredential(ctx, client.ID.String(), models.CredentialKubeconfig, name)
+	if err != nil {
+		if errors.Is(err, repositories.ErrCredentialNotFound) {
+			return nil, fmt.Errorf("kubeconfig %q not configured", name)
+		}
+		return nil, err
+	}
+
+	data, err := utils.DecryptSecret(credential.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt kubeconfig: %w", err)
+	}
+
+	return ParseKubeconfig(data, configString(cfg, "context", ""))
+}
@@ -0,0 +1,339 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	models "server/model"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeKubeAPI es un API server con un solo deployment (default/api) y sus ReplicaSets
+type fakeKubeAPI struct {
+	t        *testing.T
+	mu       sync.Mutex
+	replicas int
+	revision string
+	// ReplicaSets por revision; los de otro owner tienen que ignorarse
+	replicaSets []map[string]interface{}
+	forbidden   bool
+	patches     []kubePatch
+}
+
+type kubePatch struct {
+	path        string
+	contentType string
+	body        map[string]interface{}
+	list        []map[string]interface{} // json-patch
+}
+
+const fakeDeploymentUID = "uid-api"
+
+func newFakeKubeAPI(t *testing.T) *fakeKubeAPI {
+	f := &fakeKubeAPI{t: t, replicas: 2, revision: "3"}
+	for _, rs := range []struct{ revision, owner, image string }{
+		{"1", fakeDeploymentUID, "api:v1"},
+		{"2", fakeDeploymentUID, "api:v2"},
+		{"3", fakeDeploymentUID, "api:v3"},
+		{"2", "uid-other", "other:v2"},
+	} {
+		f.replicaSets = append(f.replicaSets, map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"name":            "api-" + rs.revision,
+				"annotations":     map[string]string{revisionAnnotation: rs.revision},
+				"ownerReferences": []map[string]string{{"uid": rs.owner}},
+			},
+			"spec": map[string]interface{}{"template": podTemplate(rs.image)},
+		})
+	}
+	return f
+}
+
+func podTemplate(image string) map[string]interface{} {
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "api", "pod-template-hash": "abc123"}},
+		"spec":     map[string]interface{}{"containers": []interface{}{map[string]interface{}{"name": "api", "image": image}}},
+	}
+}
+
+func (f *fakeKubeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.Header.Get("Authorization") != "Bearer test-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Unauthorized"})
+		return
+	}
+	if f.forbidden {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"message": `deployments.apps "api" is forbidden: User "infragent" cannot patch resource "deployments"`})
+		return
+	}
+
+	deployment := "/apis/apps/v1/namespaces/default/deployments/api"
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == deployment:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "api", "uid": fakeDeploymentUID, "annotations": map[string]string{revisionAnnotation: f.revision}},
+			"spec": map[string]interface{}{
+				"replicas": f.replicas,
+				"selector": map[string]interface{}{"matchLabels": map[string]string{"app": "api", "tier": "web"}},
+				"template": podTemplate("api:v" + f.revision),
+			},
+		})
+	case r.Method == http.MethodGet && r.URL.Path == deployment+"/scale":
+		json.NewEncoder(w).Encode(map[string]interface{}{"spec": map[string]int{"replicas": f.replicas}})
+	case r.Method == http.MethodGet && r.URL.Path == "/apis/apps/v1/namespaces/default/replicasets":
+		if got := r.URL.Query().Get("labelSelector"); got != "app=api,tier=web" {
+			f.t.Errorf("labelSelector = %q", got)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": f.replicaSets})
+	case r.Method == http.MethodPatch && (r.URL.Path == deployment || r.URL.Path == deployment+"/scale"):
+		data, _ := io.ReadAll(r.Body)
+		patch := kubePatch{path: r.URL.Path, contentType: r.Header.Get("Content-Type")}
+		if strings.HasPrefix(string(data), "[") {
+			json.Unmarshal(data, &patch.list)
+		} else {
+			json.Unmarshal(data, &patch.body)
+		}
+		f.patches = append(f.patches, patch)
+		w.Write([]byte("{}"))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "not found: " + r.URL.Path})
+	}
+}
+
+func testKubeconfig(server, token string) []byte {
+	return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: prod
+clusters:
+- name: prod
+  cluster:
+    server: %s/
+contexts:
+- name: prod
+  context:
+    cluster: prod
+    user: infragent
+- name: staging
+  context:
+    cluster: prod
+    user: infragent
+    namespace: staging
+users:
+- name: infragent
+  user:
+    token: %s
+`, server, token))
+}
+
+func TestParseKubeconfig(t *testing.T) {
+	valid := testKubeconfig("https://k8s.acme.io:6443", "test-token")
+
+	tests := []struct {
+		name          string
+		data          []byte
+		context       string
+		wantServer    string
+		wantNamespace string
+		wantErr       string
+	}{
+		{name: "current context", data: valid, wantServer: "https://k8s.acme.io:6443", wantNamespace: "default"},
+		{name: "context with namespace", data: valid, context: "staging", wantServer: "https://k8s.acme.io:6443", wantNamespace: "staging"},
+		{name: "unknown context", data: valid, context: "dev", wantErr: `context "dev" not found`},
+		{name: "not yaml", data: []byte("{{"), wantErr: "invalid kubeconfig"},
+		{
+			name:    "missing cluster",
+			data:    []byte("current-context: a\ncontexts:\n- name: a\n  context:\n    cluster: nope\n"),
+			wantErr: `cluster "nope" not found`,
+		},
+		{
+			name: "bad certificate authority",
+			data: []byte("current-context: a\ncontexts:\n- name: a\n  context:\n    cluster: c\nclusters:\n- name: c\n  cluster:\n    server: https://k8s\n" +
+				"    certificate-authority-data: " + base64.StdEncoding.EncodeToString([]byte("not a pem")) + "\n"),
+			wantErr: "invalid certificate-authority-data",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kube, err := ParseKubeconfig(tt.data, tt.context)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kube.server != tt.wantServer {
+				t.Errorf("server = %q, want %q", kube.server, tt.wantServer)
+			}
+			if kube.Namespace() != tt.wantNamespace {
+				t.Errorf("namespace = %q, want %q", kube.Namespace(), tt.wantNamespace)
+			}
+			if kube.token != "test-token" {
+				t.Errorf("token = %q", kube.token)
+			}
+		})
+	}
+}
+
+func TestKubernetesBackendDeliver(t *testing.T) {
+	tests := []struct {
+		name       string
+		action     string
+		params     map[string]interface{}
+		kubeconfig string // nombre de la credencial en el binding
+		forbidden  bool
+		wantErr    string
+		check      func(t *testing.T, action *models.Action, patches []kubePatch)
+	}{
+		{
+			name:   "restart patches the template annotation",
+			action: "restart",
+			check: func(t *testing.T, action *models.Action, patches []kubePatch) {
+				if len(patches) != 1 || patches[0].contentType != "application/strategic-merge-patch+json" {
+					t.Fatalf("patches = %+v", patches)
+				}
+				annotations := patches[0].body["spec"].(map[string]interface{})["template"].(map[string]interface{})["metadata"].(map[string]interface{})["annotations"].(map[string]interface{})
+				if annotations["kubectl.kubernetes.io/restartedAt"] != action.Result["restarted_at"] {
+					t.Errorf("restartedAt = %v, result says %v", annotations["kubectl.kubernetes.io/restartedAt"], action.Result["restarted_at"])
+				}
+			},
+		},
+		{
+			name:   "scale saves the previous replicas",
+			action: "scale",
+			params: map[string]interface{}{"replicas": float64(5)},
+			check: func(t *testing.T, action *models.Action, patches []kubePatch) {
+				if len(patches) != 1 || !strings.HasSuffix(patches[0].path, "/scale") || patches[0].body["spec"].(map[string]interface{})["replicas"] != float64(5) {
+					t.Fatalf("patches = %+v", patches)
+				}
+				if action.Result["previous_replicas"] != 2 || action.PreState["replicas"] != 2 {
+					t.Errorf("previous = %v, pre_state = %v", action.Result["previous_replicas"], action.PreState)
+				}
+				undo := action.PreState[models.PreStateUndoKey].(map[string]interface{})
+				if undo["action"] != "scale" || undo["params"].(map[string]interface{})["replicas"] != 2 {
+					t.Errorf("undo = %v", undo)
+				}
+			},
+		},
+		{
+			name:    "scale out of bounds",
+			action:  "scale",
+			params:  map[string]interface{}{"replicas": float64(50)},
+			wantErr: "out of bounds",
+			check: func(t *testing.T, action *models.Action, patches []kubePatch) {
+				if len(patches) != 0 {
+					t.Errorf("patched anyway: %+v", patches)
+				}
+			},
+		},
+		{
+			name:   "rollback to the previous revision",
+			action: "rollback",
+			check: func(t *testing.T, action *models.Action, patches []kubePatch) {
+				if action.Result["from_revision"] != 3 || action.Result["to_revision"] != 2 {
+					t.Errorf("revisions = %v -> %v", action.Result["from_revision"], action.Result["to_revision"])
+				}
+				if len(patches) != 1 || patches[0].contentType != "application/json-patch+json" || len(patches[0].list) != 1 {
+					t.Fatalf("patches = %+v", patches)
+				}
+				template := patches[0].list[0]["value"].(map[string]interface{})
+				if images := templateImages(template); images["api"] != "api:v2" {
+					t.Errorf("rolled back to %v, want api:v2 (and not the other owner's ReplicaSet)", images)
+				}
+				if _, ok := template["metadata"].(map[string]interface{})["labels"].(map[string]interface{})["pod-template-hash"]; ok {
+					t.Error("pod-template-hash left in the template")
+				}
+				if action.PreState["revision"] != 3 {
+					t.Errorf("pre_state = %v", action.PreState)
+				}
+			},
+		},
+		{
+			name:   "rollback to a given revision",
+			action: "rollback",
+			params: map[string]interface{}{"to_revision": float64(1)},
+			check: func(t *testing.T, action *models.Action, patches []kubePatch) {
+				if action.Result["to_revision"] != 1 || len(patches) != 1 {
+					t.Fatalf("to_revision = %v, patches = %d", action.Result["to_revision"], len(patches))
+				}
+				if images := templateImages(patches[0].list[0]["value"].(map[string]interface{})); images["api"] != "api:v1" {
+					t.Errorf("rolled back to %v", images)
+				}
+			},
+		},
+		{
+			name:    "rollback to an unknown revision",
+			action:  "rollback",
+			params:  map[string]interface{}{"to_revision": float64(7)},
+			wantErr: "revision 7 not found",
+		},
+		{
+			name:      "api error carries the status message",
+			action:    "restart",
+			forbidden: true,
+			wantErr:   `cannot patch resource "deployments"`,
+		},
+		{
+			name:       "kubeconfig not configured",
+			action:     "restart",
+			kubeconfig: "staging",
+			wantErr:    `kubeconfig "staging" not configured`,
+		},
+		{
+			name:    "unsupported action",
+			action:  "stop",
+			wantErr: "not supported by kubernetes backend",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeKubeAPI(t)
+			api.forbidden = tt.forbidden
+			srv := httptest.NewServer(api)
+			defer srv.Close()
+
+			client := testClient()
+			creds := fakeCredentials{}
+			creds.put(t, client, models.CredentialKubeconfig, "default", testKubeconfig(srv.URL, "test-token"))
+
+			cfg := map[string]interface{}{"max_replicas": float64(10)}
+			if tt.kubeconfig != "" {
+				cfg["kubeconfig"] = tt.kubeconfig
+			}
+			action := &models.Action{Type: tt.action, Target: "api", Status: models.ActionStatusPending}
+			decision := &models.LLMDecision{Action: tt.action, Target: "api", Params: tt.params}
+
+			NewKubernetesBackend(creds).Deliver(context.Background(), action, decision, client, &models.TargetBinding{Backend: models.BackendKubernetes, Config: cfg})
+
+			if tt.wantErr != "" {
+				errMsg, _ := action.Result["error"].(string)
+				if action.Status != models.ActionStatusFailed || !strings.Contains(errMsg, tt.wantErr) {
+					t.Fatalf("status = %s, error = %q, want %q", action.Status, errMsg, tt.wantErr)
+				}
+			} else if action.Status != models.ActionStatusSuccess {
+				t.Fatalf("status = %s, result = %v", action.Status, action.Result)
+			}
+			if tt.check != nil {
+				api.mu.Lock()
+				defer api.mu.Unlock()
+				tt.check(t, action, api.patches)
+			}
+		})
+	}
+}
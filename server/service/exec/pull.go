@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	models "server/model"
+	"sync"
+	"time"
+)
+
+// PullBackend no llama al cliente: deja la accion pending para que el SDK la pida
+// por /v1/commands (clientes detras de NAT/firewall que no aceptan trafico entrante)
+type PullBackend struct {
+	broker  *CommandBroker
+	timeout time.Duration
+}
+
+func NewPullBackend(broker *CommandBroker, timeout time.Duration) *PullBackend {
+	return &PullBackend{broker: broker, timeout: timeout}
+}
+
+// Deliver le pone deadline a la accion (si nadie la toma se marca failed)
+func (p *PullBackend) Deliver(ctx context.Context, action *models.Action, decision *models.LLMDecision, client *models.Client, binding *models.TargetBinding) {
+	deadline := time.Now().Add(p.timeout)
+	action.DeadlineAt = &deadline
+	action.Result = map[string]interface{}{"delivery": models.DeliveryPull}
+}
+
+// Notify despierta al SDK; el executor la llama recien cuando guardo lo que dejo Deliver
+func (p *PullBackend) Notify(client *models.Client) {
+	p.broker.Notify(client.ID.String())
+}
+
+// CommandBroker avisa a los long-polls abiertos de un cliente que hay comandos nuevos
+type CommandBroker struct {
+	mu      sync.Mutex
+	waiters map[string]map[chan struct{}]struct{}
+}
+
+func NewCommandBroker() *CommandBroker {
+	return &CommandBroker{waiters: make(map[string]map[chan struct{}]struct{})}
+}
+
+// Subscribe devuelve un canal que se cierra con el proximo Notify del cliente.
+// Hay que llamar a cancel cuando se deja de esperar.
+func (b *CommandBroker) Subscribe(clientID string) (<-chan struct{}, func()) {
+	ch := make(chan struct{})
+
+	b.mu.Lock()
+	if b.waiters[clientID] == nil {
+		b.waiters[clientID] = make(map[chan struct{}]struct{})
+	}
+	b.waiters[clientID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.waiters[clientID][ch]; ok {
+			delete(b.waiters[clientID], ch)
+			if len(b.waiters[clientID]) == 0 {
+				delete(b.waiters, clientID)
+			}
+		}
+	}
+	return ch, cancel
+}
+
+func (b *CommandBroker) Notify(clientID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.waiters[clientID] {
+		close(ch)
+	}
+	delete(b.waiters, clientID)
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	models "server/model"
+	"server/repositories"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExecutionQueue va adelante del Executor: un target de un cliente tiene a lo sumo una
+// accion en vuelo. Lo que llega mientras tanto se rechaza, se encola o reemplaza a lo
+// encolado segun el conflict_rule del cliente.
+//
+// El lock del target dura hasta que la accion termina: para las sincronas es lo que tarda
+// Execute, para las asincronas (webhook 202, pull) hasta que el SDK reporta el resultado
+// final (Finished) o vence su deadline.
+type ExecutionQueue struct {
+	executor *Executor
+	configs  repositories.ClientConfigStorage
+
+	mu    sync.Mutex
+	lanes map[laneKey]*lane
+}
+
+type laneKey struct {
+	clientID string
+	target   string
+}
+
+type lane struct {
+	running *models.QueueEntry
+	waiting []*queuedAction
+	timer   *time.Timer // libera el lock si la accion en vuelo nunca reporta
+}
+
+type queuedAction struct {
+	entry    models.QueueEntry
+	decision *models.LLMDecision
+	agent    *models.Agent
+	client   *models.Client
+}
+
+func NewExecutionQueue(executor *Executor, configs repositories.ClientConfigStorage) *ExecutionQueue {
+	return &ExecutionQueue{
+		executor: executor,
+		configs:  configs,
+		lanes:    make(map[laneKey]*lane),
+	}
+}
+
+// Submit ejecuta la accion si el target esta libre. Si no, aplica el conflict_rule:
+// reject la guarda como failed (target_busy), queue la deja esperando y supersede
+// descarta lo que estaba esperando y la deja como la proxima.
+func (q *ExecutionQueue) Submit(ctx context.Context, decision *models.LLMDecision, agent *models.Agent, client *models.Client, source string) (*models.QueueTicket, error) {
+
+	cfg, err := q.configs.GetClientConfig(ctx, agent.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting client config: %w", err)
+	}
+	rule := cfg.ConflictRule
+
+	item := &queuedAction{
+		entry: models.QueueEntry{
+			ID:         uuid.New().String(),
+			ClientID:   client.ID.String(),
+			Target:     decision.Target,
+			ActionType: decision.Action,
+			Source:     source,
+			UndoOf:     decision.UndoOf,
+			Status:     models.QueueQueued,
+			EnqueuedAt: time.Now(),
+		},
+		decision: decision,
+		agent:    agent,
+		client:   client,
+	}
+	key := laneKey{clientID: item.entry.ClientID, target: item.entry.Target}
+
+	q.mu.Lock()
+	l := q.lanes[key]
+	if l == nil {
+		l = &lane{}
+		q.lanes[key] = l
+	}
+
+	// target libre: se ejecuta ahora
+	if l.running == nil {
+		q.start(l, item)
+		q.mu.Unlock()
+
+		action := q.run(ctx, key, item)
+		return &models.QueueTicket{Status: models.QueueExecuted, Entry: item.entry, Action: action}, nil
+	}
+
+	switch rule {
+	case models.ConflictReject:
+		q.mu.Unlock()
+
+		item.entry.Status = models.QueueRejected
+		action := q.executor.Reject(ctx, decision, agent, client, "target_busy")
+		item.entry.ActionID = action.ID
+		return &models.QueueTicket{Status: models.QueueRejected, Entry: item.entry, Action: action}, nil
+
+	case models.ConflictSupersede:
+		superseded := l.waiting
+		l.waiting = []*queuedAction{item}
+		q.mu.Unlock()
+
+		// las descartadas quedan en el historial como failed
+		for _, old := range superseded {
+			q.executor.Reject(ctx, old.decision, old.agent, old.client, models.QueueSuperseded)
+		}
+
+	default:
+		l.waiting = append(l.waiting, item)
+		q.mu.Unlock()
+	}
+
+	return &models.QueueTicket{Status: models.QueueQueued, Entry: item.entry}, nil
+}
+
+// Finished libera el target cuando la accion que lo tenia termino (la llama ActionResults)
+func (q *ExecutionQueue) Finished(action *models.Action) {
+	if action.Status != models.ActionStatusSuccess && action.Status != models.ActionStatusFailed {
+		return
+	}
+	q.release(laneKey{clientID: action.ClientID, target: action.Target}, action.ID)
+}
+
+// Undoing dice si hay una compensacion de actionID esperando o en vuelo en la cola
+// (las encoladas todavia no estan en la base de datos)
+func (q *ExecutionQueue) Undoing(clientID, actionID string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for key, l := range q.lanes {
+		if key.clientID != clientID {
+			continue
+		}
+		if l.running != nil && l.running.UndoOf == actionID {
+			return true
+		}
+		for _, item := range l.waiting {
+			if item.entry.UndoOf == actionID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// State devuelve lo que esta corriendo y lo que espera en cada target del cliente
+func (q *ExecutionQueue) State(clientID string) []models.QueueLane {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	lanes := []models.QueueLane{}
+	for key, l := range q.lanes {
+		if key.clientID != clientID {
+			continue
+		}
+
+		state := models.QueueLane{Target: key.target, Waiting: make([]models.QueueEntry, 0, len(l.waiting))}
+		if l.running != nil {
+			running := *l.running
+			state.Running = &running
+		}
+		for _, item := range l.waiting {
+			state.Waiting = append(state.Waiting, item.entry)
+		}
+		lanes = append(lanes, state)
+	}
+
+	sort.Slice(lanes, func(i, j int) bool { return lanes[i].Target < lanes[j].Target })
+	return lanes
+}
+
+// start toma el lock del target para item (con q.mu tomado)
+func (q *ExecutionQueue) start(l *lane, item *queuedAction) {
+	now := time.Now()
+	item.entry.Status = models.QueueRunning
+	item.entry.StartedAt = &now
+	l.running = &item.entry
+}
+
+// run entrega la accion y, si quedo en vuelo, espera a Finished o al deadline para liberar el target
+func (q *ExecutionQueue) run(ctx context.Context, key laneKey, item *queuedAction) *models.Action {
+	action := q.executor.Execute(ctx, item.decision, item.agent, item.client)
+
+	q.mu.Lock()
+	l := q.lanes[key]
+	if l != nil && l.running == &item.entry {
+		l.running.ActionID = action.ID
+
+		deadline := time.Now().Add(DefaultAsyncTimeout)
+		if action.DeadlineAt != nil {
+			deadline = *action.DeadlineAt
+		}
+		actionID := action.ID
+		l.timer = time.AfterFunc(time.Until(deadline), func() { q.release(key, actionID) })
+	}
+	q.mu.Unlock()
+
+	if action.Status == models.ActionStatusSuccess || action.Status == models.ActionStatusFailed {
+		q.release(key, action.ID)
+		return action
+	}
+
+	// el SDK pudo haber reportado antes de que anotaramos el ActionID
+	if current, err := q.executor.actions.GetAction(ctx, action.ID); err == nil {
+		q.Finished(current)
+	}
+
+	return action
+}
+
+// release suelta el target si lo tiene actionID y arranca la proxima accion encolada
+func (q *ExecutionQueue) release(key laneKey, actionID string) {
+	q.mu.Lock()
+	l := q.lanes[key]
+	if l == nil || l.running == nil || l.running.ActionID != actionID {
+		q.mu.Unlock()
+		return
+	}
+
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	l.running = nil
+
+	if len(l.waiting) == 0 {
+		delete(q.lanes, key)
+		q.mu.Unlock()
+		return
+	}
+
+	next := l.waiting[0]
+	l.waiting = l.waiting[1:]
+	q.start(l, next)
+	q.mu.Unlock()
+
+	// quien pidio la accion ya no esta esperando, corre con su propio contexto
+	go q.run(context.Background(), key, next)
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	models "server/model"
+	"server/repositories"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeActions guarda las acciones en memoria
+type fakeActions struct {
+	mu      sync.Mutex
+	actions map[string]models.Action
+}
+
+func newFakeActions() *fakeActions {
+	return &fakeActions{actions: make(map[string]models.Action)}
+}
+
+func (f *fakeActions) SaveAction(ctx context.Context, action *models.Action) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.actions[action.ID] = *action
+	return nil
+}
+
+func (f *fakeActions) UpdateActionStatus(ctx context.Context, action *models.Action) error {
+	return f.SaveAction(ctx, action)
+}
+
+func (f *fakeActions) UpdateDeliveredAction(ctx context.Context, action *models.Action) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stored, ok := f.actions[action.ID]
+	if !ok {
+		return false, nil
+	}
+	if stored.Status != models.ActionStatusPending {
+		if stored.Status == models.ActionStatusRunning && stored.DeadlineAt == nil {
+			stored.DeadlineAt = action.DeadlineAt
+			f.actions[action.ID] = stored
+		}
+		return false, nil
+	}
+	f.actions[action.ID] = *action
+	return true, nil
+}
+
+func (f *fakeActions) GetAction(ctx context.Context, id string) (*models.Action, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if action, ok := f.actions[id]; ok {
+		return &action, nil
+	}
+	return nil, repositories.ErrActionNotFound
+}
+
+// failed devuelve el error de cada accion failed, por tipo
+func (f *fakeActions) failed() map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	errs := map[string]string{}
+	for _, action := range f.actions {
+		if action.Status == models.ActionStatusFailed {
+			errs[action.Type], _ = action.Result["error"].(string)
+		}
+	}
+	return errs
+}
+
+func (f *fakeActions) GetRecentActions(ctx context.Context, agentID string, limit int) ([]models.Action, error) {
+	return nil, nil
+}
+
+func (f *fakeActions) CountActionsSince(ctx context.Context, agentID, actionType string, since time.Time) (int, error) {
+	return 0, nil
+}
+
+func (f *fakeActions) GetUndeliveredActions(ctx context.Context, clientID string, limit int) ([]models.Action, error) {
+	return nil, nil
+}
+
+func (f *fakeActions) MarkActionDelivered(ctx context.Context, clientID, id string) error {
+	return nil
+}
+
+func (f *fakeActions) FailStalledActions(ctx context.Context, now time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeActions) GetUndoActions(ctx context.Context, actionID string) ([]models.Action, error) {
+	return nil, nil
+}
+
+// fakeTargets manda todos los targets al backend "test"
+type fakeTargets struct{}
+
+func (fakeTargets) GetTargetBinding(ctx context.Context, clientID, target string) (*models.TargetBinding, error) {
+	return &models.TargetBinding{ClientID: clientID, Target: target, Backend: "test"}, nil
+}
+
+func (fakeTargets) ListTargetBindings(ctx context.Context, clientID string) ([]models.TargetBinding, error) {
+	return nil, nil
+}
+
+func (fakeTargets) SaveTargetBinding(ctx context.Context, binding *models.TargetBinding) error {
+	return nil
+}
+
+func (fakeTargets) DeleteTargetBinding(ctx context.Context, clientID, target string) error {
+	return nil
+}
+
+type fakeConfigs struct {
+	config models.ClientConfig
+}
+
+func (f fakeConfigs) GetClientConfig(ctx context.Context, agentID string) (models.ClientConfig, error) {
+	return f.config, nil
+}
+
+func (f fakeConfigs) CreateNotification(ctx context.Context, notification *models.Notification) error {
+	return nil
+}
+
+// asyncBackend deja la accion en vuelo, como un webhook que contesto 202
+type asyncBackend struct {
+	delivered chan string
+}
+
+func (b *asyncBackend) Deliver(ctx context.Context, action *models.Action, decision *models.LLMDecision, client *models.Client, binding *models.TargetBinding) {
+	action.Status = models.ActionStatusRunning
+	b.delivered <- action.Type
+}
+
+func TestExecutionQueueConflictRules(t *testing.T) {
+	tests := []struct {
+		rule        string
+		wantTickets []string          // status de cada Submit: restart, scale, rollback
+		wantWaiting []string          // lo que espera mientras restart esta en vuelo
+		wantFailed  map[string]string // acciones guardadas como failed y su motivo
+		wantNext    string            // lo que arranca cuando restart termina ("" = nada)
+	}{
+		{
+			rule:        models.ConflictReject,
+			wantTickets: []string{models.QueueExecuted, models.QueueRejected, models.QueueRejected},
+			wantWaiting: []string{},
+			wantFailed:  map[string]string{"scale": "target_busy", "rollback": "target_busy"},
+		},
+		{
+			rule:        models.ConflictQueue,
+			wantTickets: []string{models.QueueExecuted, models.QueueQueued, models.QueueQueued},
+			wantWaiting: []string{"scale", "rollback"},
+			wantFailed:  map[string]string{},
+			wantNext:    "scale",
+		},
+		{
+			rule:        models.ConflictSupersede,
+			wantTickets: []string{models.QueueExecuted, models.QueueQueued, models.QueueQueued},
+			wantWaiting: []string{"rollback"},
+			wantFailed:  map[string]string{"scale": "superseded"},
+			wantNext:    "rollback",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			ctx := context.Background()
+			actions := newFakeActions()
+			backend := &asyncBackend{delivered: make(chan string, 10)}
+			executor := &Executor{actions: actions, targets: fakeTargets{}, backends: map[string]Backend{"test": backend}}
+			queue := NewExecutionQueue(executor, fakeConfigs{config: models.ClientConfig{ConflictRule: tt.rule}})
+
+			agent, client := &models.Agent{ID: "agent-1"}, testClient()
+
+			var first *models.Action
+			for i, actionType := range []string{"restart", "scale", "rollback"} {
+				ticket, err := queue.Submit(ctx, &models.LLMDecision{Action: actionType, Target: "api"}, agent, client, models.SourceTick)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ticket.Status != tt.wantTickets[i] {
+					t.Errorf("%s: ticket %s, want %s", actionType, ticket.Status, tt.wantTickets[i])
+				}
+				if i == 0 {
+					first = ticket.Action
+				}
+			}
+
+			// otro target del mismo cliente no espera
+			if ticket, _ := queue.Submit(ctx, &models.LLMDecision{Action: "restart", Target: "db"}, agent, client, models.SourceTick); ticket.Status != models.QueueExecuted {
+				t.Errorf("another target got %s", ticket.Status)
+			}
+
+			lanes := queue.State(client.ID.String())
+			if len(lanes) != 2 || lanes[0].Target != "api" || lanes[0].Running == nil || lanes[0].Running.ActionID != first.ID {
+				t.Fatalf("lanes = %+v", lanes)
+			}
+			waiting := []string{}
+			for _, entry := range lanes[0].Waiting {
+				waiting = append(waiting, entry.ActionType)
+			}
+			if strings.Join(waiting, ",") != strings.Join(tt.wantWaiting, ",") {
+				t.Errorf("waiting = %v, want %v", waiting, tt.wantWaiting)
+			}
+
+			failed := actions.failed()
+			if len(failed) != len(tt.wantFailed) {
+				t.Errorf("failed = %v, want %v", failed, tt.wantFailed)
+			}
+			for actionType, reason := range tt.wantFailed {
+				if failed[actionType] != reason {
+					t.Errorf("%s failed with %q, want %q", actionType, failed[actionType], reason)
+				}
+			}
+
+			for _, want := range []string{"restart", "restart"} {
+				if got := <-backend.delivered; got != want {
+					t.Fatalf("delivered %s, want %s", got, want)
+				}
+			}
+
+			// el SDK reporta el restart: se libera el target y arranca lo que esperaba
+			done := *first
+			done.Status = models.ActionStatusSuccess
+			queue.Finished(&done)
+
+			if tt.wantNext == "" {
+				select {
+				case got := <-backend.delivered:
+					t.Fatalf("delivered %s after the target was released", got)
+				case <-time.After(50 * time.Millisecond):
+				}
+				if lanes := queue.State(client.ID.String()); len(lanes) != 1 || lanes[0].Target != "db" {
+					t.Errorf("lanes = %+v, want only db", lanes)
+				}
+				return
+			}
+
+			select {
+			case got := <-backend.delivered:
+				if got != tt.wantNext {
+					t.Errorf("next = %s, want %s", got, tt.wantNext)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("nothing started after the target was released")
+			}
+		})
+	}
+}
+
+func TestExecutionQueueIgnoresStaleFinish(t *testing.T) {
+	actions := newFakeActions()
+	backend := &asyncBackend{delivered: make(chan string, 10)}
+	executor := &Executor{actions: actions, targets: fakeTargets{}, backends: map[string]Backend{"test": backend}}
+	queue := NewExecutionQueue(executor, fakeConfigs{config: models.ClientConfig{ConflictRule: models.ConflictQueue}})
+	agent, client := &models.Agent{ID: "agent-1"}, testClient()
+
+	queue.Submit(context.Background(), &models.LLMDecision{Action: "restart", Target: "api"}, agent, client, models.SourceTick)
+
+	// una accion vieja del mismo target no suelta el lock
+	queue.Finished(&models.Action{ID: "other", ClientID: client.ID.String(), Target: "api", Status: models.ActionStatusSuccess})
+	lanes := queue.State(client.ID.String())
+	if len(lanes) != 1 || lanes[0].Running == nil {
+		t.Fatalf("lanes = %+v, the target must still be busy", lanes)
+	}
+
+	// ni un resultado intermedio de la que esta en vuelo
+	running := *lanes[0].Running
+	queue.Finished(&models.Action{ID: running.ActionID, ClientID: client.ID.String(), Target: "api", Status: models.ActionStatusRunning})
+	if lanes := queue.State(client.ID.String()); len(lanes) != 1 {
+		t.Fatalf("a running report released the target: %+v", lanes)
+	}
+}
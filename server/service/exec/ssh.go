@@ -0,0 +1,239 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	models "server/model"
+	"server/repositories"
+	"server/utils"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const maxSSHOutput = 16 << 10 // guardamos hasta 16KB de stdout/stderr en el resultado
+
+// SSHCredential: usuario, clave (o password) y la host key esperada del servidor
+type SSHCredential struct {
+	User       string `json:"user"`
+	PrivateKey string `json:"private_key,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+	Password   string `json:"password,omitempty"`
+	HostKey    string `json:"host_key"` // formato authorized_keys: "ssh-ed25519 AAAA..."
+}
+
+// ParseSSHCredential valida la credencial y arma la config del cliente ssh.
+// Sin host key no conectamos: no aceptamos cualquier servidor.
+func ParseSSHCredential(data []byte) (*ssh.ClientConfig, error) {
+	var c SSHCredential
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid ssh credential: %w", err)
+	}
+	if c.User == "" {
+		return nil, errors.New("ssh credential: user is required")
+	}
+	if c.HostKey == "" {
+		return nil, errors.New("ssh credential: host_key is required")
+	}
+
+	hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(c.HostKey))
+	if err != nil {
+		return nil, fmt.Errorf("ssh credential: invalid host_key: %w", err)
+	}
+
+	var auth []ssh.AuthMethod
+	if c.PrivateKey != "" {
+		var signer ssh.Signer
+		if c.Passphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(c.PrivateKey), []byte(c.Passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey([]byte(c.PrivateKey))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ssh credential: invalid private_key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if c.Password != "" {
+		auth = append(auth, ssh.Password(c.Password))
+	}
+	if len(auth) == 0 {
+		return nil, errors.New("ssh credential: private_key or password is required")
+	}
+
+	return &ssh.ClientConfig{
+		User:            c.User,
+		Auth:            auth,
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
+		Timeout:         10 * time.Second,
+	}, nil
+}
+
+// SSHBackend corre por ssh SOLO los comandos registrados en command_templates para (accion, target).
+//
+// Config del binding:
+//
+//	host        host:puerto de la VM (default puerto 22)
+//	credential  nombre de la credencial ssh (default: "default")
+type SSHBackend struct {
+	credentials repositories.CredentialStorage
+	templates   repositories.CommandTemplateStorage
+}
+
+func NewSSHBackend(credentials repositories.CredentialStorage, templates repositories.CommandTemplateStorage) *SSHBackend {
+	return &SSHBackend{credentials: credentials, templates: templates}
+}
+
+func (s *SSHBackend) Deliver(ctx context.Context, action *models.Action, decision *models.LLMDecision, client *models.Client, binding *models.TargetBinding) {
+	cfg := map[string]interface{}{}
+	if binding != nil {
+		cfg = binding.Config
+	}
+
+	result := map[string]interface{}{"backend": models.BackendSSH}
+
+	tpl, err := s.templates.GetCommandTemplate(ctx, client.ID.String(), action.Type, action.Target)
+	if err != nil {
+		if errors.Is(err, repositories.ErrCommandTemplateNotFound) {
+			err = fmt.Errorf("no command registered for %s on %s", action.Type, action.Target)
+		}
+		setResult(action, result, err)
+		return
+	}
+	result["template_id"] = tpl.ID
+
+	command, err := RenderCommand(tpl, decision.Params)
+	if err != nil {
+		setResult(action, result, err)
+		return
+	}
+	result["command"] = command
+
+	host := configString(cfg, "host", "")
+	if host == "" {
+		setResult(action, result, errors.New("ssh host not configured for target"))
+		return
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "22")
+	}
+
+	config, err := s.configFor(ctx, client, configString(cfg, "credential", "default"))
+	if err != nil {
+		setResult(action, result, err)
+		return
+	}
+
+	timeout := time.Duration(commandTimeout(tpl)) * time.Second
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stdout, stderr, exitStatus, err := runSSH(ctx, host, config, command)
+	result["stdout"] = stdout
+	result["stderr"] = stderr
+	result["exit_status"] = exitStatus
+
+	if err == nil && exitStatus != 0 {
+		err = fmt.Errorf("command exited with status %d", exitStatus)
+	}
+	setResult(action, result, err)
+}
+
+func (s *SSHBackend) configFor(ctx context.Context, client *models.Client, name string) (*ssh.ClientConfig, error) {
+	credential, err := s.credentials.GetCredential(ctx, client.ID.String(), models.CredentialSSH, name)
+	if err != nil {
+		if errors.Is(err, repositories.ErrCredentialNotFound) {
+			return nil, fmt.Errorf("ssh credential %q not configured", name)
+		}
+		return nil, err
+	}
+
+	data, err := utils.DecryptSecret(credential.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt ssh credential: %w", err)
+	}
+	return ParseSSHCredential(data)
+}
+
+// runSSH corre el comando y lo mata si se pasa del timeout del contexto
+func runSSH(ctx context.Context, host string, config *ssh.ClientConfig, command string) (string, string, int, error) {
+	// el host lo elige el cliente: pasa por el guard como cualquier conexion saliente
+	dialCtx, cancel := context.WithTimeout(ctx, config.Timeout)
+	conn, err := utils.OutboundGuard().DialContext(dialCtx, "tcp", host)
+	cancel()
+	if err != nil {
+		return "", "", -1, err
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, host, config)
+	if err != nil {
+		conn.Close()
+		return "", "", -1, err
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return "", "", -1, err
+	}
+	defer session.Close()
+
+	stdout := &limitedBuffer{max: maxSSHOutput}
+	stderr := &limitedBuffer{max: maxSSHOutput}
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	done := make(chan error, 1)
+	go func() { done <- session.Run(command) }()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		session.Signal(ssh.SIGKILL)
+		client.Close()
+		return stdout.String(), stderr.String(), -1, fmt.Errorf("command timed out: %w", ctx.Err())
+	}
+
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return stdout.String(), stderr.String(), exitErr.ExitStatus(), nil
+	}
+	if err != nil {
+		return stdout.String(), stderr.String(), -1, err
+	}
+	return stdout.String(), stderr.String(), 0, nil
+}
+
+// limitedBuffer descarta lo que pase de max (el comando sigue corriendo igual)
+type limitedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+	max int
+}
+
+func (l *limitedBuffer) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if room := l.max - l.buf.Len(); room > 0 {
+		if len(p) > room {
+			l.buf.Write(p[:room])
+		} else {
+			l.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (l *limitedBuffer) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.buf.String()
+}
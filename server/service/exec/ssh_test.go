@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net"
+	models "server/model"
+	"server/repositories"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// fakeTemplates guarda los templates en memoria por accion/target
+type fakeTemplates map[string]*models.CommandTemplate
+
+func (f fakeTemplates) GetCommandTemplate(ctx context.Context, clientID, actionType, target string) (*models.CommandTemplate, error) {
+	if tpl, ok := f[actionType+"/"+target]; ok {
+		return tpl, nil
+	}
+	return nil, repositories.ErrCommandTemplateNotFound
+}
+
+func (f fakeTemplates) ListCommandTemplates(ctx context.Context, clientID string) ([]models.CommandTemplate, error) {
+	return nil, nil
+}
+
+func (f fakeTemplates) SaveCommandTemplate(ctx context.Context, tpl *models.CommandTemplate) error {
+	f[tpl.ActionType+"/"+tpl.Target] = tpl
+	return nil
+}
+
+func (f fakeTemplates) DeleteCommandTemplate(ctx context.Context, clientID, actionType, target string) error {
+	delete(f, actionType+"/"+target)
+	return nil
+}
+
+// fakeSSHServer acepta password "secret" y contesta cada exec con lo que diga run
+type fakeSSHServer struct {
+	addr    string
+	hostKey ssh.PublicKey
+	run     func(command string) (stdout string, status int, hang bool)
+
+	mu       sync.Mutex
+	commands []string
+}
+
+func newFakeSSHServer(t *testing.T) *fakeSSHServer {
+	t.Helper()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if c.User() == "deploy" && string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+	}
+	config.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &fakeSSHServer{addr: ln.Addr().String(), hostKey: signer.PublicKey()}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, config)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSSHServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	defer conn.Close()
+
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only sessions")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go s.session(channel, requests)
+	}
+}
+
+func (s *fakeSSHServer) session(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+
+	for req := range requests {
+		if req.Type != "exec" || len(req.Payload) < 4 {
+			req.Reply(false, nil)
+			continue
+		}
+		command := string(req.Payload[4:])
+		req.Reply(true, nil)
+
+		s.mu.Lock()
+		s.commands = append(s.commands, command)
+		s.mu.Unlock()
+
+		stdout, status, hang := s.run(command)
+		if hang {
+			// ignora el SIGKILL: sigue hasta que el cliente corte la conexion
+			for req := range requests {
+				req.Reply(false, nil)
+			}
+			return
+		}
+		io.WriteString(channel, stdout)
+		payload := make([]byte, 4)
+		binary.BigEndian.PutUint32(payload, uint32(status))
+		channel.SendRequest("exit-status", false, payload)
+		return
+	}
+}
+
+func sshCredential(t *testing.T, c SSHCredential) []byte {
+	t.Helper()
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseSSHCredential(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := ssh.NewSignerFromKey(priv)
+	hostKey := string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey := string(pem.EncodeToMemory(block))
+
+	tests := []struct {
+		name    string
+		cred    SSHCredential
+		wantErr string
+	}{
+		{name: "password", cred: SSHCredential{User: "deploy", Password: "secret", HostKey: hostKey}},
+		{name: "private key", cred: SSHCredential{User: "deploy", PrivateKey: privateKey, HostKey: hostKey}},
+		{name: "without user", cred: SSHCredential{Password: "secret", HostKey: hostKey}, wantErr: "user is required"},
+		{name: "without host key", cred: SSHCredential{User: "deploy", Password: "secret"}, wantErr: "host_key is required"},
+		{name: "invalid host key", cred: SSHCredential{User: "deploy", Password: "secret", HostKey: "ssh-ed25519 nope"}, wantErr: "invalid host_key"},
+		{name: "invalid private key", cred: SSHCredential{User: "deploy", PrivateKey: "nope", HostKey: hostKey}, wantErr: "invalid private_key"},
+		{name: "without auth", cred: SSHCredential{User: "deploy", HostKey: hostKey}, wantErr: "private_key or password is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseSSHCredential(sshCredential(t, tt.cred))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.User != "deploy" || len(config.Auth) != 1 || config.HostKeyCallback == nil {
+				t.Errorf("config = %+v", config)
+			}
+		})
+	}
+}
+
+func TestSSHBackendDeliver(t *testing.T) {
+	restart := &models.CommandTemplate{
+		ID: "tpl-1", ActionType: "restart", Target: "api",
+		Template:     "systemctl restart {{.unit}}",
+		ParamsSchema: map[string]models.ParamSchema{"unit": {Type: "string", Required: true}},
+	}
+	slow := &models.CommandTemplate{ID: "tpl-2", ActionType: "drain", Target: "api", Template: "drain", TimeoutSeconds: 1}
+
+	tests := []struct {
+		name         string
+		action       string
+		params       map[string]interface{}
+		otherHostKey bool
+		wantErr      string
+		wantCommands []string
+		check        func(t *testing.T, action *models.Action)
+	}{
+		{
+			name:         "runs the rendered command",
+			action:       "restart",
+			params:       map[string]interface{}{"unit": "api.service"},
+			wantCommands: []string{"systemctl restart 'api.service'"},
+			check: func(t *testing.T, action *models.Action) {
+				if action.Result["stdout"] != "ok\n" || action.Result["exit_status"] != 0 || action.Result["template_id"] != "tpl-1" {
+					t.Errorf("result = %v", action.Result)
+				}
+			},
+		},
+		{
+			name:         "quotes what the llm sends",
+			action:       "restart",
+			params:       map[string]interface{}{"unit": "api; rm -rf /"},
+			wantCommands: []string{"systemctl restart 'api; rm -rf /'"},
+		},
+		{
+			name:         "non-zero exit fails the action",
+			action:       "restart",
+			params:       map[string]interface{}{"unit": "missing.service"},
+			wantErr:      "exited with status 5",
+			wantCommands: []string{"systemctl restart 'missing.service'"},
+			check: func(t *testing.T, action *models.Action) {
+				if action.Result["exit_status"] != 5 {
+					t.Errorf("exit_status = %v", action.Result["exit_status"])
+				}
+			},
+		},
+		{
+			name:    "param outside the schema",
+			action:  "restart",
+			params:  map[string]interface{}{"unit": "api.service", "extra": "ls"},
+			wantErr: `param "extra" is not allowed`,
+		},
+		{
+			name:    "no template for the action",
+			action:  "stop",
+			wantErr: "no command registered for stop on api",
+		},
+		{
+			name:         "another host key",
+			action:       "restart",
+			params:       map[string]interface{}{"unit": "api.service"},
+			otherHostKey: true,
+			wantErr:      "host key mismatch",
+		},
+		{
+			name:         "killed at the template timeout",
+			action:       "drain",
+			wantErr:      "command timed out",
+			wantCommands: []string{"drain"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeSSHServer(t)
+			server.run = func(command string) (string, int, bool) {
+				switch {
+				case command == "drain":
+					return "", 0, true
+				case strings.Contains(command, "missing"):
+					return "", 5, false
+				}
+				return "ok\n", 0, false
+			}
+
+			hostKey := server.hostKey
+			if tt.otherHostKey {
+				hostKey = newFakeSSHServer(t).hostKey
+			}
+
+			client := testClient()
+			creds := fakeCredentials{}
+			creds.put(t, client, models.CredentialSSH, "default", sshCredential(t, SSHCredential{
+				User: "deploy", Password: "secret", HostKey: string(ssh.MarshalAuthorizedKey(hostKey)),
+			}))
+			templates := fakeTemplates{"restart/api": restart, "drain/api": slow}
+
+			action := &models.Action{Type: tt.action, Target: "api", Status: models.ActionStatusPending}
+			decision := &models.LLMDecision{Action: tt.action, Target: "api", Params: tt.params}
+			binding := &models.TargetBinding{Backend: models.BackendSSH, Config: map[string]interface{}{"host": server.addr}}
+
+			start := time.Now()
+			NewSSHBackend(creds, templates).Deliver(context.Background(), action, decision, client, binding)
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("Deliver took %s", elapsed)
+			}
+
+			if tt.wantErr != "" {
+				errMsg, _ := action.Result["error"].(string)
+				if action.Status != models.ActionStatusFailed || !strings.Contains(errMsg, tt.wantErr) {
+					t.Fatalf("status = %s, error = %q, want %q", action.Status, errMsg, tt.wantErr)
+				}
+			} else if action.Status != models.ActionStatusSuccess {
+				t.Fatalf("status = %s, result = %v", action.Status, action.Result)
+			}
+
+			server.mu.Lock()
+			commands := server.commands
+			server.mu.Unlock()
+			if strings.Join(commands, "\n") != strings.Join(tt.wantCommands, "\n") {
+				t.Errorf("commands = %q, want %q", commands, tt.wantCommands)
+			}
+			if tt.check != nil {
+				tt.check(t, action)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	models "server/model"
+	"server/utils"
+	"strconv"
+	"time"
+)
+
+// WebhookBackend hace POST de la accion al webhook del cliente (modo push).
+// El body es el ActionEnvelope si el SDK ya negocio ProtocolV1, si no la decision sola.
+type WebhookBackend struct {
+	httpClient   *http.Client
+	asyncTimeout time.Duration
+	protocols    ProtocolStorage
+	monitor      *WebhookMonitor // salud del webhook (nil = no se registra)
+}
+
+func NewWebhookBackend(asyncTimeout time.Duration, protocols ProtocolStorage, monitor *WebhookMonitor) *WebhookBackend {
+	return &WebhookBackend{
+		httpClient:   utils.OutboundGuard().WebhookClient(10 * time.Second), // la URL la eligio el cliente
+		asyncTimeout: asyncTimeout,
+		protocols:    protocols,
+		monitor:      monitor,
+	}
+}
+
+// Deliver: 200 = success, 202 = running hasta que el SDK reporte en /v1/actions/:id/result
+func (w *WebhookBackend) Deliver(ctx context.Context, action *models.Action, decision *models.LLMDecision, client *models.Client, binding *models.TargetBinding) {
+	deadline := time.Now().Add(w.asyncTimeout)
+	version := models.NegotiateProtocol(strconv.Itoa(client.ProtocolVersion))
+
+	response, err := w.post(ctx, client.WebhookURL, action, decision, &deadline, version)
+
+	// un SDK viejo no entiende el envelope: si falla sin anunciar version, va de nuevo la decision sola
+	if err == nil && version > models.ProtocolLegacy && response.StatusCode >= 400 && response.Header.Get(models.ProtocolHeader) == "" {
+		response.Body.Close()
+		version = models.ProtocolLegacy
+		response, err = w.post(ctx, client.WebhookURL, action, decision, &deadline, version)
+	}
+
+	executedAt := time.Now()
+	action.ExecutedAt = &executedAt
+
+	if w.monitor != nil {
+		if err != nil {
+			w.monitor.Record(ctx, client, err)
+		} else {
+			w.monitor.Record(ctx, client, deliveryError(response.StatusCode))
+		}
+	}
+
+	if err != nil {
+		action.Status = models.ActionStatusFailed
+		action.Result = map[string]interface{}{"error": "client_webhook_unreacheable"}
+		return
+	}
+	defer response.Body.Close()
+
+	w.negotiate(ctx, client, response.Header.Get(models.ProtocolHeader))
+
+	body := decodeResult(response.Body)
+	takePreState(action, body)
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		action.Status = models.ActionStatusSuccess
+		action.Result = body
+	case http.StatusAccepted:
+		action.Status = models.ActionStatusRunning
+		action.DeadlineAt = &deadline
+		action.Result = body
+	default:
+		action.Status = models.ActionStatusFailed
+		action.Result = body
+		action.Result["error"] = "client_webhook_error"
+		action.Result["status_code"] = response.StatusCode
+	}
+}
+
+func (w *WebhookBackend) post(ctx context.Context, url string, action *models.Action, decision *models.LLMDecision, deadline *time.Time, version int) (*http.Response, error) {
+	var payload []byte
+	if version >= models.ProtocolV1 {
+		payload, _ = json.Marshal(NewEnvelope(action, *decision, deadline))
+	} else {
+		payload, _ = json.Marshal(decision)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-type", "application/json")
+	req.Header.Set(models.ActionIDHeader, action.ID)
+	req.Header.Set(models.ProtocolHeader, strconv.Itoa(version))
+
+	return w.httpClient.Do(req)
+}
+
+// negotiate guarda la version que anuncio el SDK en su respuesta si cambio
+// (un SDK viejo no manda el header: vuelve a legacy)
+func (w *WebhookBackend) negotiate(ctx context.Context, client *models.Client, header string) {
+	version := models.NegotiateProtocol(header)
+	if version == client.ProtocolVersion || w.protocols == nil {
+		return
+	}
+	if err := w.protocols.UpdateProtocolVersion(ctx, client.ID.String(), version); err != nil {
+		log.Printf("[Webhook] no se pudo guardar la version de protocolo del cliente %s: %v", client.ID, err)
+		return
+	}
+	client.ProtocolVersion = version
+}
+
+// lee la respuesta del SDK (si es JSON) para guardarla como resultado de la accion
+func decodeResult(body io.Reader) map[string]interface{} {
+	result := map[string]interface{}{}
+	json.NewDecoder(io.LimitReader(body, 64<<10)).Decode(&result)
+	if result == nil {
+		result = map[string]interface{}{}
+	}
+	return result
+}
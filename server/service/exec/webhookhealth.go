@@ -0,0 +1,219 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	models "server/model"
+	"server/repositories"
+	"server/utils"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// fallas seguidas del webhook que pasan al agente a notify-only
+	DefaultSuspendThreshold = 5
+	// entregas que se guardan para el success rate
+	webhookHealthWindow = 20
+)
+
+// HealthStorage es lo que necesita el WebhookMonitor
+type HealthStorage interface {
+	repositories.WebhookHealthStorage
+	GetClient(ctx context.Context, id string) (*models.Client, error)
+	CreateNotification(ctx context.Context, notification *models.Notification) error
+}
+
+// WebhookMonitor lleva la salud del webhook de cada cliente. Despues de "threshold" fallas
+// seguidas suspende las acciones por webhook (el agente queda notify-only) y avisa al dueño;
+// el prober prueba los suspendidos y los reactiva con la primera respuesta.
+type WebhookMonitor struct {
+	storage    HealthStorage
+	threshold  int
+	httpClient *http.Client
+
+	mu sync.Mutex // las actualizaciones de un cliente son read-modify-write
+}
+
+func NewWebhookMonitor(storage HealthStorage, threshold int) *WebhookMonitor {
+	if threshold <= 0 {
+		threshold = DefaultSuspendThreshold
+	}
+	return &WebhookMonitor{
+		storage:    storage,
+		threshold:  threshold,
+		httpClient: utils.OutboundGuard().WebhookClient(5 * time.Second),
+	}
+}
+
+// Health devuelve la salud del webhook del cliente
+func (m *WebhookMonitor) Health(ctx context.Context, clientID string) (*models.WebhookHealth, error) {
+	return m.storage.GetWebhookHealth(ctx, clientID)
+}
+
+// Suspended: si hay que dejar de mandarle acciones al webhook del cliente
+func (m *WebhookMonitor) Suspended(ctx context.Context, clientID string) bool {
+	health, err := m.storage.GetWebhookHealth(ctx, clientID)
+	if err != nil {
+		log.Printf("[Webhook] no se pudo leer la salud del webhook de %s: %v", clientID, err)
+		return false
+	}
+	return health.Suspended
+}
+
+// Record anota el resultado de una entrega (deliveryErr nil = el webhook respondio)
+func (m *WebhookMonitor) Record(ctx context.Context, client *models.Client, deliveryErr error) {
+	m.record(ctx, client, deliveryErr, false)
+}
+
+func (m *WebhookMonitor) record(ctx context.Context, client *models.Client, deliveryErr error, probe bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	health, err := m.storage.GetWebhookHealth(ctx, client.ID.String())
+	if err != nil {
+		log.Printf("[Webhook] no se pudo leer la salud del webhook de %s: %v", client.ID, err)
+		return
+	}
+
+	now := time.Now()
+	if probe {
+		health.LastProbeAt = &now
+	}
+	health.Recent = append(health.Recent, deliveryErr == nil)
+	if len(health.Recent) > webhookHealthWindow {
+		health.Recent = health.Recent[len(health.Recent)-webhookHealthWindow:]
+	}
+
+	var notification *models.Notification
+
+	if deliveryErr == nil {
+		health.ConsecutiveFailures = 0
+		health.LastSuccessAt = &now
+		if health.SuspendedAt != nil {
+			health.SuspendedAt = nil
+			notification = ownerNotification(client, "Acciones reactivadas - "+client.CompanyName,
+				fmt.Sprintf("Tu webhook %s volvio a responder. El agente vuelve a ejecutar acciones.", client.WebhookURL))
+		}
+	} else {
+		health.ConsecutiveFailures++
+		health.LastFailureAt = &now
+		health.LastError = deliveryErr.Error()
+		if health.SuspendedAt == nil && health.ConsecutiveFailures >= m.threshold {
+			health.SuspendedAt = &now
+			notification = ownerNotification(client, "⚠️ Acciones suspendidas - "+client.CompanyName,
+				fmt.Sprintf("Tu webhook %s fallo %d veces seguidas (ultimo error: %s).\n"+
+					"El agente solo va a notificar hasta que el webhook vuelva a responder; lo probamos cada minuto.",
+					client.WebhookURL, health.ConsecutiveFailures, health.LastError))
+		}
+	}
+
+	if err := m.storage.SaveWebhookHealth(ctx, health); err != nil {
+		log.Printf("[Webhook] no se pudo guardar la salud del webhook de %s: %v", client.ID, err)
+		return
+	}
+
+	if notification != nil {
+		if err := m.storage.CreateNotification(ctx, notification); err != nil {
+			log.Printf("[Webhook] no se pudo crear la notificacion para %s: %v", client.ID, err)
+		}
+	}
+}
+
+// NotifyOnly reemplaza la entrega de una accion mientras el webhook esta suspendido:
+// no se manda nada, se le avisa al dueño lo que el agente hubiera hecho
+func (m *WebhookMonitor) NotifyOnly(ctx context.Context, action *models.Action, client *models.Client) {
+	notification := ownerNotification(client, fmt.Sprintf("Accion no ejecutada: %s %s", action.Type, action.Target),
+		fmt.Sprintf("El agente decidio %s sobre %s pero tu webhook esta suspendido, no se ejecuto.\n\nMotivo: %s",
+			action.Type, action.Target, action.Reasoning))
+	notification.ActionID = &action.ID
+
+	notified := true
+	if err := m.storage.CreateNotification(ctx, notification); err != nil {
+		log.Printf("[Webhook] no se pudo crear la notificacion para %s: %v", client.ID, err)
+		notified = false
+	}
+
+	setResult(action, map[string]interface{}{"notified": notified}, errors.New("webhook_suspended"))
+}
+
+// Probe manda un probe al webhook: cualquier respuesta que no sea de un proxy sin backend cuenta
+func (m *WebhookMonitor) Probe(ctx context.Context, client *models.Client) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, client.WebhookURL, bytes.NewBufferString("{}"))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-type", "application/json")
+	req.Header.Set(models.ProbeHeader, "1")
+	req.Header.Set(models.ProtocolHeader, strconv.Itoa(models.ProtocolVersion))
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return deliveryError(resp.StatusCode)
+}
+
+// ProbeSuspended prueba todos los webhooks suspendidos (Record los reactiva si responden)
+func (m *WebhookMonitor) ProbeSuspended(ctx context.Context) {
+	suspended, err := m.storage.ListSuspendedWebhooks(ctx)
+	if err != nil {
+		log.Printf("[Webhook] error listando webhooks suspendidos: %v", err)
+		return
+	}
+
+	for _, health := range suspended {
+		client, err := m.storage.GetClient(ctx, health.ClientID)
+		if err != nil {
+			log.Printf("[Webhook] no se pudo leer el cliente %s: %v", health.ClientID, err)
+			continue
+		}
+		m.record(ctx, client, m.Probe(ctx, client), true)
+	}
+}
+
+// StartProber corre ProbeSuspended cada "interval" hasta que se cancele el contexto
+func (m *WebhookMonitor) StartProber(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.ProbeSuspended(ctx)
+		}
+	}
+}
+
+// deliveryError decide si un status habla mal del webhook (no de la accion):
+// 404 (url equivocada) o un proxy sin backend detras
+func deliveryError(statusCode int) error {
+	switch statusCode {
+	case http.StatusNotFound, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return fmt.Errorf("webhook answered %d", statusCode)
+	}
+	return nil
+}
+
+func ownerNotification(client *models.Client, subject, body string) *models.Notification {
+	return &models.Notification{
+		ID:        uuid.New().String(),
+		ClientID:  client.ID.String(),
+		Type:      "email",
+		Recipient: client.Email,
+		Subject:   subject,
+		Body:      body,
+		Status:    "pending",
+		CreatedAt: time.Now(),
+	}
+}
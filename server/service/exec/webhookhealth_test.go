@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	models "server/model"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeHealth guarda la salud y las notificaciones en memoria, como las devuelve la base
+type fakeHealth struct {
+	mu            sync.Mutex
+	health        map[string]models.WebhookHealth
+	clients       map[string]*models.Client
+	notifications []*models.Notification
+}
+
+func newFakeHealth(clients ...*models.Client) *fakeHealth {
+	f := &fakeHealth{health: map[string]models.WebhookHealth{}, clients: map[string]*models.Client{}}
+	for _, c := range clients {
+		f.clients[c.ID.String()] = c
+	}
+	return f
+}
+
+func (f *fakeHealth) GetWebhookHealth(ctx context.Context, clientID string) (*models.WebhookHealth, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	h, ok := f.health[clientID]
+	if !ok {
+		h = models.WebhookHealth{ClientID: clientID}
+	}
+	h.Recent = append([]bool{}, h.Recent...)
+	h.Suspended = h.SuspendedAt != nil
+	return &h, nil
+}
+
+func (f *fakeHealth) SaveWebhookHealth(ctx context.Context, health *models.WebhookHealth) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.health[health.ClientID] = *health
+	return nil
+}
+
+func (f *fakeHealth) ListSuspendedWebhooks(ctx context.Context) ([]models.WebhookHealth, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var suspended []models.WebhookHealth
+	for _, h := range f.health {
+		if h.SuspendedAt != nil {
+			suspended = append(suspended, h)
+		}
+	}
+	return suspended, nil
+}
+
+func (f *fakeHealth) GetClient(ctx context.Context, id string) (*models.Client, error) {
+	if c, ok := f.clients[id]; ok {
+		return c, nil
+	}
+	return nil, errors.New("client not found")
+}
+
+func (f *fakeHealth) CreateNotification(ctx context.Context, notification *models.Notification) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.notifications = append(f.notifications, notification)
+	return nil
+}
+
+func (f *fakeHealth) subjects() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var subjects []string
+	for _, n := range f.notifications {
+		subjects = append(subjects, n.Subject)
+	}
+	return subjects
+}
+
+func TestWebhookMonitorRecord(t *testing.T) {
+	down := errors.New("connection refused")
+
+	tests := []struct {
+		name          string
+		results       []error // entregas en orden
+		wantSuspended bool
+		wantFailures  int
+		wantSubjects  []string // prefijos de las notificaciones, en orden
+	}{
+		{name: "healthy", results: []error{nil, nil}},
+		{name: "below the threshold", results: []error{down, down}, wantFailures: 2},
+		{name: "a success resets the count", results: []error{down, down, nil, down, down}, wantFailures: 2},
+		{
+			name:          "suspends at the threshold and tells the owner once",
+			results:       []error{down, down, down, down},
+			wantSuspended: true,
+			wantFailures:  4,
+			wantSubjects:  []string{"⚠️ Acciones suspendidas"},
+		},
+		{
+			name:         "the first success reactivates",
+			results:      []error{down, down, down, nil},
+			wantSubjects: []string{"⚠️ Acciones suspendidas", "Acciones reactivadas"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := testClient()
+			storage := newFakeHealth(client)
+			monitor := NewWebhookMonitor(storage, 3)
+
+			for _, result := range tt.results {
+				monitor.Record(context.Background(), client, result)
+			}
+
+			if got := monitor.Suspended(context.Background(), client.ID.String()); got != tt.wantSuspended {
+				t.Errorf("suspended = %v, want %v", got, tt.wantSuspended)
+			}
+			health, _ := monitor.Health(context.Background(), client.ID.String())
+			if health.ConsecutiveFailures != tt.wantFailures || len(health.Recent) != len(tt.results) {
+				t.Errorf("failures = %d, recent = %v", health.ConsecutiveFailures, health.Recent)
+			}
+
+			subjects := storage.subjects()
+			if len(subjects) != len(tt.wantSubjects) {
+				t.Fatalf("notifications = %q, want %q", subjects, tt.wantSubjects)
+			}
+			for i, prefix := range tt.wantSubjects {
+				if !strings.HasPrefix(subjects[i], prefix) {
+					t.Errorf("notification %d = %q, want %q", i, subjects[i], prefix)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookMonitorKeepsAWindow(t *testing.T) {
+	client := testClient()
+	monitor := NewWebhookMonitor(newFakeHealth(client), 1000)
+
+	for i := 0; i < webhookHealthWindow+5; i++ {
+		monitor.Record(context.Background(), client, errors.New("timeout"))
+	}
+	monitor.Record(context.Background(), client, nil)
+
+	health, _ := monitor.Health(context.Background(), client.ID.String())
+	if len(health.Recent) != webhookHealthWindow || !health.Recent[len(health.Recent)-1] {
+		t.Errorf("recent = %v", health.Recent)
+	}
+}
+
+func TestWebhookMonitorNotifyOnly(t *testing.T) {
+	client := testClient()
+	storage := newFakeHealth(client)
+	monitor := NewWebhookMonitor(storage, 3)
+
+	action := &models.Action{ID: "a-1", Type: "restart", Target: "api", Reasoning: "5xx spike", Status: models.ActionStatusPending}
+	monitor.NotifyOnly(context.Background(), action, client)
+
+	if action.Status != models.ActionStatusFailed || action.Result["error"] != "webhook_suspended" || action.Result["notified"] != true {
+		t.Errorf("status = %s, result = %v", action.Status, action.Result)
+	}
+	if len(storage.notifications) != 1 {
+		t.Fatalf("notifications = %d", len(storage.notifications))
+	}
+	n := storage.notifications[0]
+	if n.ActionID == nil || *n.ActionID != "a-1" || !strings.Contains(n.Body, "5xx spike") || n.Recipient != client.Email {
+		t.Errorf("notification = %+v", n)
+	}
+}
+
+func TestWebhookMonitorProbeSuspended(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		wantSuspended bool
+	}{
+		{name: "any answer from the sdk reactivates", status: http.StatusInternalServerError},
+		{name: "ok reactivates", status: http.StatusOK},
+		{name: "proxy without backend stays suspended", status: http.StatusBadGateway, wantSuspended: true},
+		{name: "wrong url stays suspended", status: http.StatusNotFound, wantSuspended: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var probed []string
+			srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				probed = append(probed, r.Header.Get(models.ProbeHeader))
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			client := testClient()
+			client.WebhookURL = srv.URL
+			storage := newFakeHealth(client)
+			monitor := NewWebhookMonitor(storage, 1)
+			monitor.httpClient = srv.Client()
+
+			monitor.Record(context.Background(), client, errors.New("timeout"))
+			monitor.ProbeSuspended(context.Background())
+
+			if len(probed) != 1 || probed[0] != "1" {
+				t.Fatalf("probes = %q", probed)
+			}
+			health, _ := monitor.Health(context.Background(), client.ID.String())
+			if health.Suspended != tt.wantSuspended || health.LastProbeAt == nil {
+				t.Errorf("suspended = %v, last probe = %v", health.Suspended, health.LastProbeAt)
+			}
+		})
+	}
+}
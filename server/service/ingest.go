@@ -8,43 +8,76 @@ import (
 	"net/smtp"
 	models "server/model"
 	"server/repositories"
-	"server/utils"
+	"time"
+
+	"github.com/google/uuid"
 )
 
+var ErrInvalidEvent = errors.New("invalid event")
+
+// un evento con created_at mas adelante que esto viene de un reloj roto: usamos la hora de llegada
+const maxEventClockSkew = 5 * time.Minute
+
 type IngestHandler struct {
-	events repositories.EventRepository
+	events repositories.EventStorage
 	agent  repositories.AgentStorage
 	client repositories.ClientStorage
 }
 
-func NewIngestHandler(e repositories.EventRepository, a repositories.AgentStorage) *IngestHandler {
-	return &IngestHandler{events: e, agent: a}
+func NewIngestHandler(e repositories.EventStorage, a repositories.AgentStorage, c repositories.ClientStorage) *IngestHandler {
+	return &IngestHandler{events: e, agent: a, client: c}
 }
 
-//RECIBIR LA API KEY PARA VER A QUE AGENTE PERTENECE
-//PARSEAMOS EL EVENTO QUE NOS MANDA EL SDK
-//AL EVENTO NUEVO LE PONEMOS AGENT ID Y CLIENT ID
-//GUARDAMOS EL EVENTO EN LA BASE DE DATOS (POSTGRE)
-
-func (IH *IngestHandler) NewEventInRequestService(ctx context.Context, apiKey string, event *models.Event) error {
-
-	apiKeyHashed := utils.HashAPIKey(apiKey)
-
-	agent, err := IH.agent.GetAgentByApiKey(ctx, apiKeyHashed)
+// Ingest guarda un lote de eventos del SDK (ya autenticado por la API key) como pendientes
+// para el proximo tick del agente y devuelve sus IDs. Valida el lote entero antes de guardar.
+func (IH *IngestHandler) Ingest(ctx context.Context, client *models.Client, events []models.Event) ([]string, error) {
+	if len(events) == 0 || len(events) > models.MaxEventBatch {
+		return nil, fmt.Errorf("%w: a batch has between 1 and %d events", ErrInvalidEvent, models.MaxEventBatch)
+	}
 
+	agent, err := IH.agent.GetAgentByClientId(ctx, client.ID.String())
 	if err != nil {
-		return errors.New("la api key no esta registrada")
+		return nil, fmt.Errorf("error getting agent: %w", err)
 	}
 
-	event.AgentID = agent.ID
-	event.ClientID = agent.ClientID
-
-	if event.Severity == "critical" {
-		go IH.sendUrgentNotification(agent.ClientID, event.Service) // disparar en segundo plano
+	now := time.Now()
+	for i := range events {
+		event := &events[i]
+		if event.Type == "" || event.Service == "" {
+			return nil, fmt.Errorf("%w: type and service are required", ErrInvalidEvent)
+		}
+		switch event.Severity {
+		case "":
+			event.Severity = models.SeverityInfo
+		case models.SeverityInfo, models.SeverityWarning, models.SeverityCritical:
+		default:
+			return nil, fmt.Errorf("%w: unknown severity %q", ErrInvalidEvent, event.Severity)
+		}
+
+		event.ID = uuid.New().String()
+		event.AgentID = agent.ID
+		event.ClientID = agent.ClientID
+		event.ProcessedAt = nil
+		event.ReceivedAt = &now
+		if event.CreatedAt.IsZero() || event.CreatedAt.After(now.Add(maxEventClockSkew)) {
+			event.CreatedAt = now
+		}
 	}
 
-	return IH.events.CreateEvent(ctx, event)
+	ids := make([]string, 0, len(events))
+	for i := range events {
+		event := &events[i]
+		if err := IH.events.CreateEvent(ctx, event); err != nil {
+			return ids, fmt.Errorf("error saving event: %w", err)
+		}
+		ids = append(ids, event.ID)
+
+		if event.Severity == models.SeverityCritical {
+			go IH.sendUrgentNotification(agent.ClientID, event.Service) // disparar en segundo plano
+		}
+	}
 
+	return ids, nil
 }
 
 func (IH *IngestHandler) sendUrgentNotification(clientId string, servicio string) {
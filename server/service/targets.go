@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	models "server/model"
+	"server/repositories"
+	service "server/service/exec"
+	"server/utils"
+)
+
+var (
+	ErrUnknownBackend         = errors.New("unknown backend")
+	ErrInvalidTargetConfig    = errors.New("invalid target config")
+	ErrUnknownCredentialKind  = errors.New("unknown credential kind")
+	ErrInvalidCommandTemplate = errors.New("invalid command template")
+)
+
+// backends que se pueden asignar a un target
+var knownBackends = map[string]bool{
+	models.BackendWebhook:    true,
+	models.BackendPull:       true,
+	models.BackendKubernetes: true,
+	models.BackendDocker:     true,
+	models.BackendSSH:        true,
+	models.BackendGitOps:     true,
+}
+
+// Targets administra que backend ejecuta cada target del cliente, sus credenciales
+// y los comandos permitidos por ssh
+type Targets struct {
+	targets     repositories.TargetStorage
+	credentials repositories.CredentialStorage
+	templates   repositories.CommandTemplateStorage
+}
+
+func NewTargets(targets repositories.TargetStorage, credentials repositories.CredentialStorage, templates repositories.CommandTemplateStorage) *Targets {
+	return &Targets{targets: targets, credentials: credentials, templates: templates}
+}
+
+func (t *Targets) List(ctx context.Context, clientID string) ([]models.TargetBinding, error) {
+	return t.targets.ListTargetBindings(ctx, clientID)
+}
+
+func (t *Targets) Save(ctx context.Context, clientID, target string, req *models.SaveTargetRequest) (*models.TargetBinding, error) {
+	if !knownBackends[req.Backend] {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBackend, req.Backend)
+	}
+	if req.Config == nil {
+		req.Config = map[string]interface{}{}
+	}
+	if err := service.ValidateBindingConfig(req.Backend, req.Config); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTargetConfig, err)
+	}
+
+	binding := &models.TargetBinding{
+		ClientID: clientID,
+		Target:   target,
+		Backend:  req.Backend,
+		Config:   req.Config,
+	}
+
+	if err := t.targets.SaveTargetBinding(ctx, binding); err != nil {
+		return nil, err
+	}
+	return binding, nil
+}
+
+func (t *Targets) Delete(ctx context.Context, clientID, target string) error {
+	return t.targets.DeleteTargetBinding(ctx, clientID, target)
+}
+
+// SaveCredential valida la credencial antes de guardarla cifrada. Nunca se devuelve el secreto.
+func (t *Targets) SaveCredential(ctx context.Context, clientID, kind, name, secret string) (*models.Credential, error) {
+	switch kind {
+	case models.CredentialKubeconfig:
+		if _, err := service.ParseKubeconfig([]byte(secret), ""); err != nil {
+			return nil, err
+		}
+	case models.CredentialDockerTLS:
+		if _, err := service.ParseDockerTLS([]byte(secret)); err != nil {
+			return nil, err
+		}
+	case models.CredentialSSH:
+		if _, err := service.ParseSSHCredential([]byte(secret)); err != nil {
+			return nil, err
+		}
+	case models.CredentialGit:
+		if _, err := service.ParseGitCredential([]byte(secret)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCredentialKind, kind)
+	}
+
+	encrypted, err := utils.EncryptSecret([]byte(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	credential := &models.Credential{
+		ClientID: clientID,
+		Kind:     kind,
+		Name:     name,
+		Secret:   encrypted,
+	}
+	if err := t.credentials.SaveCredential(ctx, credential); err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+func (t *Targets) ListCommandTemplates(ctx context.Context, clientID string) ([]models.CommandTemplate, error) {
+	return t.templates.ListCommandTemplates(ctx, clientID)
+}
+
+// SaveCommandTemplate registra el comando que el backend ssh puede correr para (accion, target)
+func (t *Targets) SaveCommandTemplate(ctx context.Context, clientID, actionType, target string, req *models.SaveCommandTemplateRequest) (*models.CommandTemplate, error) {
+	tpl := &models.CommandTemplate{
+		ClientID:       clientID,
+		ActionType:     actionType,
+		Target:         target,
+		Template:       req.Template,
+		ParamsSchema:   req.ParamsSchema,
+		TimeoutSeconds: req.TimeoutSeconds,
+	}
+	if tpl.ParamsSchema == nil {
+		tpl.ParamsSchema = map[string]models.ParamSchema{}
+	}
+
+	if err := service.ValidateCommandTemplate(tpl); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCommandTemplate, err)
+	}
+
+	if err := t.templates.SaveCommandTemplate(ctx, tpl); err != nil {
+		return nil, err
+	}
+	return tpl, nil
+}
+
+func (t *Targets) DeleteCommandTemplate(ctx context.Context, clientID, actionType, target string) error {
+	return t.templates.DeleteCommandTemplate(ctx, clientID, actionType, target)
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	models "server/model"
+	"server/repositories"
+	"server/service/agent/llm"
+	executor "server/service/exec"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotReversible   = errors.New("action is not reversible")
+	ErrAlreadyUndone   = errors.New("action already undone")
+	ErrUndoNotFinished = errors.New("only successful actions can be undone")
+	ErrUndoRejected    = errors.New("undo rejected")
+)
+
+// UndoStorage es lo que lee Undo para armar la accion compensatoria
+type UndoStorage interface {
+	repositories.ActionStorage
+	repositories.AgentStorage
+	repositories.ClientStorage
+	repositories.ClientConfigStorage
+	repositories.CapabilityStorage
+	repositories.TargetStorage
+}
+
+// Undo deshace una accion con la accion compensatoria que dejo su backend en el pre_state.
+// La compensatoria es una accion mas: pasa por ValidateDecision y por la cola del target,
+// y queda en el historial con undo_of apuntando a la original.
+type Undo struct {
+	storage UndoStorage
+	queue   *executor.ExecutionQueue
+
+	mu      sync.Mutex
+	undoing map[string]bool // acciones con un Undo entre el chequeo y el Submit
+}
+
+func NewUndo(storage UndoStorage, queue *executor.ExecutionQueue) *Undo {
+	return &Undo{storage: storage, queue: queue, undoing: make(map[string]bool)}
+}
+
+func (u *Undo) Undo(ctx context.Context, clientID, actionID string) (*models.QueueTicket, error) {
+	action, err := u.storage.GetAction(ctx, actionID)
+	if err != nil {
+		return nil, err
+	}
+	if action.ClientID != clientID {
+		return nil, repositories.ErrActionNotFound
+	}
+	if action.Status != models.ActionStatusSuccess {
+		return nil, ErrUndoNotFinished
+	}
+
+	decision, err := compensation(action)
+	if err != nil {
+		return nil, err
+	}
+
+	// del chequeo al Submit nadie mas puede deshacer la misma accion: si no, dos pedidos
+	// juntos (o uno mientras el primero espera en la cola) compensarian dos veces
+	if !u.claim(action.ID) {
+		return nil, ErrAlreadyUndone
+	}
+	defer u.release(action.ID)
+
+	// un undo que fallo se puede reintentar, uno en curso o exitoso no
+	undos, err := u.storage.GetUndoActions(ctx, action.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, undo := range undos {
+		if undo.Status != models.ActionStatusFailed {
+			return nil, ErrAlreadyUndone
+		}
+	}
+	// el que esta en la cola todavia no se guardo
+	if u.queue.Undoing(clientID, action.ID) {
+		return nil, ErrAlreadyUndone
+	}
+
+	agent, err := u.storage.GetAgent(ctx, action.AgentID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting agent: %w", err)
+	}
+	client, err := u.storage.GetClient(ctx, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting client: %w", err)
+	}
+	cfg, err := u.storage.GetClientConfig(ctx, agent.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting client config: %w", err)
+	}
+	restartCount, err := u.storage.CountActionsSince(ctx, agent.ID, "restart", time.Now().Add(-1*time.Hour))
+	if err != nil {
+		return nil, fmt.Errorf("error counting restarts: %w", err)
+	}
+
+	caps, err := u.storage.GetCapabilities(ctx, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting capabilities: %w", err)
+	}
+
+	bound, err := serverTargets(ctx, u.storage, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := llm.ValidateDecision(decision, models.AgentRunContext{RestartCountHour: restartCount, ClientConfig: cfg, Capabilities: caps, ServerTargets: bound}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUndoRejected, err)
+	}
+
+	return u.queue.Submit(ctx, decision, agent, client, models.SourceManual)
+}
+
+func (u *Undo) claim(actionID string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.undoing[actionID] {
+		return false
+	}
+	u.undoing[actionID] = true
+	return true
+}
+
+func (u *Undo) release(actionID string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	delete(u.undoing, actionID)
+}
+
+// compensation arma la decision que restaura el pre_state de la accion
+func compensation(action *models.Action) (*models.LLMDecision, error) {
+	undo, ok := action.PreState[models.PreStateUndoKey].(map[string]interface{})
+	if !ok {
+		return nil, ErrNotReversible
+	}
+	undoAction, _ := undo["action"].(string)
+	if undoAction == "" {
+		return nil, ErrNotReversible
+	}
+	params, _ := undo["params"].(map[string]interface{})
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+
+	return &models.LLMDecision{
+		Action:     undoAction,
+		Target:     action.Target,
+		Params:     params,
+		Reasoning:  fmt.Sprintf("Undo of action %s (%s %s) requested by the user", action.ID, action.Type, action.Target),
+		Confidence: 1,
+		UndoOf:     action.ID,
+	}, nil
+}
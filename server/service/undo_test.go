@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	models "server/model"
+	"server/repositories"
+	executor "server/service/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// undoTestStorage es todo lo que tocan Undo y el executor
+type undoTestStorage interface {
+	UndoStorage
+	executor.Storage
+}
+
+// fakeUndoStorage guarda las acciones en memoria; lo que no sobreescribe no se usa
+type fakeUndoStorage struct {
+	undoTestStorage
+
+	mu      sync.Mutex
+	actions map[string]models.Action
+	client  *models.Client
+}
+
+func (f *fakeUndoStorage) SaveAction(ctx context.Context, action *models.Action) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.actions[action.ID] = *action
+	return nil
+}
+
+func (f *fakeUndoStorage) UpdateDeliveredAction(ctx context.Context, action *models.Action) (bool, error) {
+	return true, f.SaveAction(ctx, action)
+}
+
+func (f *fakeUndoStorage) GetAction(ctx context.Context, id string) (*models.Action, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if action, ok := f.actions[id]; ok {
+		return &action, nil
+	}
+	return nil, repositories.ErrActionNotFound
+}
+
+func (f *fakeUndoStorage) GetUndoActions(ctx context.Context, actionID string) ([]models.Action, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var undos []models.Action
+	for _, action := range f.actions {
+		if action.UndoOf != nil && *action.UndoOf == actionID {
+			undos = append(undos, action)
+		}
+	}
+	return undos, nil
+}
+
+func (f *fakeUndoStorage) GetAgent(ctx context.Context, id string) (*models.Agent, error) {
+	return &models.Agent{ID: id, ClientID: f.client.ID.String()}, nil
+}
+
+func (f *fakeUndoStorage) GetClient(ctx context.Context, id string) (*models.Client, error) {
+	return f.client, nil
+}
+
+func (f *fakeUndoStorage) GetClientConfig(ctx context.Context, agentID string) (models.ClientConfig, error) {
+	return models.ClientConfig{AllowedActions: []string{"restart", "scale"}, ConflictRule: models.ConflictQueue}, nil
+}
+
+func (f *fakeUndoStorage) CountActionsSince(ctx context.Context, agentID, actionType string, since time.Time) (int, error) {
+	return 0, nil
+}
+
+func (f *fakeUndoStorage) GetCapabilities(ctx context.Context, clientID string) (*models.Capabilities, error) {
+	return nil, nil
+}
+
+func (f *fakeUndoStorage) ListTargetBindings(ctx context.Context, clientID string) ([]models.TargetBinding, error) {
+	return nil, nil
+}
+
+func (f *fakeUndoStorage) GetTargetBinding(ctx context.Context, clientID, target string) (*models.TargetBinding, error) {
+	return &models.TargetBinding{ClientID: clientID, Target: target, Backend: "test"}, nil
+}
+
+// heldBackend deja cada accion en vuelo (como un 202) y no vuelve hasta que se abre gate
+type heldBackend struct {
+	gate chan struct{}
+}
+
+func (b *heldBackend) Deliver(ctx context.Context, action *models.Action, decision *models.LLMDecision, client *models.Client, binding *models.TargetBinding) {
+	<-b.gate
+	action.Status = models.ActionStatusRunning
+}
+
+func newUndoTest(t *testing.T) (*Undo, *executor.ExecutionQueue, *fakeUndoStorage, *heldBackend, *models.Action) {
+	t.Helper()
+	client := &models.Client{ID: uuid.New()}
+	storage := &fakeUndoStorage{actions: map[string]models.Action{}, client: client}
+
+	// la accion a deshacer: scale 2 -> 5, su compensatoria vuelve a 2
+	scaled := &models.Action{
+		ID: uuid.New().String(), AgentID: "agent-1", ClientID: client.ID.String(), Type: "scale", Target: "api",
+		Status:   models.ActionStatusSuccess,
+		PreState: map[string]interface{}{models.PreStateUndoKey: map[string]interface{}{"action": "scale", "params": map[string]interface{}{"replicas": 2.0}}},
+	}
+	storage.SaveAction(context.Background(), scaled)
+
+	backend := &heldBackend{gate: make(chan struct{})}
+	exec := executor.NewExecutor(storage, executor.NewCommandBroker(), time.Minute)
+	exec.RegisterBackend("test", backend)
+	queue := executor.NewExecutionQueue(exec, storage)
+
+	return NewUndo(storage, queue), queue, storage, backend, scaled
+}
+
+func TestUndoOnlyOnce(t *testing.T) {
+	t.Run("concurrent requests", func(t *testing.T) {
+		undo, _, storage, backend, scaled := newUndoTest(t)
+
+		var wg sync.WaitGroup
+		results := make(chan error, 10)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := undo.Undo(context.Background(), scaled.ClientID, scaled.ID)
+				results <- err
+			}()
+		}
+		// el primero queda entregando la compensatoria mientras llegan los demas
+		time.Sleep(50 * time.Millisecond)
+		close(backend.gate)
+		wg.Wait()
+		close(results)
+
+		accepted := 0
+		for err := range results {
+			switch {
+			case err == nil:
+				accepted++
+			case !errors.Is(err, ErrAlreadyUndone):
+				t.Errorf("err = %v", err)
+			}
+		}
+		undos, _ := storage.GetUndoActions(context.Background(), scaled.ID)
+		if accepted != 1 || len(undos) != 1 {
+			t.Errorf("%d undos accepted, %d compensations saved, want 1", accepted, len(undos))
+		}
+	})
+
+	t.Run("while the first one is queued", func(t *testing.T) {
+		undo, queue, storage, backend, scaled := newUndoTest(t)
+		close(backend.gate)
+		client, _ := storage.GetClient(context.Background(), scaled.ClientID)
+
+		// otra accion tiene el target: la compensatoria espera en memoria
+		queue.Submit(context.Background(), &models.LLMDecision{Action: "restart", Target: "api"}, &models.Agent{ID: "agent-1"}, client, models.SourceTick)
+
+		ticket, err := undo.Undo(context.Background(), scaled.ClientID, scaled.ID)
+		if err != nil || ticket.Status != models.QueueQueued {
+			t.Fatalf("first undo: ticket %+v, err %v", ticket, err)
+		}
+		if _, err := undo.Undo(context.Background(), scaled.ClientID, scaled.ID); !errors.Is(err, ErrAlreadyUndone) {
+			t.Errorf("second undo: err = %v, want ErrAlreadyUndone", err)
+		}
+		if undos, _ := storage.GetUndoActions(context.Background(), scaled.ID); len(undos) != 0 {
+			t.Errorf("%d compensations saved while queued", len(undos))
+		}
+	})
+
+	t.Run("a failed undo can be retried", func(t *testing.T) {
+		undo, _, storage, backend, scaled := newUndoTest(t)
+		close(backend.gate)
+		undoOf := scaled.ID
+		storage.SaveAction(context.Background(), &models.Action{ID: uuid.New().String(), UndoOf: &undoOf, Status: models.ActionStatusFailed})
+
+		if _, err := undo.Undo(context.Background(), scaled.ClientID, scaled.ID); err != nil {
+			t.Errorf("err = %v", err)
+		}
+	})
+}
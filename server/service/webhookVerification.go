@@ -0,0 +1,113 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	models "server/model"
+	"server/repositories"
+	"server/utils"
+	"time"
+)
+
+var (
+	ErrWebhookVerification = errors.New("webhook verification failed")
+	ErrNoWebhook           = errors.New("client has no webhook_url (pull mode)")
+)
+
+// WebhookVerifier prueba que el webhook_url es del cliente: le mandamos un challenge firmado
+// con su webhook secret y su SDK tiene que contestar con su propia firma del challenge.
+// Hasta que eso pase el executor no le manda acciones al webhook.
+type WebhookVerifier struct {
+	clients    repositories.ClientStorage
+	httpClient *http.Client
+}
+
+func NewWebhookVerifier(clients repositories.ClientStorage) *WebhookVerifier {
+	return &WebhookVerifier{
+		clients:    clients,
+		httpClient: utils.OutboundGuard().WebhookClient(10 * time.Second),
+	}
+}
+
+// Verify corre el challenge contra el webhook actual del cliente
+func (v *WebhookVerifier) Verify(ctx context.Context, clientID string) (*models.Client, error) {
+	client, err := v.clients.GetClient(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client.WebhookURL == "" {
+		return client, ErrNoWebhook
+	}
+
+	if err := v.challenge(ctx, client); err != nil {
+		return client, fmt.Errorf("%w: %v", ErrWebhookVerification, err)
+	}
+
+	now := time.Now()
+	if err := v.clients.MarkWebhookVerified(ctx, client.ID.String(), client.WebhookURL, now); err != nil {
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return client, fmt.Errorf("%w: webhook_url changed during verification", ErrWebhookVerification)
+		}
+		return client, err
+	}
+	client.WebhookVerifiedAt = &now
+
+	return client, nil
+}
+
+// ChangeURL cambia el webhook (queda sin verificar) e intenta verificarlo. Si la verificacion
+// falla la url nueva queda guardada igual, el cliente la puede reintentar con Verify.
+func (v *WebhookVerifier) ChangeURL(ctx context.Context, clientID, webhookURL string) (*models.Client, error) {
+	if err := utils.OutboundGuard().ValidateURL(ctx, webhookURL); err != nil {
+		return nil, fmt.Errorf("invalid webhook_url: %w", err)
+	}
+
+	if err := v.clients.UpdateWebhookURL(ctx, clientID, webhookURL); err != nil {
+		return nil, err
+	}
+
+	return v.Verify(ctx, clientID)
+}
+
+func (v *WebhookVerifier) challenge(ctx context.Context, client *models.Client) error {
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	challenge := hex.EncodeToString(nonce)
+
+	payload, _ := json.Marshal(map[string]string{"challenge": challenge})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, client.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-type", "application/json")
+	req.Header.Set(models.ChallengeHeader, challenge)
+	req.Header.Set(models.SignatureHeader, models.SignChallenge(client.WebhookSecret, models.ChallengeFromBackend, challenge))
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("webhook answered %d", resp.StatusCode)
+	}
+
+	var answer models.WebhookChallengeResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4<<10)).Decode(&answer); err != nil {
+		return errors.New("invalid challenge response")
+	}
+	if answer.Challenge != challenge || !models.VerifyChallenge(client.WebhookSecret, models.ChallengeFromSDK, challenge, answer.Signature) {
+		return errors.New("challenge signature does not match")
+	}
+	return nil
+}
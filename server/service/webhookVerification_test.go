@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	models "server/model"
+	"server/repositories"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeClients guarda un solo cliente; MarkWebhookVerified falla si la url cambio, como la base
+type fakeClients struct {
+	repositories.ClientStorage
+
+	client *models.Client
+	marked int
+}
+
+func (f *fakeClients) GetClient(ctx context.Context, id string) (*models.Client, error) {
+	c := *f.client
+	return &c, nil
+}
+
+func (f *fakeClients) UpdateWebhookURL(ctx context.Context, id string, webhookURL string) error {
+	f.client.WebhookURL = webhookURL
+	f.client.WebhookVerifiedAt = nil
+	return nil
+}
+
+func (f *fakeClients) MarkWebhookVerified(ctx context.Context, id string, webhookURL string, at time.Time) error {
+	if f.client.WebhookURL != webhookURL {
+		return repositories.ErrUserNotFound
+	}
+	f.client.WebhookVerifiedAt = &at
+	f.marked++
+	return nil
+}
+
+func TestWebhookVerifierVerify(t *testing.T) {
+	const secret = "whsec_test"
+
+	// answer es lo que contesta el SDK al challenge que le llego
+	tests := []struct {
+		name    string
+		answer  func(w http.ResponseWriter, r *http.Request, clients *fakeClients)
+		wantErr bool
+	}{
+		{
+			name: "the sdk signs the challenge",
+			answer: func(w http.ResponseWriter, r *http.Request, _ *fakeClients) {
+				challenge := r.Header.Get(models.ChallengeHeader)
+				if !models.VerifyChallenge(secret, models.ChallengeFromBackend, challenge, r.Header.Get(models.SignatureHeader)) {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				json.NewEncoder(w).Encode(models.WebhookChallengeResponse{
+					Challenge: challenge,
+					Signature: models.SignChallenge(secret, models.ChallengeFromSDK, challenge),
+				})
+			},
+		},
+		{
+			name: "someone else's secret",
+			answer: func(w http.ResponseWriter, r *http.Request, _ *fakeClients) {
+				challenge := r.Header.Get(models.ChallengeHeader)
+				json.NewEncoder(w).Encode(models.WebhookChallengeResponse{
+					Challenge: challenge,
+					Signature: models.SignChallenge("other", models.ChallengeFromSDK, challenge),
+				})
+			},
+			wantErr: true,
+		},
+		{
+			name: "echoing the backend signature",
+			answer: func(w http.ResponseWriter, r *http.Request, _ *fakeClients) {
+				json.NewEncoder(w).Encode(models.WebhookChallengeResponse{
+					Challenge: r.Header.Get(models.ChallengeHeader),
+					Signature: r.Header.Get(models.SignatureHeader),
+				})
+			},
+			wantErr: true,
+		},
+		{
+			name: "another challenge",
+			answer: func(w http.ResponseWriter, r *http.Request, _ *fakeClients) {
+				json.NewEncoder(w).Encode(models.WebhookChallengeResponse{
+					Challenge: "abc",
+					Signature: models.SignChallenge(secret, models.ChallengeFromSDK, "abc"),
+				})
+			},
+			wantErr: true,
+		},
+		{
+			name: "any 200",
+			answer: func(w http.ResponseWriter, r *http.Request, _ *fakeClients) {
+				w.Write([]byte(`{"status":"ok"}`))
+			},
+			wantErr: true,
+		},
+		{
+			name: "error status",
+			answer: func(w http.ResponseWriter, r *http.Request, _ *fakeClients) {
+				w.WriteHeader(http.StatusUnauthorized)
+			},
+			wantErr: true,
+		},
+		{
+			name: "url changed during the challenge",
+			answer: func(w http.ResponseWriter, r *http.Request, clients *fakeClients) {
+				clients.client.WebhookURL = "https://other.example.com/hook"
+				challenge := r.Header.Get(models.ChallengeHeader)
+				json.NewEncoder(w).Encode(models.WebhookChallengeResponse{
+					Challenge: challenge,
+					Signature: models.SignChallenge(secret, models.ChallengeFromSDK, challenge),
+				})
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients := &fakeClients{}
+			srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.answer(w, r, clients)
+			}))
+			defer srv.Close()
+
+			clients.client = &models.Client{ID: uuid.New(), WebhookURL: srv.URL, WebhookSecret: secret}
+			verifier := NewWebhookVerifier(clients)
+			verifier.httpClient = srv.Client()
+
+			client, err := verifier.Verify(context.Background(), clients.client.ID.String())
+			if tt.wantErr {
+				if !errors.Is(err, ErrWebhookVerification) {
+					t.Fatalf("err = %v, want ErrWebhookVerification", err)
+				}
+				if clients.marked != 0 || clients.client.WebhookVerifiedAt != nil {
+					t.Error("marked as verified")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if clients.marked != 1 || client.WebhookVerifiedAt == nil {
+				t.Errorf("marked = %d, verified at = %v", clients.marked, client.WebhookVerifiedAt)
+			}
+		})
+	}
+}
+
+func TestWebhookVerifierPullMode(t *testing.T) {
+	clients := &fakeClients{client: &models.Client{ID: uuid.New(), DeliveryMode: models.DeliveryPull}}
+
+	if _, err := NewWebhookVerifier(clients).Verify(context.Background(), clients.client.ID.String()); !errors.Is(err, ErrNoWebhook) {
+		t.Errorf("err = %v, want ErrNoWebhook", err)
+	}
+}
+
+func TestWebhookVerifierChangeURL(t *testing.T) {
+	verifiedAt := time.Now()
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{name: "plain http", url: "http://hooks.example.com/infragent"},
+		{name: "loopback", url: "https://127.0.0.1:8443/hook"},
+		{name: "cloud metadata", url: "https://169.254.169.254/latest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients := &fakeClients{client: &models.Client{ID: uuid.New(), WebhookURL: "https://old.example.com/hook", WebhookVerifiedAt: &verifiedAt}}
+
+			_, err := NewWebhookVerifier(clients).ChangeURL(context.Background(), clients.client.ID.String(), tt.url)
+			if err == nil {
+				t.Fatal("accepted the url")
+			}
+			// una url rechazada no toca la que estaba verificada
+			if clients.client.WebhookURL != "https://old.example.com/hook" || clients.client.WebhookVerifiedAt == nil {
+				t.Errorf("client = %+v", clients.client)
+			}
+		})
+	}
+}
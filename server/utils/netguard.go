@@ -0,0 +1,305 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrBlockedAddress = errors.New("destination address not allowed")
+	ErrInvalidURL     = errors.New("invalid url")
+)
+
+// maximo de redirects que seguimos en una llamada saliente
+const maxOutboundRedirects = 3
+
+// rangos que no son de internet y no estan cubiertos por los helpers de net.IP
+var reservedNets = mustParseCIDRs(
+	"0.0.0.0/8",       // "esta" red
+	"100.64.0.0/10",   // CGNAT
+	"192.0.0.0/24",    // IETF protocol assignments
+	"192.0.2.0/24",    // TEST-NET-1
+	"198.18.0.0/15",   // benchmarking
+	"198.51.100.0/24", // TEST-NET-2
+	"203.0.113.0/24",  // TEST-NET-3
+	"240.0.0.0/4",     // reservado
+	"64:ff9b::/96",    // NAT64 (puede apuntar a una IPv4 interna)
+	"2001:db8::/32",   // documentacion
+)
+
+// NetGuard protege las llamadas salientes a URLs que nos dio un cliente (webhooks, APIs de
+// su infraestructura) contra SSRF: resuelve el host, valida cada IP y se conecta a la IP
+// validada (no vuelve a resolver, asi un DNS rebinding no sirve). Las IPs privadas,
+// loopback y link-local (ej: la metadata del cloud) estan bloqueadas salvo que la
+// instalacion sea self-hosted y las habilite.
+//
+// Variables de entorno:
+//
+//	OUTBOUND_ALLOW_PRIVATE=true      permite cualquier IP no publica
+//	OUTBOUND_ALLOWED_CIDRS=10.1.0.0/16,fd00::/8   permite solo esos rangos
+type NetGuard struct {
+	allowPrivate bool
+	allowed      []*net.IPNet
+	resolver     *net.Resolver
+}
+
+var (
+	guardOnce sync.Once
+	guard     *NetGuard
+)
+
+// OutboundGuard devuelve el guard configurado por entorno
+func OutboundGuard() *NetGuard {
+	guardOnce.Do(func() {
+		g, err := NewNetGuard(os.Getenv("OUTBOUND_ALLOW_PRIVATE") == "true", os.Getenv("OUTBOUND_ALLOWED_CIDRS"))
+		if err != nil {
+			// una config rota no puede abrir la red interna: usamos la estricta
+			g = &NetGuard{resolver: net.DefaultResolver}
+		}
+		guard = g
+	})
+	return guard
+}
+
+// NewNetGuard arma un guard; allowedCIDRs es una lista separada por comas
+func NewNetGuard(allowPrivate bool, allowedCIDRs string) (*NetGuard, error) {
+	g := &NetGuard{allowPrivate: allowPrivate, resolver: net.DefaultResolver}
+
+	for _, cidr := range strings.Split(allowedCIDRs, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed cidr %q: %w", cidr, err)
+		}
+		g.allowed = append(g.allowed, network)
+	}
+
+	return g, nil
+}
+
+// CheckIP devuelve ErrBlockedAddress si la IP no es publica y no esta permitida
+func (g *NetGuard) CheckIP(ip net.IP) error {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4 // ::ffff:10.0.0.1 es 10.0.0.1
+	}
+
+	for _, network := range g.allowed {
+		if network.Contains(ip) {
+			return nil
+		}
+	}
+
+	if isPublicIP(ip) || g.allowPrivate {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrBlockedAddress, ip)
+}
+
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, network := range reservedNets {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateURL valida una URL de cliente al registrarla: https, sin credenciales,
+// y que todas las IPs a las que resuelve esten permitidas
+func (g *NetGuard) ValidateURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("%w: must use https", ErrInvalidURL)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: must not contain credentials", ErrInvalidURL)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("%w: missing host", ErrInvalidURL)
+	}
+
+	return g.CheckHost(ctx, u.Hostname())
+}
+
+// CheckHost resuelve el host y falla si alguna de sus IPs no esta permitida. Es para
+// conexiones que no abrimos nosotros (ej: git): ahi no podemos usar DialContext
+func (g *NetGuard) CheckHost(ctx context.Context, host string) error {
+	ips, err := g.resolve(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, ip := range ips {
+		if err := g.CheckIP(ip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *NetGuard) resolve(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	addrs, err := g.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("resolve %s: no addresses", host)
+	}
+
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+	}
+	return ips, nil
+}
+
+// DialContext resuelve una vez, descarta las IPs bloqueadas y se conecta a una IP validada.
+// El Control del dialer vuelve a chequear la IP real del socket antes de conectar.
+func (g *NetGuard) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := g.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   g.control,
+	}
+
+	var lastErr error
+	for _, ip := range ips {
+		if err := g.CheckIP(ip); err != nil {
+			lastErr = err
+			continue
+		}
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// control corre justo antes del connect con la IP real del socket
+func (g *NetGuard) control(_, address string, _ syscall.RawConn) error {
+	h, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(h)
+	if ip == nil {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, h)
+	}
+	return g.CheckIP(ip)
+}
+
+// Transport es un http.Transport que solo se conecta a destinos permitidos (sin proxy:
+// un proxy resolveria el host por su cuenta). tlsConfig puede ser nil.
+func (g *NetGuard) Transport(tlsConfig *tls.Config) *http.Transport {
+	return &http.Transport{
+		Proxy:                 nil,
+		DialContext:           g.DialContext,
+		TLSClientConfig:       tlsConfig,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// Client es un http.Client con el Transport guardado y a lo sumo maxOutboundRedirects
+// redirects, todos a https (el destino de cada uno se valida al conectar)
+func (g *NetGuard) Client(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:       timeout,
+		Transport:     g.Transport(nil),
+		CheckRedirect: checkOutboundRedirect,
+	}
+}
+
+// WebhookClient es Client para los webhooks de los clientes: si hay certificado configurado
+// el backend se presenta con el, asi el SDK puede exigir mTLS
+//
+//	WEBHOOK_CLIENT_CERT=/etc/infragent/webhook.crt
+//	WEBHOOK_CLIENT_KEY=/etc/infragent/webhook.key
+func (g *NetGuard) WebhookClient(timeout time.Duration) *http.Client {
+	client := g.Client(timeout)
+	client.Transport = g.Transport(webhookTLSConfig())
+	return client
+}
+
+var (
+	webhookTLSOnce sync.Once
+	webhookTLS     *tls.Config
+)
+
+func webhookTLSConfig() *tls.Config {
+	webhookTLSOnce.Do(func() {
+		certFile, keyFile := os.Getenv("WEBHOOK_CLIENT_CERT"), os.Getenv("WEBHOOK_CLIENT_KEY")
+		if certFile == "" && keyFile == "" {
+			return
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			// sin certificado los webhooks con mTLS nos rechazan, el resto sigue andando
+			log.Printf("[NetGuard] no se pudo cargar el certificado de cliente de los webhooks: %v", err)
+			return
+		}
+		webhookTLS = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	})
+	return webhookTLS
+}
+
+func checkOutboundRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > maxOutboundRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxOutboundRedirects)
+	}
+	if req.URL.Scheme != "https" {
+		return fmt.Errorf("%w: redirect to %s", ErrInvalidURL, req.URL.Scheme)
+	}
+	return nil
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrNoCredentialsKey: sin CREDENTIALS_KEY no guardamos credenciales de clientes en texto plano
+var ErrNoCredentialsKey = errors.New("CREDENTIALS_KEY is not configured")
+
+// CREDENTIALS_KEY es una clave AES-256 en base64 (32 bytes): openssl rand -base64 32
+func credentialsKey() ([]byte, error) {
+	raw := os.Getenv("CREDENTIALS_KEY")
+	if raw == "" {
+		return nil, ErrNoCredentialsKey
+	}
+
+	key, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("CREDENTIALS_KEY must be 32 bytes encoded in base64")
+	}
+	return key, nil
+}
+
+// EncryptSecret cifra con AES-GCM las credenciales que nos da el cliente (kubeconfig, etc)
+func EncryptSecret(plaintext []byte) (string, error) {
+	key, err := credentialsKey()
+	if err != nil {
+		return "", err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func DecryptSecret(ciphertext string) ([]byte, error) {
+	key, err := credentialsKey()
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("invalid secret")
+	}
+
+	nonce, data := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, data, nil)
+}
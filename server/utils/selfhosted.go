@@ -0,0 +1,12 @@
+package utils
+
+import "os"
+
+// SelfHosted: la instalacion corre en la infraestructura del propio cliente (un solo tenant).
+// Solo en ese caso los backends pueden usar recursos del servidor mismo, como el socket
+// de Docker local o repos git en un path local; en la nube serian de todos los tenants.
+//
+//	INFRAGENT_SELF_HOSTED=true
+func SelfHosted() bool {
+	return os.Getenv("INFRAGENT_SELF_HOSTED") == "true"
+}